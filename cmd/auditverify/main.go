@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/azainwork/core-banking-api/db"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/joho/godotenv"
+)
+
+// auditverify walks the audit log hash chains and exits with status 1 when an
+// entry was changed, removed or inserted. Removing entries from the end keeps
+// a chain valid, so keep the printed heads somewhere safe and pass them as
+// -anchor on the next run.
+func main() {
+	anchor := flag.String("anchor", "", "comma-separated heads printed by an earlier run, each as <shard>:<seq>:<hash>")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Println("No .env file found")
+	}
+
+	piiKeys, err := utils.LoadPIIKeyProvider()
+	if err != nil {
+		log.Fatalf("failed to load PII keys: %v", err)
+	}
+	utils.SetPIIKeyProvider(piiKeys)
+
+	database, err := db.InitDB()
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+
+	auditService := services.NewAuditService(database)
+
+	result, err := auditService.Verify()
+	if err != nil {
+		log.Fatalf("failed to verify audit log: %v", err)
+	}
+
+	if *anchor != "" {
+		if err := auditService.CheckAnchors(result, strings.Split(*anchor, ",")); err != nil {
+			log.Fatalf("failed to check anchors: %v", err)
+		}
+	}
+
+	for _, problem := range result.Problems {
+		fmt.Printf("shard %d seq %d: %s\n", problem.Shard, problem.Seq, problem.Problem)
+	}
+
+	heads := make([]string, 0, len(result.Chains))
+	for _, chain := range result.Chains {
+		heads = append(heads, chain.Anchor())
+	}
+	fmt.Printf("checked %d entries, heads %s\n", result.Entries, strings.Join(heads, ","))
+
+	if !result.Valid {
+		fmt.Println("audit log has been tampered with")
+		os.Exit(1)
+	}
+	fmt.Println("audit log is intact")
+}
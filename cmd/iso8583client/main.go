@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/azainwork/core-banking-api/iso8583"
+)
+
+func main() {
+	addr := flag.String("addr", "localhost:8583", "ISO 8583 listener address")
+	mti := flag.String("mti", "0100", "message type: 0100 authorization, 0200 financial, 0400 reversal, 0800 echo")
+	pan := flag.String("pan", "", "primary account number")
+	expiry := flag.String("expiry", "", "card expiry as YYMM")
+	cvv := flag.String("cvv", "", "CVV2, sent in field 48")
+	amount := flag.Float64("amount", 0, "transaction amount in major units")
+	replacement := flag.Float64("replacement", -1, "actual amount for a partial reversal")
+	currency := flag.String("currency", "840", "ISO 4217 numeric currency code")
+	processingCode := flag.String("processing-code", "000000", "processing code; 01xxxx is a cash withdrawal")
+	entryMode := flag.String("entry-mode", "051", "POS entry mode; 01x or 81x is card-not-present")
+	rrn := flag.String("rrn", "", "retrieval reference number; generated when empty")
+	merchant := flag.String("merchant", "TEST MERCHANT", "card acceptor name")
+	certFile := flag.String("cert", "", "acquirer client certificate")
+	keyFile := flag.String("key", "", "acquirer client key")
+	caFile := flag.String("ca", "", "CA that signed the listener certificate")
+	flag.Parse()
+
+	now := time.Now().UTC()
+	if *rrn == "" {
+		*rrn = now.Format("060102150405")
+	}
+
+	msg := iso8583.NewMessage(*mti)
+	msg.Set(7, now.Format("0102150405"))
+	msg.Set(11, fmt.Sprintf("%06d", now.UnixNano()%1000000))
+
+	if *mti != "0800" {
+		msg.Set(2, *pan)
+		msg.Set(3, *processingCode)
+		msg.Set(4, minorUnits(*amount))
+		msg.Set(12, now.Format("150405"))
+		msg.Set(13, now.Format("0102"))
+		msg.Set(22, *entryMode)
+		msg.Set(37, *rrn)
+		msg.Set(41, "TERM0001")
+		msg.Set(42, "MERCHANT0000001")
+		msg.Set(43, *merchant)
+		msg.Set(49, *currency)
+
+		if *expiry != "" {
+			msg.Set(14, *expiry)
+		}
+		if *cvv != "" {
+			msg.Set(48, *cvv)
+		}
+		if *replacement >= 0 {
+			msg.Set(95, fmt.Sprintf("%012s%030d", minorUnits(*replacement), 0))
+		}
+	} else {
+		msg.Set(70, "301")
+	}
+
+	tlsConfig, err := clientTLSConfig(*certFile, *keyFile, *caFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", *addr, tlsConfig)
+	if err != nil {
+		log.Fatalf("failed to connect: %v", err)
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+
+	if err := iso8583.WriteMessage(conn, msg); err != nil {
+		log.Fatalf("failed to send message: %v", err)
+	}
+
+	resp, err := iso8583.ReadMessage(conn)
+	if err != nil {
+		log.Fatalf("failed to read response: %v", err)
+	}
+
+	fmt.Printf("MTI: %s\n", resp.MTI)
+	for _, field := range resp.Fields() {
+		fmt.Printf("  %03d: %s\n", field, resp.Get(field))
+	}
+}
+
+func clientTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, fmt.Errorf("-cert, -key and -ca are required")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA: %v", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("CA file contains no certificates")
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{certificate},
+		RootCAs:      rootCAs,
+		MinVersion:   tls.VersionTLS12,
+	}, nil
+}
+
+func minorUnits(amount float64) string {
+	return strconv.FormatInt(int64(math.Round(amount*100)), 10)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// jwtkey writes a new signing key to the JWT keys directory. Set
+// JWT_ACTIVE_KID to the printed key ID to start signing with it; older keys
+// left in the directory keep verifying tokens until they are removed.
+func main() {
+	dir := flag.String("dir", "keys", "directory holding the JWT keys")
+	alg := flag.String("alg", "EdDSA", "signing algorithm: EdDSA or RS256")
+	kid := flag.String("kid", "", "key ID; defaults to the current UTC time")
+	flag.Parse()
+
+	if *kid == "" {
+		*kid = time.Now().UTC().Format("20060102T150405Z")
+	}
+
+	var key crypto.Signer
+	var err error
+	switch *alg {
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case "RS256":
+		key, err = rsa.GenerateKey(rand.Reader, 3072)
+	default:
+		log.Fatalf("unsupported algorithm %q", *alg)
+	}
+	if err != nil {
+		log.Fatalf("failed to generate key: %v", err)
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		log.Fatalf("failed to encode key: %v", err)
+	}
+
+	if err := os.MkdirAll(*dir, 0o700); err != nil {
+		log.Fatalf("failed to create key directory: %v", err)
+	}
+
+	path := filepath.Join(*dir, *kid+".pem")
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		log.Fatalf("failed to create key file: %v", err)
+	}
+	defer file.Close()
+
+	if err := pem.Encode(file, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		log.Fatalf("failed to write key: %v", err)
+	}
+
+	fmt.Printf("wrote %s\nJWT_KEYS_DIR=%s\nJWT_ACTIVE_KID=%s\n", path, *dir, *kid)
+}
@@ -3,10 +3,14 @@ package main
 import (
 	"log"
 	"os"
+	"strings"
 
 	"github.com/azainwork/core-banking-api/db"
+	"github.com/azainwork/core-banking-api/iso8583"
+	"github.com/azainwork/core-banking-api/jobs"
 	"github.com/azainwork/core-banking-api/middleware"
 	"github.com/azainwork/core-banking-api/routes"
+	"github.com/azainwork/core-banking-api/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
 	"github.com/sirupsen/logrus"
@@ -19,6 +23,23 @@ func main() {
 
 	logger := logrus.New()
 	logger.SetFormatter(&logrus.JSONFormatter{})
+	logger.AddHook(utils.PIIRedactionHook{})
+
+	keyring, err := utils.LoadJWTKeys()
+	if err != nil {
+		logger.Fatal("Failed to load JWT keys:", err)
+	}
+	utils.SetJWTKeyring(keyring)
+
+	piiKeys, err := utils.LoadPIIKeyProvider()
+	if err != nil {
+		logger.Fatal("Failed to load PII keys:", err)
+	}
+	utils.SetPIIKeyProvider(piiKeys)
+
+	if _, err := utils.CardBIN(); err != nil {
+		logger.Fatal("Invalid CARD_BIN:", err)
+	}
 
 	database, err := db.InitDB()
 	if err != nil {
@@ -31,9 +52,22 @@ func main() {
 
 	router := gin.New()
 
-	router.Use(gin.Logger())
+	// ClientIP feeds rate limits, login throttling and the audit log, so
+	// X-Forwarded-For is only honoured from the proxies listed here.
+	var trustedProxies []string
+	for _, proxy := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		if proxy = strings.TrimSpace(proxy); proxy != "" {
+			trustedProxies = append(trustedProxies, proxy)
+		}
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		logger.Fatal("Invalid TRUSTED_PROXIES:", err)
+	}
+
+	router.Use(middleware.RequestID())
+	router.Use(gin.LoggerWithFormatter(middleware.RedactedLogFormatter))
 	router.Use(gin.Recovery())
-	router.Use(middleware.CORS())
+	router.Use(middleware.CORS(middleware.CORSConfigFromEnv()))
 	router.Use(middleware.RequestLogger(logger))
 
 	routes.SetupRoutes(router, database)
@@ -43,6 +77,24 @@ func main() {
 		port = "8082"
 	}
 
+	scheduler := jobs.NewScheduler(logger)
+	jobs.Register(scheduler, database, logger)
+	scheduler.Start()
+
+	isoConfig, err := iso8583.ListenerConfigFromEnv()
+	if err != nil {
+		logger.Fatal("Failed to configure ISO 8583 listener:", err)
+	}
+	if isoConfig != nil {
+		isoServer := iso8583.NewServer(database, logger)
+		go func() {
+			logger.Info("Starting ISO 8583 listener on " + isoConfig.Addr)
+			if err := isoServer.ListenAndServe(isoConfig); err != nil {
+				logger.Fatal("Failed to start ISO 8583 listener:", err)
+			}
+		}()
+	}
+
 	logger.Info("Starting Core Banking API server on port " + port)
 	if err := router.Run(":" + port); err != nil {
 		logger.Fatal("Failed to start server:", err)
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// piikey appends a new key encryption key to the local PII key file, creating
+// the file with a blind index key first if needed. Set PII_ACTIVE_KID to the
+// printed key ID, or leave it unset to use the last key in the file; rows
+// under older keys are re-encrypted in the background.
+func main() {
+	path := flag.String("file", "keys/pii.keys", "PII key file")
+	kid := flag.String("kid", "", "key ID; defaults to the current UTC time")
+	flag.Parse()
+
+	if *kid == "" {
+		*kid = time.Now().UTC().Format("20060102T150405Z")
+	}
+	if *kid == "index" {
+		log.Fatal(`"index" is reserved for the blind index key`)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(*path), 0o700); err != nil {
+		log.Fatalf("failed to create key directory: %v", err)
+	}
+
+	_, err := os.Stat(*path)
+	isNew := os.IsNotExist(err)
+
+	file, err := os.OpenFile(*path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		log.Fatalf("failed to open key file: %v", err)
+	}
+	defer file.Close()
+
+	if isNew {
+		if _, err := fmt.Fprintf(file, "index %s\n", newKey()); err != nil {
+			log.Fatalf("failed to write key: %v", err)
+		}
+	}
+	if _, err := fmt.Fprintf(file, "%s %s\n", *kid, newKey()); err != nil {
+		log.Fatalf("failed to write key: %v", err)
+	}
+
+	fmt.Printf("wrote key %s to %s\nPII_KEY_FILE=%s\nPII_ACTIVE_KID=%s\n", *kid, *path, *path, *kid)
+}
+
+func newKey() string {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		log.Fatalf("failed to generate key: %v", err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
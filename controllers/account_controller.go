@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"net/http"
+	"time"
 	_"strconv"
 
 	"github.com/azainwork/core-banking-api/models"
@@ -13,11 +14,15 @@ import (
 
 type AccountController struct {
 	accountService *services.AccountService
+	holdService    *services.HoldService
+	balanceService *services.BalanceService
 }
 
 func NewAccountController(db *gorm.DB) *AccountController {
 	return &AccountController{
 		accountService: services.NewAccountService(db),
+		holdService:    services.NewHoldService(db),
+		balanceService: services.NewBalanceService(db),
 	}
 }
 
@@ -41,7 +46,7 @@ func (c *AccountController) CreateAccount(ctx *gin.Context) {
 
 	accountType := models.AccountType(req.Type)
 
-	account, err := c.accountService.CreateAccount(userID.(string), accountType, req.InitialBalance)
+	account, err := c.accountService.CreateAccount(userID.(string), accountType, req.InitialBalance, auditActor(ctx))
 	if err != nil {
 		utils.InternalServerError(ctx, err.Error())
 		return
@@ -51,6 +56,7 @@ func (c *AccountController) CreateAccount(ctx *gin.Context) {
 		"account": gin.H{
 			"id":             account.ID,
 			"account_number": account.AccountNumber,
+			"iban":           utils.GenerateIBAN(account.AccountNumber),
 			"type":           account.Type,
 			"balance":        account.Balance,
 			"currency":       account.Currency,
@@ -77,10 +83,12 @@ func (c *AccountController) GetAccounts(ctx *gin.Context) {
 		accountList = append(accountList, gin.H{
 			"id":             account.ID,
 			"account_number": account.AccountNumber,
+			"iban":           utils.GenerateIBAN(account.AccountNumber),
 			"type":           account.Type,
 			"balance":        account.Balance,
 			"currency":       account.Currency,
 			"is_active":      account.IsActive,
+			"is_frozen":      account.IsFrozen,
 			"created_at":     account.CreatedAt,
 		})
 	}
@@ -119,10 +127,12 @@ func (c *AccountController) GetAccount(ctx *gin.Context) {
 		"account": gin.H{
 			"id":             account.ID,
 			"account_number": account.AccountNumber,
+			"iban":           utils.GenerateIBAN(account.AccountNumber),
 			"type":           account.Type,
 			"balance":        account.Balance,
 			"currency":       account.Currency,
 			"is_active":      account.IsActive,
+			"is_frozen":      account.IsFrozen,
 			"created_at":     account.CreatedAt,
 			"updated_at":     account.UpdatedAt,
 		},
@@ -153,9 +163,92 @@ func (c *AccountController) GetAccountBalance(ctx *gin.Context) {
 		return
 	}
 
+	if asOfStr := ctx.Query("as_of"); asOfStr != "" {
+		var balance float64
+		var asOf time.Time
+		if date, err := time.Parse("2006-01-02", asOfStr); err == nil {
+			asOf = time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, utils.BankLocation()).AddDate(0, 0, 1).Add(-time.Nanosecond)
+			balance, err = c.balanceService.GetClosingBalance(accountID, date)
+			if err != nil {
+				utils.ValidationError(ctx, err.Error())
+				return
+			}
+		} else if asOf, err = time.Parse(time.RFC3339, asOfStr); err == nil {
+			balance, err = c.balanceService.GetBalanceAsOf(accountID, asOf)
+			if err != nil {
+				utils.ValidationError(ctx, err.Error())
+				return
+			}
+		} else {
+			utils.ValidationError(ctx, "Invalid as_of parameter, expected RFC 3339 timestamp or YYYY-MM-DD")
+			return
+		}
+
+		utils.SuccessResponse(ctx, http.StatusOK, "Account balance retrieved successfully", gin.H{
+			"account_id": account.ID,
+			"balance":    balance,
+			"currency":   account.Currency,
+			"as_of":      asOf,
+		})
+		return
+	}
+
+	availableBalance, err := c.holdService.GetAvailableBalance(account)
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
 	utils.SuccessResponse(ctx, http.StatusOK, "Account balance retrieved successfully", gin.H{
-		"account_id": account.ID,
-		"balance":    account.Balance,
-		"currency":   account.Currency,
+		"account_id":        account.ID,
+		"balance":           account.Balance,
+		"available_balance": availableBalance,
+		"currency":          account.Currency,
 	})
-} 
\ No newline at end of file
+} 
+
+func (c *AccountController) GetDailyBalances(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	to := utils.BusinessDate(time.Now())
+	if toStr := ctx.Query("to"); toStr != "" {
+		parsed, err := time.Parse("2006-01-02", toStr)
+		if err != nil {
+			utils.ValidationError(ctx, "Invalid to parameter, expected YYYY-MM-DD")
+			return
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -29)
+	if fromStr := ctx.Query("from"); fromStr != "" {
+		parsed, err := time.Parse("2006-01-02", fromStr)
+		if err != nil {
+			utils.ValidationError(ctx, "Invalid from parameter, expected YYYY-MM-DD")
+			return
+		}
+		from = parsed
+	}
+
+	balances, err := c.balanceService.GetDailyBalances(accountID, from, to)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Daily balances retrieved successfully", gin.H{
+		"account_id": accountID,
+		"balances":   balances,
+		"count":      len(balances),
+	})
+}
@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type AdminController struct {
+	adminService       *services.AdminService
+	transactionService *services.TransactionService
+}
+
+func NewAdminController(db *gorm.DB) *AdminController {
+	return &AdminController{
+		adminService:       services.NewAdminService(db),
+		transactionService: services.NewTransactionService(db),
+	}
+}
+
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=customer teller admin"`
+}
+
+type CashDepositRequest struct {
+	Amount      float64         `json:"amount" binding:"required,gt=0"`
+	Description string          `json:"description"`
+	Metadata    models.Metadata `json:"metadata"`
+	Tags        []string        `json:"tags"`
+}
+
+func (c *AdminController) GetUsers(ctx *gin.Context) {
+	limit, offset := pagination(ctx)
+
+	users, total, err := c.adminService.GetUsers(services.UserFilter{
+		Search: ctx.Query("q"),
+		Role:   models.UserRole(ctx.Query("role")),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	userList := []gin.H{}
+	for _, user := range users {
+		userList = append(userList, adminUserResponse(&user))
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Users retrieved successfully", gin.H{
+		"users":  userList,
+		"count":  len(userList),
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (c *AdminController) GetUser(ctx *gin.Context) {
+	user, err := c.adminService.GetUser(ctx.Param("id"))
+	if err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	lockedUntil, err := c.adminService.GetLoginLockout(user)
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	response := adminUserResponse(user)
+	response["accounts"] = user.Accounts
+	response["locked_until"] = lockedUntil
+
+	utils.SuccessResponse(ctx, http.StatusOK, "User retrieved successfully", gin.H{
+		"user": response,
+	})
+}
+
+func (c *AdminController) UpdateUserRole(ctx *gin.Context) {
+	var req UpdateUserRoleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	user, err := c.adminService.UpdateUserRole(auditActor(ctx), ctx.Param("id"), models.UserRole(req.Role))
+	if err != nil {
+		adminUserError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "User role updated successfully", gin.H{
+		"user": adminUserResponse(user),
+	})
+}
+
+func (c *AdminController) ActivateUser(ctx *gin.Context) {
+	user, err := c.adminService.SetUserActive(auditActor(ctx), ctx.Param("id"), true)
+	if err != nil {
+		adminUserError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "User activated successfully", gin.H{
+		"user": adminUserResponse(user),
+	})
+}
+
+func (c *AdminController) DeactivateUser(ctx *gin.Context) {
+	user, err := c.adminService.SetUserActive(auditActor(ctx), ctx.Param("id"), false)
+	if err != nil {
+		adminUserError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "User deactivated successfully", gin.H{
+		"user": adminUserResponse(user),
+	})
+}
+
+func (c *AdminController) UnlockUser(ctx *gin.Context) {
+	user, err := c.adminService.UnlockUser(auditActor(ctx), ctx.Param("id"))
+	if err != nil {
+		adminUserError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "User unlocked successfully", gin.H{
+		"user": adminUserResponse(user),
+	})
+}
+
+func (c *AdminController) GetSecurityEvents(ctx *gin.Context) {
+	limit, offset := pagination(ctx)
+
+	events, total, err := c.adminService.GetSecurityEvents(models.SecurityEventType(ctx.Query("type")), ctx.Query("user_id"), limit, offset)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Security events retrieved successfully", gin.H{
+		"events": events,
+		"count":  len(events),
+		"total":  total,
+		"limit":  limit,
+		"offset": offset,
+	})
+}
+
+func (c *AdminController) GetAccounts(ctx *gin.Context) {
+	limit, offset := pagination(ctx)
+
+	accounts, total, err := c.adminService.GetAccounts(services.AccountFilter{
+		UserID: ctx.Query("user_id"),
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Accounts retrieved successfully", gin.H{
+		"accounts": accounts,
+		"count":    len(accounts),
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+func (c *AdminController) GetAccount(ctx *gin.Context) {
+	account, err := c.adminService.GetAccount(ctx.Param("id"))
+	if err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Account retrieved successfully", gin.H{
+		"account": account,
+	})
+}
+
+func (c *AdminController) CloseAccount(ctx *gin.Context) {
+	account, err := c.adminService.SetAccountActive(auditActor(ctx), ctx.Param("id"), false)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Account closed successfully", gin.H{
+		"account": account,
+	})
+}
+
+func (c *AdminController) ReopenAccount(ctx *gin.Context) {
+	account, err := c.adminService.SetAccountActive(auditActor(ctx), ctx.Param("id"), true)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Account reopened successfully", gin.H{
+		"account": account,
+	})
+}
+
+func (c *AdminController) CashDeposit(ctx *gin.Context) {
+	var req CashDepositRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	metadata, tags, err := validateAnnotations(req.Metadata, req.Tags)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+	metadata["teller_id"] = ctx.GetString("user_id")
+	metadata["channel"] = "branch"
+
+	description := req.Description
+	if description == "" {
+		description = "Cash deposit"
+	}
+
+	transaction, err := c.transactionService.ProcessDeposit(ctx.Param("id"), req.Amount, description, metadata, tags, auditActor(ctx))
+	if err != nil {
+		if err.Error() == "account not found" || err.Error() == "invalid account ID" {
+			utils.NotFoundError(ctx, err.Error())
+			return
+		}
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Cash deposit processed successfully", gin.H{
+		"transaction": gin.H{
+			"id":             transaction.ID,
+			"transaction_id": transaction.TransactionID,
+			"type":           transaction.Type,
+			"amount":         transaction.Amount,
+			"currency":       transaction.Currency,
+			"status":         transaction.Status,
+			"description":    transaction.Description,
+			"metadata":       transaction.Metadata,
+			"tags":           transaction.Tags,
+			"account_id":     transaction.AccountID,
+			"balance_after":  transaction.BalanceAfter,
+			"value_date":     transaction.ValueDate.Format("2006-01-02"),
+			"created_at":     transaction.CreatedAt,
+		},
+	})
+}
+
+func adminUserResponse(user *models.User) gin.H {
+	return gin.H{
+		"id":          user.ID,
+		"email":       user.Email,
+		"first_name":  user.FirstName,
+		"last_name":   user.LastName,
+		"phone":       user.Phone,
+		"role":        user.Role,
+		"permissions": user.Role.Permissions(),
+		"is_active":   user.IsActive,
+		"created_at":  user.CreatedAt,
+	}
+}
+
+func adminUserError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrCannotModifySelf):
+		utils.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+	case err.Error() == "user not found":
+		utils.NotFoundError(ctx, err.Error())
+	case err.Error() == "invalid user ID" || err.Error() == "invalid role":
+		utils.ValidationError(ctx, err.Error())
+	default:
+		utils.InternalServerError(ctx, err.Error())
+	}
+}
+
+func pagination(ctx *gin.Context) (int, int) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	offset, err := strconv.Atoi(ctx.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	return limit, offset
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type APIKeyController struct {
+	apiKeyService *services.APIKeyService
+}
+
+func NewAPIKeyController(db *gorm.DB) *APIKeyController {
+	return &APIKeyController{
+		apiKeyService: services.NewAPIKeyService(db),
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name       string     `json:"name" binding:"required,max=100"`
+	Scopes     []string   `json:"scopes" binding:"required,min=1"`
+	AllowedIPs []string   `json:"allowed_ips"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+}
+
+type RotateAPIKeyRequest struct {
+	OverlapHours *int `json:"overlap_hours"`
+}
+
+func (c *APIKeyController) CreateAPIKey(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	key, rawKey, err := c.apiKeyService.CreateAPIKey(userID.(string), services.APIKeyInput{
+		Name:       req.Name,
+		Scopes:     req.Scopes,
+		AllowedIPs: req.AllowedIPs,
+		ExpiresAt:  req.ExpiresAt,
+	}, auditActor(ctx))
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "API key created successfully, store it now as it will not be shown again", gin.H{
+		"api_key": apiKeyResponse(key),
+		"key":     rawKey,
+	})
+}
+
+func (c *APIKeyController) GetAPIKeys(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	keys, err := c.apiKeyService.GetAPIKeys(userID.(string))
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	keyList := []gin.H{}
+	for _, key := range keys {
+		keyList = append(keyList, apiKeyResponse(&key))
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "API keys retrieved successfully", gin.H{
+		"api_keys": keyList,
+		"count":    len(keyList),
+		"scopes":   models.APIScopes,
+	})
+}
+
+func (c *APIKeyController) RevokeAPIKey(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	key, err := c.apiKeyService.RevokeAPIKey(userID.(string), ctx.Param("id"), auditActor(ctx))
+	if err != nil {
+		apiKeyError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "API key revoked successfully", gin.H{
+		"api_key": apiKeyResponse(key),
+	})
+}
+
+func (c *APIKeyController) RotateAPIKey(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	var req RotateAPIKeyRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(ctx, err.Error())
+			return
+		}
+	}
+
+	overlap := services.DefaultAPIKeyOverlap
+	if req.OverlapHours != nil {
+		overlap = time.Duration(*req.OverlapHours) * time.Hour
+	}
+
+	key, rawKey, err := c.apiKeyService.RotateAPIKey(userID.(string), ctx.Param("id"), overlap, auditActor(ctx))
+	if err != nil {
+		apiKeyError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "API key rotated successfully, the previous key expires after the overlap window", gin.H{
+		"api_key": apiKeyResponse(key),
+		"key":     rawKey,
+	})
+}
+
+func apiKeyResponse(key *models.APIKey) gin.H {
+	return gin.H{
+		"id":              key.ID,
+		"name":            key.Name,
+		"prefix":          key.Prefix,
+		"scopes":          key.Scopes,
+		"allowed_ips":     key.AllowedIPs,
+		"expires_at":      key.ExpiresAt,
+		"last_used_at":    key.LastUsedAt,
+		"last_used_ip":    key.LastUsedIP,
+		"rotated_from_id": key.RotatedFromID,
+		"revoked_at":      key.RevokedAt,
+		"is_active":       key.IsActive(time.Now()),
+		"created_at":      key.CreatedAt,
+	}
+}
+
+func apiKeyError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAPIKeyNotFound):
+		utils.NotFoundError(ctx, err.Error())
+	case errors.Is(err, services.ErrAPIKeyInactive), errors.Is(err, services.ErrInvalidKeyOverlap):
+		utils.ValidationError(ctx, err.Error())
+	default:
+		utils.InternalServerError(ctx, err.Error())
+	}
+}
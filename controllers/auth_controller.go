@@ -1,7 +1,10 @@
 package controllers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/azainwork/core-banking-api/models"
 	"github.com/azainwork/core-banking-api/services"
@@ -11,18 +14,24 @@ import (
 )
 
 type AuthController struct {
-	userService *services.UserService
+	userService   *services.UserService
+	tokenService  *services.TokenService
+	mfaService    *services.MFAService
+	stepUpService *services.StepUpService
 }
 
 func NewAuthController(db *gorm.DB) *AuthController {
 	return &AuthController{
-		userService: services.NewUserService(db),
+		userService:   services.NewUserService(db),
+		tokenService:  services.NewTokenService(db),
+		mfaService:    services.NewMFAService(db),
+		stepUpService: services.NewStepUpService(db),
 	}
 }
 
 type RegisterRequest struct {
 	Email     string `json:"email" binding:"required,email"`
-	Password  string `json:"password" binding:"required,min=6"`
+	Password  string `json:"password" binding:"required"`
 	FirstName string `json:"first_name" binding:"required"`
 	LastName  string `json:"last_name" binding:"required"`
 	Phone     string `json:"phone"`
@@ -33,6 +42,47 @@ type LoginRequest struct {
 	Password string `json:"password" binding:"required"`
 }
 
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type TransactionPINRequest struct {
+	Password string `json:"password" binding:"required"`
+	PIN      string `json:"pin" binding:"required"`
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
 func (c *AuthController) Register(ctx *gin.Context) {
 	var req RegisterRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -46,57 +96,438 @@ func (c *AuthController) Register(ctx *gin.Context) {
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Phone:     req.Phone,
+		Role:      models.UserRoleCustomer,
 		IsActive:  true,
 	}
 
-	if err := c.userService.RegisterUser(user); err != nil {
-		utils.ConflictError(ctx, err.Error())
+	if err := c.userService.RegisterUser(user, auditActor(ctx)); err != nil {
+		var policyErr *services.PasswordPolicyError
+		if errors.As(err, &policyErr) {
+			utils.ValidationError(ctx, err.Error())
+			return
+		}
+		utils.InternalServerError(ctx, "Failed to register user")
+		return
+	}
+
+	// The response is the same whether or not the email was already
+	// registered, so it carries no user or tokens; the user signs in next.
+	utils.SuccessResponse(ctx, http.StatusAccepted, "Registration received. Check your email to continue", gin.H{
+		"email": req.Email,
+	})
+}
+
+func (c *AuthController) Login(ctx *gin.Context) {
+	var req LoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	user, err := c.userService.LoginUser(req.Email, req.Password, clientInfo(ctx))
+	if err != nil {
+		var throttled *services.LoginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			utils.ErrorResponse(ctx, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, services.ErrInvalidCredentials):
+			utils.UnauthorizedError(ctx, err.Error())
+		default:
+			utils.InternalServerError(ctx, "Login failed")
+		}
+		return
+	}
+
+	if user.MFAEnabled {
+		challenge, err := c.mfaService.CreateChallenge(user, clientInfo(ctx))
+		if err != nil {
+			utils.InternalServerError(ctx, err.Error())
+			return
+		}
+
+		utils.SuccessResponse(ctx, http.StatusOK, "MFA verification required", gin.H{
+			"mfa_required": true,
+			"mfa_token":    challenge.Token,
+			"expires_in":   challenge.ExpiresIn,
+		})
+		return
+	}
+
+	c.completeLogin(ctx, user, false)
+}
+
+func (c *AuthController) LoginMFA(ctx *gin.Context) {
+	var req MFALoginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	user, err := c.mfaService.VerifyChallenge(req.MFAToken, req.Code, clientInfo(ctx))
+	if err != nil {
+		var throttled *services.LoginThrottledError
+		switch {
+		case errors.As(err, &throttled):
+			ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(throttled.RetryAfter.Seconds()))))
+			utils.ErrorResponse(ctx, http.StatusTooManyRequests, err.Error())
+		case errors.Is(err, services.ErrInvalidMFAChallenge) || errors.Is(err, services.ErrInvalidMFACode):
+			utils.UnauthorizedError(ctx, err.Error())
+		default:
+			utils.InternalServerError(ctx, err.Error())
+		}
+		return
+	}
+
+	c.completeLogin(ctx, user, true)
+}
+
+func (c *AuthController) EnrollMFA(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	enrollment, err := c.mfaService.BeginEnrollment(userID.(string))
+	if err != nil {
+		mfaError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Scan the QR code and confirm with a code from your authenticator app", gin.H{
+		"secret":           enrollment.Secret,
+		"provisioning_uri": enrollment.ProvisioningURI,
+		"qr_code":          enrollment.QRCode,
+	})
+}
+
+func (c *AuthController) ConfirmMFA(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	var req MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	recoveryCodes, err := c.mfaService.ConfirmEnrollment(userID.(string), req.Code, auditActor(ctx))
+	if err != nil {
+		mfaError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "MFA enabled successfully", gin.H{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+func (c *AuthController) DisableMFA(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
 		return
 	}
 
-	token, err := utils.GenerateJWTToken(user.ID.String(), user.Email)
+	var req DisableMFARequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	if err := c.mfaService.Disable(userID.(string), req.Password, req.Code, auditActor(ctx)); err != nil {
+		mfaError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "MFA disabled successfully", nil)
+}
+
+func (c *AuthController) SetTransactionPIN(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	var req TransactionPINRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	if err := c.stepUpService.SetTransactionPIN(userID.(string), req.Password, req.PIN, auditActor(ctx)); err != nil {
+		if errors.Is(err, services.ErrInvalidTransactionPIN) {
+			utils.ValidationError(ctx, err.Error())
+			return
+		}
+		mfaError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Transaction PIN set successfully", nil)
+}
+
+func (c *AuthController) RegenerateRecoveryCodes(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	var req MFACodeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	recoveryCodes, err := c.mfaService.RegenerateRecoveryCodes(userID.(string), req.Code, auditActor(ctx))
+	if err != nil {
+		mfaError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Recovery codes regenerated successfully", gin.H{
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+func (c *AuthController) completeLogin(ctx *gin.Context, user *models.User, mfaVerified bool) {
+	tokens, err := c.tokenService.IssueTokens(user, clientInfo(ctx), mfaVerified)
 	if err != nil {
 		utils.InternalServerError(ctx, "Failed to generate token")
 		return
 	}
 
-	utils.SuccessResponse(ctx, http.StatusCreated, "User registered successfully", gin.H{
+	response := gin.H{
 		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"phone":      user.Phone,
+			"id":             user.ID,
+			"email":          user.Email,
+			"first_name":     user.FirstName,
+			"last_name":      user.LastName,
+			"phone":          user.Phone,
+			"role":           user.Role,
+			"mfa_enabled":    user.MFAEnabled,
+			"email_verified": user.IsEmailVerified(),
 		},
-		"token": token,
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"token_type":    tokens.TokenType,
+		"expires_in":    tokens.ExpiresIn,
+		"session_id":    tokens.SessionID,
+	}
+	if user.Role.RequiresMFA() && !user.MFAEnabled {
+		response["mfa_setup_required"] = true
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Login successful", response)
+}
+
+func (c *AuthController) Refresh(ctx *gin.Context) {
+	var req RefreshRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	tokens, err := c.tokenService.Refresh(req.RefreshToken, clientInfo(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReused) {
+			utils.UnauthorizedError(ctx, err.Error())
+			return
+		}
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Token refreshed successfully", gin.H{
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"token_type":    tokens.TokenType,
+		"expires_in":    tokens.ExpiresIn,
+		"session_id":    tokens.SessionID,
 	})
 }
 
-func (c *AuthController) Login(ctx *gin.Context) {
-	var req LoginRequest
+func (c *AuthController) Logout(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	if err := c.tokenService.Logout(userID.(string), ctx.GetString("jti"), ctx.GetString("session_id"), ctx.GetTime("token_expires_at")); err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Logged out successfully", nil)
+}
+
+func (c *AuthController) LogoutAll(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	sessions, err := c.tokenService.LogoutAll(userID.(string))
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Logged out of all devices successfully", gin.H{
+		"sessions_revoked": sessions,
+	})
+}
+
+func (c *AuthController) ForgotPassword(ctx *gin.Context) {
+	var req ForgotPasswordRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
 		utils.ValidationError(ctx, err.Error())
 		return
 	}
 
-	token, user, err := c.userService.LoginUser(req.Email, req.Password)
+	if err := c.userService.RequestPasswordReset(req.Email); err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "If the email is registered, a password reset link has been sent", nil)
+}
+
+func (c *AuthController) ResetPassword(ctx *gin.Context) {
+	var req ResetPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	if err := c.userService.ResetPassword(req.Token, req.Password, auditActor(ctx)); err != nil {
+		passwordError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Password reset successfully, please log in again", nil)
+}
+
+func (c *AuthController) ChangePassword(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	var req ChangePasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	revoked, err := c.userService.ChangePassword(userID.(string), req.CurrentPassword, req.NewPassword, ctx.GetString("session_id"), auditActor(ctx))
 	if err != nil {
-		utils.UnauthorizedError(ctx, err.Error())
+		passwordError(ctx, err)
 		return
 	}
 
-	utils.SuccessResponse(ctx, http.StatusOK, "Login successful", gin.H{
-		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"phone":      user.Phone,
-		},
-		"token": token,
+	utils.SuccessResponse(ctx, http.StatusOK, "Password changed successfully, other sessions have been signed out", gin.H{
+		"sessions_revoked": revoked,
+	})
+}
+
+func (c *AuthController) GetPasswordPolicy(ctx *gin.Context) {
+	utils.SuccessResponse(ctx, http.StatusOK, "Password policy retrieved successfully", gin.H{
+		"policy": c.userService.PasswordPolicy().Rules(),
+	})
+}
+
+func (c *AuthController) VerifyEmail(ctx *gin.Context) {
+	var req VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	user, err := c.userService.VerifyEmail(req.Token, auditActor(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUserToken) {
+			utils.ValidationError(ctx, err.Error())
+			return
+		}
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Email verified successfully, refresh your token to apply it", gin.H{
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
 	})
 }
 
+func (c *AuthController) ConfirmEmailChange(ctx *gin.Context) {
+	var req VerifyEmailRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	user, err := c.userService.ConfirmEmailChange(req.Token, auditActor(ctx))
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidUserToken) {
+			utils.ValidationError(ctx, err.Error())
+			return
+		}
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Email changed successfully, refresh your token to apply it", gin.H{
+		"email":             user.Email,
+		"email_verified_at": user.EmailVerifiedAt,
+	})
+}
+
+func (c *AuthController) ResendEmailVerification(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	user, err := c.userService.GetUserByID(userID.(string))
+	if err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	if err := c.userService.SendEmailVerification(user); err != nil {
+		if errors.Is(err, services.ErrEmailAlreadyVerified) {
+			utils.ConflictError(ctx, err.Error())
+			return
+		}
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Verification email sent", nil)
+}
+
+func (c *AuthController) JWKS(ctx *gin.Context) {
+	keys, err := utils.CurrentJWKS()
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	ctx.Header("Cache-Control", "public, max-age=300")
+	ctx.JSON(http.StatusOK, gin.H{"keys": keys})
+}
+
 func (c *AuthController) GetProfile(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
@@ -112,12 +543,50 @@ func (c *AuthController) GetProfile(ctx *gin.Context) {
 
 	utils.SuccessResponse(ctx, http.StatusOK, "Profile retrieved successfully", gin.H{
 		"user": gin.H{
-			"id":         user.ID,
-			"email":      user.Email,
-			"first_name": user.FirstName,
-			"last_name":  user.LastName,
-			"phone":      user.Phone,
-			"created_at": user.CreatedAt,
+			"id":             user.ID,
+			"email":          user.Email,
+			"first_name":     user.FirstName,
+			"last_name":      user.LastName,
+			"phone":          user.Phone,
+			"role":           user.Role,
+			"mfa_enabled":    user.MFAEnabled,
+			"email_verified": user.IsEmailVerified(),
+			"created_at":     user.CreatedAt,
 		},
 	})
-} 
\ No newline at end of file
+}
+
+func clientInfo(ctx *gin.Context) services.ClientInfo {
+	return services.ClientInfo{
+		UserAgent: ctx.Request.UserAgent(),
+		IPAddress: ctx.ClientIP(),
+		DeviceID:  ctx.GetHeader("X-Device-ID"),
+	}
+}
+
+func passwordError(ctx *gin.Context, err error) {
+	var policyErr *services.PasswordPolicyError
+	switch {
+	case errors.As(err, &policyErr), errors.Is(err, services.ErrPasswordReused), errors.Is(err, services.ErrInvalidUserToken):
+		utils.ValidationError(ctx, err.Error())
+	case errors.Is(err, services.ErrInvalidPassword):
+		utils.UnauthorizedError(ctx, err.Error())
+	default:
+		utils.InternalServerError(ctx, err.Error())
+	}
+}
+
+func mfaError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrInvalidMFACode), errors.Is(err, services.ErrInvalidPassword):
+		utils.UnauthorizedError(ctx, err.Error())
+	case errors.Is(err, services.ErrMFARequired):
+		utils.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrMFAAlreadyEnabled), errors.Is(err, services.ErrMFANotEnabled), errors.Is(err, services.ErrMFAEnrollmentNeeded):
+		utils.ConflictError(ctx, err.Error())
+	case err.Error() == "user not found":
+		utils.NotFoundError(ctx, err.Error())
+	default:
+		utils.InternalServerError(ctx, err.Error())
+	}
+}
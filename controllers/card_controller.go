@@ -0,0 +1,353 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CardController struct {
+	cardService    *services.CardService
+	accountService *services.AccountService
+}
+
+func NewCardController(db *gorm.DB) *CardController {
+	return &CardController{
+		cardService:    services.NewCardService(db),
+		accountService: services.NewAccountService(db),
+	}
+}
+
+type IssueCardRequest struct {
+	CardholderName      string   `json:"cardholder_name"`
+	PerTransactionLimit *float64 `json:"per_transaction_limit" binding:"omitempty,gte=0"`
+	DailyLimit          *float64 `json:"daily_limit" binding:"omitempty,gte=0"`
+	AllowOnline         *bool    `json:"allow_online"`
+	AllowATM            *bool    `json:"allow_atm"`
+}
+
+type CardControlsRequest struct {
+	PerTransactionLimit *float64 `json:"per_transaction_limit" binding:"omitempty,gte=0"`
+	DailyLimit          *float64 `json:"daily_limit" binding:"omitempty,gte=0"`
+	AllowOnline         *bool    `json:"allow_online"`
+	AllowATM            *bool    `json:"allow_atm"`
+}
+
+type CardAuthorizationRequest struct {
+	PAN          string  `json:"pan" binding:"required,numeric,min=12,max=19"`
+	ExpiryMonth  int     `json:"expiry_month" binding:"required,min=1,max=12"`
+	ExpiryYear   int     `json:"expiry_year" binding:"required"`
+	CVV          string  `json:"cvv" binding:"omitempty,numeric,len=3"`
+	Amount       float64 `json:"amount" binding:"required,gt=0"`
+	Currency     string  `json:"currency" binding:"omitempty,len=3"`
+	MerchantName string  `json:"merchant_name"`
+	Channel      string  `json:"channel" binding:"required,oneof=pos online atm"`
+	Reference    string  `json:"reference" binding:"max=64"`
+}
+
+type CardSettlementRequest struct {
+	Amount float64 `json:"amount" binding:"gte=0"`
+}
+
+func (c *CardController) IssueCard(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if accountID == "" {
+		utils.ValidationError(ctx, "Account ID is required")
+		return
+	}
+
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	var req IssueCardRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	card, pan, cvv, err := c.cardService.IssueCard(accountID, userID.(string), req.CardholderName, services.CardControls{
+		PerTransactionLimit: req.PerTransactionLimit,
+		DailyLimit:          req.DailyLimit,
+		AllowOnline:         req.AllowOnline,
+		AllowATM:            req.AllowATM,
+	}, auditActor(ctx))
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	cardData := cardResponse(card)
+	cardData["pan"] = pan
+	cardData["cvv"] = cvv
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Card issued successfully", gin.H{
+		"card": cardData,
+	})
+}
+
+func (c *CardController) GetCards(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	cards, err := c.cardService.GetCardsByUserID(userID.(string))
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	var cardList []gin.H
+	for i := range cards {
+		cardList = append(cardList, cardResponse(&cards[i]))
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Cards retrieved successfully", gin.H{
+		"cards": cardList,
+		"count": len(cardList),
+	})
+}
+
+func (c *CardController) GetCard(ctx *gin.Context) {
+	card, ok := c.ownedCard(ctx)
+	if !ok {
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Card retrieved successfully", gin.H{
+		"card": cardResponse(card),
+	})
+}
+
+func (c *CardController) UpdateCardControls(ctx *gin.Context) {
+	card, ok := c.ownedCard(ctx)
+	if !ok {
+		return
+	}
+
+	var req CardControlsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	card, err := c.cardService.UpdateControls(card.ID.String(), services.CardControls{
+		PerTransactionLimit: req.PerTransactionLimit,
+		DailyLimit:          req.DailyLimit,
+		AllowOnline:         req.AllowOnline,
+		AllowATM:            req.AllowATM,
+	}, auditActor(ctx))
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Card controls updated successfully", gin.H{
+		"card": cardResponse(card),
+	})
+}
+
+func (c *CardController) FreezeCard(ctx *gin.Context) {
+	card, ok := c.ownedCard(ctx)
+	if !ok {
+		return
+	}
+
+	card, err := c.cardService.FreezeCard(card.ID.String(), auditActor(ctx))
+	if err != nil {
+		utils.ConflictError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Card frozen successfully", gin.H{
+		"card": cardResponse(card),
+	})
+}
+
+func (c *CardController) UnfreezeCard(ctx *gin.Context) {
+	card, ok := c.ownedCard(ctx)
+	if !ok {
+		return
+	}
+
+	card, err := c.cardService.UnfreezeCard(card.ID.String(), auditActor(ctx))
+	if err != nil {
+		utils.ConflictError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Card unfrozen successfully", gin.H{
+		"card": cardResponse(card),
+	})
+}
+
+func (c *CardController) ReportLostCard(ctx *gin.Context) {
+	card, ok := c.ownedCard(ctx)
+	if !ok {
+		return
+	}
+
+	card, err := c.cardService.ReportLost(card.ID.String(), auditActor(ctx))
+	if err != nil {
+		utils.ConflictError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Card reported as lost", gin.H{
+		"card": cardResponse(card),
+	})
+}
+
+func (c *CardController) Authorize(ctx *gin.Context) {
+	var req CardAuthorizationRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	hold, err := c.cardService.Authorize(services.CardAuthorizationRequest{
+		PAN:          req.PAN,
+		ExpiryMonth:  req.ExpiryMonth,
+		ExpiryYear:   req.ExpiryYear,
+		CVV:          req.CVV,
+		Amount:       req.Amount,
+		Currency:     req.Currency,
+		MerchantName: req.MerchantName,
+		Channel:      models.CardChannel(req.Channel),
+		Reference:    req.Reference,
+	}, auditActor(ctx))
+	if err != nil {
+		var declineErr *services.DeclineError
+		if errors.As(err, &declineErr) {
+			utils.ErrorResponse(ctx, http.StatusPaymentRequired, string(declineErr.Reason))
+			return
+		}
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Authorization approved", gin.H{
+		"authorization": authorizationResponse(hold),
+	})
+}
+
+func (c *CardController) CaptureAuthorization(ctx *gin.Context) {
+	var req CardSettlementRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	transaction, err := c.cardService.Capture(ctx.Param("id"), req.Amount, auditActor(ctx))
+	if err != nil {
+		utils.ConflictError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Authorization captured successfully", gin.H{
+		"transaction": gin.H{
+			"id":             transaction.ID,
+			"transaction_id": transaction.TransactionID,
+			"type":           transaction.Type,
+			"amount":         transaction.Amount,
+			"currency":       transaction.Currency,
+			"status":         transaction.Status,
+			"description":    transaction.Description,
+			"balance_before": transaction.BalanceBefore,
+			"balance_after":  transaction.BalanceAfter,
+			"value_date":     transaction.ValueDate.Format("2006-01-02"),
+			"created_at":     transaction.CreatedAt,
+		},
+	})
+}
+
+func (c *CardController) ReverseAuthorization(ctx *gin.Context) {
+	var req CardSettlementRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	hold, err := c.cardService.Reverse(ctx.Param("id"), req.Amount, auditActor(ctx))
+	if err != nil {
+		utils.ConflictError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Authorization reversed successfully", gin.H{
+		"authorization": authorizationResponse(hold),
+	})
+}
+
+func (c *CardController) ownedCard(ctx *gin.Context) (*models.Card, bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return nil, false
+	}
+
+	cardID := ctx.Param("id")
+	if cardID == "" {
+		utils.ValidationError(ctx, "Card ID is required")
+		return nil, false
+	}
+
+	if err := c.cardService.ValidateCardOwnership(cardID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return nil, false
+	}
+
+	card, err := c.cardService.GetCardByID(cardID)
+	if err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return nil, false
+	}
+
+	return card, true
+}
+
+func cardResponse(card *models.Card) gin.H {
+	return gin.H{
+		"id":                    card.ID,
+		"token":                 card.Token,
+		"last4":                 card.Last4,
+		"expiry_month":          card.ExpiryMonth,
+		"expiry_year":           card.ExpiryYear,
+		"cardholder_name":       card.CardholderName,
+		"status":                card.Status,
+		"per_transaction_limit": card.PerTransactionLimit,
+		"daily_limit":           card.DailyLimit,
+		"allow_online":          card.AllowOnline,
+		"allow_atm":             card.AllowATM,
+		"account_id":            card.AccountID,
+		"created_at":            card.CreatedAt,
+	}
+}
+
+func authorizationResponse(hold *models.Hold) gin.H {
+	return gin.H{
+		"id":                 hold.ID,
+		"authorization_code": hold.AuthorizationCode,
+		"amount":             hold.Amount,
+		"currency":           hold.Currency,
+		"status":             hold.Status,
+		"merchant_name":      hold.MerchantName,
+		"channel":            hold.Channel,
+		"reference":          hold.NetworkReference,
+		"expires_at":         hold.ExpiresAt,
+	}
+}
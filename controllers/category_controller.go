@@ -0,0 +1,204 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type CategoryController struct {
+	categoryService *services.CategoryService
+	accountService  *services.AccountService
+}
+
+func NewCategoryController(db *gorm.DB) *CategoryController {
+	return &CategoryController{
+		categoryService: services.NewCategoryService(db),
+		accountService:  services.NewAccountService(db),
+	}
+}
+
+type CategoryRuleRequest struct {
+	Category              string   `json:"category" binding:"required"`
+	DescriptionPattern    string   `json:"description_pattern" binding:"max=255"`
+	CounterpartyAccountID string   `json:"counterparty_account_id"`
+	TransactionType       string   `json:"transaction_type"`
+	Direction             string   `json:"direction" binding:"omitempty,oneof=credit debit"`
+	MinAmount             *float64 `json:"min_amount" binding:"omitempty,gte=0"`
+	MaxAmount             *float64 `json:"max_amount" binding:"omitempty,gte=0"`
+	Priority              int      `json:"priority"`
+}
+
+type RecategorizeRequest struct {
+	Category string `json:"category" binding:"required"`
+	Learn    *bool  `json:"learn"`
+}
+
+func (c *CategoryController) GetCategories(ctx *gin.Context) {
+	categories, err := c.categoryService.GetCategories()
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Categories retrieved successfully", gin.H{
+		"categories": categories,
+		"count":      len(categories),
+	})
+}
+
+func (c *CategoryController) GetRules(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	rules, err := c.categoryService.GetRules(userID.(string))
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Category rules retrieved successfully", gin.H{
+		"rules": rules,
+		"count": len(rules),
+	})
+}
+
+func (c *CategoryController) CreateRule(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	var req CategoryRuleRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	transactionType := models.TransactionType(strings.ToLower(strings.TrimSpace(req.TransactionType)))
+	if transactionType != "" && !isKnownTransactionType(transactionType) {
+		utils.ValidationError(ctx, "Unknown transaction type: "+req.TransactionType)
+		return
+	}
+
+	rule, err := c.categoryService.CreateRule(userID.(string), services.CategoryRuleInput{
+		Category:              req.Category,
+		DescriptionPattern:    req.DescriptionPattern,
+		CounterpartyAccountID: req.CounterpartyAccountID,
+		TransactionType:       transactionType,
+		Direction:             req.Direction,
+		MinAmount:             req.MinAmount,
+		MaxAmount:             req.MaxAmount,
+		Priority:              req.Priority,
+	}, auditActor(ctx))
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Category rule created successfully", gin.H{
+		"rule": rule,
+	})
+}
+
+func (c *CategoryController) DeleteRule(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	if err := c.categoryService.DeleteRule(userID.(string), ctx.Param("ruleId"), auditActor(ctx)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Category rule deleted successfully", nil)
+}
+
+func (c *CategoryController) RecategorizeTransaction(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	var req RecategorizeRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	learn := req.Learn == nil || *req.Learn
+	entry, rule, err := c.categoryService.RecategorizeTransaction(accountID, ctx.Param("transactionId"), req.Category, learn, auditActor(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTransactionNotFound):
+			utils.NotFoundError(ctx, err.Error())
+		case errors.Is(err, services.ErrCategoryNotFound):
+			utils.ValidationError(ctx, err.Error())
+		default:
+			utils.InternalServerError(ctx, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Transaction recategorized successfully", gin.H{
+		"transaction_id": entry.TransactionID,
+		"category":       entry.Category,
+		"source":         entry.Source,
+		"learned_rule":   rule,
+	})
+}
+
+func (c *CategoryController) GetSpendingSummary(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	month := utils.BusinessDate(time.Now())
+	if monthStr := ctx.Query("month"); monthStr != "" {
+		parsed, err := time.Parse("2006-01", monthStr)
+		if err != nil {
+			utils.ValidationError(ctx, "Invalid month parameter, expected YYYY-MM")
+			return
+		}
+		month = parsed
+	}
+
+	summary, err := c.categoryService.GetSpendingSummary(accountID, month)
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Spending summary retrieved successfully", gin.H{
+		"account_id": accountID,
+		"summary":    summary,
+	})
+}
@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type EndOfDayController struct {
+	endOfDayService *services.EndOfDayService
+}
+
+func NewEndOfDayController(db *gorm.DB) *EndOfDayController {
+	return &EndOfDayController{
+		endOfDayService: services.NewEndOfDayService(db),
+	}
+}
+
+type CloseBusinessDayRequest struct {
+	BusinessDate string `json:"business_date"`
+}
+
+func (c *EndOfDayController) CloseBusinessDay(ctx *gin.Context) {
+	var req CloseBusinessDayRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(ctx, err.Error())
+			return
+		}
+	}
+
+	var date time.Time
+	var err error
+	if req.BusinessDate == "" {
+		date, err = c.endOfDayService.CurrentBusinessDate()
+		if err != nil {
+			utils.InternalServerError(ctx, err.Error())
+			return
+		}
+	} else if date, err = time.Parse("2006-01-02", req.BusinessDate); err != nil {
+		utils.ValidationError(ctx, "Invalid business_date, expected YYYY-MM-DD")
+		return
+	}
+
+	if _, err := c.endOfDayService.CloseBusinessDay(date, auditActor(ctx)); err != nil {
+		utils.ConflictError(ctx, err.Error())
+		return
+	}
+
+	day, err := c.endOfDayService.GetBusinessDay(date)
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Business day closed successfully", gin.H{
+		"business_day": day,
+	})
+}
+
+func (c *EndOfDayController) GetBusinessDays(ctx *gin.Context) {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "30"))
+	if limit <= 0 || limit > 366 {
+		limit = 30
+	}
+
+	days, err := c.endOfDayService.GetBusinessDays(limit)
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Business days retrieved successfully", gin.H{
+		"business_days": days,
+		"count":         len(days),
+	})
+}
+
+func (c *EndOfDayController) GetBusinessDay(ctx *gin.Context) {
+	date, err := time.Parse("2006-01-02", ctx.Param("date"))
+	if err != nil {
+		utils.ValidationError(ctx, "Invalid business date, expected YYYY-MM-DD")
+		return
+	}
+
+	day, err := c.endOfDayService.GetBusinessDay(date)
+	if err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Business day retrieved successfully", gin.H{
+		"business_day": day,
+	})
+}
@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxPaymentFileSize = 5 << 20
+
+type PaymentFileController struct {
+	paymentFileService *services.PaymentFileService
+	accountService     *services.AccountService
+}
+
+func NewPaymentFileController(db *gorm.DB) *PaymentFileController {
+	return &PaymentFileController{
+		paymentFileService: services.NewPaymentFileService(db),
+		accountService:     services.NewAccountService(db),
+	}
+}
+
+func (c *PaymentFileController) UploadPaymentFile(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	data, err := readPaymentFile(ctx)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	method := models.StepUpMethod(ctx.Query("step_up_method"))
+	paymentFile, report, challenge, err := c.paymentFileService.ImportPain001(accountID, userID.(string), data, method, auditActor(ctx))
+	if err != nil {
+		var rejected *services.PaymentFileRejectedError
+		switch {
+		case errors.As(err, &rejected):
+			ctx.Data(http.StatusUnprocessableEntity, "application/xml", report)
+		case errors.Is(err, services.ErrDuplicatePaymentFile):
+			ctx.Data(http.StatusConflict, "application/xml", report)
+		case errors.Is(err, services.ErrPaymentFileInProgress):
+			utils.ErrorResponse(ctx, http.StatusConflict, err.Error())
+		case errors.Is(err, services.ErrInvalidStepUpMethod), errors.Is(err, services.ErrStepUpMethodUnavailable):
+			utils.ValidationError(ctx, err.Error())
+		default:
+			utils.InternalServerError(ctx, err.Error())
+		}
+		return
+	}
+
+	ctx.Header("Location", fmt.Sprintf("/api/v1/accounts/%s/payment-files/%s", accountID, paymentFile.ID))
+
+	if challenge != nil {
+		utils.SuccessResponse(ctx, http.StatusAccepted, "Payment file requires verification", gin.H{
+			"payment_file": paymentFile,
+			"challenge": gin.H{
+				"id":         challenge.ID,
+				"method":     challenge.Method,
+				"reasons":    challenge.Reasons,
+				"expires_at": challenge.ExpiresAt,
+				"expires_in": int64(time.Until(challenge.ExpiresAt).Seconds()),
+			},
+		})
+		return
+	}
+
+	ctx.Data(http.StatusCreated, "application/xml", report)
+}
+
+func (c *PaymentFileController) VerifyPaymentFile(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	var req VerifyTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	_, report, err := c.paymentFileService.VerifyPaymentFile(userID.(string), accountID, ctx.Param("fileId"), req.Code, auditActor(ctx))
+	if err != nil {
+		transferError(ctx, err)
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/xml", report)
+}
+
+func (c *PaymentFileController) GetPaymentFiles(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	paymentFiles, err := c.paymentFileService.GetPaymentFilesByAccountID(accountID)
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Payment files retrieved successfully", gin.H{
+		"payment_files": paymentFiles,
+		"count":         len(paymentFiles),
+	})
+}
+
+func (c *PaymentFileController) GetStatusReport(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	paymentFile, err := c.paymentFileService.GetPaymentFile(accountID, ctx.Param("fileId"))
+	if err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	switch paymentFile.Status {
+	case models.PaymentFileStatusAwaitingConfirmation:
+		utils.ErrorResponse(ctx, http.StatusConflict, "Payment file is awaiting verification")
+		return
+	case models.PaymentFileStatusProcessing:
+		utils.ErrorResponse(ctx, http.StatusConflict, "Payment file is still being processed")
+		return
+	}
+
+	ctx.Data(http.StatusOK, "application/xml", []byte(paymentFile.StatusReport))
+}
+
+func readPaymentFile(ctx *gin.Context) ([]byte, error) {
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxPaymentFileSize)
+
+	if strings.HasPrefix(ctx.ContentType(), "multipart/form-data") {
+		fileHeader, err := ctx.FormFile("file")
+		if err != nil {
+			return nil, errors.New("file field is required")
+		}
+		file, err := fileHeader.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to open payment file: %v", err)
+		}
+		defer file.Close()
+		return io.ReadAll(file)
+	}
+
+	data, err := io.ReadAll(ctx.Request.Body)
+	if err != nil {
+		return nil, errors.New("payment file exceeds the 5MB limit")
+	}
+	if len(data) == 0 {
+		return nil, errors.New("payment file is required")
+	}
+	return data, nil
+}
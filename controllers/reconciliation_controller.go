@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type ReconciliationController struct {
+	reconciliationService *services.ReconciliationService
+	accountService        *services.AccountService
+}
+
+func NewReconciliationController(db *gorm.DB) *ReconciliationController {
+	return &ReconciliationController{
+		reconciliationService: services.NewReconciliationService(db),
+		accountService:        services.NewAccountService(db),
+	}
+}
+
+type ReconciliationRunRequest struct {
+	FreezeMismatched bool `json:"freeze_mismatched"`
+}
+
+type FreezeAccountRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+func (c *ReconciliationController) StartRun(ctx *gin.Context) {
+	var req ReconciliationRunRequest
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			utils.ValidationError(ctx, err.Error())
+			return
+		}
+	}
+
+	email, _ := ctx.Get("email")
+	triggeredBy, _ := email.(string)
+
+	run, err := c.reconciliationService.Run(triggeredBy, req.FreezeMismatched)
+	if err != nil {
+		if errors.Is(err, services.ErrReconciliationRunning) {
+			utils.ConflictError(ctx, err.Error())
+			return
+		}
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Reconciliation run completed", gin.H{
+		"run": run,
+	})
+}
+
+func (c *ReconciliationController) GetRuns(ctx *gin.Context) {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	runs, err := c.reconciliationService.GetRuns(limit)
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Reconciliation runs retrieved successfully", gin.H{
+		"runs":  runs,
+		"count": len(runs),
+	})
+}
+
+func (c *ReconciliationController) GetRun(ctx *gin.Context) {
+	run, err := c.reconciliationService.GetRun(ctx.Param("id"))
+	if err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Reconciliation run retrieved successfully", gin.H{
+		"run": run,
+	})
+}
+
+func (c *ReconciliationController) GetAccountDiscrepancies(ctx *gin.Context) {
+	limit, _ := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if limit <= 0 || limit > 500 {
+		limit = 50
+	}
+
+	discrepancies, err := c.reconciliationService.GetAccountDiscrepancies(ctx.Param("id"), limit)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Discrepancies retrieved successfully", gin.H{
+		"discrepancies": discrepancies,
+		"count":         len(discrepancies),
+	})
+}
+
+func (c *ReconciliationController) FreezeAccount(ctx *gin.Context) {
+	var req FreezeAccountRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	account, err := c.accountService.FreezeAccount(ctx.Param("id"), req.Reason, auditActor(ctx))
+	if err != nil {
+		utils.ConflictError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Account frozen successfully", gin.H{
+		"account": accountFreezeResponse(account),
+	})
+}
+
+func (c *ReconciliationController) UnfreezeAccount(ctx *gin.Context) {
+	account, err := c.accountService.UnfreezeAccount(ctx.Param("id"), auditActor(ctx))
+	if err != nil {
+		utils.ConflictError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Account unfrozen successfully", gin.H{
+		"account": accountFreezeResponse(account),
+	})
+}
+
+func accountFreezeResponse(account *models.Account) gin.H {
+	return gin.H{
+		"id":             account.ID,
+		"account_number": account.AccountNumber,
+		"is_frozen":      account.IsFrozen,
+		"frozen_reason":  account.FrozenReason,
+		"frozen_at":      account.FrozenAt,
+	}
+}
@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type SessionController struct {
+	tokenService *services.TokenService
+}
+
+func NewSessionController(db *gorm.DB) *SessionController {
+	return &SessionController{
+		tokenService: services.NewTokenService(db),
+	}
+}
+
+func (c *SessionController) GetSessions(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	sessions, err := c.tokenService.GetSessions(userID.(string))
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	currentSessionID := ctx.GetString("session_id")
+
+	sessionList := []gin.H{}
+	for _, session := range sessions {
+		sessionList = append(sessionList, gin.H{
+			"id":           session.ID,
+			"device_name":  session.DeviceName,
+			"user_agent":   session.UserAgent,
+			"ip_address":   session.IPAddress,
+			"created_at":   session.CreatedAt,
+			"last_seen_at": session.LastSeenAt,
+			"expires_at":   session.ExpiresAt,
+			"current":      session.ID.String() == currentSessionID,
+		})
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Sessions retrieved successfully", gin.H{
+		"sessions": sessionList,
+		"count":    len(sessionList),
+	})
+}
+
+func (c *SessionController) RevokeSession(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	if err := c.tokenService.RevokeSession(userID.(string), ctx.Param("id"), auditActor(ctx)); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			utils.NotFoundError(ctx, err.Error())
+			return
+		}
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Session revoked successfully", nil)
+}
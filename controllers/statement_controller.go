@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type StatementController struct {
+	statementService *services.StatementService
+	accountService   *services.AccountService
+}
+
+func NewStatementController(db *gorm.DB) *StatementController {
+	return &StatementController{
+		statementService: services.NewStatementService(db),
+		accountService:   services.NewAccountService(db),
+	}
+}
+
+type GenerateStatementRequest struct {
+	Period string `json:"period" binding:"required"`
+}
+
+func (c *StatementController) GenerateStatement(ctx *gin.Context) {
+	accountID, ok := c.ownedAccountID(ctx)
+	if !ok {
+		return
+	}
+
+	var req GenerateStatementRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	period, err := time.ParseInLocation("2006-01", req.Period, utils.BankLocation())
+	if err != nil {
+		utils.ValidationError(ctx, "Invalid period, expected YYYY-MM")
+		return
+	}
+
+	statement, err := c.statementService.GenerateStatement(accountID, period)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusCreated, "Statement generated successfully", gin.H{
+		"statement": statementSummary(statement),
+	})
+}
+
+func (c *StatementController) GetStatements(ctx *gin.Context) {
+	accountID, ok := c.ownedAccountID(ctx)
+	if !ok {
+		return
+	}
+
+	statements, err := c.statementService.GetStatementsByAccountID(accountID)
+	if err != nil {
+		utils.InternalServerError(ctx, err.Error())
+		return
+	}
+
+	var statementList []gin.H
+	for i := range statements {
+		statementList = append(statementList, statementSummary(&statements[i]))
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Statements retrieved successfully", gin.H{
+		"statements": statementList,
+		"count":      len(statementList),
+	})
+}
+
+func (c *StatementController) DownloadStatement(ctx *gin.Context) {
+	accountID, ok := c.ownedAccountID(ctx)
+	if !ok {
+		return
+	}
+
+	statement, err := c.statementService.GetStatement(accountID, ctx.Param("statementId"))
+	if err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%s-%s", statement.Account.AccountNumber, statement.PeriodStart.Format("2006-01"))
+
+	switch ctx.DefaultQuery("format", "json") {
+	case "json":
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".json"))
+		ctx.JSON(http.StatusOK, statement)
+	case "csv":
+		var buf bytes.Buffer
+		if err := services.WriteStatementCSV(&buf, statement); err != nil {
+			utils.InternalServerError(ctx, err.Error())
+			return
+		}
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".csv"))
+		ctx.Data(http.StatusOK, "text/csv", buf.Bytes())
+	case "pdf":
+		ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".pdf"))
+		ctx.Data(http.StatusOK, "application/pdf", services.RenderStatementPDF(statement))
+	default:
+		utils.ValidationError(ctx, "Invalid format, expected json, csv or pdf")
+	}
+}
+
+func (c *StatementController) ownedAccountID(ctx *gin.Context) (string, bool) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return "", false
+	}
+
+	accountID := ctx.Param("id")
+	if accountID == "" {
+		utils.ValidationError(ctx, "Account ID is required")
+		return "", false
+	}
+
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return "", false
+	}
+
+	return accountID, true
+}
+
+func statementSummary(statement *models.Statement) gin.H {
+	return gin.H{
+		"id":              statement.ID,
+		"account_id":      statement.AccountID,
+		"period_start":    statement.PeriodStart.Format("2006-01-02"),
+		"period_end":      statement.PeriodEnd.Format("2006-01-02"),
+		"currency":        statement.Currency,
+		"opening_balance": statement.OpeningBalance,
+		"closing_balance": statement.ClosingBalance,
+		"total_credits":   statement.TotalCredits,
+		"total_debits":    statement.TotalDebits,
+		"total_interest":  statement.TotalInterest,
+		"total_fees":      statement.TotalFees,
+		"line_count":      statement.LineCount,
+		"created_at":      statement.CreatedAt,
+	}
+}
@@ -1,36 +1,60 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/azainwork/core-banking-api/models"
 	"github.com/azainwork/core-banking-api/services"
 	"github.com/azainwork/core-banking-api/utils"
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
 type TransactionController struct {
 	transactionService *services.TransactionService
 	accountService     *services.AccountService
+	categoryService    *services.CategoryService
+	stepUpService      *services.StepUpService
 }
 
 func NewTransactionController(db *gorm.DB) *TransactionController {
 	return &TransactionController{
 		transactionService: services.NewTransactionService(db),
 		accountService:     services.NewAccountService(db),
+		categoryService:    services.NewCategoryService(db),
+		stepUpService:      services.NewStepUpService(db),
 	}
 }
 
 type TransactionRequest struct {
-	Amount      float64 `json:"amount" binding:"required,gt=0"`
-	Description string  `json:"description"`
+	Amount      float64         `json:"amount" binding:"required,gt=0"`
+	Description string          `json:"description"`
+	Metadata    models.Metadata `json:"metadata"`
+	Tags        []string        `json:"tags"`
 }
 
 type TransferRequest struct {
-	ToAccountID string  `json:"to_account_id" binding:"required"`
-	Amount      float64 `json:"amount" binding:"required,gt=0"`
-	Description string  `json:"description"`
+	ToAccountID  string          `json:"to_account_id" binding:"required"`
+	Amount       float64         `json:"amount" binding:"required,gt=0"`
+	Description  string          `json:"description"`
+	Metadata     models.Metadata `json:"metadata"`
+	Tags         []string        `json:"tags"`
+	StepUpMethod string          `json:"step_up_method"`
+}
+
+type VerifyTransferRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type UpdateTransactionRequest struct {
+	Metadata map[string]*string `json:"metadata"`
+	Tags     *[]string          `json:"tags"`
 }
 
 func (c *TransactionController) Deposit(ctx *gin.Context) {
@@ -57,7 +81,13 @@ func (c *TransactionController) Deposit(ctx *gin.Context) {
 		return
 	}
 
-	transaction, err := c.transactionService.ProcessDeposit(accountID, req.Amount, req.Description)
+	metadata, tags, err := validateAnnotations(req.Metadata, req.Tags)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	transaction, err := c.transactionService.ProcessDeposit(accountID, req.Amount, req.Description, metadata, tags, auditActor(ctx))
 	if err != nil {
 		utils.InternalServerError(ctx, err.Error())
 		return
@@ -72,8 +102,11 @@ func (c *TransactionController) Deposit(ctx *gin.Context) {
 			"currency":        transaction.Currency,
 			"status":          transaction.Status,
 			"description":     transaction.Description,
+			"metadata":        transaction.Metadata,
+			"tags":            transaction.Tags,
 			"balance_before":  transaction.BalanceBefore,
 			"balance_after":   transaction.BalanceAfter,
+			"value_date":      transaction.ValueDate.Format("2006-01-02"),
 			"created_at":      transaction.CreatedAt,
 		},
 	})
@@ -103,7 +136,17 @@ func (c *TransactionController) Withdraw(ctx *gin.Context) {
 		return
 	}
 
-	transaction, err := c.transactionService.ProcessWithdrawal(accountID, req.Amount, req.Description)
+	metadata, tags, err := validateAnnotations(req.Metadata, req.Tags)
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	transaction, err := c.transactionService.ProcessWithdrawal(accountID, req.Amount, req.Description, metadata, tags, auditActor(ctx))
+	if errors.Is(err, services.ErrAccountFrozen) {
+		utils.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+		return
+	}
 	if err != nil {
 		utils.InternalServerError(ctx, err.Error())
 		return
@@ -118,8 +161,11 @@ func (c *TransactionController) Withdraw(ctx *gin.Context) {
 			"currency":        transaction.Currency,
 			"status":          transaction.Status,
 			"description":     transaction.Description,
+			"metadata":        transaction.Metadata,
+			"tags":            transaction.Tags,
 			"balance_before":  transaction.BalanceBefore,
 			"balance_after":   transaction.BalanceAfter,
+			"value_date":      transaction.ValueDate.Format("2006-01-02"),
 			"created_at":      transaction.CreatedAt,
 		},
 	})
@@ -149,30 +195,116 @@ func (c *TransactionController) Transfer(ctx *gin.Context) {
 		return
 	}
 
-	transaction, err := c.transactionService.ProcessTransfer(fromAccountID, req.ToAccountID, req.Amount, req.Description)
+	metadata, tags, err := validateAnnotations(req.Metadata, req.Tags)
 	if err != nil {
-		utils.InternalServerError(ctx, err.Error())
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	transaction, challenge, err := c.stepUpService.InitiateTransfer(userID.(string), services.TransferInput{
+		FromAccountID: fromAccountID,
+		ToAccountID:   req.ToAccountID,
+		Amount:        req.Amount,
+		Description:   req.Description,
+		Metadata:      metadata,
+		Tags:          tags,
+		Method:        models.StepUpMethod(req.StepUpMethod),
+	}, auditActor(ctx))
+	if err != nil {
+		transferError(ctx, err)
+		return
+	}
+
+	if challenge != nil {
+		utils.SuccessResponse(ctx, http.StatusAccepted, "Transfer requires verification", gin.H{
+			"transaction": transferResponse(transaction),
+			"challenge": gin.H{
+				"id":         challenge.ID,
+				"method":     challenge.Method,
+				"reasons":    challenge.Reasons,
+				"expires_at": challenge.ExpiresAt,
+				"expires_in": int64(time.Until(challenge.ExpiresAt).Seconds()),
+			},
+		})
 		return
 	}
 
 	utils.SuccessResponse(ctx, http.StatusOK, "Transfer processed successfully", gin.H{
-		"transaction": gin.H{
-			"id":              transaction.ID,
-			"transaction_id":  transaction.TransactionID,
-			"type":            transaction.Type,
-			"amount":          transaction.Amount,
-			"currency":        transaction.Currency,
-			"status":          transaction.Status,
-			"description":     transaction.Description,
-			"from_account_id": transaction.AccountID,
-			"to_account_id":   transaction.ToAccountID,
-			"balance_before":  transaction.BalanceBefore,
-			"balance_after":   transaction.BalanceAfter,
-			"created_at":      transaction.CreatedAt,
-		},
+		"transaction": transferResponse(transaction),
 	})
 }
 
+func (c *TransactionController) VerifyTransfer(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	var req VerifyTransferRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	transaction, err := c.stepUpService.VerifyTransfer(userID.(string), accountID, ctx.Param("challengeId"), req.Code, auditActor(ctx))
+	if err != nil {
+		transferError(ctx, err)
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Transfer processed successfully", gin.H{
+		"transaction": transferResponse(transaction),
+	})
+}
+
+func transferResponse(transaction *models.Transaction) gin.H {
+	return gin.H{
+		"id":              transaction.ID,
+		"transaction_id":  transaction.TransactionID,
+		"type":            transaction.Type,
+		"amount":          transaction.Amount,
+		"currency":        transaction.Currency,
+		"status":          transaction.Status,
+		"description":     transaction.Description,
+		"metadata":        transaction.Metadata,
+		"tags":            transaction.Tags,
+		"from_account_id": transaction.AccountID,
+		"to_account_id":   transaction.ToAccountID,
+		"balance_before":  transaction.BalanceBefore,
+		"balance_after":   transaction.BalanceAfter,
+		"value_date":      transaction.ValueDate.Format("2006-01-02"),
+		"created_at":      transaction.CreatedAt,
+	}
+}
+
+func transferError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrAccountFrozen):
+		utils.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+	case errors.Is(err, services.ErrInvalidStepUpCode):
+		utils.UnauthorizedError(ctx, err.Error())
+	case errors.Is(err, services.ErrTransferChallengeNotFound):
+		utils.NotFoundError(ctx, err.Error())
+	case errors.Is(err, services.ErrTransferAlreadyConfirmed):
+		utils.ConflictError(ctx, err.Error())
+	case errors.Is(err, services.ErrTransferChallengeExpired), errors.Is(err, services.ErrTransferChallengeLocked):
+		utils.ErrorResponse(ctx, http.StatusGone, err.Error())
+	case errors.Is(err, services.ErrStepUpMethodLocked):
+		utils.ErrorResponse(ctx, http.StatusTooManyRequests, err.Error())
+	case errors.Is(err, services.ErrInsufficientBalance), errors.Is(err, services.ErrInvalidStepUpMethod), errors.Is(err, services.ErrStepUpMethodUnavailable):
+		utils.ValidationError(ctx, err.Error())
+	default:
+		utils.InternalServerError(ctx, err.Error())
+	}
+}
+
 func (c *TransactionController) GetTransactions(ctx *gin.Context) {
 	userID, exists := ctx.Get("user_id")
 	if !exists {
@@ -191,29 +323,35 @@ func (c *TransactionController) GetTransactions(ctx *gin.Context) {
 		return
 	}
 
-	limitStr := ctx.DefaultQuery("limit", "50")
-	offsetStr := ctx.DefaultQuery("offset", "0")
-
-	limit, err := strconv.Atoi(limitStr)
+	filter, err := parseTransactionFilter(ctx)
 	if err != nil {
-		utils.ValidationError(ctx, "Invalid limit parameter")
+		utils.ValidationError(ctx, err.Error())
 		return
 	}
 
-	offset, err := strconv.Atoi(offsetStr)
+	page, err := c.transactionService.ListTransactions(accountID, filter)
 	if err != nil {
-		utils.ValidationError(ctx, "Invalid offset parameter")
+		if errors.Is(err, services.ErrInvalidCursor) {
+			utils.ValidationError(ctx, err.Error())
+			return
+		}
+		utils.InternalServerError(ctx, err.Error())
 		return
 	}
 
-	transactions, err := c.transactionService.GetTransactionsByAccountID(accountID, limit, offset)
+	transactionIDs := make([]uuid.UUID, 0, len(page.Transactions))
+	for _, transaction := range page.Transactions {
+		transactionIDs = append(transactionIDs, transaction.ID)
+	}
+
+	categories, err := c.categoryService.GetTransactionCategories(accountID, transactionIDs)
 	if err != nil {
 		utils.InternalServerError(ctx, err.Error())
 		return
 	}
 
-	var transactionList []gin.H
-	for _, transaction := range transactions {
+	transactionList := []gin.H{}
+	for _, transaction := range page.Transactions {
 		transactionData := gin.H{
 			"id":              transaction.ID,
 			"transaction_id":  transaction.TransactionID,
@@ -222,24 +360,38 @@ func (c *TransactionController) GetTransactions(ctx *gin.Context) {
 			"currency":        transaction.Currency,
 			"status":          transaction.Status,
 			"description":     transaction.Description,
+			"metadata":        transaction.Metadata,
+			"tags":            transaction.Tags,
 			"balance_before":  transaction.BalanceBefore,
 			"balance_after":   transaction.BalanceAfter,
+			"value_date":      transaction.ValueDate.Format("2006-01-02"),
 			"created_at":      transaction.CreatedAt,
 		}
 
 		if transaction.ToAccountID != nil {
 			transactionData["to_account_id"] = transaction.ToAccountID
 		}
+		if category, ok := categories[transaction.ID]; ok {
+			transactionData["category"] = category.Slug
+		}
 
 		transactionList = append(transactionList, transactionData)
 	}
 
-	utils.SuccessResponse(ctx, http.StatusOK, "Transactions retrieved successfully", gin.H{
+	response := gin.H{
 		"transactions": transactionList,
 		"count":        len(transactionList),
-		"limit":        limit,
-		"offset":       offset,
-	})
+		"limit":        page.Limit,
+		"next_cursor":  nil,
+	}
+	if page.NextCursor != "" {
+		response["next_cursor"] = page.NextCursor
+	}
+	if page.Total != nil {
+		response["total"] = *page.Total
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Transactions retrieved successfully", response)
 }
 
 func (c *TransactionController) GetTransaction(ctx *gin.Context) {
@@ -275,11 +427,262 @@ func (c *TransactionController) GetTransaction(ctx *gin.Context) {
 			"currency":        transaction.Currency,
 			"status":          transaction.Status,
 			"description":     transaction.Description,
+			"metadata":        transaction.Metadata,
+			"tags":            transaction.Tags,
 			"account_id":      transaction.AccountID,
 			"to_account_id":   transaction.ToAccountID,
 			"balance_before":  transaction.BalanceBefore,
 			"balance_after":   transaction.BalanceAfter,
+			"value_date":      transaction.ValueDate.Format("2006-01-02"),
 			"created_at":      transaction.CreatedAt,
 		},
 	})
-} 
\ No newline at end of file
+}
+
+func (c *TransactionController) UpdateTransaction(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	var req UpdateTransactionRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	transaction, err := c.transactionService.UpdateAnnotations(accountID, ctx.Param("transactionId"), services.TransactionAnnotations{
+		Metadata: req.Metadata,
+		Tags:     req.Tags,
+	}, auditActor(ctx))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrTransactionNotFound):
+			utils.NotFoundError(ctx, err.Error())
+		case errors.Is(err, services.ErrTransactionNotEditable):
+			utils.ErrorResponse(ctx, http.StatusForbidden, err.Error())
+		case errors.Is(err, services.ErrInvalidAnnotations):
+			utils.ValidationError(ctx, err.Error())
+		default:
+			utils.InternalServerError(ctx, err.Error())
+		}
+		return
+	}
+
+	utils.SuccessResponse(ctx, http.StatusOK, "Transaction updated successfully", gin.H{
+		"transaction": gin.H{
+			"id":             transaction.ID,
+			"transaction_id": transaction.TransactionID,
+			"metadata":       transaction.Metadata,
+			"tags":           transaction.Tags,
+			"updated_at":     transaction.UpdatedAt,
+		},
+	})
+}
+
+func (c *TransactionController) ExportTransactions(ctx *gin.Context) {
+	userID, exists := ctx.Get("user_id")
+	if !exists {
+		utils.UnauthorizedError(ctx, "User not authenticated")
+		return
+	}
+
+	accountID := ctx.Param("id")
+	if accountID == "" {
+		utils.ValidationError(ctx, "Account ID is required")
+		return
+	}
+
+	if err := c.accountService.ValidateAccountOwnership(accountID, userID.(string)); err != nil {
+		utils.NotFoundError(ctx, err.Error())
+		return
+	}
+
+	location := utils.BankLocation()
+	today := time.Now().In(location)
+
+	from := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, location).AddDate(0, 0, -30)
+	if fromStr := ctx.Query("from"); fromStr != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", fromStr, location)
+		if err != nil {
+			utils.ValidationError(ctx, "Invalid from parameter, expected YYYY-MM-DD")
+			return
+		}
+		from = parsed
+	}
+
+	to := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, location)
+	if toStr := ctx.Query("to"); toStr != "" {
+		parsed, err := time.ParseInLocation("2006-01-02", toStr, location)
+		if err != nil {
+			utils.ValidationError(ctx, "Invalid to parameter, expected YYYY-MM-DD")
+			return
+		}
+		to = parsed
+	}
+
+	format := services.ExportFormat(ctx.DefaultQuery("format", "csv"))
+
+	export, err := c.transactionService.NewExport(accountID, format, from, to.AddDate(0, 0, 1))
+	if err != nil {
+		utils.ValidationError(ctx, err.Error())
+		return
+	}
+
+	ctx.Header("Content-Type", export.ContentType())
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename()))
+	ctx.Status(http.StatusOK)
+
+	if err := export.Stream(ctx.Writer); err != nil {
+		ctx.Error(err)
+		ctx.Abort()
+	}
+}
+
+func parseTransactionFilter(ctx *gin.Context) (services.TransactionFilter, error) {
+	filter := services.TransactionFilter{
+		Cursor:    ctx.Query("cursor"),
+		Search:    strings.TrimSpace(ctx.Query("q")),
+		Direction: services.TransactionDirection(ctx.Query("direction")),
+	}
+
+	if limitStr := ctx.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("invalid limit parameter")
+		}
+		filter.Limit = limit
+	}
+
+	location := utils.BankLocation()
+	if fromStr := ctx.Query("from"); fromStr != "" {
+		from, err := parseTransactionTime(fromStr, location)
+		if err != nil {
+			return filter, errors.New("invalid from parameter, expected YYYY-MM-DD or RFC 3339")
+		}
+		filter.From = &from
+	}
+	if toStr := ctx.Query("to"); toStr != "" {
+		to, err := parseTransactionTime(toStr, location)
+		if err != nil {
+			return filter, errors.New("invalid to parameter, expected YYYY-MM-DD or RFC 3339")
+		}
+		if len(toStr) == len("2006-01-02") {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.To = &to
+	}
+
+	for _, value := range splitQueryList(ctx, "type") {
+		transactionType := models.TransactionType(value)
+		if !isKnownTransactionType(transactionType) {
+			return filter, fmt.Errorf("invalid type %q", value)
+		}
+		filter.Types = append(filter.Types, transactionType)
+	}
+
+	for _, value := range splitQueryList(ctx, "status") {
+		status := models.TransactionStatus(value)
+		switch status {
+		case models.TransactionStatusPending, models.TransactionStatusCompleted, models.TransactionStatusFailed, models.TransactionStatusCancelled:
+		default:
+			return filter, fmt.Errorf("invalid status %q", value)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	if value := ctx.Query("min_amount"); value != "" {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || amount < 0 {
+			return filter, errors.New("invalid min_amount parameter")
+		}
+		filter.MinAmount = &amount
+	}
+	if value := ctx.Query("max_amount"); value != "" {
+		amount, err := strconv.ParseFloat(value, 64)
+		if err != nil || amount < 0 {
+			return filter, errors.New("invalid max_amount parameter")
+		}
+		filter.MaxAmount = &amount
+	}
+	if filter.MinAmount != nil && filter.MaxAmount != nil && *filter.MinAmount > *filter.MaxAmount {
+		return filter, errors.New("min_amount cannot be greater than max_amount")
+	}
+
+	if metadata := ctx.QueryMap("metadata"); len(metadata) > 0 {
+		filter.Metadata = models.Metadata(metadata)
+		if err := services.ValidateMetadata(filter.Metadata); err != nil {
+			return filter, err
+		}
+	}
+
+	if tags := splitQueryList(ctx, "tag"); len(tags) > 0 {
+		normalized, err := services.NormalizeTags(tags)
+		if err != nil {
+			return filter, err
+		}
+		filter.Tags = normalized
+	}
+
+	switch filter.Direction {
+	case "", services.TransactionDirectionCredit, services.TransactionDirectionDebit:
+	default:
+		return filter, errors.New("invalid direction, expected credit or debit")
+	}
+
+	filter.IncludeTotal = ctx.Query("include_total") == "true"
+
+	return filter, nil
+}
+
+func parseTransactionTime(value string, location *time.Location) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, location); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func splitQueryList(ctx *gin.Context, name string) []string {
+	var values []string
+	for _, raw := range ctx.QueryArray(name) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func isKnownTransactionType(transactionType models.TransactionType) bool {
+	switch transactionType {
+	case models.TransactionTypeDeposit, models.TransactionTypeWithdraw, models.TransactionTypeTransfer,
+		models.TransactionTypeCardPayment, models.TransactionTypeCardRefund, models.TransactionTypeFee, models.TransactionTypeInterest,
+		models.TransactionTypeOpeningBalance:
+		return true
+	}
+	return false
+}
+
+func validateAnnotations(metadata models.Metadata, tags []string) (models.Metadata, models.Tags, error) {
+	if metadata == nil {
+		metadata = models.Metadata{}
+	}
+	if err := services.ValidateClientMetadata(metadata); err != nil {
+		return nil, nil, err
+	}
+
+	normalized, err := services.NormalizeTags(tags)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return metadata, normalized, nil
+}
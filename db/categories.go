@@ -0,0 +1,92 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/azainwork/core-banking-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var defaultCategories = []models.Category{
+	{Slug: "salary", Name: "Salary", Kind: models.CategoryKindIncome},
+	{Slug: "deposits", Name: "Deposits", Kind: models.CategoryKindIncome},
+	{Slug: "interest", Name: "Interest", Kind: models.CategoryKindIncome},
+	{Slug: "refunds", Name: "Refunds", Kind: models.CategoryKindIncome},
+	{Slug: "groceries", Name: "Groceries", Kind: models.CategoryKindExpense},
+	{Slug: "dining", Name: "Dining", Kind: models.CategoryKindExpense},
+	{Slug: "transport", Name: "Transport", Kind: models.CategoryKindExpense},
+	{Slug: "shopping", Name: "Shopping", Kind: models.CategoryKindExpense},
+	{Slug: "utilities", Name: "Utilities", Kind: models.CategoryKindExpense},
+	{Slug: "housing", Name: "Housing", Kind: models.CategoryKindExpense},
+	{Slug: "subscriptions", Name: "Subscriptions", Kind: models.CategoryKindExpense},
+	{Slug: "entertainment", Name: "Entertainment", Kind: models.CategoryKindExpense},
+	{Slug: "health", Name: "Health", Kind: models.CategoryKindExpense},
+	{Slug: "travel", Name: "Travel", Kind: models.CategoryKindExpense},
+	{Slug: "cash_withdrawal", Name: "Cash Withdrawal", Kind: models.CategoryKindExpense},
+	{Slug: "fees", Name: "Fees", Kind: models.CategoryKindExpense},
+	{Slug: "uncategorized", Name: "Uncategorized", Kind: models.CategoryKindExpense},
+	{Slug: "transfers_in", Name: "Incoming Transfers", Kind: models.CategoryKindTransfer},
+	{Slug: "transfers_out", Name: "Outgoing Transfers", Kind: models.CategoryKindTransfer},
+	{Slug: "opening_balance", Name: "Opening Balance", Kind: models.CategoryKindTransfer},
+}
+
+var defaultCategoryRules = []struct {
+	Slug      string
+	Pattern   string
+	Direction string
+}{
+	{"salary", `\b(salary|payroll|gaji)\b`, models.CategoryDirectionCredit},
+	{"groceries", `\b(supermarket|grocery|groceries|indomaret|alfamart|hypermart|aldi|lidl|tesco|whole foods)\b`, models.CategoryDirectionDebit},
+	{"dining", `\b(restaurant|cafe|coffee|starbucks|mcdonald'?s|kfc|pizza|burger|bakery|grabfood|gofood)\b`, models.CategoryDirectionDebit},
+	{"transport", `\b(uber|lyft|grab|gojek|taxi|parking|toll|fuel|pertamina|shell|railway|transit)\b`, models.CategoryDirectionDebit},
+	{"subscriptions", `\b(netflix|spotify|disney\+?|youtube premium|apple\.com|icloud)\b`, models.CategoryDirectionDebit},
+	{"shopping", `\b(amazon|tokopedia|shopee|lazada|ebay|ikea|mall)\b`, models.CategoryDirectionDebit},
+	{"utilities", `\b(electricity|pln|water|internet|telkom|indihome|telkomsel|xl axiata)\b`, models.CategoryDirectionDebit},
+	{"housing", `\b(rent|mortgage|apartment)\b`, models.CategoryDirectionDebit},
+	{"entertainment", `\b(cinema|cinepolis|xxi|steam|playstation|concert|tickets?)\b`, models.CategoryDirectionDebit},
+	{"health", `\b(pharmacy|apotek|clinic|hospital|dental|doctor)\b`, models.CategoryDirectionDebit},
+	{"travel", `\b(airlines?|hotel|airbnb|traveloka|agoda|booking\.com|garuda)\b`, models.CategoryDirectionDebit},
+}
+
+func seedCategories(db *gorm.DB) error {
+	categories := make([]models.Category, len(defaultCategories))
+	copy(categories, defaultCategories)
+
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&categories).Error; err != nil {
+		return fmt.Errorf("failed to seed categories: %v", err)
+	}
+
+	var systemRules int64
+	if err := db.Model(&models.CategoryRule{}).Where("source = ?", models.CategoryRuleSourceSystem).Count(&systemRules).Error; err != nil {
+		return fmt.Errorf("failed to count category rules: %v", err)
+	}
+	if systemRules > 0 {
+		return nil
+	}
+
+	var seeded []models.Category
+	if err := db.Find(&seeded).Error; err != nil {
+		return fmt.Errorf("failed to load categories: %v", err)
+	}
+	bySlug := make(map[string]models.Category, len(seeded))
+	for _, category := range seeded {
+		bySlug[category.Slug] = category
+	}
+
+	rules := make([]models.CategoryRule, 0, len(defaultCategoryRules))
+	for _, rule := range defaultCategoryRules {
+		rules = append(rules, models.CategoryRule{
+			CategoryID:         bySlug[rule.Slug].ID,
+			Source:             models.CategoryRuleSourceSystem,
+			DescriptionPattern: rule.Pattern,
+			Direction:          rule.Direction,
+		})
+	}
+
+	if err := db.Create(&rules).Error; err != nil {
+		return fmt.Errorf("failed to seed category rules: %v", err)
+	}
+
+	return nil
+}
@@ -4,8 +4,11 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -76,11 +79,283 @@ func InitDB() (*gorm.DB, error) {
 }
 
 func autoMigrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	// Accounts that existed before email verification was introduced are
+	// treated as verified rather than locked out.
+	grandfatherEmails := db.Migrator().HasTable(&models.User{}) && !db.Migrator().HasColumn(&models.User{}, "EmailVerifiedAt")
+	backfillAuthorizedAmounts := db.Migrator().HasTable(&models.Hold{}) && !db.Migrator().HasColumn(&models.Hold{}, "AuthorizedAmount")
+	// Accounts opened before reconciliation was introduced have no opening
+	// balance transaction and would otherwise all reconcile as mismatched.
+	backfillOpenings := db.Migrator().HasTable(&models.Account{}) && !db.Migrator().HasTable(&models.ReconciliationRun{})
+
+	if err := db.AutoMigrate(
 		&models.User{},
 		&models.Account{},
 		&models.Transaction{},
-	)
+		&models.Card{},
+		&models.Hold{},
+		&models.Statement{},
+		&models.StatementLine{},
+		&models.StatementTypeTotal{},
+		&models.PaymentFile{},
+		&models.PaymentFileItem{},
+		&models.ReconciliationRun{},
+		&models.ReconciliationDiscrepancy{},
+		&models.BusinessDay{},
+		&models.BalanceSnapshot{},
+		&models.DailyReport{},
+		&models.TrialBalanceLine{},
+		&models.Category{},
+		&models.CategoryRule{},
+		&models.TransactionCategory{},
+		&models.RefreshToken{},
+		&models.RevokedToken{},
+		&models.Session{},
+		&models.MFARecoveryCode{},
+		&models.MFAChallenge{},
+		&models.UserToken{},
+		&models.LoginThrottle{},
+		&models.SecurityEvent{},
+		&models.RateLimitBucket{},
+		&models.APIKey{},
+		&models.TransferChallenge{},
+		&models.PasswordHistory{},
+		&models.AuditLog{},
+	); err != nil {
+		return err
+	}
+
+	if err := protectAuditLogs(db); err != nil {
+		return err
+	}
+
+	if backfillOpenings {
+		if err := backfillOpeningBalances(db); err != nil {
+			return err
+		}
+	}
+
+	if backfillAuthorizedAmounts {
+		if err := db.Model(&models.Hold{}).Where("authorized_amount = 0").Update("authorized_amount", gorm.Expr("amount")).Error; err != nil {
+			return err
+		}
+	}
+
+	if grandfatherEmails {
+		if err := db.Model(&models.User{}).Where("email_verified_at IS NULL").Update("email_verified_at", gorm.Expr("created_at")).Error; err != nil {
+			return err
+		}
+	}
+
+	// Emails are encrypted with a random data key, so uniqueness moved to the
+	// blind index.
+	if db.Migrator().HasIndex(&models.User{}, "idx_users_email") {
+		if err := db.Migrator().DropIndex(&models.User{}, "idx_users_email"); err != nil {
+			return err
+		}
+	}
+
+	// Audit sequence numbers are unique per shard rather than globally.
+	if db.Migrator().HasIndex(&models.AuditLog{}, "idx_audit_logs_seq") {
+		if err := db.Migrator().DropIndex(&models.AuditLog{}, "idx_audit_logs_seq"); err != nil {
+			return err
+		}
+	}
+
+	if err := encryptLegacyUsers(db); err != nil {
+		return err
+	}
+
+	// Opening balances were first backfilled as deposits; their category is
+	// seeded alongside the type that replaced them.
+	var openingCategories int64
+	if err := db.Model(&models.Category{}).Where("slug = ?", "opening_balance").Count(&openingCategories).Error; err != nil {
+		return err
+	}
+	if openingCategories == 0 {
+		if err := retypeOpeningBalances(db); err != nil {
+			return err
+		}
+	}
+
+	if err := seedCategories(db); err != nil {
+		return err
+	}
+
+	if err := bootstrapAdmins(db); err != nil {
+		return err
+	}
+
+	return db.Exec("UPDATE transactions SET value_date = (created_at AT TIME ZONE ?)::date WHERE value_date IS NULL", utils.BankLocation().String()).Error
+}
+
+// bootstrapAdmins promotes the verified users listed in ADMIN_EMAILS, but only
+// until the first admin exists. Later admins are granted through the admin API
+// so that a stale environment variable cannot hand out the role on restart.
+func bootstrapAdmins(db *gorm.DB) error {
+	var indexes []string
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			index, err := models.EmailIndex(email)
+			if err != nil {
+				return err
+			}
+			indexes = append(indexes, index)
+		}
+	}
+	if len(indexes) == 0 {
+		return nil
+	}
+
+	var admins int64
+	if err := db.Model(&models.User{}).Where("role = ?", models.UserRoleAdmin).Count(&admins).Error; err != nil {
+		return err
+	}
+	if admins > 0 {
+		return nil
+	}
+
+	return db.Model(&models.User{}).
+		Where("email_index IN ? AND email_verified_at IS NOT NULL AND is_active = ?", indexes, true).
+		Update("role", models.UserRoleAdmin).Error
+}
+
+// backfillOpeningBalances records the opening balance of legacy accounts. It is
+// taken from the balance the account's first own ledger entry started from,
+// less any credits received before it, so that the backfill cannot paper over
+// later drift. Accounts without own entries only have their stored balance to
+// go on.
+func backfillOpeningBalances(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var accounts []models.Account
+		if err := tx.Find(&accounts).Error; err != nil {
+			return err
+		}
+
+		for _, account := range accounts {
+			var transactions []models.Transaction
+			if err := tx.Where("(account_id = ? OR to_account_id = ?) AND status = ?", account.ID, account.ID, models.TransactionStatusCompleted).
+				Order("created_at ASC, id ASC").
+				Find(&transactions).Error; err != nil {
+				return err
+			}
+
+			received := 0.0
+			opening := 0.0
+			ownEntry := false
+			for _, transaction := range transactions {
+				if transaction.AccountID == account.ID {
+					opening = transaction.BalanceBefore - received
+					ownEntry = true
+					break
+				}
+				received += transaction.SignedAmountFor(account.ID)
+			}
+			if !ownEntry {
+				opening = account.Balance - received
+			}
+
+			if opening < 0.005 {
+				continue
+			}
+
+			transaction := &models.Transaction{
+				TransactionID: utils.GenerateTransactionID(),
+				Type:          models.TransactionTypeOpeningBalance,
+				Amount:        opening,
+				Currency:      account.Currency,
+				Status:        models.TransactionStatusCompleted,
+				Description:   "Opening balance",
+				AccountID:     account.ID,
+				BalanceBefore: 0,
+				BalanceAfter:  opening,
+				ValueDate:     utils.BusinessDate(account.CreatedAt),
+				CreatedAt:     account.CreatedAt,
+			}
+			if err := tx.Omit("Account", "ToAccount").Create(transaction).Error; err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// retypeOpeningBalances moves opening balances that were booked as deposits,
+// by the backfill or by account creation, to their own type. Both wrote them
+// as "Opening balance" from a zero balance, and they are the first completed
+// entry on the account; a later deposit with the same description is left
+// alone. Their default category is dropped so that they are categorized again.
+func retypeOpeningBalances(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var ids []uuid.UUID
+		if err := tx.Model(&models.Transaction{}).
+			Where("transactions.type = ? AND transactions.description = ? AND transactions.balance_before = 0",
+				models.TransactionTypeDeposit, "Opening balance").
+			Where(`NOT EXISTS (
+				SELECT 1 FROM transactions earlier
+				WHERE (earlier.account_id = transactions.account_id OR earlier.to_account_id = transactions.account_id)
+				AND earlier.status = ? AND earlier.deleted_at IS NULL
+				AND (earlier.created_at, earlier.id) < (transactions.created_at, transactions.id))`, models.TransactionStatusCompleted).
+			Pluck("transactions.id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&models.Transaction{}).Where("id IN ?", ids).Update("type", models.TransactionTypeOpeningBalance).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("transaction_id IN ? AND source = ?", ids, models.CategorizationSourceDefault).Delete(&models.TransactionCategory{}).Error
+	})
+}
+
+// encryptLegacyUsers encrypts users stored before PII encryption and fills in
+// their email blind index, without which they could not log in. Rows written
+// under an older key are left to the background re-encryption job.
+func encryptLegacyUsers(db *gorm.DB) error {
+	for {
+		var users []models.User
+		if err := db.Where("email_index IS NULL AND email <> ''").Limit(500).Find(&users).Error; err != nil {
+			return err
+		}
+		if len(users) == 0 {
+			return nil
+		}
+
+		for i := range users {
+			if err := db.Model(&users[i]).Select(models.UserPIIColumns).Updates(&users[i]).Error; err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// protectAuditLogs rejects updates, deletes and truncation of audit_logs.
+// Anyone allowed to drop the triggers can still edit rows, which the hash
+// chain then reveals.
+func protectAuditLogs(db *gorm.DB) error {
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+		"DROP TRIGGER IF EXISTS audit_logs_no_change ON audit_logs",
+		"CREATE TRIGGER audit_logs_no_change BEFORE UPDATE OR DELETE ON audit_logs FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only()",
+		"DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs",
+		"CREATE TRIGGER audit_logs_no_truncate BEFORE TRUNCATE ON audit_logs FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only()",
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
 }
 
 func GetDB() *gorm.DB {
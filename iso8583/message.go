@@ -0,0 +1,273 @@
+package iso8583
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const maxMessageLength = 8192
+
+type lengthType int
+
+const (
+	fixedLength lengthType = iota
+	llvar
+	lllvar
+)
+
+type fieldSpec struct {
+	length  int
+	kind    lengthType
+	numeric bool
+}
+
+var fieldSpecs = map[int]fieldSpec{
+	2:  {19, llvar, true},
+	3:  {6, fixedLength, true},
+	4:  {12, fixedLength, true},
+	7:  {10, fixedLength, true},
+	11: {6, fixedLength, true},
+	12: {6, fixedLength, true},
+	13: {4, fixedLength, true},
+	14: {4, fixedLength, true},
+	18: {4, fixedLength, true},
+	22: {3, fixedLength, true},
+	25: {2, fixedLength, true},
+	32: {11, llvar, true},
+	37: {12, fixedLength, false},
+	38: {6, fixedLength, false},
+	39: {2, fixedLength, false},
+	41: {8, fixedLength, false},
+	42: {15, fixedLength, false},
+	43: {40, fixedLength, false},
+	48: {999, lllvar, false},
+	49: {3, fixedLength, true},
+	54: {120, lllvar, false},
+	70: {3, fixedLength, true},
+	90: {42, fixedLength, true},
+	95: {42, fixedLength, false},
+}
+
+type Message struct {
+	MTI    string
+	fields map[int]string
+}
+
+func NewMessage(mti string) *Message {
+	return &Message{MTI: mti, fields: make(map[int]string)}
+}
+
+func (m *Message) Set(field int, value string) {
+	m.fields[field] = value
+}
+
+func (m *Message) Get(field int) string {
+	return m.fields[field]
+}
+
+func (m *Message) Has(field int) bool {
+	_, ok := m.fields[field]
+	return ok
+}
+
+func (m *Message) Fields() []int {
+	fields := make([]int, 0, len(m.fields))
+	for field := range m.fields {
+		fields = append(fields, field)
+	}
+	sort.Ints(fields)
+	return fields
+}
+
+func (m *Message) Pack() ([]byte, error) {
+	if len(m.MTI) != 4 {
+		return nil, fmt.Errorf("invalid MTI %q", m.MTI)
+	}
+
+	bitmap := make([]byte, 8)
+	var body strings.Builder
+
+	for _, field := range m.Fields() {
+		spec, ok := fieldSpecs[field]
+		if !ok {
+			return nil, fmt.Errorf("unsupported field %d", field)
+		}
+
+		encoded, err := encodeField(field, spec, m.fields[field])
+		if err != nil {
+			return nil, err
+		}
+
+		if field > 64 && len(bitmap) == 8 {
+			bitmap = append(bitmap, make([]byte, 8)...)
+			bitmap[0] |= 0x80
+		}
+		bitmap[(field-1)/8] |= 0x80 >> uint((field-1)%8)
+		body.WriteString(encoded)
+	}
+
+	return []byte(m.MTI + strings.ToUpper(hex.EncodeToString(bitmap)) + body.String()), nil
+}
+
+func Unpack(data []byte) (*Message, error) {
+	if len(data) < 20 {
+		return nil, errors.New("message too short")
+	}
+
+	m := NewMessage(string(data[:4]))
+	pos := 4
+
+	bitmap, err := hex.DecodeString(string(data[pos : pos+16]))
+	if err != nil {
+		return nil, fmt.Errorf("invalid primary bitmap: %v", err)
+	}
+	pos += 16
+
+	if bitmap[0]&0x80 != 0 {
+		if len(data) < pos+16 {
+			return nil, errors.New("message too short for secondary bitmap")
+		}
+		secondary, err := hex.DecodeString(string(data[pos : pos+16]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid secondary bitmap: %v", err)
+		}
+		bitmap = append(bitmap, secondary...)
+		pos += 16
+	}
+
+	for field := 2; field <= len(bitmap)*8; field++ {
+		if bitmap[(field-1)/8]&(0x80>>uint((field-1)%8)) == 0 {
+			continue
+		}
+
+		spec, ok := fieldSpecs[field]
+		if !ok {
+			return nil, fmt.Errorf("unsupported field %d", field)
+		}
+
+		value, next, err := decodeField(field, spec, data, pos)
+		if err != nil {
+			return nil, err
+		}
+		m.fields[field] = value
+		pos = next
+	}
+
+	if pos != len(data) {
+		return nil, fmt.Errorf("unexpected %d trailing bytes", len(data)-pos)
+	}
+
+	return m, nil
+}
+
+func ReadMessage(r io.Reader) (*Message, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+
+	length := int(binary.BigEndian.Uint16(header))
+	if length == 0 || length > maxMessageLength {
+		return nil, fmt.Errorf("invalid message length %d", length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+
+	return Unpack(data)
+}
+
+func WriteMessage(w io.Writer, m *Message) error {
+	data, err := m.Pack()
+	if err != nil {
+		return err
+	}
+
+	if len(data) > maxMessageLength {
+		return fmt.Errorf("message too long: %d bytes", len(data))
+	}
+
+	frame := make([]byte, 2, 2+len(data))
+	binary.BigEndian.PutUint16(frame, uint16(len(data)))
+	frame = append(frame, data...)
+
+	_, err = w.Write(frame)
+	return err
+}
+
+func encodeField(field int, spec fieldSpec, value string) (string, error) {
+	if spec.numeric && !isNumeric(value) {
+		return "", fmt.Errorf("field %d must be numeric", field)
+	}
+
+	switch spec.kind {
+	case llvar, lllvar:
+		if len(value) > spec.length {
+			return "", fmt.Errorf("field %d exceeds maximum length %d", field, spec.length)
+		}
+		width := 2
+		if spec.kind == lllvar {
+			width = 3
+		}
+		return fmt.Sprintf("%0*d%s", width, len(value), value), nil
+	default:
+		if len(value) > spec.length {
+			return "", fmt.Errorf("field %d exceeds length %d", field, spec.length)
+		}
+		if spec.numeric {
+			return strings.Repeat("0", spec.length-len(value)) + value, nil
+		}
+		return value + strings.Repeat(" ", spec.length-len(value)), nil
+	}
+}
+
+func decodeField(field int, spec fieldSpec, data []byte, pos int) (string, int, error) {
+	length := spec.length
+
+	if spec.kind != fixedLength {
+		width := 2
+		if spec.kind == lllvar {
+			width = 3
+		}
+		if len(data) < pos+width {
+			return "", 0, fmt.Errorf("field %d: missing length prefix", field)
+		}
+		n, err := strconv.Atoi(string(data[pos : pos+width]))
+		if err != nil || n > spec.length {
+			return "", 0, fmt.Errorf("field %d: invalid length prefix", field)
+		}
+		length = n
+		pos += width
+	}
+
+	if len(data) < pos+length {
+		return "", 0, fmt.Errorf("field %d: message truncated", field)
+	}
+
+	value := string(data[pos : pos+length])
+	if spec.numeric && !isNumeric(value) {
+		return "", 0, fmt.Errorf("field %d must be numeric", field)
+	}
+	if !spec.numeric && spec.kind == fixedLength {
+		value = strings.TrimRight(value, " ")
+	}
+
+	return value, pos + length, nil
+}
+
+func isNumeric(value string) bool {
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
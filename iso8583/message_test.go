@@ -0,0 +1,189 @@
+package iso8583
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestPackKnownMessages(t *testing.T) {
+	tests := []struct {
+		name   string
+		mti    string
+		fields map[int]string
+		want   string
+	}{
+		{
+			"primary bitmap",
+			"0800",
+			map[int]string{7: "1018123045", 11: "1"},
+			"0800" + "0220000000000000" + "1018123045" + "000001",
+		},
+		{
+			"secondary bitmap",
+			"0800",
+			map[int]string{70: "301"},
+			"0800" + "8000000000000000" + "0400000000000000" + "301",
+		},
+		{
+			"variable and padded fields",
+			"0100",
+			map[int]string{2: "4111111111111111", 4: "1050", 41: "TERM1"},
+			"0100" + "5000000000800000" + "164111111111111111" + "000000001050" + "TERM1   ",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMessage(tt.mti)
+			for field, value := range tt.fields {
+				m.Set(field, value)
+			}
+
+			data, err := m.Pack()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Pack() = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestPackUnpackRoundTrip(t *testing.T) {
+	m := NewMessage("0200")
+	m.Set(2, "4111111111111111")
+	m.Set(3, "000000")
+	m.Set(4, "000000012345")
+	m.Set(11, "123456")
+	m.Set(14, "2812")
+	m.Set(37, "RRN000000001")
+	m.Set(43, "COFFEE SHOP            JAKARTA      ID")
+	m.Set(48, "123")
+	m.Set(49, "360")
+	m.Set(90, "010012345610181230450000000000100000000000")
+
+	data, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Unpack(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.MTI != m.MTI {
+		t.Errorf("MTI = %q, want %q", got.MTI, m.MTI)
+	}
+	if !reflect.DeepEqual(got.fields, m.fields) {
+		t.Errorf("fields = %v, want %v", got.fields, m.fields)
+	}
+}
+
+func TestUnpackPadding(t *testing.T) {
+	m := NewMessage("0100")
+	m.Set(4, "1050")
+	m.Set(41, "TERM1")
+
+	data, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Unpack(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Numeric fields keep their leading zeros; text fields lose the spaces
+	// they were padded with.
+	if got.Get(4) != "000000001050" {
+		t.Errorf("field 4 = %q, want 000000001050", got.Get(4))
+	}
+	if got.Get(41) != "TERM1" {
+		t.Errorf("field 41 = %q, want TERM1", got.Get(41))
+	}
+}
+
+func TestPackErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		mti   string
+		field int
+		value string
+	}{
+		{"short MTI", "080", 11, "1"},
+		{"unsupported field", "0800", 5, "1"},
+		{"letters in numeric field", "0800", 11, "12A"},
+		{"fixed field too long", "0800", 11, "1234567"},
+		{"LLVAR too long", "0100", 2, "41111111111111111111"},
+		{"LLLVAR too long", "0100", 48, string(make([]byte, 1000))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := NewMessage(tt.mti)
+			m.Set(tt.field, tt.value)
+			if _, err := m.Pack(); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestUnpackErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"too short", "0800022000"},
+		{"bitmap not hex", "0800" + "ZZ20000000000000" + "1018123045" + "000001"},
+		{"missing secondary bitmap", "0800" + "8000000000000000" + "04"},
+		{"unsupported field", "0800" + "0800000000000000" + "000001"},
+		{"truncated fixed field", "0800" + "0220000000000000" + "1018123045" + "0001"},
+		{"letters in numeric field", "0800" + "0220000000000000" + "1018123045" + "00000A"},
+		{"length prefix not numeric", "0100" + "4000000000000000" + "1A4111111111111111"},
+		{"length prefix over maximum", "0100" + "4000000000000000" + "20" + "41111111111111111111"},
+		{"truncated variable field", "0100" + "4000000000000000" + "16" + "411111"},
+		{"trailing bytes", "0800" + "0220000000000000" + "1018123045" + "000001" + "X"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if m, err := Unpack([]byte(tt.data)); err == nil {
+				t.Errorf("Unpack = %v, want an error", m.fields)
+			}
+		})
+	}
+}
+
+func TestReadWriteMessage(t *testing.T) {
+	m := NewMessage("0800")
+	m.Set(7, "1018123045")
+	m.Set(11, "000001")
+	m.Set(70, "301")
+
+	var buf bytes.Buffer
+	if err := WriteMessage(&buf, m); err != nil {
+		t.Fatal(err)
+	}
+
+	frame := buf.Bytes()
+	if length := int(frame[0])<<8 | int(frame[1]); length != len(frame)-2 {
+		t.Errorf("length header = %d, want %d", length, len(frame)-2)
+	}
+
+	got, err := ReadMessage(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.fields, m.fields) {
+		t.Errorf("fields = %v, want %v", got.fields, m.fields)
+	}
+
+	for _, header := range [][]byte{{0, 0}, {0xFF, 0xFF}} {
+		if _, err := ReadMessage(bytes.NewReader(header)); err == nil {
+			t.Errorf("ReadMessage(% X) succeeded, want an error", header)
+		}
+	}
+}
@@ -0,0 +1,470 @@
+package iso8583
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+const (
+	connectionIdleTimeout = 5 * time.Minute
+	handshakeTimeout      = 10 * time.Second
+)
+
+const (
+	ResponseApproved           = "00"
+	ResponseDoNotHonor         = "05"
+	ResponseInvalidTransaction = "12"
+	ResponseInvalidAmount      = "13"
+	ResponseInvalidCard        = "14"
+	ResponseOriginalNotFound   = "25"
+	ResponseFormatError        = "30"
+	ResponseLostCard           = "41"
+	ResponseInsufficientFunds  = "51"
+	ResponseExpiredCard        = "54"
+	ResponseNotPermitted       = "57"
+	ResponseExceedsLimit       = "61"
+	ResponseRestrictedCard     = "62"
+	ResponseCVVFailure         = "N7"
+	ResponseSystemError        = "96"
+)
+
+var declineResponseCodes = map[services.DeclineReason]string{
+	services.DeclineInvalidCard:        ResponseInvalidCard,
+	services.DeclineExpiredCard:        ResponseExpiredCard,
+	services.DeclineCardBlocked:        ResponseRestrictedCard,
+	services.DeclineCardLost:           ResponseLostCard,
+	services.DeclineInvalidCVV:         ResponseCVVFailure,
+	services.DeclineChannelNotAllowed:  ResponseNotPermitted,
+	services.DeclineCurrencyMismatch:   ResponseInvalidTransaction,
+	services.DeclineExceedsTxLimit:     ResponseExceedsLimit,
+	services.DeclineExceedsDailyLimit:  ResponseExceedsLimit,
+	services.DeclineInsufficientFunds:  ResponseInsufficientFunds,
+	services.DeclineAccountUnavailable: ResponseDoNotHonor,
+}
+
+var currencyCodes = map[string]string{
+	"360": "IDR",
+	"826": "GBP",
+	"840": "USD",
+	"978": "EUR",
+}
+
+var echoedFields = []int{2, 3, 4, 7, 11, 12, 13, 32, 37, 41, 42, 49}
+
+// cardNetwork is the part of CardService the listener drives.
+type cardNetwork interface {
+	Authorize(req services.CardAuthorizationRequest, actor services.AuditActor) (*models.Hold, error)
+	Capture(holdID string, amount float64, actor services.AuditActor) (*models.Transaction, error)
+	Reverse(holdID string, amount float64, actor services.AuditActor) (*models.Hold, error)
+	ReverseNetwork(holdID, stan string, remaining float64, actor services.AuditActor) (*models.Hold, error)
+	GetHoldForReversal(pan, reference string) (*models.Hold, error)
+}
+
+type Server struct {
+	cardService cardNetwork
+	logger      *logrus.Logger
+}
+
+// ListenerConfig describes the ISO 8583 listener. Acquirers authenticate with
+// a client certificate signed by the configured CA; AllowedPeers further
+// limits them to the listed certificate common names.
+type ListenerConfig struct {
+	Addr         string
+	TLS          *tls.Config
+	AllowedPeers map[string]bool
+}
+
+// ListenerConfigFromEnv returns nil when ISO8583_ADDR is unset, which keeps
+// the listener off. An address without a host, such as ":8583", binds to
+// loopback; use 0.0.0.0 explicitly to accept remote acquirers. Mutual TLS is
+// required through ISO8583_TLS_CERT, ISO8583_TLS_KEY and ISO8583_CLIENT_CA,
+// and ISO8583_ALLOWED_PEERS optionally lists the accepted common names.
+func ListenerConfigFromEnv() (*ListenerConfig, error) {
+	addr := os.Getenv("ISO8583_ADDR")
+	if addr == "" {
+		return nil, nil
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid ISO8583_ADDR: %v", err)
+	}
+	if host == "" {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+
+	certFile, keyFile, caFile := os.Getenv("ISO8583_TLS_CERT"), os.Getenv("ISO8583_TLS_KEY"), os.Getenv("ISO8583_CLIENT_CA")
+	if certFile == "" || keyFile == "" || caFile == "" {
+		return nil, errors.New("ISO8583_ADDR requires ISO8583_TLS_CERT, ISO8583_TLS_KEY and ISO8583_CLIENT_CA")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load ISO 8583 certificate: %v", err)
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ISO 8583 client CA: %v", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("ISO 8583 client CA contains no certificates")
+	}
+
+	config := &ListenerConfig{
+		Addr: addr,
+		TLS: &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			ClientCAs:    clientCAs,
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			MinVersion:   tls.VersionTLS12,
+		},
+		AllowedPeers: map[string]bool{},
+	}
+	for _, peer := range strings.Split(os.Getenv("ISO8583_ALLOWED_PEERS"), ",") {
+		if peer = strings.TrimSpace(peer); peer != "" {
+			config.AllowedPeers[peer] = true
+		}
+	}
+
+	return config, nil
+}
+
+func NewServer(db *gorm.DB, logger *logrus.Logger) *Server {
+	return &Server{
+		cardService: services.NewCardService(db),
+		logger:      logger,
+	}
+}
+
+func (s *Server) ListenAndServe(config *ListenerConfig) error {
+	listener, err := tls.Listen("tcp", config.Addr, config.TLS)
+	if err != nil {
+		return err
+	}
+	defer listener.Close()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		go s.serveConn(conn.(*tls.Conn), config.AllowedPeers)
+	}
+}
+
+func (s *Server) serveConn(conn *tls.Conn, allowedPeers map[string]bool) {
+	defer conn.Close()
+
+	// The handshake verifies the client certificate, so no message is read
+	// from a peer that has not authenticated.
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	if err := conn.Handshake(); err != nil {
+		s.logger.WithField("remote_addr", conn.RemoteAddr().String()).Warn("ISO 8583 handshake failed: ", err)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	peer := conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	if len(allowedPeers) > 0 && !allowedPeers[peer] {
+		s.logger.WithFields(logrus.Fields{
+			"remote_addr": conn.RemoteAddr().String(),
+			"peer":        peer,
+		}).Warn("ISO 8583 peer is not allowed")
+		return
+	}
+
+	actor := services.SystemAuditActor("iso8583:" + peer)
+	if host, _, err := net.SplitHostPort(conn.RemoteAddr().String()); err == nil {
+		actor.IPAddress = host
+	}
+
+	for {
+		conn.SetReadDeadline(time.Now().Add(connectionIdleTimeout))
+
+		req, err := ReadMessage(conn)
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				s.logger.WithField("remote_addr", conn.RemoteAddr().String()).Warn("ISO 8583 read failed: ", err)
+			}
+			return
+		}
+
+		resp := s.Handle(req, actor)
+
+		s.logger.WithFields(logrus.Fields{
+			"remote_addr":   conn.RemoteAddr().String(),
+			"peer":          peer,
+			"mti":           req.MTI,
+			"stan":          req.Get(11),
+			"rrn":           req.Get(37),
+			"response_code": resp.Get(39),
+		}).Info("ISO 8583 message")
+
+		if err := WriteMessage(conn, resp); err != nil {
+			s.logger.WithField("remote_addr", conn.RemoteAddr().String()).Warn("ISO 8583 write failed: ", err)
+			return
+		}
+	}
+}
+
+// Handle processes one request from an acquirer and builds the response.
+// Captures and reversals are recorded in the audit log under actor.
+func (s *Server) Handle(req *Message, actor services.AuditActor) *Message {
+	switch req.MTI {
+	case "0100":
+		return s.handleAuthorization(req, false, actor)
+	case "0200":
+		return s.handleAuthorization(req, true, actor)
+	case "0400", "0420":
+		return s.handleReversal(req, actor)
+	case "0800":
+		resp := NewMessage("0810")
+		for _, field := range []int{7, 11, 70} {
+			if req.Has(field) {
+				resp.Set(field, req.Get(field))
+			}
+		}
+		resp.Set(39, ResponseApproved)
+		return resp
+	default:
+		return respond(req, ResponseInvalidTransaction)
+	}
+}
+
+func (s *Server) handleAuthorization(req *Message, capture bool, actor services.AuditActor) *Message {
+	authReq, err := authorizationRequest(req)
+	if err != nil {
+		return respond(req, ResponseFormatError)
+	}
+
+	if authReq.Amount <= 0 {
+		return respond(req, ResponseInvalidAmount)
+	}
+
+	hold, err := s.cardService.Authorize(authReq, actor)
+	if err != nil {
+		return respond(req, responseCodeFor(err))
+	}
+
+	// A retransmission gets the original hold back, which may since have
+	// been reversed or have lapsed; its approval no longer stands.
+	if !holdStands(hold, time.Now()) {
+		return respond(req, ResponseInvalidTransaction)
+	}
+
+	if capture && hold.Status == models.HoldStatusActive {
+		if _, err := s.cardService.Capture(hold.ID.String(), hold.Amount, actor); err != nil {
+			s.logger.WithField("hold_id", hold.ID).Error("ISO 8583 capture failed: ", err)
+			if _, err := s.cardService.Reverse(hold.ID.String(), 0, actor); err != nil {
+				s.logger.WithField("hold_id", hold.ID).Error("ISO 8583 release after failed capture failed: ", err)
+			}
+			return respond(req, ResponseSystemError)
+		}
+	}
+
+	resp := respond(req, ResponseApproved)
+	resp.Set(38, hold.AuthorizationCode)
+	return resp
+}
+
+func (s *Server) handleReversal(req *Message, actor services.AuditActor) *Message {
+	reference := networkReference(req)
+	if reference == "" || !req.Has(2) || !req.Has(4) {
+		return respond(req, ResponseFormatError)
+	}
+
+	original, err := parseAmount(req.Get(4))
+	if err != nil {
+		return respond(req, ResponseFormatError)
+	}
+
+	hold, err := s.cardService.GetHoldForReversal(req.Get(2), reference)
+	if err != nil {
+		return respond(req, ResponseOriginalNotFound)
+	}
+
+	if !reversalMatches(req, original, hold) {
+		s.logger.WithField("hold_id", hold.ID).Warn("ISO 8583 reversal does not match the original authorization")
+		return respond(req, ResponseOriginalNotFound)
+	}
+
+	// Field 95 carries the amount actually dispensed or settled for a partial
+	// reversal, which is what stays on the hold.
+	var remaining float64
+	if replacement := req.Get(95); len(replacement) >= 12 {
+		actual, err := parseAmount(replacement[:12])
+		if err != nil {
+			return respond(req, ResponseFormatError)
+		}
+		if actual >= original {
+			return respond(req, ResponseInvalidAmount)
+		}
+		remaining = actual
+	}
+
+	if _, err := s.cardService.ReverseNetwork(hold.ID.String(), req.Get(11), remaining, actor); err != nil {
+		s.logger.WithField("hold_id", hold.ID).Warn("ISO 8583 reversal failed: ", err)
+		return respond(req, ResponseInvalidTransaction)
+	}
+
+	return respond(req, ResponseApproved)
+}
+
+// holdStands reports whether an authorization can still be approved on hold.
+func holdStands(hold *models.Hold, now time.Time) bool {
+	switch hold.Status {
+	case models.HoldStatusActive:
+		return hold.ExpiresAt.After(now)
+	case models.HoldStatusCaptured:
+		return true
+	}
+	return false
+}
+
+// reversalMatches reports whether a reversal repeats the original amount and,
+// when field 90 carries the original data elements, the original STAN.
+func reversalMatches(req *Message, original float64, hold *models.Hold) bool {
+	originalSTAN := ""
+	if data := req.Get(90); len(data) >= 10 {
+		originalSTAN = data[4:10]
+	}
+	if originalSTAN != "" && hold.STAN != "" && originalSTAN != hold.STAN {
+		return false
+	}
+	return sameAmount(original, hold.AuthorizedAmount)
+}
+
+func authorizationRequest(req *Message) (services.CardAuthorizationRequest, error) {
+	var authReq services.CardAuthorizationRequest
+
+	if !req.Has(2) || !req.Has(4) {
+		return authReq, errors.New("missing PAN or amount")
+	}
+
+	amount, err := parseAmount(req.Get(4))
+	if err != nil {
+		return authReq, err
+	}
+
+	authReq.PAN = req.Get(2)
+	authReq.Amount = amount
+	authReq.CVV = req.Get(48)
+	authReq.Reference = networkReference(req)
+	authReq.STAN = req.Get(11)
+	authReq.Channel = channelFor(req)
+
+	if expiry := req.Get(14); len(expiry) == 4 {
+		year, _ := strconv.Atoi(expiry[:2])
+		month, _ := strconv.Atoi(expiry[2:])
+		authReq.ExpiryYear = 2000 + year
+		authReq.ExpiryMonth = month
+	}
+
+	if code := req.Get(49); code != "" {
+		if currency, ok := currencyCodes[code]; ok {
+			authReq.Currency = currency
+		} else {
+			authReq.Currency = code
+		}
+	}
+
+	if location := req.Get(43); location != "" {
+		name := location
+		if len(name) > 25 {
+			name = name[:25]
+		}
+		authReq.MerchantName = strings.TrimSpace(name)
+	}
+
+	return authReq, nil
+}
+
+func channelFor(req *Message) models.CardChannel {
+	if strings.HasPrefix(req.Get(3), "01") {
+		return models.CardChannelATM
+	}
+
+	entryMode := req.Get(22)
+	if strings.HasPrefix(entryMode, "01") || strings.HasPrefix(entryMode, "81") {
+		return models.CardChannelOnline
+	}
+
+	return models.CardChannelPOS
+}
+
+func networkReference(req *Message) string {
+	if rrn := strings.TrimSpace(req.Get(37)); rrn != "" {
+		return rrn
+	}
+	if req.Has(11) && req.Has(7) {
+		return req.Get(7) + req.Get(11)
+	}
+	return ""
+}
+
+func responseCodeFor(err error) string {
+	var declineErr *services.DeclineError
+	if errors.As(err, &declineErr) {
+		if code, ok := declineResponseCodes[declineErr.Reason]; ok {
+			return code
+		}
+		return ResponseDoNotHonor
+	}
+
+	return ResponseSystemError
+}
+
+func respond(req *Message, code string) *Message {
+	resp := NewMessage(responseMTI(req.MTI))
+	for _, field := range echoedFields {
+		if req.Has(field) {
+			resp.Set(field, req.Get(field))
+		}
+	}
+	resp.Set(39, code)
+	return resp
+}
+
+func responseMTI(mti string) string {
+	if len(mti) != 4 {
+		return "0000"
+	}
+	n, err := strconv.Atoi(mti)
+	if err != nil {
+		return "0000"
+	}
+	return fmt.Sprintf("%04d", n+10)
+}
+
+func sameAmount(a, b float64) bool {
+	return math.Abs(a-b) < 0.005
+}
+
+func parseAmount(value string) (float64, error) {
+	minor, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	return float64(minor) / 100, nil
+}
@@ -0,0 +1,326 @@
+package iso8583
+
+import (
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+func TestReversalMatches(t *testing.T) {
+	hold := &models.Hold{AuthorizedAmount: 123.45, STAN: "123456"}
+
+	// Field 90 holds the original MTI, STAN, transmission time, acquirer and
+	// forwarding institution.
+	originalData := func(stan string) string {
+		return "0100" + stan + "1018123045" + "00000000001" + "00000000000"
+	}
+
+	tests := []struct {
+		name     string
+		amount   float64
+		field90  string
+		holdSTAN string
+		want     bool
+	}{
+		{"same amount, no original data", 123.45, "", "123456", true},
+		{"same amount and STAN", 123.45, originalData("123456"), "123456", true},
+		{"float rounding", 123.449999, originalData("123456"), "123456", true},
+		{"different amount", 123.44, "", "123456", false},
+		{"partial amount", 100, originalData("123456"), "123456", false},
+		{"different STAN", 123.45, originalData("654321"), "123456", false},
+		{"hold without STAN", 123.45, originalData("654321"), "", true},
+		{"short original data is ignored", 123.45, "0100654", "123456", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewMessage("0400")
+			if tt.field90 != "" {
+				req.Set(90, tt.field90)
+			}
+			hold.STAN = tt.holdSTAN
+
+			if got := reversalMatches(req, tt.amount, hold); got != tt.want {
+				t.Errorf("reversalMatches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		value string
+		want  float64
+		ok    bool
+	}{
+		{"000000012345", 123.45, true},
+		{"000000000001", 0.01, true},
+		{"000000000000", 0, true},
+		{"00000001234A", 0, false},
+		{"", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, err := parseAmount(tt.value)
+		if (err == nil) != tt.ok || !sameAmount(got, tt.want) {
+			t.Errorf("parseAmount(%q) = %v, %v, want %v", tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestNetworkReference(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields map[int]string
+		want   string
+	}{
+		{"retrieval reference number", map[int]string{37: "RRN000000001", 7: "1018123045", 11: "123456"}, "RRN000000001"},
+		{"transmission time and STAN", map[int]string{7: "1018123045", 11: "123456"}, "1018123045123456"},
+		{"blank retrieval reference number", map[int]string{37: "   ", 7: "1018123045", 11: "123456"}, "1018123045123456"},
+		{"STAN only", map[int]string{11: "123456"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := NewMessage("0400")
+			for field, value := range tt.fields {
+				req.Set(field, value)
+			}
+			if got := networkReference(req); got != tt.want {
+				t.Errorf("networkReference = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResponseMTI(t *testing.T) {
+	tests := []struct {
+		mti  string
+		want string
+	}{
+		{"0100", "0110"},
+		{"0200", "0210"},
+		{"0400", "0410"},
+		{"0800", "0810"},
+		{"01A0", "0000"},
+		{"100", "0000"},
+	}
+
+	for _, tt := range tests {
+		if got := responseMTI(tt.mti); got != tt.want {
+			t.Errorf("responseMTI(%q) = %q, want %q", tt.mti, got, tt.want)
+		}
+	}
+}
+
+// fakeCardNetwork keeps one hold in memory and applies reversals the way
+// CardService does, counting what reaches the ledger.
+type fakeCardNetwork struct {
+	hold     models.Hold
+	refunded float64
+	released int
+	captured int
+}
+
+// Authorize answers every request as a retransmission of the stored hold.
+func (f *fakeCardNetwork) Authorize(req services.CardAuthorizationRequest, actor services.AuditActor) (*models.Hold, error) {
+	if req.Reference != f.hold.NetworkReference {
+		return nil, errors.New("unexpected authorization")
+	}
+	hold := f.hold
+	return &hold, nil
+}
+
+func (f *fakeCardNetwork) Capture(holdID string, amount float64, actor services.AuditActor) (*models.Transaction, error) {
+	f.captured++
+	f.hold.CapturedAmount = amount
+	f.hold.Status = models.HoldStatusCaptured
+	return &models.Transaction{}, nil
+}
+
+func (f *fakeCardNetwork) Reverse(string, float64, services.AuditActor) (*models.Hold, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeCardNetwork) GetHoldForReversal(pan, reference string) (*models.Hold, error) {
+	if reference != f.hold.NetworkReference {
+		return nil, errors.New("authorization not found")
+	}
+	hold := f.hold
+	return &hold, nil
+}
+
+func (f *fakeCardNetwork) ReverseNetwork(holdID, stan string, remaining float64, actor services.AuditActor) (*models.Hold, error) {
+	amount := f.hold.NetworkReversal(stan, remaining)
+	if amount == 0 {
+		return &f.hold, nil
+	}
+
+	switch f.hold.Status {
+	case models.HoldStatusCaptured:
+		f.refunded += amount
+		f.hold.CapturedAmount -= amount
+		if f.hold.CapturedAmount <= 0 {
+			f.hold.Status = models.HoldStatusReleased
+		}
+	case models.HoldStatusActive:
+		if amount >= f.hold.Amount {
+			f.hold.Status = models.HoldStatusReleased
+			f.released++
+		} else {
+			f.hold.Amount -= amount
+		}
+	}
+	f.hold.ReversalSTAN = stan
+	return &f.hold, nil
+}
+
+func newReversalServer(hold models.Hold) (*Server, *fakeCardNetwork) {
+	logger := logrus.New()
+	logger.SetOutput(io.Discard)
+
+	hold.ID = uuid.New()
+	hold.AuthorizedAmount = 100
+	hold.STAN = "000001"
+	hold.NetworkReference = "RRN000000001"
+	cards := &fakeCardNetwork{hold: hold}
+	return &Server{cardService: cards, logger: logger}, cards
+}
+
+func reversalRequest(mti, stan, actual string) *Message {
+	req := NewMessage(mti)
+	req.Set(2, "4000000000000002")
+	req.Set(4, "000000010000")
+	req.Set(11, stan)
+	req.Set(37, "RRN000000001")
+	req.Set(90, "0200"+"000001"+"1018123045"+"00000000001"+"00000000000")
+	if actual != "" {
+		req.Set(95, actual+"000000000000"+"000000000000000000")
+	}
+	return req
+}
+
+func TestRetransmittedFullReversal(t *testing.T) {
+	server, cards := newReversalServer(models.Hold{Amount: 100, Status: models.HoldStatusActive})
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		resp := server.Handle(reversalRequest("0400", "000002", ""), services.AuditActor{})
+		if resp.MTI != "0410" || resp.Get(39) != ResponseApproved {
+			t.Fatalf("attempt %d: response %s %s, want 0410 %s", attempt, resp.MTI, resp.Get(39), ResponseApproved)
+		}
+	}
+
+	if cards.hold.Status != models.HoldStatusReleased || cards.released != 1 {
+		t.Errorf("hold %s released %d times, want released once", cards.hold.Status, cards.released)
+	}
+}
+
+func TestRetransmittedPartialReversalAdvice(t *testing.T) {
+	server, cards := newReversalServer(models.Hold{Amount: 100, CapturedAmount: 100, Status: models.HoldStatusCaptured})
+
+	// The repeat of the advice and a later copy under a new STAN both ask
+	// for the same 60.00 to stay settled.
+	for _, stan := range []string{"000002", "000002", "000003"} {
+		resp := server.Handle(reversalRequest("0420", stan, "000000006000"), services.AuditActor{})
+		if resp.MTI != "0430" || resp.Get(39) != ResponseApproved {
+			t.Fatalf("STAN %s: response %s %s, want 0430 %s", stan, resp.MTI, resp.Get(39), ResponseApproved)
+		}
+	}
+
+	if cards.refunded != 40 || cards.hold.CapturedAmount != 60 || cards.hold.Status != models.HoldStatusCaptured {
+		t.Errorf("refunded %.2f leaving %.2f %s, want 40.00 refunded leaving 60.00 captured", cards.refunded, cards.hold.CapturedAmount, cards.hold.Status)
+	}
+
+	// A full reversal afterwards still refunds what is left.
+	if resp := server.Handle(reversalRequest("0420", "000004", ""), services.AuditActor{}); resp.Get(39) != ResponseApproved {
+		t.Fatalf("full reversal: response %s, want %s", resp.Get(39), ResponseApproved)
+	}
+	if cards.refunded != 100 || cards.hold.Status != models.HoldStatusReleased {
+		t.Errorf("refunded %.2f with hold %s, want 100.00 and released", cards.refunded, cards.hold.Status)
+	}
+}
+
+func TestReversalReplacementAmount(t *testing.T) {
+	tests := []struct {
+		name   string
+		actual string
+		code   string
+		amount float64
+	}{
+		{"partial", "000000002500", ResponseApproved, 25},
+		{"nothing dispensed", "000000000000", ResponseApproved, 0},
+		{"equal to the original", "000000010000", ResponseInvalidAmount, 100},
+		{"above the original", "000000015000", ResponseInvalidAmount, 100},
+		{"not a number", "00000000A500", ResponseFormatError, 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, cards := newReversalServer(models.Hold{Amount: 100, Status: models.HoldStatusActive})
+
+			resp := server.Handle(reversalRequest("0400", "000002", tt.actual), services.AuditActor{})
+			if resp.Get(39) != tt.code {
+				t.Errorf("response code = %s, want %s", resp.Get(39), tt.code)
+			}
+			remaining := cards.hold.Amount
+			if cards.hold.Status == models.HoldStatusReleased {
+				remaining = 0
+			}
+			if !sameAmount(remaining, tt.amount) {
+				t.Errorf("hold left at %.2f, want %.2f", remaining, tt.amount)
+			}
+		})
+	}
+}
+
+func TestRetransmittedAuthorization(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name     string
+		mti      string
+		status   models.HoldStatus
+		expires  time.Time
+		code     string
+		captures int
+	}{
+		{"active authorization", "0100", models.HoldStatusActive, now.Add(time.Hour), ResponseApproved, 0},
+		{"captured authorization", "0100", models.HoldStatusCaptured, now.Add(time.Hour), ResponseApproved, 0},
+		{"reversed authorization", "0100", models.HoldStatusReleased, now.Add(time.Hour), ResponseInvalidTransaction, 0},
+		{"expired authorization", "0100", models.HoldStatusExpired, now.Add(-time.Hour), ResponseInvalidTransaction, 0},
+		{"lapsed authorization", "0100", models.HoldStatusActive, now.Add(-time.Minute), ResponseInvalidTransaction, 0},
+		{"active financial request", "0200", models.HoldStatusActive, now.Add(time.Hour), ResponseApproved, 1},
+		{"captured financial request", "0200", models.HoldStatusCaptured, now.Add(time.Hour), ResponseApproved, 0},
+		{"reversed financial request", "0200", models.HoldStatusReleased, now.Add(time.Hour), ResponseInvalidTransaction, 0},
+		{"lapsed financial request", "0200", models.HoldStatusActive, now.Add(-time.Minute), ResponseInvalidTransaction, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, cards := newReversalServer(models.Hold{Amount: 100, Status: tt.status, ExpiresAt: tt.expires, AuthorizationCode: "123456"})
+
+			req := NewMessage(tt.mti)
+			req.Set(2, "4000000000000002")
+			req.Set(4, "000000010000")
+			req.Set(11, "000001")
+			req.Set(37, "RRN000000001")
+
+			resp := server.Handle(req, services.AuditActor{})
+			if resp.Get(39) != tt.code {
+				t.Errorf("response code = %s, want %s", resp.Get(39), tt.code)
+			}
+			if approved := resp.Get(38) != ""; approved != (tt.code == ResponseApproved) {
+				t.Errorf("authorization code %q with response %s", resp.Get(38), resp.Get(39))
+			}
+			if cards.captured != tt.captures {
+				t.Errorf("captured %d times, want %d", cards.captured, tt.captures)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package jobs
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+func Register(scheduler *Scheduler, db *gorm.DB, logger *logrus.Logger) {
+	statementService := services.NewStatementService(db)
+	reconciliationService := services.NewReconciliationService(db)
+	endOfDayService := services.NewEndOfDayService(db)
+	tokenService := services.NewTokenService(db)
+	mfaService := services.NewMFAService(db)
+	userService := services.NewUserService(db)
+	loginThrottleService := services.NewLoginThrottleService(db)
+	stepUpService := services.NewStepUpService(db)
+	piiService := services.NewPIIService(db)
+	holdService := services.NewHoldService(db)
+	paymentFileService := services.NewPaymentFileService(db)
+
+	scheduler.Every("month_end_statements", time.Hour, func(now time.Time) error {
+		generated, err := statementService.GenerateMonthEndStatements(now)
+		if generated > 0 {
+			logger.WithField("count", generated).Info("Generated month-end statements")
+		}
+		return err
+	})
+
+	scheduler.Every("end_of_day", 15*time.Minute, func(now time.Time) error {
+		closed, err := endOfDayService.CloseDueBusinessDays(now)
+		if closed > 0 {
+			logger.WithField("count", closed).Info("Closed business days")
+		}
+		return err
+	})
+
+	scheduler.Every("token_cleanup", time.Hour, func(now time.Time) error {
+		purged, err := tokenService.PurgeExpired(now)
+		if err != nil {
+			return err
+		}
+		challenges, err := mfaService.PurgeExpiredChallenges(now)
+		if err != nil {
+			return err
+		}
+		userTokens, err := userService.PurgeExpiredTokens(now)
+		if purged+challenges+userTokens > 0 {
+			logger.WithField("count", purged+challenges+userTokens).Info("Purged expired tokens")
+		}
+		return err
+	})
+
+	scheduler.Every("login_throttle_cleanup", time.Hour, func(now time.Time) error {
+		purged, err := loginThrottleService.PurgeExpired(now)
+		if purged > 0 {
+			logger.WithField("count", purged).Info("Purged expired login throttles")
+		}
+		return err
+	})
+
+	scheduler.Every("hold_expiry", 15*time.Minute, func(now time.Time) error {
+		expired, err := holdService.ExpireHolds(now)
+		if expired > 0 {
+			logger.WithField("count", expired).Info("Expired card authorizations")
+		}
+		return err
+	})
+
+	scheduler.Every("payment_file_resume", 5*time.Minute, func(now time.Time) error {
+		resumed, err := paymentFileService.ResumeStalledFiles(now)
+		if resumed > 0 {
+			logger.WithField("count", resumed).Info("Resumed interrupted payment files")
+		}
+		return err
+	})
+
+	scheduler.Every("transfer_challenge_expiry", time.Minute, func(now time.Time) error {
+		cancelled, err := stepUpService.CancelExpiredTransfers(now)
+		if cancelled > 0 {
+			logger.WithField("count", cancelled).Info("Cancelled unconfirmed transfers")
+		}
+		if err != nil {
+			return err
+		}
+		cancelledFiles, err := paymentFileService.CancelExpiredPaymentFiles(now)
+		if cancelledFiles > 0 {
+			logger.WithField("count", cancelledFiles).Info("Cancelled unconfirmed payment files")
+		}
+		return err
+	})
+
+	scheduler.Every("pii_reencryption", 10*time.Minute, func(now time.Time) error {
+		total := 0
+		for {
+			count, err := piiService.ReencryptBatch()
+			total += count
+			if err != nil || count == 0 {
+				if total > 0 {
+					logger.WithField("count", total).Info("Re-encrypted PII under the active key")
+				}
+				return err
+			}
+		}
+	})
+
+	scheduler.Every("balance_reconciliation", reconciliationInterval(), func(now time.Time) error {
+		run, err := reconciliationService.Run("scheduler", os.Getenv("RECONCILIATION_FREEZE") == "true")
+		if err != nil {
+			return err
+		}
+		if run.AccountsMismatched > 0 {
+			logger.WithFields(logrus.Fields{
+				"run_id":              run.ID,
+				"accounts_mismatched": run.AccountsMismatched,
+				"accounts_frozen":     run.AccountsFrozen,
+				"discrepancies":       run.DiscrepancyCount,
+			}).Warn("Balance reconciliation found discrepancies")
+		}
+		if run.Error != "" {
+			return errors.New(run.Error)
+		}
+		return nil
+	})
+}
+
+func reconciliationInterval() time.Duration {
+	interval, err := time.ParseDuration(os.Getenv("RECONCILIATION_INTERVAL"))
+	if err != nil || interval <= 0 {
+		return 24 * time.Hour
+	}
+	return interval
+}
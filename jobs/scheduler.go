@@ -0,0 +1,74 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(now time.Time) error
+}
+
+type Scheduler struct {
+	logger *logrus.Logger
+	jobs   []job
+	stop   chan struct{}
+}
+
+func NewScheduler(logger *logrus.Logger) *Scheduler {
+	return &Scheduler{
+		logger: logger,
+		stop:   make(chan struct{}),
+	}
+}
+
+func (s *Scheduler) Every(name string, interval time.Duration, run func(now time.Time) error) {
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+}
+
+func (s *Scheduler) Start() {
+	for _, j := range s.jobs {
+		go s.loop(j)
+	}
+}
+
+func (s *Scheduler) Stop() {
+	close(s.stop)
+}
+
+func (s *Scheduler) loop(j job) {
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	s.runOnce(j, time.Now())
+	for {
+		select {
+		case now := <-ticker.C:
+			s.runOnce(j, now)
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+func (s *Scheduler) runOnce(j job, now time.Time) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.logger.WithField("job", j.name).Error("Job panicked: ", r)
+		}
+	}()
+
+	start := time.Now()
+	if err := j.run(now); err != nil {
+		s.logger.WithField("job", j.name).Error("Job failed: ", err)
+		return
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"job":     j.name,
+		"latency": time.Since(start),
+	}).Debug("Job completed")
+}
@@ -1,18 +1,37 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
-	"os"
 	"strings"
 
-	_"github.com/azainwork/core-banking-api/utils"
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/services"
+	"github.com/azainwork/core-banking-api/utils"
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
 )
 
-func AuthMiddleware() gin.HandlerFunc {
+const (
+	AuthMethodJWT    = "jwt"
+	AuthMethodAPIKey = "api_key"
+)
+
+// AuthMiddleware accepts either a Bearer JWT or an API key, sent as
+// "Authorization: ApiKey <key>" or in the X-API-Key header, and stores the
+// same principal fields in the context for both.
+func AuthMiddleware(db *gorm.DB) gin.HandlerFunc {
+	tokenService := services.NewTokenService(db)
+	apiKeyService := services.NewAPIKeyService(db)
+
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
+
+		if apiKey := apiKeyFromRequest(c, authHeader); apiKey != "" {
+			authenticateAPIKey(c, apiKeyService, apiKey)
+			return
+		}
+
 		if authHeader == "" {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
 			c.Abort()
@@ -27,49 +46,125 @@ func AuthMiddleware() gin.HandlerFunc {
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
+		claims, err := utils.ParseJWTToken(tokenString)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		if !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+		userID, ok := claims["user_id"].(string)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
 			c.Abort()
 			return
 		}
 
-		claims, ok := token.Claims.(jwt.MapClaims)
+		email, ok := claims["email"].(string)
 		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email in token"})
 			c.Abort()
 			return
 		}
 
-		userID, ok := claims["user_id"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
+		jti, ok := claims["jti"].(string)
+		if !ok || jti == "" {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
 			c.Abort()
 			return
 		}
 
-		email, ok := claims["email"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email in token"})
+		sessionID, _ := claims["sid"].(string)
+
+		revoked, err := tokenService.IsRevoked(jti, sessionID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
 			c.Abort()
 			return
 		}
+		if revoked {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has been revoked"})
+			c.Abort()
+			return
+		}
+
+		expiresAt, err := claims.GetExpirationTime()
+		if err != nil || expiresAt == nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			c.Abort()
+			return
+		}
+
+		if err := tokenService.TouchSession(sessionID, c.ClientIP()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify token"})
+			c.Abort()
+			return
+		}
+
+		role, _ := claims["role"].(string)
+		mfaVerified, _ := claims["mfa"].(bool)
+		emailVerified, _ := claims["ev"].(bool)
 
+		var permissions []models.Permission
+		if granted, ok := claims["permissions"].([]interface{}); ok {
+			for _, permission := range granted {
+				if value, ok := permission.(string); ok {
+					permissions = append(permissions, models.Permission(value))
+				}
+			}
+		}
+
+		c.Set("auth_method", AuthMethodJWT)
 		c.Set("user_id", userID)
 		c.Set("email", email)
+		c.Set("jti", jti)
+		c.Set("session_id", sessionID)
+		c.Set("role", models.UserRole(role))
+		c.Set("permissions", permissions)
+		c.Set("mfa_verified", mfaVerified)
+		c.Set("email_verified", emailVerified)
+		c.Set("token_expires_at", expiresAt.Time)
 
 		c.Next()
 	}
-} 
\ No newline at end of file
+}
+
+func apiKeyFromRequest(c *gin.Context, authHeader string) string {
+	if strings.HasPrefix(authHeader, "ApiKey ") {
+		return strings.TrimSpace(strings.TrimPrefix(authHeader, "ApiKey "))
+	}
+	return strings.TrimSpace(c.GetHeader("X-API-Key"))
+}
+
+func authenticateAPIKey(c *gin.Context, apiKeyService *services.APIKeyService, rawKey string) {
+	key, err := apiKeyService.Authenticate(rawKey, c.ClientIP())
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidAPIKey):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid API key"})
+		case errors.Is(err, services.ErrAPIKeyIPNotAllowed):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify API key"})
+		}
+		c.Abort()
+		return
+	}
+
+	scopes := make([]models.APIScope, 0, len(key.Scopes))
+	for _, scope := range key.Scopes {
+		scopes = append(scopes, models.APIScope(scope))
+	}
+
+	c.Set("auth_method", AuthMethodAPIKey)
+	c.Set("user_id", key.UserID.String())
+	c.Set("email", key.User.Email)
+	c.Set("api_key_id", key.ID.String())
+	c.Set("scopes", scopes)
+	c.Set("role", key.User.Role)
+	c.Set("permissions", []models.Permission{})
+	c.Set("mfa_verified", false)
+	c.Set("email_verified", key.User.IsEmailVerified())
+
+	c.Next()
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"os"
+
+	"github.com/gin-gonic/gin"
+)
+
+func CardNetworkAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		networkKey := os.Getenv("CARD_NETWORK_KEY")
+		if networkKey == "" {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Card network access is not configured"})
+			c.Abort()
+			return
+		}
+
+		providedKey := c.GetHeader("X-Network-Key")
+		if subtle.ConstantTimeCompare([]byte(providedKey), []byte(networkKey)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid network key"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,21 +1,149 @@
 package middleware
 
 import (
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
 	"github.com/gin-gonic/gin"
 )
 
-func CORS() gin.HandlerFunc {
-	return gin.HandlerFunc(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Credentials", "true")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Header("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// CORSConfigFromEnv reads comma separated lists from CORS_ALLOWED_ORIGINS,
+// CORS_ALLOWED_METHODS, CORS_ALLOWED_HEADERS and CORS_EXPOSED_HEADERS.
+// Origins are exact, such as https://app.example.com, or match any subdomain
+// when written as https://*.example.com. There is no catch-all origin.
+func CORSConfigFromEnv() CORSConfig {
+	config := CORSConfig{
+		AllowedOrigins:   envList("CORS_ALLOWED_ORIGINS", "http://localhost:3000"),
+		AllowedMethods:   envList("CORS_ALLOWED_METHODS", "GET, POST, PUT, PATCH, DELETE, OPTIONS"),
+		AllowedHeaders:   envList("CORS_ALLOWED_HEADERS", "Authorization, Content-Type, Accept, Cache-Control, X-Requested-With, X-CSRF-Token, X-API-Key, X-Device-ID"),
+		ExposedHeaders:   envList("CORS_EXPOSED_HEADERS", "Content-Disposition, X-Request-ID, RateLimit-Policy, RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset, Retry-After"),
+		AllowCredentials: os.Getenv("CORS_ALLOW_CREDENTIALS") != "false",
+		MaxAge:           10 * time.Minute,
+	}
+
+	if maxAge, err := time.ParseDuration(os.Getenv("CORS_MAX_AGE")); err == nil && maxAge >= 0 {
+		config.MaxAge = maxAge
+	}
+
+	return config
+}
+
+// CORS reflects the request origin only when it is on the allowlist, so that
+// credentials are never shared with an arbitrary site. Preflight requests from
+// other origins, or for methods that are not allowed, get 403.
+func CORS(config CORSConfig) gin.HandlerFunc {
+	allowedMethods := strings.Join(config.AllowedMethods, ", ")
+	allowedHeaders := strings.Join(config.AllowedHeaders, ", ")
+	exposedHeaders := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	methods := make(map[string]bool, len(config.AllowedMethods))
+	for _, method := range config.AllowedMethods {
+		methods[strings.ToUpper(method)] = true
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		// Responses differ by origin, so caches must key on it even when the
+		// origin is rejected.
+		c.Writer.Header().Add("Vary", "Origin")
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+		}
+
+		if origin == "" {
+			c.Next()
+			return
+		}
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
+		if !originAllowed(config.AllowedOrigins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
 			return
 		}
 
-		c.Next()
-	})
-} 
\ No newline at end of file
+		if preflight && !methods[strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))] {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		if config.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if !preflight {
+			if exposedHeaders != "" {
+				c.Header("Access-Control-Expose-Headers", exposedHeaders)
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Methods", allowedMethods)
+		c.Header("Access-Control-Allow-Headers", allowedHeaders)
+		c.Header("Access-Control-Max-Age", maxAge)
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+func originAllowed(allowed []string, origin string) bool {
+	origin = strings.ToLower(strings.TrimSuffix(origin, "/"))
+
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(strings.TrimSuffix(pattern, "/"))
+		if pattern == origin {
+			return true
+		}
+
+		// https://*.example.com matches https://app.example.com and
+		// https://a.b.example.com, but not https://example.com itself.
+		scheme, host, ok := strings.Cut(pattern, "://*.")
+		if !ok {
+			continue
+		}
+		prefix := scheme + "://"
+		if !strings.HasPrefix(origin, prefix) {
+			continue
+		}
+		rest := strings.TrimPrefix(origin, prefix)
+		if strings.HasSuffix(rest, "."+host) && len(rest) > len(host)+1 && !strings.ContainsAny(rest[:len(rest)-len(host)-1], "/:@?#\\") {
+			return true
+		}
+	}
+
+	return false
+}
+
+func envList(name, fallback string) []string {
+	value := os.Getenv(name)
+	if value == "" {
+		value = fallback
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{
+		"https://app.example.com",
+		"https://*.example.org",
+		"http://*.localhost:3000/",
+	}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"https://app.example.com", true},
+		{"https://APP.Example.com", true},
+		{"https://app.example.com/", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com:8443", false},
+		{"https://evil.app.example.com", false},
+		{"https://app.example.com.evil.com", false},
+
+		{"https://a.example.org", true},
+		{"https://a.b.example.org", true},
+		{"https://A.Example.ORG", true},
+		{"https://example.org", false},
+		{"https://.example.org", false},
+		{"https://evilexample.org", false},
+		{"https://example.org.evil.com", false},
+		{"http://a.example.org", false},
+		{"https://a.example.org:8443", false},
+		{"https://evil.com/.example.org", false},
+		{"https://evil.com?.example.org", false},
+		{"https://evil.com#.example.org", false},
+		{"https://evil.com\\.example.org", false},
+		{"https://user@a.example.org", false},
+		{"https://evil.com:1@a.example.org", false},
+		{"wss://a.example.org", false},
+
+		{"http://app.localhost:3000", true},
+		{"http://app.localhost", false},
+		{"http://app.localhost:3001", false},
+
+		{"null", false},
+		{"", false},
+		{"*", false},
+	}
+
+	for _, tt := range tests {
+		if got := originAllowed(allowed, tt.origin); got != tt.want {
+			t.Errorf("originAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestOriginAllowedHasNoCatchAll(t *testing.T) {
+	for _, pattern := range []string{"*", "https://*", "https://*.", "*://*.example.com"} {
+		for _, origin := range []string{"https://example.com", "https://a.example.com", "http://a.example.com"} {
+			if originAllowed([]string{pattern}, origin) {
+				t.Errorf("pattern %q allowed %q", pattern, origin)
+			}
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(CORS(CORSConfig{
+		AllowedOrigins:   []string{"https://*.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Authorization"},
+		AllowCredentials: true,
+	}))
+	router.POST("/accounts", func(c *gin.Context) { c.Status(http.StatusCreated) })
+
+	tests := []struct {
+		name   string
+		origin string
+		method string
+		status int
+		allow  string
+	}{
+		{"allowed", "https://app.example.com", "POST", http.StatusNoContent, "https://app.example.com"},
+		{"method not allowed", "https://app.example.com", "DELETE", http.StatusForbidden, ""},
+		{"apex not allowed", "https://example.com", "POST", http.StatusForbidden, ""},
+		{"other site", "https://example.com.evil.com", "POST", http.StatusForbidden, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodOptions, "/accounts", nil)
+			req.Header.Set("Origin", tt.origin)
+			req.Header.Set("Access-Control-Request-Method", tt.method)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if got := rec.Header().Get("Access-Control-Allow-Origin"); got != tt.allow {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.allow)
+			}
+			if tt.allow != "" && rec.Header().Get("Access-Control-Allow-Credentials") != "true" {
+				t.Error("credentials are not allowed")
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+func RequireVerifiedEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !c.GetBool("email_verified") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Please verify your email address to use this endpoint"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -1,8 +1,10 @@
 package middleware
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/azainwork/core-banking-api/utils"
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -30,6 +32,31 @@ func RequestLogger(logger *logrus.Logger) gin.HandlerFunc {
 			"method":      method,
 			"path":        path,
 			"user_agent":  c.Request.UserAgent(),
+			"request_id":  c.GetString("request_id"),
 		}).Info("HTTP Request")
 	})
-} 
\ No newline at end of file
+}
+
+// RedactedLogFormatter is gin's default access log line with email addresses
+// and phone numbers masked in the path, which includes the query string.
+func RedactedLogFormatter(param gin.LogFormatterParams) string {
+	var statusColor, methodColor, resetColor string
+	if param.IsOutputColor() {
+		statusColor = param.StatusCodeColor()
+		methodColor = param.MethodColor()
+		resetColor = param.ResetColor()
+	}
+
+	if param.Latency > time.Minute {
+		param.Latency = param.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v |%s %3d %s| %13v | %15s |%s %-7s %s %#v\n%s",
+		param.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		statusColor, param.StatusCode, resetColor,
+		param.Latency,
+		param.ClientIP,
+		methodColor, param.Method, resetColor,
+		utils.RedactPII(param.Path),
+		utils.RedactPII(param.ErrorMessage),
+	)
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/gin-gonic/gin"
+)
+
+func RequirePermission(required ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, _ := c.Get("permissions")
+		granted, _ := value.([]models.Permission)
+
+		role, _ := c.Get("role")
+		if userRole, ok := role.(models.UserRole); ok && userRole.RequiresMFA() && !c.GetBool("mfa_verified") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Staff accounts must sign in with MFA to use this endpoint"})
+			c.Abort()
+			return
+		}
+
+		for _, permission := range required {
+			if !hasPermission(granted, permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Missing permission: " + string(permission)})
+				c.Abort()
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+// RequireScope restricts API key principals to the scopes granted to their
+// key. User sessions are not scoped and always pass.
+func RequireScope(scope models.APIScope) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") != AuthMethodAPIKey {
+			c.Next()
+			return
+		}
+
+		value, _ := c.Get("scopes")
+		scopes, _ := value.([]models.APIScope)
+		for _, granted := range scopes {
+			if granted == scope {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "API key is missing scope: " + string(scope)})
+		c.Abort()
+	}
+}
+
+func RequireUserSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("auth_method") == AuthMethodAPIKey {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint cannot be used with an API key"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func hasPermission(granted []models.Permission, permission models.Permission) bool {
+	for _, p := range granted {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+type RateLimitPolicy struct {
+	Name   string
+	Limit  int
+	Window time.Duration
+}
+
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// RateLimitStore holds one token bucket per key. A bucket holds up to
+// policy.Limit tokens and refills at Limit per Window.
+type RateLimitStore interface {
+	Take(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error)
+}
+
+// NewRateLimitStore selects the backend from RATE_LIMIT_STORE. The memory
+// store is per process; use "postgres" when running several replicas.
+func NewRateLimitStore(db *gorm.DB) RateLimitStore {
+	if os.Getenv("RATE_LIMIT_STORE") == "postgres" {
+		return NewPostgresRateLimitStore(db)
+	}
+	return NewMemoryRateLimitStore()
+}
+
+// RateLimitPolicyFromEnv reads an override such as RATE_LIMIT_AUTH=10/1m for
+// the named policy and falls back to the given limit and window.
+func RateLimitPolicyFromEnv(name string, limit int, window time.Duration) RateLimitPolicy {
+	policy := RateLimitPolicy{Name: name, Limit: limit, Window: window}
+
+	value := os.Getenv("RATE_LIMIT_" + strings.ToUpper(name))
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) != 2 {
+		return policy
+	}
+
+	envLimit, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	if err != nil || envLimit <= 0 {
+		return policy
+	}
+	envWindow, err := time.ParseDuration(strings.TrimSpace(parts[1]))
+	if err != nil || envWindow <= 0 {
+		return policy
+	}
+
+	policy.Limit = envLimit
+	policy.Window = envWindow
+	return policy
+}
+
+// RateLimit limits requests per caller, identified by the API key, then the
+// authenticated user, then the client IP. Place it after AuthMiddleware on
+// protected groups so that limits follow the user rather than the address.
+func RateLimit(store RateLimitStore, policy RateLimitPolicy) gin.HandlerFunc {
+	return rateLimit(store, policy, rateLimitKey)
+}
+
+// RateLimitByIP limits requests per client IP whoever they claim to be. Place
+// it before AuthMiddleware so that requests with guessed tokens or API keys
+// are counted even though they never authenticate.
+func RateLimitByIP(store RateLimitStore, policy RateLimitPolicy) gin.HandlerFunc {
+	return rateLimit(store, policy, func(c *gin.Context) string {
+		return "ip:" + c.ClientIP()
+	})
+}
+
+func rateLimit(store RateLimitStore, policy RateLimitPolicy, key func(c *gin.Context) string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result, err := store.Take(policy.Name+":"+key(c), policy, time.Now())
+		if err != nil {
+			// Fail open so that a storage outage does not take the API down.
+			c.Next()
+			return
+		}
+
+		c.Header("RateLimit-Policy", fmt.Sprintf("%d;w=%d", policy.Limit, int(policy.Window.Seconds())))
+		c.Header("RateLimit-Limit", strconv.Itoa(policy.Limit))
+		c.Header("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+		c.Header("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.ResetAfter)))
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(ceilSeconds(result.RetryAfter)))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Rate limit exceeded, please retry later"})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+func rateLimitKey(c *gin.Context) string {
+	if apiKeyID := c.GetString("api_key_id"); apiKeyID != "" {
+		return "key:" + apiKeyID
+	}
+	if userID := c.GetString("user_id"); userID != "" {
+		return "user:" + userID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+func ceilSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int(math.Ceil(d.Seconds()))
+}
+
+// takeToken refills a bucket for the time elapsed since it was last updated
+// and consumes one token when available.
+func takeToken(tokens float64, updatedAt time.Time, policy RateLimitPolicy, now time.Time) (float64, RateLimitResult) {
+	capacity := float64(policy.Limit)
+	rate := capacity / policy.Window.Seconds()
+
+	if elapsed := now.Sub(updatedAt).Seconds(); elapsed > 0 {
+		tokens = math.Min(capacity, tokens+elapsed*rate)
+	}
+
+	result := RateLimitResult{}
+	if tokens >= 1 {
+		tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = time.Duration((1 - tokens) / rate * float64(time.Second))
+	}
+
+	result.Remaining = int(math.Floor(tokens))
+	result.ResetAfter = time.Duration((capacity - tokens) / rate * float64(time.Second))
+
+	return tokens, result
+}
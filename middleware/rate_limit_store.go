@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Idle buckets are dropped once they have had time to refill completely, at
+// which point a fresh bucket behaves identically.
+const rateLimitSweepInterval = 10 * time.Minute
+
+type memoryBucket struct {
+	tokens    float64
+	updatedAt time.Time
+	window    time.Duration
+}
+
+type MemoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*memoryBucket
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() *MemoryRateLimitStore {
+	return &MemoryRateLimitStore{buckets: make(map[string]*memoryBucket)}
+}
+
+func (s *MemoryRateLimitStore) Take(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) > rateLimitSweepInterval {
+		for k, bucket := range s.buckets {
+			if now.Sub(bucket.updatedAt) > bucket.window {
+				delete(s.buckets, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{tokens: float64(policy.Limit), updatedAt: now}
+		s.buckets[key] = bucket
+	}
+
+	tokens, result := takeToken(bucket.tokens, bucket.updatedAt, policy, now)
+	bucket.tokens = tokens
+	bucket.updatedAt = now
+	bucket.window = policy.Window
+
+	return result, nil
+}
+
+// PostgresRateLimitStore keeps buckets in the rate_limit_buckets table so that
+// every replica draws from the same bucket. Each take locks the bucket row.
+type PostgresRateLimitStore struct {
+	db        *gorm.DB
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+func NewPostgresRateLimitStore(db *gorm.DB) *PostgresRateLimitStore {
+	return &PostgresRateLimitStore{db: db}
+}
+
+func (s *PostgresRateLimitStore) Take(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	var result RateLimitResult
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RateLimitBucket{
+			Key:       key,
+			Tokens:    float64(policy.Limit),
+			UpdatedAt: now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var bucket models.RateLimitBucket
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&bucket).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("rate limit bucket %s disappeared", key)
+			}
+			return err
+		}
+
+		var tokens float64
+		tokens, result = takeToken(bucket.Tokens, bucket.UpdatedAt, policy, now)
+
+		return tx.Model(&bucket).Updates(map[string]interface{}{"tokens": tokens, "updated_at": now}).Error
+	})
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("failed to apply rate limit: %v", err)
+	}
+
+	s.sweep(now, policy.Window)
+
+	return result, nil
+}
+
+func (s *PostgresRateLimitStore) sweep(now time.Time, window time.Duration) {
+	s.mu.Lock()
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		s.mu.Unlock()
+		return
+	}
+	s.lastSweep = now
+	s.mu.Unlock()
+
+	// Buckets idle for a day have refilled under any sensible policy.
+	cutoff := now.Add(-24 * time.Hour)
+	if window > 24*time.Hour {
+		cutoff = now.Add(-window)
+	}
+	go s.db.Where("updated_at < ?", cutoff).Delete(&models.RateLimitBucket{})
+}
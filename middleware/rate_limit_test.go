@@ -0,0 +1,219 @@
+package middleware
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMemoryRateLimitStoreRefill(t *testing.T) {
+	// Two tokens refilled over two seconds: one token per second.
+	policy := RateLimitPolicy{Name: "test", Limit: 2, Window: 2 * time.Second}
+	start := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	store := NewMemoryRateLimitStore()
+
+	tests := []struct {
+		name       string
+		at         time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+		resetAfter time.Duration
+	}{
+		{"first request", 0, true, 1, 0, time.Second},
+		{"second request", 0, true, 0, 0, 2 * time.Second},
+		{"bucket empty", 0, false, 0, time.Second, 2 * time.Second},
+		{"half a token", 500 * time.Millisecond, false, 0, 500 * time.Millisecond, 1500 * time.Millisecond},
+		{"clock went back", 250 * time.Millisecond, false, 0, 500 * time.Millisecond, 1500 * time.Millisecond},
+		{"one token", 750 * time.Millisecond, true, 0, 0, 2 * time.Second},
+		{"empty again", 750 * time.Millisecond, false, 0, time.Second, 2 * time.Second},
+		{"refill is capped", time.Hour, true, 1, 0, time.Second},
+	}
+
+	for _, tt := range tests {
+		result, err := store.Take("user:1", policy, start.Add(tt.at))
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := RateLimitResult{Allowed: tt.allowed, Remaining: tt.remaining, RetryAfter: tt.retryAfter, ResetAfter: tt.resetAfter}
+		if result != want {
+			t.Errorf("%s: Take = %+v, want %+v", tt.name, result, want)
+		}
+	}
+}
+
+func TestMemoryRateLimitStoreKeys(t *testing.T) {
+	policy := RateLimitPolicy{Name: "test", Limit: 1, Window: time.Minute}
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	store := NewMemoryRateLimitStore()
+
+	for _, key := range []string{"user:1", "user:2"} {
+		if result, _ := store.Take(key, policy, now); !result.Allowed {
+			t.Errorf("first request for %s was limited", key)
+		}
+	}
+	if result, _ := store.Take("user:1", policy, now); result.Allowed {
+		t.Error("second request for user:1 was allowed")
+	}
+
+	// Buckets idle for longer than their window are swept; the next take
+	// starts a full bucket, just as an idle one would have refilled.
+	later := now.Add(rateLimitSweepInterval + time.Second)
+	if _, err := store.Take("user:3", policy, later); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.buckets["user:1"]; ok {
+		t.Error("idle bucket was not swept")
+	}
+	if result, _ := store.Take("user:1", policy, later); !result.Allowed || result.Remaining != 0 {
+		t.Errorf("Take after sweep = %+v, want allowed with nothing remaining", result)
+	}
+}
+
+func TestCeilSeconds(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want int
+	}{
+		{-time.Second, 0},
+		{0, 0},
+		{time.Nanosecond, 1},
+		{time.Second, 1},
+		{1500 * time.Millisecond, 2},
+		{time.Minute, 60},
+	}
+
+	for _, tt := range tests {
+		if got := ceilSeconds(tt.d); got != tt.want {
+			t.Errorf("ceilSeconds(%v) = %d, want %d", tt.d, got, tt.want)
+		}
+	}
+}
+
+type stubRateLimitStore struct {
+	result RateLimitResult
+	err    error
+	key    string
+}
+
+func (s *stubRateLimitStore) Take(key string, policy RateLimitPolicy, now time.Time) (RateLimitResult, error) {
+	s.key = key
+	return s.result, s.err
+}
+
+func TestRateLimitHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := RateLimitPolicy{Name: "auth", Limit: 10, Window: time.Minute}
+
+	tests := []struct {
+		name       string
+		store      *stubRateLimitStore
+		status     int
+		remaining  string
+		reset      string
+		retryAfter string
+	}{
+		{"allowed", &stubRateLimitStore{result: RateLimitResult{Allowed: true, Remaining: 9, ResetAfter: 6 * time.Second}}, http.StatusOK, "9", "6", ""},
+		{"limited", &stubRateLimitStore{result: RateLimitResult{RetryAfter: 4200 * time.Millisecond, ResetAfter: 59 * time.Second}}, http.StatusTooManyRequests, "0", "59", "5"},
+		{"store error fails open", &stubRateLimitStore{err: errors.New("connection refused")}, http.StatusOK, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := gin.New()
+			router.Use(RateLimit(tt.store, policy))
+			router.POST("/auth/login", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+			req.RemoteAddr = "203.0.113.7:51234"
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Errorf("status = %d, want %d", rec.Code, tt.status)
+			}
+			if tt.store.key != "auth:ip:203.0.113.7" {
+				t.Errorf("key = %q, want auth:ip:203.0.113.7", tt.store.key)
+			}
+			for header, want := range map[string]string{
+				"RateLimit-Remaining": tt.remaining,
+				"RateLimit-Reset":     tt.reset,
+				"Retry-After":         tt.retryAfter,
+			} {
+				if got := rec.Header().Get(header); got != want {
+					t.Errorf("%s = %q, want %q", header, got, want)
+				}
+			}
+			if tt.store.err == nil && rec.Header().Get("RateLimit-Policy") != "10;w=60" {
+				t.Errorf("RateLimit-Policy = %q, want 10;w=60", rec.Header().Get("RateLimit-Policy"))
+			}
+		})
+	}
+}
+
+func TestRateLimitPolicyFromEnv(t *testing.T) {
+	tests := []struct {
+		value  string
+		limit  int
+		window time.Duration
+	}{
+		{"", 5, time.Minute},
+		{"10/30s", 10, 30 * time.Second},
+		{" 10 / 1h ", 10, time.Hour},
+		{"10", 5, time.Minute},
+		{"0/1m", 5, time.Minute},
+		{"-1/1m", 5, time.Minute},
+		{"10/0s", 5, time.Minute},
+		{"10/minute", 5, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("RATE_LIMIT_AUTH", tt.value)
+		policy := RateLimitPolicyFromEnv("auth", 5, time.Minute)
+		if policy.Name != "auth" || policy.Limit != tt.limit || policy.Window != tt.window {
+			t.Errorf("RATE_LIMIT_AUTH=%q: policy = %+v, want %d/%v", tt.value, policy, tt.limit, tt.window)
+		}
+	}
+}
+
+func TestRateLimitKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	policy := RateLimitPolicy{Name: "test", Limit: 10, Window: time.Minute}
+
+	tests := []struct {
+		name    string
+		limiter func(RateLimitStore, RateLimitPolicy) gin.HandlerFunc
+		values  map[string]string
+		want    string
+	}{
+		{"API key", RateLimit, map[string]string{"api_key_id": "k1", "user_id": "u1"}, "test:key:k1"},
+		{"user", RateLimit, map[string]string{"user_id": "u1"}, "test:user:u1"},
+		{"anonymous", RateLimit, nil, "test:ip:203.0.113.7"},
+		{"by IP ignores the user", RateLimitByIP, map[string]string{"api_key_id": "k1", "user_id": "u1"}, "test:ip:203.0.113.7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &stubRateLimitStore{result: RateLimitResult{Allowed: true}}
+			router := gin.New()
+			router.Use(func(c *gin.Context) {
+				for key, value := range tt.values {
+					c.Set(key, value)
+				}
+			}, tt.limiter(store, policy))
+			router.GET("/profile", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+			req := httptest.NewRequest(http.MethodGet, "/profile", nil)
+			req.RemoteAddr = "203.0.113.7:51234"
+			router.ServeHTTP(httptest.NewRecorder(), req)
+
+			if store.key != tt.want {
+				t.Errorf("key = %q, want %q", store.key, tt.want)
+			}
+		})
+	}
+}
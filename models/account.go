@@ -21,6 +21,9 @@ type Account struct {
 	Balance     float64     `json:"balance" gorm:"not null;default:0"`
 	Currency    string      `json:"currency" gorm:"default:'USD'"`
 	IsActive    bool        `json:"is_active" gorm:"default:true"`
+	IsFrozen    bool        `json:"is_frozen" gorm:"not null;default:false"`
+	FrozenReason string     `json:"frozen_reason,omitempty"`
+	FrozenAt    *time.Time  `json:"frozen_at,omitempty"`
 	UserID      uuid.UUID   `json:"user_id" gorm:"type:uuid;not null"`
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
@@ -0,0 +1,114 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type APIScope string
+
+const (
+	APIScopeAccountsRead      APIScope = "accounts:read"
+	APIScopeAccountsWrite     APIScope = "accounts:write"
+	APIScopeTransactionsRead  APIScope = "transactions:read"
+	APIScopeTransactionsWrite APIScope = "transactions:write"
+	APIScopeTransfersWrite    APIScope = "transfers:write"
+	APIScopeCardsRead         APIScope = "cards:read"
+	APIScopeCardsWrite        APIScope = "cards:write"
+)
+
+var APIScopes = []APIScope{
+	APIScopeAccountsRead,
+	APIScopeAccountsWrite,
+	APIScopeTransactionsRead,
+	APIScopeTransactionsWrite,
+	APIScopeTransfersWrite,
+	APIScopeCardsRead,
+	APIScopeCardsWrite,
+}
+
+func (s APIScope) IsValid() bool {
+	for _, scope := range APIScopes {
+		if scope == s {
+			return true
+		}
+	}
+	return false
+}
+
+type StringList []string
+
+// APIKey authenticates server-to-server calls on behalf of its owner. The
+// full key is only returned at creation; Prefix identifies it in requests and
+// listings while SecretHash verifies the remainder.
+//
+// Keys are owned by a user and act with that user's accounts. Organization
+// owned keys are not supported because accounts, roles and permissions have
+// no notion of an organization yet; an owner type would come with that model.
+type APIKey struct {
+	ID            uuid.UUID  `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID        uuid.UUID  `json:"user_id" gorm:"type:uuid;not null;index"`
+	Name          string     `json:"name" gorm:"not null"`
+	Prefix        string     `json:"prefix" gorm:"uniqueIndex;not null"`
+	SecretHash    string     `json:"-" gorm:"not null"`
+	Scopes        StringList `json:"scopes" gorm:"type:jsonb;not null;default:'[]'"`
+	AllowedIPs    StringList `json:"allowed_ips" gorm:"type:jsonb;not null;default:'[]'"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP    string     `json:"last_used_ip,omitempty"`
+	RotatedFromID *uuid.UUID `json:"rotated_from_id,omitempty" gorm:"type:uuid"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
+
+func (k *APIKey) IsActive(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+}
+
+func (k *APIKey) HasScope(scope APIScope) bool {
+	for _, granted := range k.Scopes {
+		if APIScope(granted) == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	data, err := jsonbBytes(value)
+	if err != nil {
+		return err
+	}
+
+	*l = StringList{}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, l)
+}
@@ -0,0 +1,169 @@
+package models
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// AuditGenesisHash is the previous hash of the first entry in each chain.
+var AuditGenesisHash = strings.Repeat("0", 64)
+
+type AuditActorType string
+
+const (
+	AuditActorUser   AuditActorType = "user"
+	AuditActorAPIKey AuditActorType = "api_key"
+	AuditActorSystem AuditActorType = "system"
+)
+
+type AuditAction string
+
+const (
+	AuditActionAccountCreate      AuditAction = "account.create"
+	AuditActionAccountClose       AuditAction = "account.close"
+	AuditActionAccountReopen      AuditAction = "account.reopen"
+	AuditActionAccountFreeze      AuditAction = "account.freeze"
+	AuditActionAccountUnfreeze    AuditAction = "account.unfreeze"
+	AuditActionUserUpdate         AuditAction = "user.update"
+	AuditActionUserRoleChange     AuditAction = "user.role_change"
+	AuditActionUserActivate       AuditAction = "user.activate"
+	AuditActionUserDeactivate     AuditAction = "user.deactivate"
+	AuditActionUserUnlock         AuditAction = "user.unlock"
+	AuditActionUserRegister       AuditAction = "user.register"
+	AuditActionEmailVerify        AuditAction = "user.email_verify"
+	AuditActionDeposit            AuditAction = "transaction.deposit"
+	AuditActionWithdrawal         AuditAction = "transaction.withdrawal"
+	AuditActionTransfer           AuditAction = "transaction.transfer"
+	AuditActionTransferPending    AuditAction = "transaction.transfer_pending"
+	AuditActionTransferFailed     AuditAction = "transaction.transfer_failed"
+	AuditActionTransferCanceled   AuditAction = "transaction.transfer_cancel"
+	AuditActionTransactionEdit    AuditAction = "transaction.annotate"
+	AuditActionCardCapture        AuditAction = "transaction.card_capture"
+	AuditActionCardRefund         AuditAction = "transaction.card_refund"
+	AuditActionCardIssue          AuditAction = "card.issue"
+	AuditActionCardControls       AuditAction = "card.controls_update"
+	AuditActionCardFreeze         AuditAction = "card.freeze"
+	AuditActionCardUnfreeze       AuditAction = "card.unfreeze"
+	AuditActionCardLost           AuditAction = "card.report_lost"
+	AuditActionCardCVVLockout     AuditAction = "card.cvv_lockout"
+	AuditActionHoldAuthorize      AuditAction = "hold.authorize"
+	AuditActionHoldRelease        AuditAction = "hold.release"
+	AuditActionAPIKeyCreate       AuditAction = "api_key.create"
+	AuditActionAPIKeyRevoke       AuditAction = "api_key.revoke"
+	AuditActionAPIKeyRotate       AuditAction = "api_key.rotate"
+	AuditActionMFAEnable          AuditAction = "user.mfa_enable"
+	AuditActionMFADisable         AuditAction = "user.mfa_disable"
+	AuditActionMFARecoveryCodes   AuditAction = "user.mfa_recovery_codes"
+	AuditActionPasswordChange     AuditAction = "user.password_change"
+	AuditActionPasswordReset      AuditAction = "user.password_reset"
+	AuditActionTransactionPIN     AuditAction = "user.transaction_pin_set"
+	AuditActionSessionRevoke      AuditAction = "session.revoke"
+	AuditActionBusinessDayClose   AuditAction = "business_day.close"
+	AuditActionCategoryRuleCreate AuditAction = "category_rule.create"
+	AuditActionCategoryRuleUpdate AuditAction = "category_rule.update"
+	AuditActionCategoryRuleDelete AuditAction = "category_rule.delete"
+	AuditActionPaymentFileCreate  AuditAction = "payment_file.create"
+	AuditActionPaymentFileCancel  AuditAction = "payment_file.cancel"
+)
+
+type AuditTargetType string
+
+const (
+	AuditTargetUser         AuditTargetType = "user"
+	AuditTargetAccount      AuditTargetType = "account"
+	AuditTargetTransaction  AuditTargetType = "transaction"
+	AuditTargetCard         AuditTargetType = "card"
+	AuditTargetHold         AuditTargetType = "hold"
+	AuditTargetAPIKey       AuditTargetType = "api_key"
+	AuditTargetSession      AuditTargetType = "session"
+	AuditTargetBusinessDay  AuditTargetType = "business_day"
+	AuditTargetCategoryRule AuditTargetType = "category_rule"
+	AuditTargetPaymentFile  AuditTargetType = "payment_file"
+)
+
+// AuditChanges holds the before and after values of each changed field as
+// JSON. It is stored as text rather than jsonb so that the bytes covered by
+// the hash are exactly the bytes read back.
+type AuditChanges []byte
+
+// AuditLog is one entry in an append-only chain. The log is split into
+// independent chains, one per shard, each numbered from 1. Each hash covers
+// the entry's contents and the hash of the entry before it in the same shard,
+// so editing or removing an entry breaks every later link.
+type AuditLog struct {
+	ID         uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Shard      int             `json:"shard" gorm:"not null;default:0;uniqueIndex:idx_audit_logs_shard_seq,priority:1"`
+	Seq        int64           `json:"seq" gorm:"not null;uniqueIndex:idx_audit_logs_shard_seq,priority:2"`
+	ActorType  AuditActorType  `json:"actor_type" gorm:"not null"`
+	ActorID    string          `json:"actor_id" gorm:"index"`
+	APIKeyID   string          `json:"api_key_id,omitempty"`
+	Action     AuditAction     `json:"action" gorm:"not null;index"`
+	TargetType AuditTargetType `json:"target_type" gorm:"not null;index:idx_audit_logs_target"`
+	TargetID   string          `json:"target_id" gorm:"index:idx_audit_logs_target"`
+	Changes    AuditChanges    `json:"changes" gorm:"type:text;not null"`
+	IPAddress  string          `json:"ip_address,omitempty"`
+	RequestID  string          `json:"request_id,omitempty" gorm:"index"`
+	PrevHash   string          `json:"prev_hash" gorm:"not null"`
+	Hash       string          `json:"hash" gorm:"not null;uniqueIndex"`
+	CreatedAt  time.Time       `json:"created_at" gorm:"not null;index"`
+}
+
+// ComputeHash returns the SHA-256 of the entry's fields and previous hash.
+// CreatedAt must already be truncated to the microsecond precision of the
+// database for the hash to survive a round trip.
+func (l *AuditLog) ComputeHash() string {
+	payload, _ := json.Marshal([]interface{}{
+		l.ID.String(),
+		l.Shard,
+		l.Seq,
+		l.PrevHash,
+		l.ActorType,
+		l.ActorID,
+		l.APIKeyID,
+		l.Action,
+		l.TargetType,
+		l.TargetID,
+		string(l.Changes),
+		l.IPAddress,
+		l.RequestID,
+		l.CreatedAt.UTC().Format(time.RFC3339Nano),
+	})
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Value stores the bytes unchanged, even when empty, because they are hashed.
+func (c AuditChanges) Value() (driver.Value, error) {
+	return string(c), nil
+}
+
+func (c *AuditChanges) Scan(value interface{}) error {
+	data, err := jsonbBytes(value)
+	if err != nil {
+		return err
+	}
+	*c = append(AuditChanges(nil), data...)
+	return nil
+}
+
+func (c AuditChanges) MarshalJSON() ([]byte, error) {
+	if len(c) == 0 {
+		return []byte("{}"), nil
+	}
+	return c, nil
+}
+
+func (l *AuditLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
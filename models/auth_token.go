@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type TokenRevocationReason string
+
+const (
+	TokenRevocationLogout         TokenRevocationReason = "logout"
+	TokenRevocationLogoutAll      TokenRevocationReason = "logout_all"
+	TokenRevocationReuseDetected  TokenRevocationReason = "reuse_detected"
+	TokenRevocationSessionRevoked TokenRevocationReason = "session_revoked"
+	TokenRevocationPasswordChange TokenRevocationReason = "password_change"
+)
+
+type RefreshToken struct {
+	ID              uuid.UUID             `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID          uuid.UUID             `json:"user_id" gorm:"type:uuid;not null;index"`
+	FamilyID        uuid.UUID             `json:"family_id" gorm:"type:uuid;not null;index"`
+	ParentID        *uuid.UUID            `json:"parent_id,omitempty" gorm:"type:uuid"`
+	TokenHash       string                `json:"-" gorm:"uniqueIndex;not null"`
+	AccessTokenJTI  string                `json:"-" gorm:"not null"`
+	AccessExpiresAt time.Time             `json:"-" gorm:"not null"`
+	UserAgent       string                `json:"user_agent"`
+	IPAddress       string                `json:"ip_address"`
+	MFAVerified     bool                  `json:"mfa_verified" gorm:"not null;default:false"`
+	ExpiresAt       time.Time             `json:"expires_at" gorm:"not null;index"`
+	UsedAt          *time.Time            `json:"used_at,omitempty"`
+	RevokedAt       *time.Time            `json:"revoked_at,omitempty"`
+	RevokedReason   TokenRevocationReason `json:"revoked_reason,omitempty"`
+	CreatedAt       time.Time             `json:"created_at"`
+	UpdatedAt       time.Time             `json:"updated_at"`
+}
+
+type RevokedToken struct {
+	JTI       string                `json:"jti" gorm:"primary_key"`
+	UserID    uuid.UUID             `json:"user_id" gorm:"type:uuid;not null;index"`
+	Reason    TokenRevocationReason `json:"reason"`
+	ExpiresAt time.Time             `json:"expires_at" gorm:"not null;index"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+func (t *RefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+// Session is a login on one device. Its ID is the family ID shared by every
+// refresh token rotated from that login.
+type Session struct {
+	ID            uuid.UUID             `json:"id" gorm:"type:uuid;primary_key"`
+	UserID        uuid.UUID             `json:"user_id" gorm:"type:uuid;not null;index"`
+	DeviceID      string                `json:"-" gorm:"not null;index"`
+	DeviceName    string                `json:"device_name"`
+	UserAgent     string                `json:"user_agent"`
+	IPAddress     string                `json:"ip_address"`
+	CreatedAt     time.Time             `json:"created_at"`
+	LastSeenAt    time.Time             `json:"last_seen_at" gorm:"not null"`
+	ExpiresAt     time.Time             `json:"expires_at" gorm:"not null;index"`
+	RevokedAt     *time.Time            `json:"revoked_at,omitempty"`
+	RevokedReason TokenRevocationReason `json:"revoked_reason,omitempty"`
+}
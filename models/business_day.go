@@ -0,0 +1,96 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type BusinessDayStatus string
+
+const (
+	BusinessDayStatusClosing BusinessDayStatus = "closing"
+	BusinessDayStatusClosed  BusinessDayStatus = "closed"
+)
+
+type BusinessDayStep string
+
+const (
+	BusinessDayStepLocked              BusinessDayStep = "locked"
+	BusinessDayStepBalancesSnapshotted BusinessDayStep = "balances_snapshotted"
+	BusinessDayStepReportGenerated     BusinessDayStep = "report_generated"
+	BusinessDayStepClosed              BusinessDayStep = "closed"
+)
+
+type BusinessDay struct {
+	ID        uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Date      time.Time         `json:"date" gorm:"type:date;not null;uniqueIndex"`
+	Status    BusinessDayStatus `json:"status" gorm:"not null;index"`
+	Step      BusinessDayStep   `json:"step" gorm:"not null"`
+	StartedAt time.Time         `json:"started_at" gorm:"not null"`
+	ClosedAt  *time.Time        `json:"closed_at,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	UpdatedAt time.Time         `json:"updated_at"`
+
+	Reports      []DailyReport      `json:"reports,omitempty" gorm:"foreignKey:BusinessDate;references:Date"`
+	TrialBalance []TrialBalanceLine `json:"trial_balance,omitempty" gorm:"foreignKey:BusinessDate;references:Date"`
+}
+
+func (b *BusinessDay) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+type BalanceSnapshot struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AccountID    uuid.UUID `json:"account_id" gorm:"type:uuid;not null;uniqueIndex:idx_balance_snapshots_account_date"`
+	BusinessDate time.Time `json:"business_date" gorm:"type:date;not null;uniqueIndex:idx_balance_snapshots_account_date;index"`
+	Currency     string    `json:"currency" gorm:"not null"`
+	Balance      float64   `json:"balance" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+func (b *BalanceSnapshot) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == uuid.Nil {
+		b.ID = uuid.New()
+	}
+	return nil
+}
+
+// CurrentValueDate takes a share lock on business_days so that a close cannot
+// start while the calling transaction is still writing to the ledger.
+func CurrentValueDate(tx *gorm.DB) (time.Time, error) {
+	db := tx.Session(&gorm.Session{NewDB: true})
+
+	if err := db.Exec("LOCK TABLE business_days IN SHARE MODE").Error; err != nil {
+		return time.Time{}, fmt.Errorf("failed to lock business days: %v", err)
+	}
+
+	var lastClosed *time.Time
+	if err := db.Model(&BusinessDay{}).Select("MAX(date)").Scan(&lastClosed).Error; err != nil {
+		return time.Time{}, fmt.Errorf("failed to find last business day: %v", err)
+	}
+
+	return nextValueDate(lastClosed, utils.BusinessDate(time.Now())), nil
+}
+
+// nextValueDate is today, or the day after the last closed business day when
+// that has already been closed. lastClosed comes from a date column and is
+// already midnight UTC; converting it to the bank's zone would move it back a
+// day west of UTC and value-date postings into the closed day.
+func nextValueDate(lastClosed *time.Time, today time.Time) time.Time {
+	if lastClosed == nil {
+		return today
+	}
+
+	year, month, day := lastClosed.Date()
+	if next := time.Date(year, month, day+1, 0, 0, 0, 0, time.UTC); next.After(today) {
+		return next
+	}
+	return today
+}
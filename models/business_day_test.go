@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/azainwork/core-banking-api/utils"
+)
+
+func TestNextValueDate(t *testing.T) {
+	date := func(day int) *time.Time {
+		d := time.Date(2026, 10, day, 0, 0, 0, 0, time.UTC)
+		return &d
+	}
+
+	for _, zone := range []string{"America/New_York", "UTC", "Asia/Jakarta"} {
+		t.Run(zone, func(t *testing.T) {
+			t.Setenv("BANK_TIMEZONE", zone)
+			if utils.BankLocation().String() != zone {
+				t.Skipf("time zone %s is not available", zone)
+			}
+
+			// Shortly after the 17th was closed, still the 17th in every zone.
+			now := time.Date(2026, 10, 17, 23, 30, 0, 0, utils.BankLocation())
+			today := utils.BusinessDate(now)
+
+			tests := []struct {
+				name       string
+				lastClosed *time.Time
+				want       time.Time
+			}{
+				{"nothing closed", nil, *date(17)},
+				{"yesterday closed", date(16), *date(17)},
+				{"older day closed", date(10), *date(17)},
+				{"today closed", date(17), *date(18)},
+				{"closed ahead", date(18), *date(19)},
+			}
+
+			for _, tt := range tests {
+				if got := nextValueDate(tt.lastClosed, today); !got.Equal(tt.want) {
+					t.Errorf("%s: nextValueDate = %s, want %s", tt.name, got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+				}
+			}
+		})
+	}
+}
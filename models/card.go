@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CardStatus string
+
+const (
+	CardStatusActive  CardStatus = "active"
+	CardStatusBlocked CardStatus = "blocked"
+	CardStatusLost    CardStatus = "lost"
+)
+
+type Card struct {
+	ID                  uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Token               string         `json:"token" gorm:"uniqueIndex;not null"`
+	PANHash             string         `json:"-" gorm:"uniqueIndex;not null"`
+	Last4               string         `json:"last4" gorm:"not null"`
+	ExpiryMonth         int            `json:"expiry_month" gorm:"not null"`
+	ExpiryYear          int            `json:"expiry_year" gorm:"not null"`
+	CVVHash             string         `json:"-" gorm:"not null"`
+	CardholderName      string         `json:"cardholder_name" gorm:"not null"`
+	Status              CardStatus     `json:"status" gorm:"not null;default:'active'"`
+	PerTransactionLimit float64        `json:"per_transaction_limit" gorm:"not null;default:0"`
+	DailyLimit          float64        `json:"daily_limit" gorm:"not null;default:0"`
+	AllowOnline         bool           `json:"allow_online" gorm:"default:true"`
+	AllowATM            bool           `json:"allow_atm" gorm:"default:true"`
+	CVVFailures         int            `json:"-" gorm:"not null;default:0"`
+	AccountID           uuid.UUID      `json:"account_id" gorm:"type:uuid;not null;index"`
+	UserID              uuid.UUID      `json:"user_id" gorm:"type:uuid;not null;index"`
+	CreatedAt           time.Time      `json:"created_at"`
+	UpdatedAt           time.Time      `json:"updated_at"`
+	DeletedAt           gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Account Account `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+}
+
+func (c *Card) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+func (c *Card) IsExpired(now time.Time) bool {
+	expiry := time.Date(c.ExpiryYear, time.Month(c.ExpiryMonth)+1, 1, 0, 0, 0, 0, now.Location())
+	return !now.Before(expiry)
+}
@@ -0,0 +1,172 @@
+package models
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type CategoryKind string
+
+const (
+	CategoryKindIncome   CategoryKind = "income"
+	CategoryKindExpense  CategoryKind = "expense"
+	CategoryKindTransfer CategoryKind = "transfer"
+)
+
+type CategoryRuleSource string
+
+const (
+	CategoryRuleSourceSystem  CategoryRuleSource = "system"
+	CategoryRuleSourceUser    CategoryRuleSource = "user"
+	CategoryRuleSourceLearned CategoryRuleSource = "learned"
+)
+
+type CategorizationSource string
+
+const (
+	CategorizationSourceDefault CategorizationSource = "default"
+	CategorizationSourceRule    CategorizationSource = "rule"
+	CategorizationSourceManual  CategorizationSource = "manual"
+)
+
+const (
+	CategoryDirectionCredit = "credit"
+	CategoryDirectionDebit  = "debit"
+)
+
+type Category struct {
+	ID        uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Slug      string       `json:"slug" gorm:"uniqueIndex;not null"`
+	Name      string       `json:"name" gorm:"not null"`
+	Kind      CategoryKind `json:"kind" gorm:"not null"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type CategoryRule struct {
+	ID                    uuid.UUID          `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID                *uuid.UUID         `json:"user_id,omitempty" gorm:"type:uuid;index"`
+	CategoryID            uuid.UUID          `json:"category_id" gorm:"type:uuid;not null"`
+	Source                CategoryRuleSource `json:"source" gorm:"not null"`
+	Priority              int                `json:"priority" gorm:"not null;default:0"`
+	DescriptionPattern    string             `json:"description_pattern,omitempty"`
+	CounterpartyAccountID *uuid.UUID         `json:"counterparty_account_id,omitempty" gorm:"type:uuid"`
+	TransactionType       TransactionType    `json:"transaction_type,omitempty"`
+	Direction             string             `json:"direction,omitempty"`
+	MinAmount             *float64           `json:"min_amount,omitempty"`
+	MaxAmount             *float64           `json:"max_amount,omitempty"`
+	MatchCount            int                `json:"match_count" gorm:"not null;default:0"`
+	CreatedAt             time.Time          `json:"created_at"`
+	UpdatedAt             time.Time          `json:"updated_at"`
+	DeletedAt             gorm.DeletedAt     `json:"-" gorm:"index"`
+
+	Category Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+}
+
+type TransactionCategory struct {
+	ID            uuid.UUID            `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TransactionID uuid.UUID            `json:"transaction_id" gorm:"type:uuid;not null;uniqueIndex:idx_transaction_categories_entry"`
+	AccountID     uuid.UUID            `json:"account_id" gorm:"type:uuid;not null;uniqueIndex:idx_transaction_categories_entry,priority:1"`
+	CategoryID    uuid.UUID            `json:"category_id" gorm:"type:uuid;not null;index"`
+	RuleID        *uuid.UUID           `json:"rule_id,omitempty" gorm:"type:uuid"`
+	Source        CategorizationSource `json:"source" gorm:"not null"`
+	CreatedAt     time.Time            `json:"created_at"`
+	UpdatedAt     time.Time            `json:"updated_at"`
+
+	Category Category `json:"category,omitempty" gorm:"foreignKey:CategoryID"`
+}
+
+var categoryPatterns sync.Map
+
+func (c *Category) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+func (r *CategoryRule) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+func (tc *TransactionCategory) BeforeCreate(tx *gorm.DB) error {
+	if tc.ID == uuid.Nil {
+		tc.ID = uuid.New()
+	}
+	return nil
+}
+
+func (r *CategoryRule) Matches(t *Transaction, accountID uuid.UUID) bool {
+	amount := t.SignedAmountFor(accountID)
+
+	if r.TransactionType != "" && r.TransactionType != t.Type {
+		return false
+	}
+	if r.Direction == CategoryDirectionCredit && amount < 0 || r.Direction == CategoryDirectionDebit && amount > 0 {
+		return false
+	}
+	if r.MinAmount != nil && t.Amount < *r.MinAmount {
+		return false
+	}
+	if r.MaxAmount != nil && t.Amount > *r.MaxAmount {
+		return false
+	}
+	if r.CounterpartyAccountID != nil {
+		counterparty := t.CounterpartyFor(accountID)
+		if counterparty == nil || *counterparty != *r.CounterpartyAccountID {
+			return false
+		}
+	}
+	if r.DescriptionPattern != "" {
+		pattern, err := compileCategoryPattern(r.DescriptionPattern)
+		if err != nil || !pattern.MatchString(t.Description) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func DefaultCategorySlug(t *Transaction, accountID uuid.UUID) string {
+	switch t.Type {
+	case TransactionTypeTransfer:
+		if t.SignedAmountFor(accountID) > 0 {
+			return "transfers_in"
+		}
+		return "transfers_out"
+	case TransactionTypeDeposit:
+		return "deposits"
+	case TransactionTypeWithdraw:
+		return "cash_withdrawal"
+	case TransactionTypeCardRefund:
+		return "refunds"
+	case TransactionTypeFee:
+		return "fees"
+	case TransactionTypeInterest:
+		return "interest"
+	case TransactionTypeOpeningBalance:
+		return "opening_balance"
+	}
+	return "uncategorized"
+}
+
+func compileCategoryPattern(pattern string) (*regexp.Regexp, error) {
+	if compiled, ok := categoryPatterns.Load(pattern); ok {
+		return compiled.(*regexp.Regexp), nil
+	}
+
+	compiled, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryPatterns.Store(pattern, compiled)
+	return compiled, nil
+}
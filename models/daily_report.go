@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type DailyReport struct {
+	ID                uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BusinessDate      time.Time `json:"business_date" gorm:"type:date;not null;uniqueIndex:idx_daily_reports_date_currency"`
+	Currency          string    `json:"currency" gorm:"not null;uniqueIndex:idx_daily_reports_date_currency"`
+	AccountCount      int       `json:"account_count" gorm:"not null;default:0"`
+	OpeningBalance    float64   `json:"opening_balance" gorm:"not null;default:0"`
+	ClosingBalance    float64   `json:"closing_balance" gorm:"not null;default:0"`
+	TransactionCount  int       `json:"transaction_count" gorm:"not null;default:0"`
+	TotalDeposits     float64   `json:"total_deposits" gorm:"not null;default:0"`
+	TotalWithdrawals  float64   `json:"total_withdrawals" gorm:"not null;default:0"`
+	TotalTransfers    float64   `json:"total_transfers" gorm:"not null;default:0"`
+	TotalCardPayments float64   `json:"total_card_payments" gorm:"not null;default:0"`
+	TotalCardRefunds  float64   `json:"total_card_refunds" gorm:"not null;default:0"`
+	TotalFees         float64   `json:"total_fees" gorm:"not null;default:0"`
+	TotalInterest     float64   `json:"total_interest" gorm:"not null;default:0"`
+	TrialDebits       float64   `json:"trial_debits" gorm:"not null;default:0"`
+	TrialCredits      float64   `json:"trial_credits" gorm:"not null;default:0"`
+	IsBalanced        bool      `json:"is_balanced" gorm:"not null"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+func (d *DailyReport) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
+
+type TrialBalanceLine struct {
+	ID           uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	BusinessDate time.Time `json:"business_date" gorm:"type:date;not null;index"`
+	Currency     string    `json:"currency" gorm:"not null"`
+	Position     int       `json:"position" gorm:"not null"`
+	Ledger       string    `json:"ledger" gorm:"not null"`
+	Debit        float64   `json:"debit" gorm:"not null;default:0"`
+	Credit       float64   `json:"credit" gorm:"not null;default:0"`
+}
+
+func (t *TrialBalanceLine) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type HoldStatus string
+
+const (
+	HoldStatusActive   HoldStatus = "active"
+	HoldStatusCaptured HoldStatus = "captured"
+	HoldStatusReleased HoldStatus = "released"
+	HoldStatusExpired  HoldStatus = "expired"
+)
+
+type CardChannel string
+
+const (
+	CardChannelPOS    CardChannel = "pos"
+	CardChannelOnline CardChannel = "online"
+	CardChannelATM    CardChannel = "atm"
+)
+
+type Hold struct {
+	ID                uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AccountID         uuid.UUID      `json:"account_id" gorm:"type:uuid;not null;index"`
+	CardID            *uuid.UUID     `json:"card_id,omitempty" gorm:"type:uuid;index;uniqueIndex:idx_holds_card_network_reference,where:network_reference <> ''"`
+	Amount            float64        `json:"amount" gorm:"not null"`
+	AuthorizedAmount  float64        `json:"authorized_amount" gorm:"not null;default:0"`
+	CapturedAmount    float64        `json:"captured_amount" gorm:"not null;default:0"`
+	Currency          string         `json:"currency" gorm:"default:'USD'"`
+	Status            HoldStatus     `json:"status" gorm:"not null;default:'active';index"`
+	AuthorizationCode string         `json:"authorization_code" gorm:"not null"`
+	MerchantName      string         `json:"merchant_name"`
+	Channel           CardChannel    `json:"channel"`
+	NetworkReference  string         `json:"network_reference,omitempty" gorm:"index"`
+	STAN              string         `json:"stan,omitempty"`
+	ReversalSTAN      string         `json:"reversal_stan,omitempty"`
+	ExpiresAt         time.Time      `json:"expires_at" gorm:"not null"`
+	TransactionID     *uuid.UUID     `json:"transaction_id,omitempty" gorm:"type:uuid"`
+	CreatedAt         time.Time      `json:"created_at"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Account Account `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+	Card    *Card   `json:"card,omitempty" gorm:"foreignKey:CardID"`
+}
+
+func (h *Hold) BeforeCreate(tx *gorm.DB) error {
+	if h.ID == uuid.Nil {
+		h.ID = uuid.New()
+	}
+	return nil
+}
+
+// NetworkReversal works out how much a network reversal that leaves remaining
+// outstanding takes off the hold. Reversals only ever lower the outstanding
+// amount, so a retransmission, or one carrying a STAN that was already
+// applied, reverses nothing.
+func (h *Hold) NetworkReversal(stan string, remaining float64) float64 {
+	if stan != "" && stan == h.ReversalSTAN {
+		return 0
+	}
+
+	var outstanding float64
+	switch h.Status {
+	case HoldStatusActive:
+		outstanding = h.Amount
+	case HoldStatusCaptured:
+		outstanding = h.CapturedAmount
+	}
+
+	amount := math.Round((outstanding-remaining)*100) / 100
+	if amount <= 0 {
+		return 0
+	}
+	return amount
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type PaymentFileStatus string
+
+const (
+	PaymentFileStatusAwaitingConfirmation PaymentFileStatus = "awaiting_confirmation"
+	PaymentFileStatusProcessing           PaymentFileStatus = "processing"
+	PaymentFileStatusAccepted             PaymentFileStatus = "accepted"
+	PaymentFileStatusPartiallyAccepted    PaymentFileStatus = "partially_accepted"
+	PaymentFileStatusRejected             PaymentFileStatus = "rejected"
+	PaymentFileStatusCancelled            PaymentFileStatus = "cancelled"
+)
+
+type PaymentFile struct {
+	ID                   uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AccountID            uuid.UUID         `json:"account_id" gorm:"type:uuid;not null;uniqueIndex:idx_payment_files_account_message"`
+	UserID               uuid.UUID         `json:"user_id" gorm:"type:uuid;not null;index"`
+	MessageID            string            `json:"message_id" gorm:"not null;uniqueIndex:idx_payment_files_account_message"`
+	Status               PaymentFileStatus `json:"status" gorm:"not null"`
+	NumberOfTransactions int               `json:"number_of_transactions" gorm:"not null"`
+	ControlSum           float64           `json:"control_sum" gorm:"not null"`
+	AcceptedCount        int               `json:"accepted_count" gorm:"not null;default:0"`
+	RejectedCount        int               `json:"rejected_count" gorm:"not null;default:0"`
+	StatusReport         string            `json:"-" gorm:"type:text"`
+	Document             string            `json:"-" gorm:"type:text"`
+	CreatedAt            time.Time         `json:"created_at"`
+	UpdatedAt            time.Time         `json:"updated_at"`
+	DeletedAt            gorm.DeletedAt    `json:"-" gorm:"index"`
+}
+
+func (p *PaymentFile) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+type PaymentFileItemStatus string
+
+const (
+	PaymentFileItemStatusPending  PaymentFileItemStatus = "pending"
+	PaymentFileItemStatusAccepted PaymentFileItemStatus = "accepted"
+	PaymentFileItemStatusRejected PaymentFileItemStatus = "rejected"
+)
+
+// PaymentFileItem records the outcome of one credit transfer in a payment
+// file, so that processing interrupted by a restart resumes where it stopped.
+type PaymentFileItem struct {
+	ID            uuid.UUID             `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	PaymentFileID uuid.UUID             `json:"payment_file_id" gorm:"type:uuid;not null;uniqueIndex:idx_payment_file_items_position"`
+	Position      int                   `json:"position" gorm:"not null;uniqueIndex:idx_payment_file_items_position"`
+	EndToEndID    string                `json:"end_to_end_id"`
+	Status        PaymentFileItemStatus `json:"status" gorm:"not null;default:'pending'"`
+	ReasonCode    string                `json:"reason_code,omitempty"`
+	ReasonInfo    string                `json:"reason_info,omitempty"`
+	TransactionID *uuid.UUID            `json:"transaction_id,omitempty" gorm:"type:uuid"`
+	CreatedAt     time.Time             `json:"created_at"`
+	UpdatedAt     time.Time             `json:"updated_at"`
+}
+
+func (i *PaymentFileItem) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/azainwork/core-banking-api/utils"
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("pii", PIISerializer{})
+}
+
+// PIISerializer encrypts string columns tagged `gorm:"serializer:pii"` on
+// write and decrypts them on read, so the Go fields keep holding plaintext.
+// Updates through a map bypass serializers; pass values from EncryptPII there.
+type PIISerializer struct{}
+
+func (PIISerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return fmt.Errorf("failed to scan PII value: %#v", dbValue)
+	}
+
+	plaintext, err := utils.DecryptPII(value)
+	if err != nil {
+		return err
+	}
+
+	field.ReflectValueOf(ctx, dst).SetString(plaintext)
+	return nil
+}
+
+func (PIISerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	plaintext, ok := fieldValue.(string)
+	if !ok {
+		return nil, fmt.Errorf("PII fields must be strings, got %T", fieldValue)
+	}
+	return utils.EncryptPII(plaintext)
+}
+
+// EmailIndex is the blind index used to look users up by email. Addresses
+// are compared case-insensitively.
+func EmailIndex(email string) (string, error) {
+	return utils.BlindIndex("user_email", strings.ToLower(strings.TrimSpace(email)))
+}
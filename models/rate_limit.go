@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+type RateLimitBucket struct {
+	Key       string    `json:"key" gorm:"primary_key"`
+	Tokens    float64   `json:"tokens" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null;index;autoUpdateTime:false"`
+}
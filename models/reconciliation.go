@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ReconciliationRunStatus string
+
+const (
+	ReconciliationRunStatusRunning   ReconciliationRunStatus = "running"
+	ReconciliationRunStatusCompleted ReconciliationRunStatus = "completed"
+	ReconciliationRunStatusFailed    ReconciliationRunStatus = "failed"
+)
+
+type DiscrepancyType string
+
+const (
+	DiscrepancyTypeBalanceMismatch DiscrepancyType = "balance_mismatch"
+	DiscrepancyTypeChainBreak      DiscrepancyType = "chain_break"
+	DiscrepancyTypeAmountMismatch  DiscrepancyType = "amount_mismatch"
+)
+
+type ReconciliationRun struct {
+	ID                 uuid.UUID               `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Status             ReconciliationRunStatus `json:"status" gorm:"not null;index"`
+	TriggeredBy        string                  `json:"triggered_by" gorm:"not null"`
+	FreezeMismatched   bool                    `json:"freeze_mismatched" gorm:"not null;default:false"`
+	AccountsChecked    int                     `json:"accounts_checked" gorm:"not null;default:0"`
+	AccountsMismatched int                     `json:"accounts_mismatched" gorm:"not null;default:0"`
+	AccountsFrozen     int                     `json:"accounts_frozen" gorm:"not null;default:0"`
+	DiscrepancyCount   int                     `json:"discrepancy_count" gorm:"not null;default:0"`
+	Error              string                  `json:"error,omitempty" gorm:"type:text"`
+	StartedAt          time.Time               `json:"started_at" gorm:"not null"`
+	CompletedAt        *time.Time              `json:"completed_at,omitempty"`
+	CreatedAt          time.Time               `json:"created_at"`
+	UpdatedAt          time.Time               `json:"updated_at"`
+
+	Discrepancies []ReconciliationDiscrepancy `json:"discrepancies,omitempty" gorm:"foreignKey:RunID"`
+}
+
+func (r *ReconciliationRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+type ReconciliationDiscrepancy struct {
+	ID            uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	RunID         uuid.UUID       `json:"run_id" gorm:"type:uuid;not null;index"`
+	AccountID     uuid.UUID       `json:"account_id" gorm:"type:uuid;not null;index"`
+	Type          DiscrepancyType `json:"type" gorm:"not null"`
+	TransactionID *uuid.UUID      `json:"transaction_id,omitempty" gorm:"type:uuid;index"`
+	Expected      float64         `json:"expected"`
+	Actual        float64         `json:"actual"`
+	Difference    float64         `json:"difference"`
+	CreatedAt     time.Time       `json:"created_at"`
+}
+
+func (d *ReconciliationDiscrepancy) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package models
+
+type UserRole string
+
+const (
+	UserRoleCustomer UserRole = "customer"
+	UserRoleTeller   UserRole = "teller"
+	UserRoleAdmin    UserRole = "admin"
+)
+
+type Permission string
+
+const (
+	PermissionUsersRead      Permission = "users:read"
+	PermissionUsersManage    Permission = "users:manage"
+	PermissionAccountsRead   Permission = "accounts:read"
+	PermissionAccountsManage Permission = "accounts:manage"
+	PermissionCashDeposit    Permission = "transactions:cash_deposit"
+	PermissionReconciliation Permission = "reconciliation:manage"
+	PermissionEndOfDay       Permission = "eod:manage"
+	PermissionAuditRead      Permission = "audit:read"
+)
+
+var RolePermissions = map[UserRole][]Permission{
+	UserRoleCustomer: {},
+	UserRoleTeller: {
+		PermissionUsersRead,
+		PermissionAccountsRead,
+		PermissionCashDeposit,
+	},
+	UserRoleAdmin: {
+		PermissionUsersRead,
+		PermissionUsersManage,
+		PermissionAccountsRead,
+		PermissionAccountsManage,
+		PermissionCashDeposit,
+		PermissionReconciliation,
+		PermissionEndOfDay,
+		PermissionAuditRead,
+	},
+}
+
+func (r UserRole) IsValid() bool {
+	_, ok := RolePermissions[r]
+	return ok
+}
+
+func (r UserRole) Permissions() []Permission {
+	return RolePermissions[r]
+}
+
+func (r UserRole) HasPermission(permission Permission) bool {
+	for _, granted := range RolePermissions[r] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// RequiresMFA reports whether the role holds any permissions, in which case
+// they are only granted to sessions that completed a second factor.
+func (r UserRole) RequiresMFA() bool {
+	return len(RolePermissions[r]) > 0
+}
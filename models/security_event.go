@@ -0,0 +1,46 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type SecurityEventType string
+
+const (
+	SecurityEventLoginLockout  SecurityEventType = "login_lockout"
+	SecurityEventLoginUnlock   SecurityEventType = "login_unlock"
+	SecurityEventStepUpLockout SecurityEventType = "step_up_lockout"
+)
+
+type SecurityEvent struct {
+	ID        uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Type      SecurityEventType `json:"type" gorm:"not null;index"`
+	UserID    *uuid.UUID        `json:"user_id,omitempty" gorm:"type:uuid;index"`
+	ActorID   *uuid.UUID        `json:"actor_id,omitempty" gorm:"type:uuid"`
+	Email     string            `json:"email,omitempty" gorm:"serializer:pii"`
+	IPAddress string            `json:"ip_address,omitempty"`
+	Details   string            `json:"details"`
+	CreatedAt time.Time         `json:"created_at" gorm:"index"`
+}
+
+// LoginThrottle tracks consecutive failed logins for a key such as
+// "email:"+EmailIndex(email) or "ip:10.0.0.1", so emails are only stored as
+// their blind index. Keys are independent of whether the email exists so that
+// throttling does not reveal registered accounts.
+type LoginThrottle struct {
+	Key          string     `json:"key" gorm:"primary_key"`
+	Failures     int        `json:"failures" gorm:"not null;default:0"`
+	LastFailedAt time.Time  `json:"last_failed_at" gorm:"not null"`
+	LockedUntil  *time.Time `json:"locked_until,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at" gorm:"index"`
+}
+
+func (e *SecurityEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrStatementImmutable = errors.New("statements are immutable")
+
+type Statement struct {
+	ID             uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	AccountID      uuid.UUID `json:"account_id" gorm:"type:uuid;not null;uniqueIndex:idx_statements_account_period"`
+	PeriodStart    time.Time `json:"period_start" gorm:"type:date;not null;uniqueIndex:idx_statements_account_period"`
+	PeriodEnd      time.Time `json:"period_end" gorm:"type:date;not null"`
+	Currency       string    `json:"currency" gorm:"default:'USD'"`
+	OpeningBalance float64   `json:"opening_balance" gorm:"not null"`
+	ClosingBalance float64   `json:"closing_balance" gorm:"not null"`
+	TotalCredits   float64   `json:"total_credits" gorm:"not null;default:0"`
+	TotalDebits    float64   `json:"total_debits" gorm:"not null;default:0"`
+	TotalInterest  float64   `json:"total_interest" gorm:"not null;default:0"`
+	TotalFees      float64   `json:"total_fees" gorm:"not null;default:0"`
+	LineCount      int       `json:"line_count" gorm:"not null;default:0"`
+	CreatedAt      time.Time `json:"created_at"`
+
+	Account    Account              `json:"account,omitempty" gorm:"foreignKey:AccountID"`
+	Lines      []StatementLine      `json:"lines,omitempty" gorm:"foreignKey:StatementID"`
+	TypeTotals []StatementTypeTotal `json:"type_totals,omitempty" gorm:"foreignKey:StatementID"`
+}
+
+type StatementLine struct {
+	ID             uuid.UUID       `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	StatementID    uuid.UUID       `json:"statement_id" gorm:"type:uuid;not null;index"`
+	Position       int             `json:"position" gorm:"not null"`
+	TransactionID  uuid.UUID       `json:"transaction_id" gorm:"type:uuid;not null"`
+	Reference      string          `json:"reference" gorm:"not null"`
+	PostedAt       time.Time       `json:"posted_at" gorm:"not null"`
+	Type           TransactionType `json:"type" gorm:"not null"`
+	Description    string          `json:"description"`
+	Amount         float64         `json:"amount" gorm:"not null"`
+	RunningBalance float64         `json:"running_balance" gorm:"not null"`
+}
+
+type StatementTypeTotal struct {
+	ID          uuid.UUID       `json:"-" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	StatementID uuid.UUID       `json:"-" gorm:"type:uuid;not null;index"`
+	Type        TransactionType `json:"type" gorm:"not null"`
+	Count       int             `json:"count" gorm:"not null"`
+	Credits     float64         `json:"credits" gorm:"not null"`
+	Debits      float64         `json:"debits" gorm:"not null"`
+}
+
+func (s *Statement) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+func (s *Statement) BeforeUpdate(tx *gorm.DB) error {
+	return ErrStatementImmutable
+}
+
+func (s *Statement) BeforeDelete(tx *gorm.DB) error {
+	return ErrStatementImmutable
+}
+
+func (l *StatementLine) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+func (l *StatementLine) BeforeUpdate(tx *gorm.DB) error {
+	return ErrStatementImmutable
+}
+
+func (l *StatementLine) BeforeDelete(tx *gorm.DB) error {
+	return ErrStatementImmutable
+}
+
+func (t *StatementTypeTotal) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
+
+func (t *StatementTypeTotal) BeforeUpdate(tx *gorm.DB) error {
+	return ErrStatementImmutable
+}
+
+func (t *StatementTypeTotal) BeforeDelete(tx *gorm.DB) error {
+	return ErrStatementImmutable
+}
@@ -10,11 +10,26 @@ import (
 type TransactionType string
 
 const (
-	TransactionTypeDeposit  TransactionType = "deposit"
-	TransactionTypeWithdraw TransactionType = "withdraw"
-	TransactionTypeTransfer TransactionType = "transfer"
+	TransactionTypeDeposit     TransactionType = "deposit"
+	TransactionTypeWithdraw    TransactionType = "withdraw"
+	TransactionTypeTransfer    TransactionType = "transfer"
+	TransactionTypeCardPayment TransactionType = "card_payment"
+	TransactionTypeCardRefund  TransactionType = "card_refund"
+	TransactionTypeFee         TransactionType = "fee"
+	TransactionTypeInterest    TransactionType = "interest"
+	// TransactionTypeOpeningBalance records the initial balance an account is
+	// opened with, or the balance a legacy account already held when the
+	// ledger started tracking it. It is left out of deposit totals.
+	TransactionTypeOpeningBalance TransactionType = "opening_balance"
 )
 
+var CreditTransactionTypes = []TransactionType{
+	TransactionTypeDeposit,
+	TransactionTypeCardRefund,
+	TransactionTypeInterest,
+	TransactionTypeOpeningBalance,
+}
+
 type TransactionStatus string
 
 const (
@@ -25,7 +40,7 @@ const (
 )
 
 type Transaction struct {
-	ID              uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ID              uuid.UUID         `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid();index:idx_transactions_account_history,priority:3;index:idx_transactions_to_account_history,priority:3"`
 	TransactionID   string            `json:"transaction_id" gorm:"uniqueIndex;not null"`
 	Type            TransactionType   `json:"type" gorm:"not null"`
 	Amount          float64           `json:"amount" gorm:"not null"`
@@ -33,14 +48,17 @@ type Transaction struct {
 	Status          TransactionStatus `json:"status" gorm:"default:'pending'"`
 	Description     string            `json:"description"`
 	
-	AccountID       uuid.UUID         `json:"account_id" gorm:"type:uuid;not null"`
+	AccountID       uuid.UUID         `json:"account_id" gorm:"type:uuid;not null;index:idx_transactions_account_history,priority:1"`
 	
-	ToAccountID     *uuid.UUID        `json:"to_account_id,omitempty" gorm:"type:uuid"`
+	ToAccountID     *uuid.UUID        `json:"to_account_id,omitempty" gorm:"type:uuid;index:idx_transactions_to_account_history,priority:1"`
 	
 	BalanceBefore   float64           `json:"balance_before"`
 	BalanceAfter    float64           `json:"balance_after"`
+	ValueDate       time.Time         `json:"value_date" gorm:"type:date;index"`
+	Metadata        Metadata          `json:"metadata" gorm:"type:jsonb;not null;default:'{}';index:idx_transactions_metadata,type:gin"`
+	Tags            Tags              `json:"tags" gorm:"type:jsonb;not null;default:'[]';index:idx_transactions_tags,type:gin"`
 	
-	CreatedAt       time.Time         `json:"created_at"`
+	CreatedAt       time.Time         `json:"created_at" gorm:"index:idx_transactions_account_history,priority:2;index:idx_transactions_to_account_history,priority:2"`
 	UpdatedAt       time.Time         `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt    `json:"-" gorm:"index"`
 
@@ -52,5 +70,33 @@ func (t *Transaction) BeforeCreate(tx *gorm.DB) error {
 	if t.ID == uuid.Nil {
 		t.ID = uuid.New()
 	}
+	if t.ValueDate.IsZero() {
+		valueDate, err := CurrentValueDate(tx)
+		if err != nil {
+			return err
+		}
+		t.ValueDate = valueDate
+	}
 	return nil
-} 
\ No newline at end of file
+}
+
+func (t *Transaction) SignedAmountFor(accountID uuid.UUID) float64 {
+	if t.ToAccountID != nil && *t.ToAccountID == accountID {
+		return t.Amount
+	}
+
+	for _, creditType := range CreditTransactionTypes {
+		if t.Type == creditType {
+			return t.Amount
+		}
+	}
+
+	return -t.Amount
+}
+
+func (t *Transaction) CounterpartyFor(accountID uuid.UUID) *uuid.UUID {
+	if t.ToAccountID != nil && *t.ToAccountID == accountID {
+		return &t.AccountID
+	}
+	return t.ToAccountID
+}
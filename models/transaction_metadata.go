@@ -0,0 +1,74 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+type Metadata map[string]string
+
+type Tags []string
+
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return "{}", nil
+	}
+
+	data, err := json.Marshal(map[string]string(m))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (m *Metadata) Scan(value interface{}) error {
+	data, err := jsonbBytes(value)
+	if err != nil {
+		return err
+	}
+
+	*m = Metadata{}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, m)
+}
+
+func (t Tags) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+
+	data, err := json.Marshal([]string(t))
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+func (t *Tags) Scan(value interface{}) error {
+	data, err := jsonbBytes(value)
+	if err != nil {
+		return err
+	}
+
+	*t = Tags{}
+	if len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, t)
+}
+
+func jsonbBytes(value interface{}) ([]byte, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return nil, fmt.Errorf("unsupported jsonb value type %T", value)
+	}
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const TransferChallengeMaxAttempts = 5
+
+type StepUpMethod string
+
+const (
+	StepUpMethodPIN  StepUpMethod = "pin"
+	StepUpMethodTOTP StepUpMethod = "totp"
+	StepUpMethodOTP  StepUpMethod = "otp"
+)
+
+func (m StepUpMethod) IsValid() bool {
+	switch m {
+	case StepUpMethodPIN, StepUpMethodTOTP, StepUpMethodOTP:
+		return true
+	}
+	return false
+}
+
+type StepUpReason string
+
+const (
+	StepUpReasonAmountThreshold StepUpReason = "amount_threshold"
+	StepUpReasonNewBeneficiary  StepUpReason = "new_beneficiary"
+	StepUpReasonUnusualAmount   StepUpReason = "unusual_amount"
+	StepUpReasonVelocity        StepUpReason = "velocity"
+)
+
+// TransferChallenge holds back a pending transfer, or a whole payment file,
+// until the user confirms it with a second factor. It is executed by the
+// verify call and is cancelled once the challenge expires or runs out of
+// attempts.
+type TransferChallenge struct {
+	ID            uuid.UUID    `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TransactionID *uuid.UUID   `json:"transaction_id,omitempty" gorm:"type:uuid;uniqueIndex"`
+	PaymentFileID *uuid.UUID   `json:"payment_file_id,omitempty" gorm:"type:uuid;uniqueIndex"`
+	UserID        uuid.UUID    `json:"user_id" gorm:"type:uuid;not null;index"`
+	Method        StepUpMethod `json:"method" gorm:"not null"`
+	Reasons       StringList   `json:"reasons" gorm:"type:jsonb;not null;default:'[]'"`
+	CodeHash      string       `json:"-"`
+	Attempts      int          `json:"attempts" gorm:"not null;default:0"`
+	IPAddress     string       `json:"ip_address"`
+	ExpiresAt     time.Time    `json:"expires_at" gorm:"not null;index"`
+	VerifiedAt    *time.Time   `json:"verified_at,omitempty"`
+	CreatedAt     time.Time    `json:"created_at"`
+
+	Transaction Transaction `json:"-" gorm:"foreignKey:TransactionID"`
+}
+
+func (c *TransferChallenge) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
@@ -9,12 +9,28 @@ import (
 
 type User struct {
 	ID        uuid.UUID `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Email     string    `json:"email" gorm:"uniqueIndex;not null"`
+	Email     string    `json:"email" gorm:"serializer:pii;not null"`
 	Password  string    `json:"-" gorm:"not null"`
-	FirstName string    `json:"first_name" gorm:"not null"`
-	LastName  string    `json:"last_name" gorm:"not null"`
-	Phone     string    `json:"phone"`
+	FirstName string    `json:"first_name" gorm:"serializer:pii;not null"`
+	LastName  string    `json:"last_name" gorm:"serializer:pii;not null"`
+	Phone     string    `json:"phone" gorm:"serializer:pii"`
+
+	EmailIndex string `json:"-" gorm:"uniqueIndex"`
+	Role      UserRole  `json:"role" gorm:"not null;default:'customer'"`
 	IsActive  bool      `json:"is_active" gorm:"default:true"`
+
+	EmailVerifiedAt   *time.Time `json:"email_verified_at,omitempty"`
+	PasswordChangedAt *time.Time `json:"password_changed_at,omitempty"`
+
+	MFAEnabled       bool       `json:"mfa_enabled" gorm:"not null;default:false"`
+	MFASecret        string     `json:"-"`
+	MFAPendingSecret string     `json:"-"`
+	MFALastUsedStep  int64      `json:"-" gorm:"not null;default:0"`
+	MFAEnabledAt     *time.Time `json:"mfa_enabled_at,omitempty"`
+
+	TransactionPINHash  string     `json:"-"`
+	TransactionPINSetAt *time.Time `json:"transaction_pin_set_at,omitempty"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
@@ -22,9 +38,29 @@ type User struct {
 	Accounts []Account `json:"accounts,omitempty" gorm:"foreignKey:UserID"`
 }
 
+// UserPIIColumns are the encrypted columns plus the blind index derived from
+// them, for updates that rewrite the ciphertexts.
+var UserPIIColumns = []string{"email", "email_index", "first_name", "last_name", "phone"}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
 func (u *User) BeforeCreate(tx *gorm.DB) error {
 	if u.ID == uuid.Nil {
 		u.ID = uuid.New()
 	}
 	return nil
+}
+
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.Email == "" {
+		return nil
+	}
+	index, err := EmailIndex(u.Email)
+	if err != nil {
+		return err
+	}
+	u.EmailIndex = index
+	return nil
 } 
\ No newline at end of file
@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserTokenPurpose string
+
+const (
+	UserTokenPasswordReset     UserTokenPurpose = "password_reset"
+	UserTokenEmailVerification UserTokenPurpose = "email_verification"
+	UserTokenEmailChange       UserTokenPurpose = "email_change"
+)
+
+type UserToken struct {
+	ID        uuid.UUID        `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID        `json:"user_id" gorm:"type:uuid;not null;index"`
+	Purpose   UserTokenPurpose `json:"purpose" gorm:"not null;index"`
+	TokenHash string           `json:"-" gorm:"uniqueIndex;not null"`
+	Email     string           `json:"email" gorm:"serializer:pii;not null"`
+	ExpiresAt time.Time        `json:"expires_at" gorm:"not null;index"`
+	UsedAt    *time.Time       `json:"used_at,omitempty"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+func (t *UserToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
@@ -1,8 +1,11 @@
 package routes
 
 import (
+	"time"
+
 	"github.com/azainwork/core-banking-api/controllers"
 	"github.com/azainwork/core-banking-api/middleware"
+	"github.com/azainwork/core-banking-api/models"
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
@@ -11,6 +14,24 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 	authController := controllers.NewAuthController(db)
 	accountController := controllers.NewAccountController(db)
 	transactionController := controllers.NewTransactionController(db)
+	cardController := controllers.NewCardController(db)
+	statementController := controllers.NewStatementController(db)
+	paymentFileController := controllers.NewPaymentFileController(db)
+	reconciliationController := controllers.NewReconciliationController(db)
+	endOfDayController := controllers.NewEndOfDayController(db)
+	categoryController := controllers.NewCategoryController(db)
+	adminController := controllers.NewAdminController(db)
+	apiKeyController := controllers.NewAPIKeyController(db)
+	sessionController := controllers.NewSessionController(db)
+	auditController := controllers.NewAuditController(db)
+
+	rateLimitStore := middleware.NewRateLimitStore(db)
+	authLimit := middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicyFromEnv("auth", 10, time.Minute))
+	apiLimit := middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicyFromEnv("api", 300, time.Minute))
+	clientLimit := middleware.RateLimitByIP(rateLimitStore, middleware.RateLimitPolicyFromEnv("client", 1200, time.Minute))
+	cardNetworkLimit := middleware.RateLimit(rateLimitStore, middleware.RateLimitPolicyFromEnv("card_network", 3000, time.Minute))
+
+	router.GET("/.well-known/jwks.json", authController.JWKS)
 
 	api := router.Group("/api/v1")
 
@@ -22,32 +43,146 @@ func SetupRoutes(router *gin.Engine, db *gorm.DB) {
 	})
 
 	public := api.Group("/auth")
+	public.Use(authLimit)
 	{
 		public.POST("/register", authController.Register)
 		public.POST("/login", authController.Login)
+		public.POST("/login/mfa", authController.LoginMFA)
+		public.POST("/refresh", authController.Refresh)
+		public.POST("/password/forgot", authController.ForgotPassword)
+		public.POST("/password/reset", authController.ResetPassword)
+		public.GET("/password/policy", authController.GetPasswordPolicy)
+		public.POST("/email/verify", authController.VerifyEmail)
+		public.POST("/email/change/confirm", authController.ConfirmEmailChange)
 	}
 
 	protected := api.Group("/")
-	protected.Use(middleware.AuthMiddleware())
+	protected.Use(clientLimit, middleware.AuthMiddleware(db), apiLimit)
 	{
-		protected.GET("/profile", authController.GetProfile)
+		protected.GET("/profile", middleware.RequireScope(models.APIScopeAccountsRead), authController.GetProfile)
+		protected.POST("/profile/password", middleware.RequireUserSession(), authLimit, authController.ChangePassword)
+
+		session := protected.Group("/auth")
+		session.Use(middleware.RequireUserSession(), authLimit)
+		{
+			session.POST("/logout", authController.Logout)
+			session.POST("/logout-all", authController.LogoutAll)
+			session.POST("/mfa/enroll", authController.EnrollMFA)
+			session.POST("/mfa/confirm", authController.ConfirmMFA)
+			session.POST("/mfa/disable", authController.DisableMFA)
+			session.POST("/mfa/recovery-codes", authController.RegenerateRecoveryCodes)
+			session.PUT("/transaction-pin", authController.SetTransactionPIN)
+			session.POST("/email/verification", authController.ResendEmailVerification)
+		}
+
+		sessions := protected.Group("/sessions")
+		sessions.Use(middleware.RequireUserSession())
+		{
+			sessions.GET("/", sessionController.GetSessions)
+			sessions.DELETE("/:id", sessionController.RevokeSession)
+		}
+
+		apiKeys := protected.Group("/api-keys")
+		apiKeys.Use(middleware.RequireUserSession())
+		{
+			apiKeys.POST("/", apiKeyController.CreateAPIKey)
+			apiKeys.GET("/", apiKeyController.GetAPIKeys)
+			apiKeys.DELETE("/:id", apiKeyController.RevokeAPIKey)
+			apiKeys.POST("/:id/rotate", apiKeyController.RotateAPIKey)
+		}
+
+		accountsRead := middleware.RequireScope(models.APIScopeAccountsRead)
+		accountsWrite := middleware.RequireScope(models.APIScopeAccountsWrite)
+		transactionsRead := middleware.RequireScope(models.APIScopeTransactionsRead)
+		transactionsWrite := middleware.RequireScope(models.APIScopeTransactionsWrite)
+		transfersWrite := middleware.RequireScope(models.APIScopeTransfersWrite)
+		cardsRead := middleware.RequireScope(models.APIScopeCardsRead)
+		cardsWrite := middleware.RequireScope(models.APIScopeCardsWrite)
 
 		accounts := protected.Group("/accounts")
 		{
-			accounts.POST("/", accountController.CreateAccount)
-			accounts.GET("/", accountController.GetAccounts)
-			accounts.GET("/:id", accountController.GetAccount)
-			accounts.GET("/:id/balance", accountController.GetAccountBalance)
+			accounts.POST("/", accountsWrite, middleware.RequireVerifiedEmail(), accountController.CreateAccount)
+			accounts.GET("/", accountsRead, accountController.GetAccounts)
+			accounts.GET("/:id", accountsRead, accountController.GetAccount)
+			accounts.GET("/:id/balance", accountsRead, accountController.GetAccountBalance)
+			accounts.GET("/:id/balance/daily", accountsRead, accountController.GetDailyBalances)
+			accounts.POST("/:id/cards", cardsWrite, middleware.RequireVerifiedEmail(), cardController.IssueCard)
+			accounts.POST("/:id/statements", accountsWrite, statementController.GenerateStatement)
+			accounts.GET("/:id/statements", accountsRead, statementController.GetStatements)
+			accounts.GET("/:id/statements/:statementId", accountsRead, statementController.DownloadStatement)
+			accounts.POST("/:id/payment-files", transfersWrite, middleware.RequireVerifiedEmail(), paymentFileController.UploadPaymentFile)
+			accounts.POST("/:id/payment-files/:fileId/verify", transfersWrite, middleware.RequireVerifiedEmail(), paymentFileController.VerifyPaymentFile)
+			accounts.GET("/:id/payment-files", transactionsRead, paymentFileController.GetPaymentFiles)
+			accounts.GET("/:id/payment-files/:fileId/status-report", transactionsRead, paymentFileController.GetStatusReport)
+			accounts.GET("/:id/spending", transactionsRead, categoryController.GetSpendingSummary)
 		}
 
 		transactions := protected.Group("/accounts/:id/transactions")
 		{
-			transactions.POST("/deposit", transactionController.Deposit)
-			transactions.POST("/withdraw", transactionController.Withdraw)
-			transactions.POST("/transfer", transactionController.Transfer)
-			transactions.GET("/", transactionController.GetTransactions)
+			transactions.POST("/deposit", transfersWrite, middleware.RequireVerifiedEmail(), transactionController.Deposit)
+			transactions.POST("/withdraw", transfersWrite, middleware.RequireVerifiedEmail(), transactionController.Withdraw)
+			transactions.POST("/transfer", transfersWrite, middleware.RequireVerifiedEmail(), transactionController.Transfer)
+			transactions.POST("/transfers/:challengeId/verify", transfersWrite, middleware.RequireVerifiedEmail(), transactionController.VerifyTransfer)
+			transactions.GET("/", transactionsRead, transactionController.GetTransactions)
+			transactions.GET("/export", transactionsRead, transactionController.ExportTransactions)
+			transactions.PATCH("/:transactionId", transactionsWrite, transactionController.UpdateTransaction)
+			transactions.PUT("/:transactionId/category", transactionsWrite, categoryController.RecategorizeTransaction)
 		}
 
-		protected.GET("/transactions/:id", transactionController.GetTransaction)
+		protected.GET("/transactions/:id", transactionsRead, transactionController.GetTransaction)
+
+		categories := protected.Group("/categories")
+		{
+			categories.GET("/", transactionsRead, categoryController.GetCategories)
+			categories.GET("/rules", transactionsRead, categoryController.GetRules)
+			categories.POST("/rules", transactionsWrite, categoryController.CreateRule)
+			categories.DELETE("/rules/:ruleId", transactionsWrite, categoryController.DeleteRule)
+		}
+
+		cards := protected.Group("/cards")
+		{
+			cards.GET("/", cardsRead, cardController.GetCards)
+			cards.GET("/:id", cardsRead, cardController.GetCard)
+			cards.PUT("/:id/controls", cardsWrite, cardController.UpdateCardControls)
+			cards.POST("/:id/freeze", cardsWrite, cardController.FreezeCard)
+			cards.POST("/:id/unfreeze", cardsWrite, cardController.UnfreezeCard)
+			cards.POST("/:id/lost", cardsWrite, cardController.ReportLostCard)
+		}
+
+		admin := protected.Group("/admin")
+		admin.Use(middleware.RequireUserSession())
+		{
+			admin.GET("/users", middleware.RequirePermission(models.PermissionUsersRead), adminController.GetUsers)
+			admin.GET("/users/:id", middleware.RequirePermission(models.PermissionUsersRead), adminController.GetUser)
+			admin.PUT("/users/:id/role", middleware.RequirePermission(models.PermissionUsersManage), adminController.UpdateUserRole)
+			admin.POST("/users/:id/activate", middleware.RequirePermission(models.PermissionUsersManage), adminController.ActivateUser)
+			admin.POST("/users/:id/deactivate", middleware.RequirePermission(models.PermissionUsersManage), adminController.DeactivateUser)
+			admin.POST("/users/:id/unlock", middleware.RequirePermission(models.PermissionUsersManage), adminController.UnlockUser)
+			admin.GET("/security-events", middleware.RequirePermission(models.PermissionUsersRead), adminController.GetSecurityEvents)
+			admin.GET("/accounts", middleware.RequirePermission(models.PermissionAccountsRead), adminController.GetAccounts)
+			admin.GET("/accounts/:id", middleware.RequirePermission(models.PermissionAccountsRead), adminController.GetAccount)
+			admin.POST("/accounts/:id/cash-deposits", middleware.RequirePermission(models.PermissionCashDeposit), adminController.CashDeposit)
+			admin.POST("/accounts/:id/close", middleware.RequirePermission(models.PermissionAccountsManage), adminController.CloseAccount)
+			admin.POST("/accounts/:id/reopen", middleware.RequirePermission(models.PermissionAccountsManage), adminController.ReopenAccount)
+			admin.POST("/accounts/:id/freeze", middleware.RequirePermission(models.PermissionAccountsManage), reconciliationController.FreezeAccount)
+			admin.POST("/accounts/:id/unfreeze", middleware.RequirePermission(models.PermissionAccountsManage), reconciliationController.UnfreezeAccount)
+			admin.GET("/accounts/:id/discrepancies", middleware.RequirePermission(models.PermissionReconciliation), reconciliationController.GetAccountDiscrepancies)
+			admin.POST("/reconciliation/runs", middleware.RequirePermission(models.PermissionReconciliation), reconciliationController.StartRun)
+			admin.GET("/reconciliation/runs", middleware.RequirePermission(models.PermissionReconciliation), reconciliationController.GetRuns)
+			admin.GET("/reconciliation/runs/:id", middleware.RequirePermission(models.PermissionReconciliation), reconciliationController.GetRun)
+			admin.POST("/eod", middleware.RequirePermission(models.PermissionEndOfDay), endOfDayController.CloseBusinessDay)
+			admin.GET("/eod", middleware.RequirePermission(models.PermissionEndOfDay), endOfDayController.GetBusinessDays)
+			admin.GET("/eod/:date", middleware.RequirePermission(models.PermissionEndOfDay), endOfDayController.GetBusinessDay)
+			admin.GET("/audit-logs", middleware.RequirePermission(models.PermissionAuditRead), auditController.GetAuditLogs)
+			admin.GET("/audit-logs/verify", middleware.RequirePermission(models.PermissionAuditRead), auditController.VerifyAuditLog)
+		}
+	}
+
+	cardNetwork := api.Group("/card-network")
+	cardNetwork.Use(cardNetworkLimit, middleware.CardNetworkAuth())
+	{
+		cardNetwork.POST("/authorizations", cardController.Authorize)
+		cardNetwork.POST("/authorizations/:id/capture", cardController.CaptureAuthorization)
+		cardNetwork.POST("/authorizations/:id/reverse", cardController.ReverseAuthorization)
 	}
 } 
\ No newline at end of file
@@ -3,6 +3,7 @@ package services
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/azainwork/core-banking-api/models"
 	"github.com/azainwork/core-banking-api/utils"
@@ -18,7 +19,7 @@ func NewAccountService(db *gorm.DB) *AccountService {
 	return &AccountService{db: db}
 }
 
-func (s *AccountService) CreateAccount(userID string, accountType models.AccountType, initialBalance float64) (*models.Account, error) {
+func (s *AccountService) CreateAccount(userID string, accountType models.AccountType, initialBalance float64, actor AuditActor) (*models.Account, error) {
 	userUUID, err := uuid.Parse(userID)
 	if err != nil {
 		return nil, errors.New("invalid user ID")
@@ -47,8 +48,44 @@ func (s *AccountService) CreateAccount(userID string, accountType models.Account
 		UserID:       userUUID,
 	}
 
-	if err := s.db.Create(account).Error; err != nil {
-		return nil, fmt.Errorf("failed to create account: %v", err)
+	var opening *models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(account).Error; err != nil {
+			return fmt.Errorf("failed to create account: %v", err)
+		}
+
+		if err := recordAudit(tx, actor, models.AuditActionAccountCreate, models.AuditTargetAccount, account.ID.String(), nil, auditAccount(account)); err != nil {
+			return err
+		}
+
+		if initialBalance <= 0 {
+			return nil
+		}
+
+		opening = &models.Transaction{
+			TransactionID: utils.GenerateTransactionID(),
+			Type:          models.TransactionTypeOpeningBalance,
+			Amount:        initialBalance,
+			Currency:      account.Currency,
+			Status:        models.TransactionStatusCompleted,
+			Description:   "Opening balance",
+			AccountID:     account.ID,
+			BalanceBefore: 0,
+			BalanceAfter:  initialBalance,
+		}
+
+		if err := tx.Omit("Account", "ToAccount").Create(opening).Error; err != nil {
+			return fmt.Errorf("failed to record opening balance: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if opening != nil {
+		categorizeCommitted(s.db, opening)
 	}
 
 	return account, nil
@@ -134,4 +171,69 @@ func (s *AccountService) ValidateAccountOwnership(accountID, userID string) erro
 	}
 
 	return nil
-} 
\ No newline at end of file
+} 
+
+func (s *AccountService) FreezeAccount(accountID, reason string, actor AuditActor) (*models.Account, error) {
+	account, err := s.GetAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.IsFrozen {
+		return nil, errors.New("account is already frozen")
+	}
+
+	before := auditAccount(account)
+	now := time.Now()
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Updates(map[string]interface{}{
+			"is_frozen":     true,
+			"frozen_reason": reason,
+			"frozen_at":     now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to freeze account: %v", err)
+		}
+		account.IsFrozen = true
+		account.FrozenReason = reason
+		account.FrozenAt = &now
+
+		return recordAudit(tx, actor, models.AuditActionAccountFreeze, models.AuditTargetAccount, account.ID.String(), before, auditAccount(account))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+func (s *AccountService) UnfreezeAccount(accountID string, actor AuditActor) (*models.Account, error) {
+	account, err := s.GetAccountByID(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !account.IsFrozen {
+		return nil, errors.New("account is not frozen")
+	}
+
+	before := auditAccount(account)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Updates(map[string]interface{}{
+			"is_frozen":     false,
+			"frozen_reason": "",
+			"frozen_at":     nil,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to unfreeze account: %v", err)
+		}
+		account.IsFrozen = false
+		account.FrozenReason = ""
+		account.FrozenAt = nil
+
+		return recordAudit(tx, actor, models.AuditActionAccountUnfreeze, models.AuditTargetAccount, account.ID.String(), before, auditAccount(account))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var ErrCannotModifySelf = errors.New("you cannot change your own role or status")
+
+type AdminService struct {
+	db            *gorm.DB
+	tokenService  *TokenService
+	loginThrottle *LoginThrottleService
+}
+
+func NewAdminService(db *gorm.DB) *AdminService {
+	return &AdminService{
+		db:            db,
+		tokenService:  NewTokenService(db),
+		loginThrottle: NewLoginThrottleService(db),
+	}
+}
+
+type UserFilter struct {
+	Search string
+	Role   models.UserRole
+	Limit  int
+	Offset int
+}
+
+type AccountFilter struct {
+	UserID string
+	Limit  int
+	Offset int
+}
+
+func (s *AdminService) GetUsers(filter UserFilter) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	query := s.db.Model(&models.User{})
+	// Names and emails are encrypted, so search only supports an exact email
+	// through its blind index, or a user ID.
+	if filter.Search != "" {
+		if id, err := uuid.Parse(filter.Search); err == nil {
+			query = query.Where("id = ?", id)
+		} else {
+			emailIndex, err := models.EmailIndex(filter.Search)
+			if err != nil {
+				return nil, 0, fmt.Errorf("failed to index email: %v", err)
+			}
+			query = query.Where("email_index = ?", emailIndex)
+		}
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %v", err)
+	}
+
+	if err := query.Order("created_at DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&users).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to find users: %v", err)
+	}
+
+	return users, total, nil
+}
+
+func (s *AdminService) GetUser(userID string) (*models.User, error) {
+	var user models.User
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if err := s.db.Preload("Accounts").Where("id = ?", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to find user: %v", err)
+	}
+
+	return &user, nil
+}
+
+func (s *AdminService) UpdateUserRole(actor AuditActor, userID string, role models.UserRole) (*models.User, error) {
+	if !role.IsValid() {
+		return nil, errors.New("invalid role")
+	}
+
+	return s.updateUser(actor, userID, models.AuditActionUserRoleChange, map[string]interface{}{"role": role})
+}
+
+func (s *AdminService) SetUserActive(actor AuditActor, userID string, active bool) (*models.User, error) {
+	action := models.AuditActionUserDeactivate
+	if active {
+		action = models.AuditActionUserActivate
+	}
+
+	return s.updateUser(actor, userID, action, map[string]interface{}{"is_active": active})
+}
+
+func (s *AdminService) GetLoginLockout(user *models.User) (*time.Time, error) {
+	return s.loginThrottle.IsLocked(user.Email)
+}
+
+func (s *AdminService) UnlockUser(actor AuditActor, userID string) (*models.User, error) {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loginThrottle.Unlock(user, actor.ID); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		return recordAudit(tx, actor, models.AuditActionUserUnlock, models.AuditTargetUser, user.ID.String(), nil, nil)
+	}); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *AdminService) GetSecurityEvents(eventType models.SecurityEventType, userID string, limit, offset int) ([]models.SecurityEvent, int64, error) {
+	return s.loginThrottle.GetSecurityEvents(eventType, userID, limit, offset)
+}
+
+func (s *AdminService) GetAccounts(filter AccountFilter) ([]models.Account, int64, error) {
+	var accounts []models.Account
+	var total int64
+
+	query := s.db.Model(&models.Account{})
+	if filter.UserID != "" {
+		userID, err := uuid.Parse(filter.UserID)
+		if err != nil {
+			return nil, 0, errors.New("invalid user ID")
+		}
+		query = query.Where("user_id = ?", userID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count accounts: %v", err)
+	}
+
+	if err := query.Order("created_at DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&accounts).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to find accounts: %v", err)
+	}
+
+	return accounts, total, nil
+}
+
+func (s *AdminService) GetAccount(accountID string) (*models.Account, error) {
+	var account models.Account
+
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	if err := s.db.Preload("User").Where("id = ?", id).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("account not found")
+		}
+		return nil, fmt.Errorf("failed to find account: %v", err)
+	}
+
+	return &account, nil
+}
+
+func (s *AdminService) SetAccountActive(actor AuditActor, accountID string, active bool) (*models.Account, error) {
+	account, err := s.GetAccount(accountID)
+	if err != nil {
+		return nil, err
+	}
+
+	if account.IsActive == active {
+		if active {
+			return nil, errors.New("account is already active")
+		}
+		return nil, errors.New("account is already closed")
+	}
+
+	if !active && account.Balance != 0 {
+		return nil, errors.New("account balance must be zero before it can be closed")
+	}
+
+	action := models.AuditActionAccountClose
+	if active {
+		action = models.AuditActionAccountReopen
+	}
+
+	before := auditAccount(account)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(account).Update("is_active", active).Error; err != nil {
+			return fmt.Errorf("failed to update account: %v", err)
+		}
+		account.IsActive = active
+
+		return recordAudit(tx, actor, action, models.AuditTargetAccount, account.ID.String(), before, auditAccount(account))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return account, nil
+}
+
+// updateUser applies an administrative change and ends the user's sessions so
+// that the new role or status is reflected in their next token.
+func (s *AdminService) updateUser(actor AuditActor, userID string, action models.AuditAction, updates map[string]interface{}) (*models.User, error) {
+	user, err := s.GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(user.ID.String(), actor.ID) {
+		return nil, ErrCannotModifySelf
+	}
+
+	before := auditUser(user)
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update user: %v", err)
+		}
+
+		var updated models.User
+		if err := tx.Where("id = ?", user.ID).First(&updated).Error; err != nil {
+			return fmt.Errorf("failed to find user: %v", err)
+		}
+
+		return recordAudit(tx, actor, action, models.AuditTargetUser, user.ID.String(), before, auditUser(&updated))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := s.tokenService.LogoutAll(user.ID.String()); err != nil {
+		return nil, err
+	}
+
+	return s.GetUser(userID)
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	apiKeyPrefix         = "cbk_"
+	apiKeyUsageInterval  = time.Minute
+	DefaultAPIKeyOverlap = 24 * time.Hour
+	MaxAPIKeyOverlap     = 7 * 24 * time.Hour
+)
+
+var (
+	ErrAPIKeyNotFound     = errors.New("API key not found")
+	ErrInvalidAPIKey      = errors.New("invalid or expired API key")
+	ErrAPIKeyIPNotAllowed = errors.New("API key is not allowed from this IP address")
+	ErrAPIKeyInactive     = errors.New("only active API keys can be rotated")
+	ErrInvalidKeyOverlap  = errors.New("overlap must be between 0 and 168 hours")
+)
+
+type APIKeyService struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyService(db *gorm.DB) *APIKeyService {
+	return &APIKeyService{db: db}
+}
+
+type APIKeyInput struct {
+	Name       string
+	Scopes     []string
+	AllowedIPs []string
+	ExpiresAt  *time.Time
+}
+
+// CreateAPIKey stores a new key and returns it together with the plaintext
+// key, which cannot be recovered later.
+func (s *APIKeyService) CreateAPIKey(userID string, input APIKeyInput, actor AuditActor) (*models.APIKey, string, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, "", errors.New("invalid user ID")
+	}
+
+	scopes, err := normalizeScopes(input.Scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	allowedIPs, err := normalizeAllowedIPs(input.AllowedIPs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if input.ExpiresAt != nil && !input.ExpiresAt.After(time.Now()) {
+		return nil, "", errors.New("expiry must be in the future")
+	}
+
+	key := &models.APIKey{
+		UserID:     userUUID,
+		Name:       strings.TrimSpace(input.Name),
+		Scopes:     scopes,
+		AllowedIPs: allowedIPs,
+		ExpiresAt:  input.ExpiresAt,
+	}
+
+	var rawKey string
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		rawKey, err = s.create(tx, key)
+		if err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionAPIKeyCreate, models.AuditTargetAPIKey, key.ID.String(), nil, auditAPIKey(key))
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return key, rawKey, nil
+}
+
+func (s *APIKeyService) GetAPIKeys(userID string) ([]models.APIKey, error) {
+	var keys []models.APIKey
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if err := s.db.Where("user_id = ?", userUUID).Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to find API keys: %v", err)
+	}
+
+	return keys, nil
+}
+
+func (s *APIKeyService) RevokeAPIKey(userID, keyID string, actor AuditActor) (*models.APIKey, error) {
+	var key *models.APIKey
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		key, err = s.findOwnedKey(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID, keyID)
+		if err != nil {
+			return err
+		}
+
+		if key.RevokedAt != nil {
+			return nil
+		}
+
+		before := auditAPIKey(key)
+		now := time.Now()
+		if err := tx.Model(key).Update("revoked_at", now).Error; err != nil {
+			return fmt.Errorf("failed to revoke API key: %v", err)
+		}
+		key.RevokedAt = &now
+
+		return recordAudit(tx, actor, models.AuditActionAPIKeyRevoke, models.AuditTargetAPIKey, key.ID.String(), before, auditAPIKey(key))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// RotateAPIKey issues a replacement with the same name, scopes and allowlist.
+// The old key keeps working for the overlap window so that integrations can
+// be redeployed with the new secret without downtime.
+func (s *APIKeyService) RotateAPIKey(userID, keyID string, overlap time.Duration, actor AuditActor) (*models.APIKey, string, error) {
+	if overlap < 0 || overlap > MaxAPIKeyOverlap {
+		return nil, "", ErrInvalidKeyOverlap
+	}
+
+	var replacement *models.APIKey
+	var rawKey string
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		key, err := s.findOwnedKey(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID, keyID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if !key.IsActive(now) {
+			return ErrAPIKeyInactive
+		}
+
+		replacement = &models.APIKey{
+			UserID:        key.UserID,
+			Name:          key.Name,
+			Scopes:        key.Scopes,
+			AllowedIPs:    key.AllowedIPs,
+			ExpiresAt:     key.ExpiresAt,
+			RotatedFromID: &key.ID,
+		}
+		rawKey, err = s.create(tx, replacement)
+		if err != nil {
+			return err
+		}
+
+		before := auditAPIKey(key)
+		retireAt := now.Add(overlap)
+		if key.ExpiresAt == nil || retireAt.Before(*key.ExpiresAt) {
+			if err := tx.Model(key).Update("expires_at", retireAt).Error; err != nil {
+				return fmt.Errorf("failed to expire rotated API key: %v", err)
+			}
+			key.ExpiresAt = &retireAt
+		}
+
+		if err := recordAudit(tx, actor, models.AuditActionAPIKeyRotate, models.AuditTargetAPIKey, key.ID.String(), before, auditAPIKey(key)); err != nil {
+			return err
+		}
+		return recordAudit(tx, actor, models.AuditActionAPIKeyCreate, models.AuditTargetAPIKey, replacement.ID.String(), nil, auditAPIKey(replacement))
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return replacement, rawKey, nil
+}
+
+// Authenticate resolves a presented key to its active key and owner.
+func (s *APIKeyService) Authenticate(rawKey, ipAddress string) (*models.APIKey, error) {
+	prefix, secret, ok := parseAPIKey(rawKey)
+	if !ok {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var key models.APIKey
+	if err := s.db.Preload("User").Where("prefix = ?", prefix).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, fmt.Errorf("failed to find API key: %v", err)
+	}
+
+	now := time.Now()
+	if subtle.ConstantTimeCompare([]byte(utils.HashToken(secret)), []byte(key.SecretHash)) != 1 {
+		return nil, ErrInvalidAPIKey
+	}
+	if !key.IsActive(now) || !key.User.IsActive {
+		return nil, ErrInvalidAPIKey
+	}
+	if !ipAllowed(key.AllowedIPs, ipAddress) {
+		return nil, ErrAPIKeyIPNotAllowed
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) > apiKeyUsageInterval {
+		if err := s.db.Model(&key).Updates(map[string]interface{}{"last_used_at": now, "last_used_ip": ipAddress}).Error; err != nil {
+			return nil, fmt.Errorf("failed to record API key usage: %v", err)
+		}
+	}
+
+	return &key, nil
+}
+
+func (s *APIKeyService) create(tx *gorm.DB, key *models.APIKey) (string, error) {
+	prefix := make([]byte, 6)
+	if _, err := rand.Read(prefix); err != nil {
+		return "", fmt.Errorf("failed to generate API key: %v", err)
+	}
+
+	secret, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate API key: %v", err)
+	}
+
+	key.Prefix = hex.EncodeToString(prefix)
+	key.SecretHash = utils.HashToken(secret)
+
+	if err := tx.Create(key).Error; err != nil {
+		return "", fmt.Errorf("failed to create API key: %v", err)
+	}
+
+	return apiKeyPrefix + key.Prefix + "_" + secret, nil
+}
+
+func (s *APIKeyService) findOwnedKey(tx *gorm.DB, userID, keyID string) (*models.APIKey, error) {
+	var key models.APIKey
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	keyUUID, err := uuid.Parse(keyID)
+	if err != nil {
+		return nil, ErrAPIKeyNotFound
+	}
+
+	if err := tx.Where("id = ? AND user_id = ?", keyUUID, userUUID).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrAPIKeyNotFound
+		}
+		return nil, fmt.Errorf("failed to find API key: %v", err)
+	}
+
+	return &key, nil
+}
+
+func parseAPIKey(rawKey string) (string, string, bool) {
+	if !strings.HasPrefix(rawKey, apiKeyPrefix) {
+		return "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(rawKey, apiKeyPrefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", false
+	}
+
+	return parts[0], parts[1], true
+}
+
+func normalizeScopes(scopes []string) (models.StringList, error) {
+	normalized := models.StringList{}
+	seen := make(map[string]bool, len(scopes))
+
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if seen[scope] {
+			continue
+		}
+		if !models.APIScope(scope).IsValid() {
+			return nil, fmt.Errorf("invalid scope %q", scope)
+		}
+		seen[scope] = true
+		normalized = append(normalized, scope)
+	}
+
+	if len(normalized) == 0 {
+		return nil, errors.New("at least one scope is required")
+	}
+
+	return normalized, nil
+}
+
+func normalizeAllowedIPs(entries []string) (models.StringList, error) {
+	normalized := models.StringList{}
+
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			normalized = append(normalized, network.String())
+			continue
+		}
+		if ip := net.ParseIP(entry); ip != nil {
+			normalized = append(normalized, ip.String())
+			continue
+		}
+		return nil, fmt.Errorf("invalid IP address or CIDR %q", entry)
+	}
+
+	return normalized, nil
+}
+
+func ipAllowed(allowlist models.StringList, ipAddress string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+
+	ip := net.ParseIP(ipAddress)
+	if ip == nil {
+		return false
+	}
+
+	for _, entry := range allowlist {
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			if network.Contains(ip) {
+				return true
+			}
+			continue
+		}
+		if allowed := net.ParseIP(entry); allowed != nil && allowed.Equal(ip) {
+			return true
+		}
+	}
+
+	return false
+}
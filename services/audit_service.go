@@ -0,0 +1,484 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// auditChainLockKey plus the shard number serializes appends to a shard,
+	// so that every entry links to the one committed before it. Each actor
+	// always writes to the same shard, so writes by different actors only
+	// wait on each other when their actors share a shard.
+	auditChainLockKey    = 7_141_766_105
+	auditChainShards     = 32
+	auditVerifyBatchSize = 1000
+	auditMaxProblems     = 100
+	auditRedacted        = "[redacted]"
+)
+
+var (
+	ErrAuditAnchorMismatch = errors.New("audit log does not contain the expected entry")
+	ErrInvalidAuditAnchor  = errors.New("anchor must be <shard>:<seq>:<hash>")
+)
+
+// auditRedactedFields are recorded as changed without their values, keeping
+// encrypted PII out of the audit log.
+var auditRedactedFields = map[string]bool{
+	"email":      true,
+	"first_name": true,
+	"last_name":  true,
+	"phone":      true,
+}
+
+// AuditActor identifies who made a change. Controllers build it from the
+// request; background jobs use SystemAuditActor.
+type AuditActor struct {
+	Type      models.AuditActorType
+	ID        string
+	APIKeyID  string
+	IPAddress string
+	RequestID string
+}
+
+func SystemAuditActor(name string) AuditActor {
+	return AuditActor{Type: models.AuditActorSystem, ID: name}
+}
+
+// auditShard picks the chain for the actor's entries. A database transaction
+// records all of its entries with one actor, so it only ever holds one shard
+// lock and cannot deadlock against another transaction on the audit log.
+func auditShard(actor AuditActor) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(string(actor.Type) + ":" + actor.ID))
+	return int(hash.Sum32() % auditChainShards)
+}
+
+// orUser attributes unauthenticated requests, such as registration or a
+// password reset link, to the user they act on.
+func (a AuditActor) orUser(userID uuid.UUID) AuditActor {
+	if a.ID == "" {
+		a.Type = models.AuditActorUser
+		a.ID = userID.String()
+	}
+	return a
+}
+
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+type AuditFilter struct {
+	ActorID    string
+	Action     models.AuditAction
+	TargetType models.AuditTargetType
+	TargetID   string
+	RequestID  string
+	From       *time.Time
+	To         *time.Time
+	Limit      int
+	Offset     int
+}
+
+type AuditProblem struct {
+	Shard   int    `json:"shard"`
+	Seq     int64  `json:"seq"`
+	Problem string `json:"problem"`
+}
+
+// AuditChainHead is the last entry of one shard. Kept from an earlier run,
+// it serves as an anchor that also detects entries removed from the end.
+type AuditChainHead struct {
+	Shard    int    `json:"shard"`
+	Entries  int64  `json:"entries"`
+	HeadSeq  int64  `json:"head_seq"`
+	HeadHash string `json:"head_hash"`
+}
+
+// Anchor formats the head for CheckAnchors.
+func (h AuditChainHead) Anchor() string {
+	return fmt.Sprintf("%d:%d:%s", h.Shard, h.HeadSeq, h.HeadHash)
+}
+
+type AuditVerification struct {
+	Valid    bool             `json:"valid"`
+	Entries  int64            `json:"entries"`
+	Chains   []AuditChainHead `json:"chains"`
+	Problems []AuditProblem   `json:"problems"`
+}
+
+type AuditService struct {
+	db *gorm.DB
+}
+
+func NewAuditService(db *gorm.DB) *AuditService {
+	return &AuditService{db: db}
+}
+
+func (s *AuditService) GetAuditLogs(filter AuditFilter) ([]models.AuditLog, int64, error) {
+	var entries []models.AuditLog
+	var total int64
+
+	query := s.db.Model(&models.AuditLog{})
+	if filter.ActorID != "" {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != "" {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit logs: %v", err)
+	}
+
+	if err := query.Order("created_at DESC, seq DESC").Limit(filter.Limit).Offset(filter.Offset).Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to find audit logs: %v", err)
+	}
+
+	return entries, total, nil
+}
+
+// Verify walks every shard's chain and reports each entry whose sequence
+// number, previous hash or own hash does not match. Removing entries from
+// the end leaves a valid shorter chain, which only CheckAnchor against a
+// previously recorded head can detect.
+func (s *AuditService) Verify() (*AuditVerification, error) {
+	result := &AuditVerification{Chains: []AuditChainHead{}, Problems: []AuditProblem{}}
+
+	var shards []int
+	if err := s.db.Model(&models.AuditLog{}).Distinct("shard").Order("shard").Pluck("shard", &shards).Error; err != nil {
+		return nil, fmt.Errorf("failed to find audit log shards: %v", err)
+	}
+
+	for _, shard := range shards {
+		head := AuditChainHead{Shard: shard, HeadHash: models.AuditGenesisHash}
+
+		for {
+			var entries []models.AuditLog
+			if err := s.db.Where("shard = ? AND seq > ?", shard, head.HeadSeq).Order("seq").Limit(auditVerifyBatchSize).Find(&entries).Error; err != nil {
+				return nil, fmt.Errorf("failed to load audit logs: %v", err)
+			}
+			if len(entries) == 0 {
+				break
+			}
+
+			result.Problems = verifyAuditChain(&head, entries, result.Problems)
+		}
+
+		result.Entries += head.Entries
+		result.Chains = append(result.Chains, head)
+	}
+
+	result.Valid = len(result.Problems) == 0
+	return result, nil
+}
+
+// verifyAuditChain checks entries, in sequence order, against the chain
+// ending at head and moves head to the last of them.
+func verifyAuditChain(head *AuditChainHead, entries []models.AuditLog, problems []AuditProblem) []AuditProblem {
+	report := func(seq int64, problem string) {
+		if len(problems) < auditMaxProblems {
+			problems = append(problems, AuditProblem{Shard: head.Shard, Seq: seq, Problem: problem})
+		}
+	}
+
+	for i := range entries {
+		entry := &entries[i]
+
+		if entry.Shard != head.Shard {
+			report(entry.Seq, fmt.Sprintf("entry belongs to shard %d", entry.Shard))
+		}
+		if entry.Seq != head.HeadSeq+1 {
+			report(entry.Seq, fmt.Sprintf("expected sequence %d, entries are missing", head.HeadSeq+1))
+		}
+		if entry.PrevHash != head.HeadHash {
+			report(entry.Seq, "previous hash does not match the preceding entry")
+		}
+		if entry.ComputeHash() != entry.Hash {
+			report(entry.Seq, "hash does not match the entry contents")
+		}
+
+		head.Entries++
+		head.HeadSeq = entry.Seq
+		head.HeadHash = entry.Hash
+	}
+
+	return problems
+}
+
+// CheckAnchors checks heads recorded by earlier runs, each as
+// <shard>:<seq>:<hash>, and adds a problem to result for every one that no
+// longer matches.
+func (s *AuditService) CheckAnchors(result *AuditVerification, anchors []string) error {
+	for _, anchor := range anchors {
+		parts := strings.SplitN(anchor, ":", 3)
+		if len(parts) != 3 {
+			return ErrInvalidAuditAnchor
+		}
+		shard, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return ErrInvalidAuditAnchor
+		}
+		seq, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			return ErrInvalidAuditAnchor
+		}
+
+		if err := s.CheckAnchor(shard, seq, parts[2]); err != nil {
+			if !errors.Is(err, ErrAuditAnchorMismatch) {
+				return err
+			}
+			result.Valid = false
+			result.Problems = append(result.Problems, AuditProblem{Shard: shard, Seq: seq, Problem: err.Error()})
+		}
+	}
+
+	return nil
+}
+
+// CheckAnchor confirms that the entry with the given shard and sequence
+// number still has the hash recorded for it earlier, for example by a
+// previous verification run.
+func (s *AuditService) CheckAnchor(shard int, seq int64, hash string) error {
+	var entry models.AuditLog
+	if err := s.db.Where("shard = ? AND seq = ?", shard, seq).First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrAuditAnchorMismatch
+		}
+		return fmt.Errorf("failed to find audit log: %v", err)
+	}
+
+	if entry.Hash != hash {
+		return ErrAuditAnchorMismatch
+	}
+
+	return nil
+}
+
+// recordAudit appends an entry describing a change made in tx, so that the
+// entry commits or rolls back with the change itself. The shard lock is held
+// until tx ends, so callers record last to keep that window short, must not
+// do slow work, such as sending mail, in the same tx, and must use the same
+// actor for every entry in a tx.
+func recordAudit(tx *gorm.DB, actor AuditActor, action models.AuditAction, targetType models.AuditTargetType, targetID string, before, after map[string]interface{}) error {
+	changes, err := json.Marshal(auditDiff(before, after))
+	if err != nil {
+		return fmt.Errorf("failed to encode audit changes: %v", err)
+	}
+
+	shard := auditShard(actor)
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLockKey+shard).Error; err != nil {
+		return fmt.Errorf("failed to lock audit log: %v", err)
+	}
+
+	var previous models.AuditLog
+	if err := tx.Where("shard = ?", shard).Order("seq DESC").Limit(1).Find(&previous).Error; err != nil {
+		return fmt.Errorf("failed to find previous audit log: %v", err)
+	}
+
+	entry := &models.AuditLog{
+		ID:         uuid.New(),
+		Shard:      shard,
+		Seq:        previous.Seq + 1,
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		APIKeyID:   actor.APIKeyID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Changes:    changes,
+		IPAddress:  actor.IPAddress,
+		RequestID:  actor.RequestID,
+		PrevHash:   previous.Hash,
+		CreatedAt:  time.Now().UTC().Truncate(time.Microsecond),
+	}
+	if entry.PrevHash == "" {
+		entry.PrevHash = models.AuditGenesisHash
+	}
+	entry.Hash = entry.ComputeHash()
+
+	if err := tx.Create(entry).Error; err != nil {
+		return fmt.Errorf("failed to record audit log: %v", err)
+	}
+
+	return nil
+}
+
+// auditDiff returns the fields whose values differ between the snapshots. A
+// nil before records a creation.
+func auditDiff(before, after map[string]interface{}) map[string]AuditChange {
+	changes := map[string]AuditChange{}
+
+	for field, to := range after {
+		from, existed := before[field]
+		if existed && auditEqual(from, to) {
+			continue
+		}
+		if auditRedactedFields[field] {
+			if existed {
+				from = auditRedacted
+			}
+			to = auditRedacted
+		}
+		changes[field] = AuditChange{From: from, To: to}
+	}
+
+	for field, from := range before {
+		if _, ok := after[field]; ok {
+			continue
+		}
+		if auditRedactedFields[field] {
+			from = auditRedacted
+		}
+		changes[field] = AuditChange{From: from}
+	}
+
+	return changes
+}
+
+func auditEqual(a, b interface{}) bool {
+	encodedA, errA := json.Marshal(a)
+	encodedB, errB := json.Marshal(b)
+	return errA == nil && errB == nil && string(encodedA) == string(encodedB)
+}
+
+func auditUser(user *models.User) map[string]interface{} {
+	return map[string]interface{}{
+		"email":             user.Email,
+		"first_name":        user.FirstName,
+		"last_name":         user.LastName,
+		"phone":             user.Phone,
+		"role":              user.Role,
+		"is_active":         user.IsActive,
+		"email_verified_at": user.EmailVerifiedAt,
+		"mfa_enabled":       user.MFAEnabled,
+	}
+}
+
+func auditAccount(account *models.Account) map[string]interface{} {
+	return map[string]interface{}{
+		"account_number": account.AccountNumber,
+		"type":           account.Type,
+		"balance":        account.Balance,
+		"currency":       account.Currency,
+		"is_active":      account.IsActive,
+		"is_frozen":      account.IsFrozen,
+		"frozen_reason":  account.FrozenReason,
+		"user_id":        account.UserID,
+	}
+}
+
+func auditCard(card *models.Card) map[string]interface{} {
+	return map[string]interface{}{
+		"last4":                 card.Last4,
+		"status":                card.Status,
+		"per_transaction_limit": card.PerTransactionLimit,
+		"daily_limit":           card.DailyLimit,
+		"allow_online":          card.AllowOnline,
+		"allow_atm":             card.AllowATM,
+		"account_id":            card.AccountID,
+		"user_id":               card.UserID,
+	}
+}
+
+func auditHold(hold *models.Hold) map[string]interface{} {
+	return map[string]interface{}{
+		"account_id":      hold.AccountID,
+		"card_id":         hold.CardID,
+		"amount":          hold.Amount,
+		"captured_amount": hold.CapturedAmount,
+		"currency":        hold.Currency,
+		"status":          hold.Status,
+		"transaction_id":  hold.TransactionID,
+	}
+}
+
+func auditAPIKey(key *models.APIKey) map[string]interface{} {
+	return map[string]interface{}{
+		"name":            key.Name,
+		"prefix":          key.Prefix,
+		"scopes":          key.Scopes,
+		"allowed_ips":     key.AllowedIPs,
+		"expires_at":      key.ExpiresAt,
+		"revoked_at":      key.RevokedAt,
+		"rotated_from_id": key.RotatedFromID,
+		"user_id":         key.UserID,
+	}
+}
+
+func auditBusinessDay(day *models.BusinessDay) map[string]interface{} {
+	return map[string]interface{}{
+		"date":      day.Date.Format(statementDateFormat),
+		"status":    day.Status,
+		"step":      day.Step,
+		"closed_at": day.ClosedAt,
+	}
+}
+
+func auditCategoryRule(rule *models.CategoryRule) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":                 rule.UserID,
+		"category_id":             rule.CategoryID,
+		"source":                  rule.Source,
+		"priority":                rule.Priority,
+		"description_pattern":     rule.DescriptionPattern,
+		"counterparty_account_id": rule.CounterpartyAccountID,
+		"transaction_type":        rule.TransactionType,
+		"direction":               rule.Direction,
+		"min_amount":              rule.MinAmount,
+		"max_amount":              rule.MaxAmount,
+	}
+}
+
+func auditPaymentFile(paymentFile *models.PaymentFile) map[string]interface{} {
+	return map[string]interface{}{
+		"account_id":             paymentFile.AccountID,
+		"user_id":                paymentFile.UserID,
+		"message_id":             paymentFile.MessageID,
+		"status":                 paymentFile.Status,
+		"number_of_transactions": paymentFile.NumberOfTransactions,
+		"control_sum":            paymentFile.ControlSum,
+		"accepted_count":         paymentFile.AcceptedCount,
+		"rejected_count":         paymentFile.RejectedCount,
+	}
+}
+
+func auditTransaction(transaction *models.Transaction) map[string]interface{} {
+	return map[string]interface{}{
+		"transaction_id": transaction.TransactionID,
+		"type":           transaction.Type,
+		"amount":         transaction.Amount,
+		"currency":       transaction.Currency,
+		"status":         transaction.Status,
+		"account_id":     transaction.AccountID,
+		"to_account_id":  transaction.ToAccountID,
+		"balance_before": transaction.BalanceBefore,
+		"balance_after":  transaction.BalanceAfter,
+		"description":    transaction.Description,
+	}
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const MaxBalanceSeriesDays = 366
+
+type DailyBalance struct {
+	Date           string  `json:"date"`
+	OpeningBalance float64 `json:"opening_balance"`
+	Credits        float64 `json:"credits"`
+	Debits         float64 `json:"debits"`
+	ClosingBalance float64 `json:"closing_balance"`
+}
+
+type BalanceService struct {
+	db *gorm.DB
+}
+
+func NewBalanceService(db *gorm.DB) *BalanceService {
+	return &BalanceService{db: db}
+}
+
+// Balances are computed on value date: an entry counts from its creation time
+// when booked on its value date, from the start of the value date when booked
+// ahead of it, and from the end of the value date when back-valued.
+func (s *BalanceService) GetBalanceAsOf(accountID string, asOf time.Time) (float64, error) {
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return 0, errors.New("invalid account ID")
+	}
+
+	if asOf.After(time.Now()) {
+		return 0, errors.New("as_of cannot be in the future")
+	}
+
+	date := utils.BusinessDate(asOf)
+	balance, err := s.closingBalance(id, date.AddDate(0, 0, -1))
+	if err != nil {
+		return 0, err
+	}
+
+	intraday, err := netMovement(completedAccountTransactions(s.db, id).Where("value_date = ? AND created_at <= ?", date, asOf), id)
+	if err != nil {
+		return 0, err
+	}
+
+	return balance + intraday, nil
+}
+
+func (s *BalanceService) GetClosingBalance(accountID string, date time.Time) (float64, error) {
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return 0, errors.New("invalid account ID")
+	}
+
+	date = statementDate(date)
+	if date.After(utils.BusinessDate(time.Now())) {
+		return 0, errors.New("as_of cannot be in the future")
+	}
+
+	return s.closingBalance(id, date)
+}
+
+func (s *BalanceService) GetDailyBalances(accountID string, from, to time.Time) ([]DailyBalance, error) {
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	from, to = statementDate(from), statementDate(to)
+	if today := utils.BusinessDate(time.Now()); to.After(today) {
+		to = today
+	}
+	if to.Before(from) {
+		return nil, errors.New("from must not be after to")
+	}
+	if int(to.Sub(from).Hours()/24) >= MaxBalanceSeriesDays {
+		return nil, fmt.Errorf("date range cannot exceed %d days", MaxBalanceSeriesDays)
+	}
+
+	opening, err := s.closingBalance(id, from.AddDate(0, 0, -1))
+	if err != nil {
+		return nil, err
+	}
+
+	var movements []struct {
+		ValueDate time.Time
+		Credits   float64
+		Debits    float64
+	}
+	if err := completedAccountTransactions(s.db, id).
+		Select("value_date, "+
+			"COALESCE(SUM(CASE WHEN ("+signedAmountSQL+") > 0 THEN amount END), 0) AS credits, "+
+			"COALESCE(SUM(CASE WHEN ("+signedAmountSQL+") < 0 THEN amount END), 0) AS debits",
+			id, models.CreditTransactionTypes, id, models.CreditTransactionTypes).
+		Where("value_date >= ? AND value_date <= ?", from, to).
+		Group("value_date").
+		Scan(&movements).Error; err != nil {
+		return nil, fmt.Errorf("failed to calculate daily movements: %v", err)
+	}
+
+	byDate := make(map[string]int, len(movements))
+	for i, movement := range movements {
+		byDate[movement.ValueDate.Format(statementDateFormat)] = i
+	}
+
+	balances := make([]DailyBalance, 0, int(to.Sub(from).Hours()/24)+1)
+	running := opening
+	for date := from; !date.After(to); date = date.AddDate(0, 0, 1) {
+		day := DailyBalance{Date: date.Format(statementDateFormat), OpeningBalance: running}
+		if i, ok := byDate[day.Date]; ok {
+			day.Credits = movements[i].Credits
+			day.Debits = movements[i].Debits
+		}
+		running += day.Credits - day.Debits
+		day.ClosingBalance = running
+		balances = append(balances, day)
+	}
+
+	return balances, nil
+}
+
+func (s *BalanceService) closingBalance(accountID uuid.UUID, date time.Time) (float64, error) {
+	var snapshot models.BalanceSnapshot
+	err := s.db.Where("account_id = ? AND business_date <= ?", accountID, date).Order("business_date DESC").First(&snapshot).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return netMovement(completedAccountTransactions(s.db, accountID).Where("value_date <= ?", date), accountID)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to find balance snapshot: %v", err)
+	}
+
+	backValued, err := netMovement(completedAccountTransactions(s.db, accountID).
+		Where("value_date <= ? AND created_at > ?", snapshot.BusinessDate, snapshot.CreatedAt), accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	since, err := netMovement(completedAccountTransactions(s.db, accountID).
+		Where("value_date > ? AND value_date <= ?", snapshot.BusinessDate, date), accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	return snapshot.Balance + backValued + since, nil
+}
@@ -0,0 +1,847 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	cardValidityYears     = 3
+	defaultCardHoldTTL    = 7 * 24 * time.Hour
+	maxCardIssueAttempts  = 5
+	defaultCVVMaxFailures = 3
+)
+
+var ErrAuthorizationExpired = errors.New("authorization has expired")
+
+type DeclineReason string
+
+const (
+	DeclineInvalidCard        DeclineReason = "invalid_card"
+	DeclineExpiredCard        DeclineReason = "expired_card"
+	DeclineCardBlocked        DeclineReason = "card_blocked"
+	DeclineCardLost           DeclineReason = "card_lost"
+	DeclineInvalidCVV         DeclineReason = "invalid_cvv"
+	DeclineChannelNotAllowed  DeclineReason = "channel_not_allowed"
+	DeclineCurrencyMismatch   DeclineReason = "currency_mismatch"
+	DeclineExceedsTxLimit     DeclineReason = "exceeds_transaction_limit"
+	DeclineExceedsDailyLimit  DeclineReason = "exceeds_daily_limit"
+	DeclineInsufficientFunds  DeclineReason = "insufficient_funds"
+	DeclineAccountUnavailable DeclineReason = "account_unavailable"
+)
+
+type DeclineError struct {
+	Reason DeclineReason
+}
+
+func (e *DeclineError) Error() string {
+	return "authorization declined: " + string(e.Reason)
+}
+
+type CardControls struct {
+	PerTransactionLimit *float64
+	DailyLimit          *float64
+	AllowOnline         *bool
+	AllowATM            *bool
+}
+
+type CardAuthorizationRequest struct {
+	PAN          string
+	ExpiryMonth  int
+	ExpiryYear   int
+	CVV          string
+	Amount       float64
+	Currency     string
+	MerchantName string
+	Channel      models.CardChannel
+	Reference    string
+	STAN         string
+}
+
+type CardService struct {
+	db *gorm.DB
+}
+
+func NewCardService(db *gorm.DB) *CardService {
+	return &CardService{db: db}
+}
+
+func (s *CardService) IssueCard(accountID, userID, cardholderName string, controls CardControls, actor AuditActor) (*models.Card, string, string, error) {
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, "", "", errors.New("invalid account ID")
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, "", "", errors.New("invalid user ID")
+	}
+
+	var account models.Account
+	if err := s.db.Preload("User").Where("id = ? AND user_id = ? AND is_active = ?", accountUUID, userUUID, true).First(&account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, "", "", errors.New("account not found")
+		}
+		return nil, "", "", fmt.Errorf("failed to find account: %v", err)
+	}
+
+	if cardholderName == "" {
+		cardholderName = strings.ToUpper(account.User.FirstName + " " + account.User.LastName)
+	}
+
+	expiry := time.Now().AddDate(cardValidityYears, 0, 0)
+	cvv := utils.GenerateCVV()
+
+	card := &models.Card{
+		ExpiryMonth:    int(expiry.Month()),
+		ExpiryYear:     expiry.Year(),
+		CardholderName: cardholderName,
+		Status:         models.CardStatusActive,
+		AllowOnline:    true,
+		AllowATM:       true,
+		AccountID:      account.ID,
+		UserID:         userUUID,
+	}
+	applyCardControls(card, controls)
+
+	if card.PerTransactionLimit < 0 || card.DailyLimit < 0 {
+		return nil, "", "", errors.New("card limits cannot be negative")
+	}
+
+	bin, err := utils.CardBIN()
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	var pan string
+	for attempt := 0; attempt < maxCardIssueAttempts; attempt++ {
+		pan = utils.GenerateCardNumber(bin)
+
+		panHash, err := utils.HashCardData(pan)
+		if err != nil {
+			return nil, "", "", err
+		}
+
+		var count int64
+		if err := s.db.Model(&models.Card{}).Where("pan_hash = ?", panHash).Count(&count).Error; err != nil {
+			return nil, "", "", fmt.Errorf("failed to check card number: %v", err)
+		}
+		if count == 0 {
+			card.PANHash = panHash
+			break
+		}
+	}
+
+	if card.PANHash == "" {
+		return nil, "", "", errors.New("failed to generate a unique card number")
+	}
+
+	card.Token = utils.GenerateCardToken()
+	card.Last4 = pan[len(pan)-4:]
+
+	cvvHash, err := utils.HashCardData(card.Token + ":" + cvv)
+	if err != nil {
+		return nil, "", "", err
+	}
+	card.CVVHash = cvvHash
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(card).Error; err != nil {
+			return fmt.Errorf("failed to create card: %v", err)
+		}
+		return recordAudit(tx, actor, models.AuditActionCardIssue, models.AuditTargetCard, card.ID.String(), nil, auditCard(card))
+	}); err != nil {
+		return nil, "", "", err
+	}
+
+	return card, pan, cvv, nil
+}
+
+func (s *CardService) GetCardByID(cardID string) (*models.Card, error) {
+	var card models.Card
+
+	id, err := uuid.Parse(cardID)
+	if err != nil {
+		return nil, errors.New("invalid card ID")
+	}
+
+	if err := s.db.Where("id = ?", id).First(&card).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("card not found")
+		}
+		return nil, fmt.Errorf("failed to find card: %v", err)
+	}
+
+	return &card, nil
+}
+
+func (s *CardService) GetCardsByUserID(userID string) ([]models.Card, error) {
+	var cards []models.Card
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if err := s.db.Where("user_id = ?", userUUID).Order("created_at DESC").Find(&cards).Error; err != nil {
+		return nil, fmt.Errorf("failed to find cards: %v", err)
+	}
+
+	return cards, nil
+}
+
+func (s *CardService) ValidateCardOwnership(cardID, userID string) error {
+	cardUUID, err := uuid.Parse(cardID)
+	if err != nil {
+		return errors.New("invalid card ID")
+	}
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	var count int64
+	if err := s.db.Model(&models.Card{}).Where("id = ? AND user_id = ?", cardUUID, userUUID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to validate card ownership: %v", err)
+	}
+
+	if count == 0 {
+		return errors.New("card not found or access denied")
+	}
+
+	return nil
+}
+
+func (s *CardService) UpdateControls(cardID string, controls CardControls, actor AuditActor) (*models.Card, error) {
+	card, err := s.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	before := auditCard(card)
+	applyCardControls(card, controls)
+
+	if card.PerTransactionLimit < 0 || card.DailyLimit < 0 {
+		return nil, errors.New("card limits cannot be negative")
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(card).Select("per_transaction_limit", "daily_limit", "allow_online", "allow_atm").Updates(card).Error; err != nil {
+			return fmt.Errorf("failed to update card controls: %v", err)
+		}
+		return recordAudit(tx, actor, models.AuditActionCardControls, models.AuditTargetCard, card.ID.String(), before, auditCard(card))
+	}); err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+func (s *CardService) FreezeCard(cardID string, actor AuditActor) (*models.Card, error) {
+	return s.changeStatus(cardID, models.CardStatusActive, models.CardStatusBlocked, models.AuditActionCardFreeze, actor)
+}
+
+func (s *CardService) UnfreezeCard(cardID string, actor AuditActor) (*models.Card, error) {
+	return s.changeStatus(cardID, models.CardStatusBlocked, models.CardStatusActive, models.AuditActionCardUnfreeze, actor)
+}
+
+func (s *CardService) ReportLost(cardID string, actor AuditActor) (*models.Card, error) {
+	card, err := s.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Status == models.CardStatusLost {
+		return nil, errors.New("card is already reported as lost")
+	}
+
+	if err := s.updateStatus(card, models.CardStatusLost, models.AuditActionCardLost, actor); err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+func (s *CardService) changeStatus(cardID string, from, to models.CardStatus, action models.AuditAction, actor AuditActor) (*models.Card, error) {
+	card, err := s.GetCardByID(cardID)
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Status != from {
+		return nil, fmt.Errorf("card cannot be changed from %s to %s", card.Status, to)
+	}
+
+	if err := s.updateStatus(card, to, action, actor); err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+func (s *CardService) updateStatus(card *models.Card, status models.CardStatus, action models.AuditAction, actor AuditActor) error {
+	before := auditCard(card)
+
+	updates := map[string]interface{}{"status": status}
+	if status == models.CardStatusActive {
+		updates["cvv_failures"] = 0
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(card).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update card status: %v", err)
+		}
+		card.Status = status
+		if status == models.CardStatusActive {
+			card.CVVFailures = 0
+		}
+
+		return recordAudit(tx, actor, action, models.AuditTargetCard, card.ID.String(), before, auditCard(card))
+	})
+}
+
+func (s *CardService) Authorize(req CardAuthorizationRequest, actor AuditActor) (*models.Hold, error) {
+	if req.Amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+
+	panHash, err := utils.HashCardData(req.PAN)
+	if err != nil {
+		return nil, err
+	}
+
+	var card models.Card
+	if err := s.db.Where("pan_hash = ?", panHash).First(&card).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, &DeclineError{Reason: DeclineInvalidCard}
+		}
+		return nil, fmt.Errorf("failed to find card: %v", err)
+	}
+
+	var hold *models.Hold
+	var declined error
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		// The card lock serialises authorizations on the card, so the
+		// retransmission lookup and the CVV failure count cannot race.
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", card.ID).First(&card).Error; err != nil {
+			return fmt.Errorf("failed to find card: %v", err)
+		}
+
+		if req.Reference != "" {
+			existing, err := findNetworkHold(tx, card.ID, req.Reference)
+			if err != nil {
+				return err
+			}
+			if existing != nil {
+				hold = existing
+				return nil
+			}
+		}
+
+		if err := s.checkCard(&card, req); err != nil {
+			var decline *DeclineError
+			if errors.As(err, &decline) && decline.Reason == DeclineInvalidCVV {
+				// The failure has to be committed, so the decline is
+				// returned once the transaction is done.
+				declined = err
+				return recordCVVFailure(tx, &card, actor)
+			}
+			return err
+		}
+
+		if cvvChecked(req) && card.CVVFailures > 0 {
+			if err := tx.Model(&card).Update("cvv_failures", 0).Error; err != nil {
+				return fmt.Errorf("failed to reset CVV failures: %v", err)
+			}
+		}
+
+		var account models.Account
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND is_active = ?", card.AccountID, true).
+			First(&account).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return &DeclineError{Reason: DeclineAccountUnavailable}
+			}
+			return fmt.Errorf("failed to find account: %v", err)
+		}
+
+		if account.IsFrozen {
+			return &DeclineError{Reason: DeclineAccountUnavailable}
+		}
+
+		if req.Currency != "" && req.Currency != account.Currency {
+			return &DeclineError{Reason: DeclineCurrencyMismatch}
+		}
+
+		if card.DailyLimit > 0 {
+			spent, err := cardSpendSince(tx, card.ID, startOfDay(time.Now()))
+			if err != nil {
+				return err
+			}
+			if spent+req.Amount > card.DailyLimit {
+				return &DeclineError{Reason: DeclineExceedsDailyLimit}
+			}
+		}
+
+		held, err := heldAmount(tx, account.ID)
+		if err != nil {
+			return err
+		}
+		if account.Balance-held < req.Amount {
+			return &DeclineError{Reason: DeclineInsufficientFunds}
+		}
+
+		hold = &models.Hold{
+			AccountID:         account.ID,
+			CardID:            &card.ID,
+			Amount:            req.Amount,
+			AuthorizedAmount:  req.Amount,
+			Currency:          account.Currency,
+			Status:            models.HoldStatusActive,
+			AuthorizationCode: utils.GenerateAuthorizationCode(),
+			MerchantName:      req.MerchantName,
+			Channel:           req.Channel,
+			NetworkReference:  req.Reference,
+			STAN:              req.STAN,
+			ExpiresAt:         time.Now().Add(cardHoldTTL()),
+		}
+
+		if err := tx.Create(hold).Error; err != nil {
+			return fmt.Errorf("failed to create hold: %v", err)
+		}
+
+		return recordAudit(tx, actor, models.AuditActionHoldAuthorize, models.AuditTargetHold, hold.ID.String(), nil, auditHold(hold))
+	})
+	if err != nil {
+		var decline *DeclineError
+		if req.Reference != "" && !errors.As(err, &decline) {
+			// Another authorization with the same reference won the unique
+			// index, so answer with its hold.
+			if existing, findErr := findNetworkHold(s.db, card.ID, req.Reference); findErr == nil && existing != nil {
+				return existing, nil
+			}
+		}
+		return nil, err
+	}
+	if declined != nil {
+		return nil, declined
+	}
+
+	return hold, nil
+}
+
+func findNetworkHold(tx *gorm.DB, cardID uuid.UUID, reference string) (*models.Hold, error) {
+	var hold models.Hold
+	if err := tx.Where("card_id = ? AND network_reference = ?", cardID, reference).First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to find authorization: %v", err)
+	}
+	return &hold, nil
+}
+
+// recordCVVFailure counts a wrong CVV against the card and blocks it once
+// CARD_CVV_MAX_FAILURES is reached, so the CVV cannot be brute forced through
+// repeated authorizations. Unfreezing the card clears the count.
+func recordCVVFailure(tx *gorm.DB, card *models.Card, actor AuditActor) error {
+	before := auditCard(card)
+
+	if !countCVVFailure(card, cardCVVMaxFailures()) {
+		if err := tx.Model(card).Update("cvv_failures", card.CVVFailures).Error; err != nil {
+			return fmt.Errorf("failed to record CVV failure: %v", err)
+		}
+		return nil
+	}
+
+	if err := tx.Model(card).Updates(map[string]interface{}{
+		"cvv_failures": card.CVVFailures,
+		"status":       card.Status,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to block card: %v", err)
+	}
+
+	return recordAudit(tx, actor, models.AuditActionCardCVVLockout, models.AuditTargetCard, card.ID.String(), before, auditCard(card))
+}
+
+// countCVVFailure adds a failure to the card and blocks it once maxFailures
+// is reached. It reports whether the card was blocked.
+func countCVVFailure(card *models.Card, maxFailures int) bool {
+	card.CVVFailures++
+	if card.CVVFailures < maxFailures {
+		return false
+	}
+	card.Status = models.CardStatusBlocked
+	return true
+}
+
+func cvvChecked(req CardAuthorizationRequest) bool {
+	return req.CVV != "" || req.Channel == models.CardChannelOnline
+}
+
+func cardCVVMaxFailures() int {
+	return envInt("CARD_CVV_MAX_FAILURES", defaultCVVMaxFailures)
+}
+
+func (s *CardService) Capture(holdID string, amount float64, actor AuditActor) (*models.Transaction, error) {
+	id, err := uuid.Parse(holdID)
+	if err != nil {
+		return nil, errors.New("invalid authorization ID")
+	}
+
+	var transaction *models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var hold models.Hold
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&hold).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("authorization not found")
+			}
+			return fmt.Errorf("failed to find authorization: %v", err)
+		}
+
+		if hold.Status != models.HoldStatusActive {
+			return fmt.Errorf("authorization is already %s", hold.Status)
+		}
+
+		// An expired hold no longer reserves funds, so capturing it could
+		// overdraw the account.
+		if !time.Now().Before(hold.ExpiresAt) {
+			return ErrAuthorizationExpired
+		}
+
+		if amount <= 0 {
+			amount = hold.Amount
+		}
+		if amount > hold.Amount {
+			return errors.New("capture amount exceeds authorized amount")
+		}
+
+		var account models.Account
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", hold.AccountID).First(&account).Error; err != nil {
+			return fmt.Errorf("failed to find account: %v", err)
+		}
+
+		if account.IsFrozen {
+			return ErrAccountFrozen
+		}
+
+		transaction = &models.Transaction{
+			TransactionID: utils.GenerateTransactionID(),
+			Type:          models.TransactionTypeCardPayment,
+			Amount:        amount,
+			Currency:      account.Currency,
+			Status:        models.TransactionStatusCompleted,
+			Description:   strings.TrimSpace("Card payment " + hold.MerchantName),
+			AccountID:     account.ID,
+			BalanceBefore: account.Balance,
+			BalanceAfter:  account.Balance - amount,
+		}
+
+		if err := tx.Create(transaction).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %v", err)
+		}
+
+		if err := tx.Model(&models.Account{}).Where("id = ?", account.ID).Update("balance", transaction.BalanceAfter).Error; err != nil {
+			return fmt.Errorf("failed to update account balance: %v", err)
+		}
+
+		if err := tx.Model(&hold).Updates(map[string]interface{}{
+			"status":          models.HoldStatusCaptured,
+			"captured_amount": amount,
+			"transaction_id":  transaction.ID,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update authorization: %v", err)
+		}
+
+		after := auditTransaction(transaction)
+		after["hold_id"] = hold.ID
+		return recordAudit(tx, actor, models.AuditActionCardCapture, models.AuditTargetTransaction, transaction.ID.String(), nil, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	categorizeCommitted(s.db, transaction)
+
+	return transaction, nil
+}
+
+func (s *CardService) Reverse(holdID string, amount float64, actor AuditActor) (*models.Hold, error) {
+	id, err := uuid.Parse(holdID)
+	if err != nil {
+		return nil, errors.New("invalid authorization ID")
+	}
+
+	var hold models.Hold
+	var refund *models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockHold(tx, id, &hold); err != nil {
+			return err
+		}
+
+		if hold.Status != models.HoldStatusActive && hold.Status != models.HoldStatusCaptured {
+			return fmt.Errorf("authorization is already %s", hold.Status)
+		}
+
+		var err error
+		refund, err = reverseHold(tx, &hold, amount, actor)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if refund != nil {
+		categorizeCommitted(s.db, refund)
+	}
+
+	return &hold, nil
+}
+
+// ReverseNetwork applies a reversal from the card network that leaves
+// remaining outstanding on the hold, zero meaning a full reversal. Acquirers
+// retransmit reversals until they see a response, so repeats, and reversals
+// of holds that are already released, succeed without changing anything.
+func (s *CardService) ReverseNetwork(holdID, stan string, remaining float64, actor AuditActor) (*models.Hold, error) {
+	id, err := uuid.Parse(holdID)
+	if err != nil {
+		return nil, errors.New("invalid authorization ID")
+	}
+
+	var hold models.Hold
+	var refund *models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := lockHold(tx, id, &hold); err != nil {
+			return err
+		}
+
+		amount := hold.NetworkReversal(stan, remaining)
+		if amount == 0 {
+			return nil
+		}
+
+		var err error
+		if refund, err = reverseHold(tx, &hold, amount, actor); err != nil {
+			return err
+		}
+
+		hold.ReversalSTAN = stan
+		if err := tx.Model(&models.Hold{}).Where("id = ?", hold.ID).Update("reversal_stan", stan).Error; err != nil {
+			return fmt.Errorf("failed to update authorization: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if refund != nil {
+		categorizeCommitted(s.db, refund)
+	}
+
+	return &hold, nil
+}
+
+func lockHold(tx *gorm.DB, id uuid.UUID, hold *models.Hold) error {
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("authorization not found")
+		}
+		return fmt.Errorf("failed to find authorization: %v", err)
+	}
+	return nil
+}
+
+// reverseHold takes amount off an active or captured hold, releasing it
+// entirely when amount is zero or covers what is left. Captured holds are
+// refunded to the account.
+func reverseHold(tx *gorm.DB, hold *models.Hold, amount float64, actor AuditActor) (*models.Transaction, error) {
+	if hold.Status == models.HoldStatusCaptured {
+		refund, err := refundCapturedHold(tx, hold, amount)
+		if err != nil {
+			return nil, err
+		}
+
+		after := auditTransaction(refund)
+		after["hold_id"] = hold.ID
+		return refund, recordAudit(tx, actor, models.AuditActionCardRefund, models.AuditTargetTransaction, refund.ID.String(), nil, after)
+	}
+
+	before := auditHold(hold)
+	updates := map[string]interface{}{}
+	if amount <= 0 || amount >= hold.Amount {
+		updates["status"] = models.HoldStatusReleased
+		hold.Status = models.HoldStatusReleased
+	} else {
+		updates["amount"] = hold.Amount - amount
+		hold.Amount -= amount
+	}
+
+	if err := tx.Model(&models.Hold{}).Where("id = ?", hold.ID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update authorization: %v", err)
+	}
+
+	return nil, recordAudit(tx, actor, models.AuditActionHoldRelease, models.AuditTargetHold, hold.ID.String(), before, auditHold(hold))
+}
+
+// GetHoldForReversal finds the authorization a reversal refers to. References
+// are only unique per acquirer, so the lookup is scoped to the card.
+func (s *CardService) GetHoldForReversal(pan, reference string) (*models.Hold, error) {
+	var hold models.Hold
+
+	if pan == "" || reference == "" {
+		return nil, errors.New("card number and network reference are required")
+	}
+
+	panHash, err := utils.HashCardData(pan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash card number: %v", err)
+	}
+
+	var card models.Card
+	if err := s.db.Where("pan_hash = ?", panHash).First(&card).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("authorization not found")
+		}
+		return nil, fmt.Errorf("failed to find card: %v", err)
+	}
+
+	if err := s.db.Where("card_id = ? AND network_reference = ?", card.ID, reference).Order("created_at DESC").First(&hold).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("authorization not found")
+		}
+		return nil, fmt.Errorf("failed to find authorization: %v", err)
+	}
+
+	return &hold, nil
+}
+
+func (s *CardService) checkCard(card *models.Card, req CardAuthorizationRequest) error {
+	switch card.Status {
+	case models.CardStatusLost:
+		return &DeclineError{Reason: DeclineCardLost}
+	case models.CardStatusBlocked:
+		return &DeclineError{Reason: DeclineCardBlocked}
+	}
+
+	if req.ExpiryMonth != 0 && (req.ExpiryMonth != card.ExpiryMonth || req.ExpiryYear != card.ExpiryYear) {
+		return &DeclineError{Reason: DeclineInvalidCard}
+	}
+
+	if card.IsExpired(time.Now()) {
+		return &DeclineError{Reason: DeclineExpiredCard}
+	}
+
+	if cvvChecked(req) {
+		cvvHash, err := utils.HashCardData(card.Token + ":" + req.CVV)
+		if err != nil {
+			return err
+		}
+		if cvvHash != card.CVVHash {
+			return &DeclineError{Reason: DeclineInvalidCVV}
+		}
+	}
+
+	if (req.Channel == models.CardChannelOnline && !card.AllowOnline) || (req.Channel == models.CardChannelATM && !card.AllowATM) {
+		return &DeclineError{Reason: DeclineChannelNotAllowed}
+	}
+
+	if card.PerTransactionLimit > 0 && req.Amount > card.PerTransactionLimit {
+		return &DeclineError{Reason: DeclineExceedsTxLimit}
+	}
+
+	return nil
+}
+
+func refundCapturedHold(tx *gorm.DB, hold *models.Hold, amount float64) (*models.Transaction, error) {
+	if amount <= 0 || amount > hold.CapturedAmount {
+		amount = hold.CapturedAmount
+	}
+
+	var account models.Account
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", hold.AccountID).First(&account).Error; err != nil {
+		return nil, fmt.Errorf("failed to find account: %v", err)
+	}
+
+	transaction := &models.Transaction{
+		TransactionID: utils.GenerateTransactionID(),
+		Type:          models.TransactionTypeCardRefund,
+		Amount:        amount,
+		Currency:      account.Currency,
+		Status:        models.TransactionStatusCompleted,
+		Description:   strings.TrimSpace("Card refund " + hold.MerchantName),
+		AccountID:     account.ID,
+		BalanceBefore: account.Balance,
+		BalanceAfter:  account.Balance + amount,
+	}
+
+	if err := tx.Create(transaction).Error; err != nil {
+		return nil, fmt.Errorf("failed to create transaction: %v", err)
+	}
+
+	if err := tx.Model(&models.Account{}).Where("id = ?", account.ID).Update("balance", transaction.BalanceAfter).Error; err != nil {
+		return nil, fmt.Errorf("failed to update account balance: %v", err)
+	}
+
+	hold.CapturedAmount -= amount
+	updates := map[string]interface{}{"captured_amount": hold.CapturedAmount}
+	if hold.CapturedAmount <= 0 {
+		hold.Status = models.HoldStatusReleased
+		updates["status"] = models.HoldStatusReleased
+	}
+
+	if err := tx.Model(&models.Hold{}).Where("id = ?", hold.ID).Updates(updates).Error; err != nil {
+		return nil, fmt.Errorf("failed to update authorization: %v", err)
+	}
+
+	return transaction, nil
+}
+
+func applyCardControls(card *models.Card, controls CardControls) {
+	if controls.PerTransactionLimit != nil {
+		card.PerTransactionLimit = *controls.PerTransactionLimit
+	}
+	if controls.DailyLimit != nil {
+		card.DailyLimit = *controls.DailyLimit
+	}
+	if controls.AllowOnline != nil {
+		card.AllowOnline = *controls.AllowOnline
+	}
+	if controls.AllowATM != nil {
+		card.AllowATM = *controls.AllowATM
+	}
+}
+
+func cardSpendSince(db *gorm.DB, cardID uuid.UUID, since time.Time) (float64, error) {
+	var total float64
+	if err := db.Model(&models.Hold{}).
+		Where("card_id = ? AND created_at >= ? AND status IN ?", cardID, since, []models.HoldStatus{models.HoldStatusActive, models.HoldStatusCaptured}).
+		Select("COALESCE(SUM(CASE WHEN status = ? THEN captured_amount ELSE amount END), 0)", models.HoldStatusCaptured).
+		Scan(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to calculate card spend: %v", err)
+	}
+
+	return total, nil
+}
+
+func cardHoldTTL() time.Duration {
+	if ttl, err := time.ParseDuration(os.Getenv("CARD_HOLD_TTL")); err == nil && ttl > 0 {
+		return ttl
+	}
+	return defaultCardHoldTTL
+}
+
+func startOfDay(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/azainwork/core-banking-api/models"
+)
+
+func TestCountCVVFailure(t *testing.T) {
+	tests := []struct {
+		env         string
+		maxFailures int
+	}{
+		{"", 3},
+		{"5", 5},
+		{"0", 3},
+		{"many", 3},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("CARD_CVV_MAX_FAILURES", tt.env)
+		if got := cardCVVMaxFailures(); got != tt.maxFailures {
+			t.Fatalf("CARD_CVV_MAX_FAILURES=%q: cardCVVMaxFailures = %d, want %d", tt.env, got, tt.maxFailures)
+		}
+
+		card := &models.Card{Status: models.CardStatusActive}
+		for i := 1; i < tt.maxFailures; i++ {
+			if countCVVFailure(card, tt.maxFailures) || card.Status != models.CardStatusActive {
+				t.Fatalf("CARD_CVV_MAX_FAILURES=%q: failure %d blocked the card", tt.env, i)
+			}
+		}
+		if !countCVVFailure(card, tt.maxFailures) || card.Status != models.CardStatusBlocked {
+			t.Fatalf("CARD_CVV_MAX_FAILURES=%q: failure %d did not block the card", tt.env, tt.maxFailures)
+		}
+		if card.CVVFailures != tt.maxFailures {
+			t.Errorf("CARD_CVV_MAX_FAILURES=%q: CVVFailures = %d, want %d", tt.env, card.CVVFailures, tt.maxFailures)
+		}
+	}
+}
+
+func TestCVVChecked(t *testing.T) {
+	tests := []struct {
+		req  CardAuthorizationRequest
+		want bool
+	}{
+		{CardAuthorizationRequest{Channel: models.CardChannelPOS}, false},
+		{CardAuthorizationRequest{Channel: models.CardChannelPOS, CVV: "123"}, true},
+		{CardAuthorizationRequest{Channel: models.CardChannelOnline}, true},
+	}
+
+	for _, tt := range tests {
+		if got := cvvChecked(tt.req); got != tt.want {
+			t.Errorf("cvvChecked(%+v) = %v, want %v", tt.req, got, tt.want)
+		}
+	}
+}
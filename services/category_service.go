@@ -0,0 +1,508 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrCategoryNotFound = errors.New("category not found")
+
+var digitRuns = regexp.MustCompile(`\d+`)
+
+type CategoryService struct {
+	db *gorm.DB
+}
+
+func NewCategoryService(db *gorm.DB) *CategoryService {
+	return &CategoryService{db: db}
+}
+
+type CategoryRuleInput struct {
+	Category              string
+	DescriptionPattern    string
+	CounterpartyAccountID string
+	TransactionType       models.TransactionType
+	Direction             string
+	MinAmount             *float64
+	MaxAmount             *float64
+	Priority              int
+}
+
+type CategorySpending struct {
+	Slug    string              `json:"slug"`
+	Name    string              `json:"name"`
+	Kind    models.CategoryKind `json:"kind"`
+	Debits  float64             `json:"debits"`
+	Credits float64             `json:"credits"`
+	Count   int                 `json:"count"`
+	Share   float64             `json:"share"`
+}
+
+type SpendingSummary struct {
+	Month         string             `json:"month"`
+	TotalSpending float64            `json:"total_spending"`
+	TotalIncome   float64            `json:"total_income"`
+	Categories    []CategorySpending `json:"categories"`
+}
+
+func (s *CategoryService) GetCategories() ([]models.Category, error) {
+	var categories []models.Category
+
+	if err := s.db.Order("kind ASC, name ASC").Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to find categories: %v", err)
+	}
+
+	return categories, nil
+}
+
+func (s *CategoryService) GetRules(userID string) ([]models.CategoryRule, error) {
+	var rules []models.CategoryRule
+
+	if err := s.db.Preload("Category").
+		Where("user_id = ?", userID).
+		Order("priority DESC, updated_at DESC").
+		Find(&rules).Error; err != nil {
+		return nil, fmt.Errorf("failed to find category rules: %v", err)
+	}
+
+	return rules, nil
+}
+
+func (s *CategoryService) CreateRule(userID string, input CategoryRuleInput, actor AuditActor) (*models.CategoryRule, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	category, err := s.findCategory(input.Category)
+	if err != nil {
+		return nil, err
+	}
+
+	rule := &models.CategoryRule{
+		UserID:             &userUUID,
+		CategoryID:         category.ID,
+		Source:             models.CategoryRuleSourceUser,
+		Priority:           input.Priority,
+		DescriptionPattern: strings.TrimSpace(input.DescriptionPattern),
+		TransactionType:    input.TransactionType,
+		Direction:          input.Direction,
+		MinAmount:          input.MinAmount,
+		MaxAmount:          input.MaxAmount,
+	}
+
+	if rule.DescriptionPattern != "" {
+		if _, err := regexp.Compile("(?i)" + rule.DescriptionPattern); err != nil {
+			return nil, errors.New("invalid description pattern")
+		}
+	}
+
+	if input.CounterpartyAccountID != "" {
+		counterparty, err := uuid.Parse(input.CounterpartyAccountID)
+		if err != nil {
+			return nil, errors.New("invalid counterparty account ID")
+		}
+		rule.CounterpartyAccountID = &counterparty
+	}
+
+	if rule.Direction != "" && rule.Direction != models.CategoryDirectionCredit && rule.Direction != models.CategoryDirectionDebit {
+		return nil, errors.New("direction must be credit or debit")
+	}
+
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return nil, errors.New("min_amount cannot be greater than max_amount")
+	}
+
+	if rule.DescriptionPattern == "" && rule.CounterpartyAccountID == nil && rule.TransactionType == "" &&
+		rule.Direction == "" && rule.MinAmount == nil && rule.MaxAmount == nil {
+		return nil, errors.New("rule must have at least one condition")
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rule).Error; err != nil {
+			return fmt.Errorf("failed to create category rule: %v", err)
+		}
+
+		return recordAudit(tx, actor, models.AuditActionCategoryRuleCreate, models.AuditTargetCategoryRule, rule.ID.String(), nil, auditCategoryRule(rule))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rule.Category = *category
+	return rule, nil
+}
+
+func (s *CategoryService) DeleteRule(userID, ruleID string, actor AuditActor) error {
+	id, err := uuid.Parse(ruleID)
+	if err != nil {
+		return errors.New("invalid rule ID")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var rule models.CategoryRule
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ? AND user_id = ?", id, userID).First(&rule).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("category rule not found")
+			}
+			return fmt.Errorf("failed to find category rule: %v", err)
+		}
+
+		if err := tx.Delete(&rule).Error; err != nil {
+			return fmt.Errorf("failed to delete category rule: %v", err)
+		}
+
+		return recordAudit(tx, actor, models.AuditActionCategoryRuleDelete, models.AuditTargetCategoryRule, rule.ID.String(), auditCategoryRule(&rule), nil)
+	})
+}
+
+func (s *CategoryService) GetTransactionCategories(accountID string, transactionIDs []uuid.UUID) (map[uuid.UUID]models.Category, error) {
+	categories := make(map[uuid.UUID]models.Category, len(transactionIDs))
+	if len(transactionIDs) == 0 {
+		return categories, nil
+	}
+
+	var entries []models.TransactionCategory
+	if err := s.db.Preload("Category").
+		Where("account_id = ? AND transaction_id IN ?", accountID, transactionIDs).
+		Find(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to find transaction categories: %v", err)
+	}
+
+	for _, entry := range entries {
+		categories[entry.TransactionID] = entry.Category
+	}
+
+	return categories, nil
+}
+
+// RecategorizeTransaction overrides the category of the account's side of a
+// transaction. When learn is set, a rule matching the same counterparty or
+// description is stored for the account owner so that future transactions
+// land in the same category.
+func (s *CategoryService) RecategorizeTransaction(accountID, transactionID, categorySlug string, learn bool, actor AuditActor) (*models.TransactionCategory, *models.CategoryRule, error) {
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, nil, errors.New("invalid account ID")
+	}
+
+	transactionUUID, err := uuid.Parse(transactionID)
+	if err != nil {
+		return nil, nil, errors.New("invalid transaction ID")
+	}
+
+	category, err := s.findCategory(categorySlug)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var account models.Account
+	if err := s.db.Where("id = ?", accountUUID).First(&account).Error; err != nil {
+		return nil, nil, errors.New("account not found")
+	}
+
+	var transaction models.Transaction
+	if err := s.db.Where("id = ? AND (account_id = ? OR to_account_id = ?)", transactionUUID, accountUUID, accountUUID).
+		First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrTransactionNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to find transaction: %v", err)
+	}
+
+	entry := &models.TransactionCategory{
+		TransactionID: transaction.ID,
+		AccountID:     accountUUID,
+		CategoryID:    category.ID,
+		Source:        models.CategorizationSourceManual,
+	}
+
+	var rule *models.CategoryRule
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "account_id"}, {Name: "transaction_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"category_id": category.ID, "rule_id": nil, "source": models.CategorizationSourceManual, "updated_at": time.Now()}),
+		}).Create(entry).Error; err != nil {
+			return fmt.Errorf("failed to categorize transaction: %v", err)
+		}
+		if err := tx.Where("account_id = ? AND transaction_id = ?", accountUUID, transaction.ID).First(entry).Error; err != nil {
+			return fmt.Errorf("failed to find transaction category: %v", err)
+		}
+
+		if !learn {
+			return nil
+		}
+
+		learned, err := s.learnRule(tx, account.UserID, &transaction, accountUUID, category.ID, actor)
+		rule = learned
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	entry.Category = *category
+	if rule != nil {
+		rule.Category = *category
+	}
+
+	return entry, rule, nil
+}
+
+func (s *CategoryService) GetSpendingSummary(accountID string, month time.Time) (*SpendingSummary, error) {
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	from := time.Date(month.Year(), month.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 1, 0)
+
+	if err := s.categorizeMissing(accountUUID, from, to); err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Slug    string
+		Name    string
+		Kind    models.CategoryKind
+		Debits  float64
+		Credits float64
+		Count   int
+	}
+	if err := s.db.Table("transactions").
+		Select("categories.slug, categories.name, categories.kind, "+
+			"COALESCE(SUM(CASE WHEN signed < 0 THEN -signed ELSE 0 END), 0) AS debits, "+
+			"COALESCE(SUM(CASE WHEN signed > 0 THEN signed ELSE 0 END), 0) AS credits, "+
+			"COUNT(*) AS count").
+		Joins("CROSS JOIN LATERAL (SELECT "+signedAmountSQL+" AS signed) AS entry", accountUUID, models.CreditTransactionTypes).
+		Joins("JOIN transaction_categories ON transaction_categories.transaction_id = transactions.id AND transaction_categories.account_id = ?", accountUUID).
+		Joins("JOIN categories ON categories.id = transaction_categories.category_id").
+		Where("transactions.status = ? AND transactions.deleted_at IS NULL", models.TransactionStatusCompleted).
+		Where("transactions.value_date >= ? AND transactions.value_date < ?", from, to).
+		Group("categories.slug, categories.name, categories.kind").
+		Order("debits DESC, credits DESC").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to summarize spending: %v", err)
+	}
+
+	summary := &SpendingSummary{
+		Month:      from.Format("2006-01"),
+		Categories: make([]CategorySpending, 0, len(rows)),
+	}
+	for _, row := range rows {
+		switch row.Kind {
+		case models.CategoryKindExpense:
+			summary.TotalSpending += row.Debits - row.Credits
+		case models.CategoryKindIncome:
+			summary.TotalIncome += row.Credits - row.Debits
+		}
+	}
+
+	for _, row := range rows {
+		spending := CategorySpending{
+			Slug:    row.Slug,
+			Name:    row.Name,
+			Kind:    row.Kind,
+			Debits:  roundAmount(row.Debits),
+			Credits: roundAmount(row.Credits),
+			Count:   row.Count,
+		}
+		if row.Kind == models.CategoryKindExpense && summary.TotalSpending > 0 {
+			spending.Share = roundAmount((row.Debits - row.Credits) / summary.TotalSpending * 100)
+		}
+		summary.Categories = append(summary.Categories, spending)
+	}
+
+	summary.TotalSpending = roundAmount(summary.TotalSpending)
+	summary.TotalIncome = roundAmount(summary.TotalIncome)
+
+	return summary, nil
+}
+
+func (s *CategoryService) categorizeMissing(accountID uuid.UUID, from, to time.Time) error {
+	var transactions []models.Transaction
+	if err := s.db.Where("(account_id = ? OR to_account_id = ?) AND value_date >= ? AND value_date < ?", accountID, accountID, from, to).
+		Where("NOT EXISTS (SELECT 1 FROM transaction_categories WHERE transaction_categories.transaction_id = transactions.id AND transaction_categories.account_id = ?)", accountID).
+		Find(&transactions).Error; err != nil {
+		return fmt.Errorf("failed to find uncategorized transactions: %v", err)
+	}
+
+	for i := range transactions {
+		if err := categorizeEntry(s.db, &transactions[i], accountID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// categorizeCommitted categorizes transactions after the ledger transaction
+// that created them has committed, so a categorization problem can never roll
+// back money movement. A failure only leaves the entry for categorizeMissing.
+func categorizeCommitted(db *gorm.DB, transactions ...*models.Transaction) {
+	for _, transaction := range transactions {
+		if err := categorizeTransaction(db, transaction); err != nil {
+			log.Printf("failed to categorize transaction %s: %v", transaction.ID, err)
+		}
+	}
+}
+
+func categorizeTransaction(db *gorm.DB, t *models.Transaction) error {
+	if err := categorizeEntry(db, t, t.AccountID); err != nil {
+		return err
+	}
+	if t.ToAccountID != nil {
+		return categorizeEntry(db, t, *t.ToAccountID)
+	}
+	return nil
+}
+
+// categorizeEntry assigns a category to one side of a transaction. The
+// owner's rules are tried before the system rules; an existing assignment,
+// including a manual one, is never overwritten.
+func categorizeEntry(db *gorm.DB, t *models.Transaction, accountID uuid.UUID) error {
+	var account models.Account
+	if err := db.Select("id", "user_id").Where("id = ?", accountID).First(&account).Error; err != nil {
+		return fmt.Errorf("failed to find account: %v", err)
+	}
+
+	var rules []models.CategoryRule
+	if err := db.Where("user_id = ? OR user_id IS NULL", account.UserID).
+		Order("user_id IS NULL, priority DESC, updated_at DESC").
+		Find(&rules).Error; err != nil {
+		return fmt.Errorf("failed to find category rules: %v", err)
+	}
+
+	entry := models.TransactionCategory{
+		TransactionID: t.ID,
+		AccountID:     accountID,
+		Source:        models.CategorizationSourceDefault,
+	}
+	for i := range rules {
+		if rules[i].Matches(t, accountID) {
+			entry.CategoryID = rules[i].CategoryID
+			entry.RuleID = &rules[i].ID
+			entry.Source = models.CategorizationSourceRule
+			break
+		}
+	}
+
+	if entry.RuleID == nil {
+		var category models.Category
+		if err := db.Where("slug = ?", models.DefaultCategorySlug(t, accountID)).First(&category).Error; err != nil {
+			return fmt.Errorf("failed to find default category: %v", err)
+		}
+		entry.CategoryID = category.ID
+	}
+
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry)
+	if result.Error != nil {
+		return fmt.Errorf("failed to categorize transaction: %v", result.Error)
+	}
+
+	if entry.RuleID != nil && result.RowsAffected > 0 {
+		if err := db.Model(&models.CategoryRule{}).Where("id = ?", *entry.RuleID).
+			UpdateColumn("match_count", gorm.Expr("match_count + 1")).Error; err != nil {
+			return fmt.Errorf("failed to update category rule: %v", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *CategoryService) learnRule(tx *gorm.DB, userID uuid.UUID, transaction *models.Transaction, accountID, categoryID uuid.UUID, actor AuditActor) (*models.CategoryRule, error) {
+	rule := models.CategoryRule{
+		UserID:          &userID,
+		Source:          models.CategoryRuleSourceLearned,
+		TransactionType: transaction.Type,
+		Direction:       models.CategoryDirectionDebit,
+	}
+	if transaction.SignedAmountFor(accountID) > 0 {
+		rule.Direction = models.CategoryDirectionCredit
+	}
+
+	query := tx.Where("user_id = ? AND source = ? AND transaction_type = ? AND direction = ?", userID, rule.Source, rule.TransactionType, rule.Direction)
+	if counterparty := transaction.CounterpartyFor(accountID); counterparty != nil {
+		rule.CounterpartyAccountID = counterparty
+		query = query.Where("counterparty_account_id = ?", *counterparty)
+	} else {
+		rule.DescriptionPattern = learnedPattern(transaction.Description)
+		if rule.DescriptionPattern == "" {
+			return nil, nil
+		}
+		query = query.Where("counterparty_account_id IS NULL AND description_pattern = ?", rule.DescriptionPattern)
+	}
+
+	var existing models.CategoryRule
+	err := query.First(&existing).Error
+	if err == nil {
+		before := auditCategoryRule(&existing)
+		if err := tx.Model(&existing).Update("category_id", categoryID).Error; err != nil {
+			return nil, fmt.Errorf("failed to update category rule: %v", err)
+		}
+		existing.CategoryID = categoryID
+		if err := recordAudit(tx, actor, models.AuditActionCategoryRuleUpdate, models.AuditTargetCategoryRule, existing.ID.String(), before, auditCategoryRule(&existing)); err != nil {
+			return nil, err
+		}
+		return &existing, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find category rule: %v", err)
+	}
+
+	rule.CategoryID = categoryID
+	if err := tx.Create(&rule).Error; err != nil {
+		return nil, fmt.Errorf("failed to create category rule: %v", err)
+	}
+
+	if err := recordAudit(tx, actor, models.AuditActionCategoryRuleCreate, models.AuditTargetCategoryRule, rule.ID.String(), nil, auditCategoryRule(&rule)); err != nil {
+		return nil, err
+	}
+
+	return &rule, nil
+}
+
+func (s *CategoryService) findCategory(slug string) (*models.Category, error) {
+	var category models.Category
+
+	if err := s.db.Where("slug = ?", strings.ToLower(strings.TrimSpace(slug))).First(&category).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCategoryNotFound
+		}
+		return nil, fmt.Errorf("failed to find category: %v", err)
+	}
+
+	return &category, nil
+}
+
+func learnedPattern(description string) string {
+	fields := strings.Fields(strings.ToLower(description))
+	if len(fields) == 0 {
+		return ""
+	}
+
+	for i, field := range fields {
+		parts := digitRuns.Split(field, -1)
+		for j := range parts {
+			parts[j] = regexp.QuoteMeta(parts[j])
+		}
+		fields[i] = strings.Join(parts, `\d+`)
+	}
+
+	return "^" + strings.Join(fields, `\s+`) + "$"
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
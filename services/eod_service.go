@@ -0,0 +1,431 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type EndOfDayService struct {
+	db *gorm.DB
+}
+
+func NewEndOfDayService(db *gorm.DB) *EndOfDayService {
+	return &EndOfDayService{db: db}
+}
+
+type dailyTotals struct {
+	Currency          string
+	TransactionCount  int
+	TotalDeposits     float64
+	TotalWithdrawals  float64
+	TotalTransfers    float64
+	TotalCardPayments float64
+	TotalCardRefunds  float64
+	TotalFees         float64
+	TotalInterest     float64
+}
+
+type currencyBalance struct {
+	Currency     string
+	AccountCount int
+	Balance      float64
+}
+
+type ledgerBalance struct {
+	Currency string
+	Ledger   string
+	Balance  float64
+}
+
+func (s *EndOfDayService) CurrentBusinessDate() (time.Time, error) {
+	var valueDate time.Time
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		valueDate, err = models.CurrentValueDate(tx)
+		return err
+	})
+	return valueDate, err
+}
+
+func (s *EndOfDayService) CloseBusinessDay(date time.Time, actor AuditActor) (*models.BusinessDay, error) {
+	date = statementDate(date)
+	if date.After(utils.BusinessDate(time.Now())) {
+		return nil, errors.New("cannot close a future business date")
+	}
+
+	day, err := s.lockBusinessDay(date)
+	if err != nil {
+		return nil, err
+	}
+
+	for day.Step != models.BusinessDayStepClosed {
+		var next models.BusinessDayStep
+		switch day.Step {
+		case models.BusinessDayStepLocked:
+			err = s.snapshotBalances(date)
+			next = models.BusinessDayStepBalancesSnapshotted
+		case models.BusinessDayStepBalancesSnapshotted:
+			err = s.generateReport(date)
+			next = models.BusinessDayStepReportGenerated
+		case models.BusinessDayStepReportGenerated:
+			next = models.BusinessDayStepClosed
+		default:
+			return nil, fmt.Errorf("unknown business day step %q", day.Step)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			before := auditBusinessDay(day)
+			updates := map[string]interface{}{"step": next}
+			if next == models.BusinessDayStepClosed {
+				now := time.Now()
+				updates["status"] = models.BusinessDayStatusClosed
+				updates["closed_at"] = now
+				day.Status = models.BusinessDayStatusClosed
+				day.ClosedAt = &now
+			}
+			if err := tx.Model(day).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to update business day: %v", err)
+			}
+			day.Step = next
+
+			if next != models.BusinessDayStepClosed {
+				return nil
+			}
+			return recordAudit(tx, actor, models.AuditActionBusinessDayClose, models.AuditTargetBusinessDay, day.ID.String(), before, auditBusinessDay(day))
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return day, nil
+}
+
+func (s *EndOfDayService) CloseDueBusinessDays(now time.Time) (int, error) {
+	actor := SystemAuditActor("end_of_day")
+
+	var closing []models.BusinessDay
+	if err := s.db.Where("status = ?", models.BusinessDayStatusClosing).Order("date ASC").Find(&closing).Error; err != nil {
+		return 0, fmt.Errorf("failed to find unfinished business days: %v", err)
+	}
+
+	closed := 0
+	for _, day := range closing {
+		if _, err := s.CloseBusinessDay(day.Date, actor); err != nil {
+			return closed, fmt.Errorf("business date %s: %v", day.Date.Format(statementDateFormat), err)
+		}
+		closed++
+	}
+
+	var latest *time.Time
+	if err := s.db.Model(&models.BusinessDay{}).Select("MAX(date)").Scan(&latest).Error; err != nil {
+		return closed, fmt.Errorf("failed to find last business day: %v", err)
+	}
+
+	yesterday := utils.BusinessDate(now).AddDate(0, 0, -1)
+	next := yesterday
+	if latest != nil {
+		next = statementDate(*latest).AddDate(0, 0, 1)
+	}
+
+	for ; !next.After(yesterday); next = next.AddDate(0, 0, 1) {
+		if _, err := s.CloseBusinessDay(next, actor); err != nil {
+			return closed, fmt.Errorf("business date %s: %v", next.Format(statementDateFormat), err)
+		}
+		closed++
+	}
+
+	return closed, nil
+}
+
+func (s *EndOfDayService) GetBusinessDays(limit int) ([]models.BusinessDay, error) {
+	var days []models.BusinessDay
+
+	if err := s.db.Order("date DESC").Limit(limit).Find(&days).Error; err != nil {
+		return nil, fmt.Errorf("failed to find business days: %v", err)
+	}
+
+	return days, nil
+}
+
+func (s *EndOfDayService) GetBusinessDay(date time.Time) (*models.BusinessDay, error) {
+	var day models.BusinessDay
+
+	if err := s.db.
+		Preload("Reports", func(db *gorm.DB) *gorm.DB { return db.Order("currency ASC") }).
+		Preload("TrialBalance", func(db *gorm.DB) *gorm.DB { return db.Order("currency ASC, position ASC") }).
+		Where("date = ?", statementDate(date)).
+		First(&day).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("business day not found")
+		}
+		return nil, fmt.Errorf("failed to find business day: %v", err)
+	}
+
+	return &day, nil
+}
+
+func (s *EndOfDayService) lockBusinessDay(date time.Time) (*models.BusinessDay, error) {
+	var day models.BusinessDay
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("date = ?", date).First(&day).Error
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return fmt.Errorf("failed to find business day: %v", err)
+		}
+
+		var latest *time.Time
+		if err := tx.Model(&models.BusinessDay{}).Select("MAX(date)").Scan(&latest).Error; err != nil {
+			return fmt.Errorf("failed to find last business day: %v", err)
+		}
+		if latest != nil && !statementDate(*latest).Before(date) {
+			return fmt.Errorf("business date %s is not after the last business date %s", date.Format(statementDateFormat), latest.Format(statementDateFormat))
+		}
+
+		day = models.BusinessDay{
+			Date:      date,
+			Status:    models.BusinessDayStatusClosing,
+			Step:      models.BusinessDayStepLocked,
+			StartedAt: time.Now(),
+		}
+		if err := tx.Omit("Reports", "TrialBalance").Create(&day).Error; err != nil {
+			return fmt.Errorf("failed to lock business day: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &day, nil
+}
+
+func (s *EndOfDayService) snapshotBalances(date time.Time) error {
+	end := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, utils.BankLocation()).AddDate(0, 0, 1)
+
+	err := s.db.Exec(`
+		INSERT INTO balance_snapshots (id, account_id, business_date, currency, balance, created_at)
+		SELECT gen_random_uuid(), a.id, @date, a.currency, a.balance - COALESCE((
+			SELECT SUM(CASE WHEN t.to_account_id = a.id THEN t.amount WHEN t.type IN @credits THEN t.amount ELSE -t.amount END)
+			FROM transactions t
+			WHERE (t.account_id = a.id OR t.to_account_id = a.id)
+				AND t.status = @completed AND t.value_date > @date AND t.deleted_at IS NULL
+		), 0), NOW()
+		FROM accounts a
+		WHERE a.deleted_at IS NULL AND a.created_at < @end
+		ON CONFLICT (account_id, business_date) DO NOTHING`,
+		map[string]interface{}{
+			"date":      date,
+			"end":       end,
+			"credits":   models.CreditTransactionTypes,
+			"completed": models.TransactionStatusCompleted,
+		}).Error
+	if err != nil {
+		return fmt.Errorf("failed to snapshot balances: %v", err)
+	}
+
+	return nil
+}
+
+func (s *EndOfDayService) generateReport(date time.Time) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("business_date = ?", date).Delete(&models.DailyReport{}).Error; err != nil {
+			return fmt.Errorf("failed to clear daily report: %v", err)
+		}
+		if err := tx.Where("business_date = ?", date).Delete(&models.TrialBalanceLine{}).Error; err != nil {
+			return fmt.Errorf("failed to clear trial balance: %v", err)
+		}
+
+		var totals []dailyTotals
+		if err := tx.Model(&models.Transaction{}).
+			Select(`currency, COUNT(*) AS transaction_count,
+				COALESCE(SUM(CASE WHEN type = @deposit THEN amount END), 0) AS total_deposits,
+				COALESCE(SUM(CASE WHEN type = @withdraw THEN amount END), 0) AS total_withdrawals,
+				COALESCE(SUM(CASE WHEN type = @transfer THEN amount END), 0) AS total_transfers,
+				COALESCE(SUM(CASE WHEN type = @cardPayment THEN amount END), 0) AS total_card_payments,
+				COALESCE(SUM(CASE WHEN type = @cardRefund THEN amount END), 0) AS total_card_refunds,
+				COALESCE(SUM(CASE WHEN type = @fee THEN amount END), 0) AS total_fees,
+				COALESCE(SUM(CASE WHEN type = @interest THEN amount END), 0) AS total_interest`,
+				map[string]interface{}{
+					"deposit":     models.TransactionTypeDeposit,
+					"withdraw":    models.TransactionTypeWithdraw,
+					"transfer":    models.TransactionTypeTransfer,
+					"cardPayment": models.TransactionTypeCardPayment,
+					"cardRefund":  models.TransactionTypeCardRefund,
+					"fee":         models.TransactionTypeFee,
+					"interest":    models.TransactionTypeInterest,
+				}).
+			Where("status = ? AND value_date = ?", models.TransactionStatusCompleted, date).
+			Group("currency").
+			Scan(&totals).Error; err != nil {
+			return fmt.Errorf("failed to calculate daily totals: %v", err)
+		}
+
+		closing, err := snapshotTotals(tx, date)
+		if err != nil {
+			return err
+		}
+
+		var previous *time.Time
+		if err := tx.Model(&models.BalanceSnapshot{}).Select("MAX(business_date)").Where("business_date < ?", date).Scan(&previous).Error; err != nil {
+			return fmt.Errorf("failed to find previous snapshot: %v", err)
+		}
+		opening := map[string]currencyBalance{}
+		if previous != nil {
+			if opening, err = snapshotTotals(tx, *previous); err != nil {
+				return err
+			}
+		}
+
+		ledgers, err := trialBalanceLedgers(tx, date)
+		if err != nil {
+			return err
+		}
+
+		reports := map[string]*models.DailyReport{}
+		report := func(currency string) *models.DailyReport {
+			if r, ok := reports[currency]; ok {
+				return r
+			}
+			r := &models.DailyReport{BusinessDate: date, Currency: currency}
+			reports[currency] = r
+			return r
+		}
+
+		for _, total := range totals {
+			r := report(total.Currency)
+			r.TransactionCount = total.TransactionCount
+			r.TotalDeposits = total.TotalDeposits
+			r.TotalWithdrawals = total.TotalWithdrawals
+			r.TotalTransfers = total.TotalTransfers
+			r.TotalCardPayments = total.TotalCardPayments
+			r.TotalCardRefunds = total.TotalCardRefunds
+			r.TotalFees = total.TotalFees
+			r.TotalInterest = total.TotalInterest
+		}
+		for currency, balance := range closing {
+			r := report(currency)
+			r.AccountCount = balance.AccountCount
+			r.ClosingBalance = balance.Balance
+		}
+		for currency, balance := range opening {
+			report(currency).OpeningBalance = balance.Balance
+		}
+
+		var lines []models.TrialBalanceLine
+		for _, ledger := range ledgers {
+			r := report(ledger.Currency)
+			line := models.TrialBalanceLine{
+				BusinessDate: date,
+				Currency:     ledger.Currency,
+				Ledger:       ledger.Ledger,
+			}
+
+			if ledger.Balance >= 0 {
+				line.Debit = ledger.Balance
+			} else {
+				line.Credit = -ledger.Balance
+			}
+
+			r.TrialDebits += line.Debit
+			r.TrialCredits += line.Credit
+			lines = append(lines, line)
+		}
+
+		sort.Slice(lines, func(i, j int) bool {
+			if lines[i].Currency != lines[j].Currency {
+				return lines[i].Currency < lines[j].Currency
+			}
+			return lines[i].Ledger < lines[j].Ledger
+		})
+		for i := range lines {
+			lines[i].Position = i + 1
+		}
+
+		for _, r := range reports {
+			r.IsBalanced = math.Abs(r.TrialDebits-r.TrialCredits) < reconciliationTolerance
+			if err := tx.Create(r).Error; err != nil {
+				return fmt.Errorf("failed to create daily report: %v", err)
+			}
+		}
+		if len(lines) > 0 {
+			if err := tx.Create(&lines).Error; err != nil {
+				return fmt.Errorf("failed to create trial balance: %v", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+func snapshotTotals(tx *gorm.DB, date time.Time) (map[string]currencyBalance, error) {
+	var balances []currencyBalance
+	if err := tx.Model(&models.BalanceSnapshot{}).
+		Select("currency, COUNT(*) AS account_count, COALESCE(SUM(balance), 0) AS balance").
+		Where("business_date = ?", date).
+		Group("currency").
+		Scan(&balances).Error; err != nil {
+		return nil, fmt.Errorf("failed to total balance snapshots: %v", err)
+	}
+
+	totals := make(map[string]currencyBalance, len(balances))
+	for _, balance := range balances {
+		totals[balance.Currency] = balance
+	}
+	return totals, nil
+}
+
+func trialBalanceLedgers(tx *gorm.DB, date time.Time) ([]ledgerBalance, error) {
+	var ledgers []ledgerBalance
+
+	cash := []models.TransactionType{models.TransactionTypeDeposit, models.TransactionTypeWithdraw}
+	card := []models.TransactionType{models.TransactionTypeCardPayment, models.TransactionTypeCardRefund}
+
+	if err := tx.Model(&models.Transaction{}).
+		Select(`currency, CASE
+				WHEN type IN @cash THEN 'cash'
+				WHEN type IN @card THEN 'card_settlement'
+				WHEN type = @fee THEN 'fee_income'
+				WHEN type = @opening THEN 'opening_balances'
+				ELSE 'interest_expense'
+			END AS ledger,
+			SUM(CASE WHEN type IN @debits THEN amount ELSE -amount END) AS balance`,
+			map[string]interface{}{
+				"cash":    cash,
+				"card":    card,
+				"fee":     models.TransactionTypeFee,
+				"opening": models.TransactionTypeOpeningBalance,
+				"debits":  []models.TransactionType{models.TransactionTypeDeposit, models.TransactionTypeCardRefund, models.TransactionTypeInterest, models.TransactionTypeOpeningBalance},
+			}).
+		Where("status = ? AND value_date <= ?", models.TransactionStatusCompleted, date).
+		Where("type IN ?", append(append(cash, card...), models.TransactionTypeFee, models.TransactionTypeInterest, models.TransactionTypeOpeningBalance)).
+		Group("currency, ledger").
+		Scan(&ledgers).Error; err != nil {
+		return nil, fmt.Errorf("failed to calculate ledger balances: %v", err)
+	}
+
+	var deposits []ledgerBalance
+	if err := tx.Table("balance_snapshots AS s").
+		Select("s.currency, 'customer_deposits_' || a.type AS ledger, -SUM(s.balance) AS balance").
+		Joins("JOIN accounts a ON a.id = s.account_id").
+		Where("s.business_date = ?", date).
+		Group("s.currency, a.type").
+		Scan(&deposits).Error; err != nil {
+		return nil, fmt.Errorf("failed to calculate customer deposits: %v", err)
+	}
+
+	return append(ledgers, deposits...), nil
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type HoldService struct {
+	db *gorm.DB
+}
+
+func NewHoldService(db *gorm.DB) *HoldService {
+	return &HoldService{db: db}
+}
+
+func (s *HoldService) GetHeldAmount(accountID uuid.UUID) (float64, error) {
+	return heldAmount(s.db, accountID)
+}
+
+func (s *HoldService) GetAvailableBalance(account *models.Account) (float64, error) {
+	held, err := heldAmount(s.db, account.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return account.Balance - held, nil
+}
+
+func (s *HoldService) GetHoldsByAccountID(accountID string) ([]models.Hold, error) {
+	var holds []models.Hold
+
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	if err := s.db.Where("account_id = ? AND status = ? AND expires_at > ?", accountUUID, models.HoldStatusActive, time.Now()).
+		Order("created_at DESC").
+		Find(&holds).Error; err != nil {
+		return nil, fmt.Errorf("failed to find holds: %v", err)
+	}
+
+	return holds, nil
+}
+
+// ExpireHolds marks active holds past their expiry, which no longer count
+// against the available balance, as expired so that they cannot be captured.
+func (s *HoldService) ExpireHolds(now time.Time) (int64, error) {
+	result := s.db.Model(&models.Hold{}).
+		Where("status = ? AND expires_at <= ?", models.HoldStatusActive, now).
+		Update("status", models.HoldStatusExpired)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to expire holds: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func heldAmount(db *gorm.DB, accountID uuid.UUID) (float64, error) {
+	var total float64
+	if err := db.Model(&models.Hold{}).
+		Where("account_id = ? AND status = ? AND expires_at > ?", accountID, models.HoldStatusActive, time.Now()).
+		Select("COALESCE(SUM(amount), 0)").
+		Scan(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to calculate held amount: %v", err)
+	}
+
+	return total, nil
+}
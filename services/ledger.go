@@ -0,0 +1,27 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func completedAccountTransactions(db *gorm.DB, accountID uuid.UUID) *gorm.DB {
+	return db.Model(&models.Transaction{}).
+		Where("(account_id = ? OR to_account_id = ?) AND status = ?", accountID, accountID, models.TransactionStatusCompleted)
+}
+
+const signedAmountSQL = "CASE WHEN to_account_id = ? THEN amount WHEN type IN ? THEN amount ELSE -amount END"
+
+func netMovement(query *gorm.DB, accountID uuid.UUID) (float64, error) {
+	var total float64
+	if err := query.
+		Select("COALESCE(SUM("+signedAmountSQL+"), 0)", accountID, models.CreditTransactionTypes).
+		Scan(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to calculate net movement: %v", err)
+	}
+
+	return total, nil
+}
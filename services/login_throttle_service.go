@@ -0,0 +1,321 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	loginDelayBase = time.Second
+	loginDelayMax  = 30 * time.Second
+)
+
+type LoginThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginThrottledError) Error() string {
+	return "too many failed login attempts, please try again later"
+}
+
+type LoginThrottleService struct {
+	db *gorm.DB
+}
+
+func NewLoginThrottleService(db *gorm.DB) *LoginThrottleService {
+	return &LoginThrottleService{db: db}
+}
+
+func loginMaxFailures() int {
+	return envInt("LOGIN_MAX_FAILURES", 5)
+}
+
+func loginIPMaxFailures() int {
+	return envInt("LOGIN_IP_MAX_FAILURES", 20)
+}
+
+func loginFailureWindow() time.Duration {
+	return envDuration("LOGIN_FAILURE_WINDOW", 15*time.Minute)
+}
+
+func loginLockoutDuration() time.Duration {
+	return envDuration("LOGIN_LOCKOUT_DURATION", 15*time.Minute)
+}
+
+func stepUpMaxFailures() int {
+	return envInt("STEP_UP_MAX_FAILURES", 5)
+}
+
+func envInt(name string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// emailThrottleKey uses the email blind index so that throttle rows do not
+// store addresses in plaintext.
+func emailThrottleKey(email string) string {
+	index, err := models.EmailIndex(email)
+	if err != nil {
+		index = utils.HashToken(strings.ToLower(strings.TrimSpace(email)))
+	}
+	return "email:" + index
+}
+
+func ipThrottleKey(ip string) string {
+	return "ip:" + ip
+}
+
+// stepUpThrottleKey counts wrong transaction PINs and TOTP codes per user
+// rather than per challenge, so that starting a new challenge does not give
+// an attacker a fresh set of guesses.
+func stepUpThrottleKey(userID uuid.UUID, method models.StepUpMethod) string {
+	return "step_up:" + string(method) + ":" + userID.String()
+}
+
+// loginDelay is the wait required after the given number of consecutive
+// failures: none after the first, then doubling from one second.
+func loginDelay(failures int) time.Duration {
+	if failures < 2 {
+		return 0
+	}
+	delay := loginDelayBase << (failures - 2)
+	if delay <= 0 || delay > loginDelayMax {
+		return loginDelayMax
+	}
+	return delay
+}
+
+// Check returns a *LoginThrottledError when the email or IP address is locked
+// out or is still inside the progressive delay after its last failure.
+func (s *LoginThrottleService) Check(email, ip string) error {
+	var throttles []models.LoginThrottle
+	if err := s.db.Where("key IN ?", []string{emailThrottleKey(email), ipThrottleKey(ip)}).Find(&throttles).Error; err != nil {
+		return fmt.Errorf("failed to check login throttle: %v", err)
+	}
+
+	if wait := throttleWait(throttles, time.Now()); wait > 0 {
+		return &LoginThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+// throttleWait is how long a login must wait given the throttles of its
+// email and IP address: until a lockout ends, or for emails until the
+// progressive delay after the last failure has passed.
+func throttleWait(throttles []models.LoginThrottle, now time.Time) time.Duration {
+	var wait time.Duration
+	for _, throttle := range throttles {
+		if throttle.LockedUntil != nil && throttle.LockedUntil.After(now) {
+			wait = maxDuration(wait, throttle.LockedUntil.Sub(now))
+			continue
+		}
+		if strings.HasPrefix(throttle.Key, "email:") && now.Sub(throttle.LastFailedAt) < loginFailureWindow() {
+			wait = maxDuration(wait, throttle.LastFailedAt.Add(loginDelay(throttle.Failures)).Sub(now))
+		}
+	}
+	return wait
+}
+
+// RecordFailure counts a failed login against both the email and the IP
+// address and locks whichever reaches its limit.
+func (s *LoginThrottleService) RecordFailure(email, ip string, userID *uuid.UUID) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.recordFailure(tx, emailThrottleKey(email), loginMaxFailures(), models.SecurityEventLoginLockout, "failed login attempts", userID, email, ip); err != nil {
+			return err
+		}
+		return s.recordFailure(tx, ipThrottleKey(ip), loginIPMaxFailures(), models.SecurityEventLoginLockout, "failed login attempts", nil, "", ip)
+	})
+}
+
+func (s *LoginThrottleService) RecordSuccess(email string) error {
+	if err := s.db.Where("key = ?", emailThrottleKey(email)).Delete(&models.LoginThrottle{}).Error; err != nil {
+		return fmt.Errorf("failed to reset login throttle: %v", err)
+	}
+	return nil
+}
+
+// StepUpLocked reports whether the user's PIN or TOTP method is locked out
+// after too many wrong codes.
+func (s *LoginThrottleService) StepUpLocked(tx *gorm.DB, userID uuid.UUID, method models.StepUpMethod) (bool, error) {
+	var throttle models.LoginThrottle
+	if err := tx.Where("key = ?", stepUpThrottleKey(userID, method)).First(&throttle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check step-up throttle: %v", err)
+	}
+
+	return throttle.LockedUntil != nil && throttle.LockedUntil.After(time.Now()), nil
+}
+
+func (s *LoginThrottleService) RecordStepUpFailure(tx *gorm.DB, user *models.User, method models.StepUpMethod, ip string) error {
+	return s.recordFailure(tx, stepUpThrottleKey(user.ID, method), stepUpMaxFailures(), models.SecurityEventStepUpLockout, "wrong verification codes", &user.ID, user.Email, ip)
+}
+
+func (s *LoginThrottleService) RecordStepUpSuccess(tx *gorm.DB, userID uuid.UUID, method models.StepUpMethod) error {
+	if err := tx.Where("key = ?", stepUpThrottleKey(userID, method)).Delete(&models.LoginThrottle{}).Error; err != nil {
+		return fmt.Errorf("failed to reset step-up throttle: %v", err)
+	}
+	return nil
+}
+
+func (s *LoginThrottleService) IsLocked(email string) (*time.Time, error) {
+	var throttle models.LoginThrottle
+	if err := s.db.Where("key = ?", emailThrottleKey(email)).First(&throttle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to check login throttle: %v", err)
+	}
+
+	if throttle.LockedUntil == nil || !throttle.LockedUntil.After(time.Now()) {
+		return nil, nil
+	}
+	return throttle.LockedUntil, nil
+}
+
+// Unlock clears the failed attempts of a user's email, PIN and TOTP before the
+// lockout expires on its own.
+func (s *LoginThrottleService) Unlock(user *models.User, actorID string) error {
+	var actor *uuid.UUID
+	if id, err := uuid.Parse(actorID); err == nil {
+		actor = &id
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		keys := []string{
+			emailThrottleKey(user.Email),
+			stepUpThrottleKey(user.ID, models.StepUpMethodPIN),
+			stepUpThrottleKey(user.ID, models.StepUpMethodTOTP),
+		}
+		if err := tx.Where("key IN ?", keys).Delete(&models.LoginThrottle{}).Error; err != nil {
+			return fmt.Errorf("failed to unlock user: %v", err)
+		}
+
+		return tx.Create(&models.SecurityEvent{
+			Type:    models.SecurityEventLoginUnlock,
+			UserID:  &user.ID,
+			ActorID: actor,
+			Email:   user.Email,
+			Details: "login lockout cleared by administrator",
+		}).Error
+	})
+}
+
+func (s *LoginThrottleService) GetSecurityEvents(eventType models.SecurityEventType, userID string, limit, offset int) ([]models.SecurityEvent, int64, error) {
+	var events []models.SecurityEvent
+	var total int64
+
+	query := s.db.Model(&models.SecurityEvent{})
+	if eventType != "" {
+		query = query.Where("type = ?", eventType)
+	}
+	if userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return nil, 0, errors.New("invalid user ID")
+		}
+		query = query.Where("user_id = ?", id)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count security events: %v", err)
+	}
+
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&events).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to find security events: %v", err)
+	}
+
+	return events, total, nil
+}
+
+func (s *LoginThrottleService) PurgeExpired(now time.Time) (int64, error) {
+	result := s.db.Where("last_failed_at < ? AND (locked_until IS NULL OR locked_until < ?)", now.Add(-loginFailureWindow()), now).
+		Delete(&models.LoginThrottle{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge login throttles: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+func (s *LoginThrottleService) recordFailure(tx *gorm.DB, key string, maxFailures int, eventType models.SecurityEventType, attempts string, userID *uuid.UUID, email, ip string) error {
+	now := time.Now()
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.LoginThrottle{Key: key, LastFailedAt: now}).Error; err != nil {
+		return fmt.Errorf("failed to record login failure: %v", err)
+	}
+
+	var throttle models.LoginThrottle
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&throttle).Error; err != nil {
+		return fmt.Errorf("failed to record login failure: %v", err)
+	}
+
+	locked := countFailure(&throttle, now, maxFailures)
+
+	if err := tx.Model(&throttle).Select("failures", "last_failed_at", "locked_until").Updates(&throttle).Error; err != nil {
+		return fmt.Errorf("failed to record login failure: %v", err)
+	}
+
+	if !locked {
+		return nil
+	}
+
+	if err := tx.Create(&models.SecurityEvent{
+		Type:      eventType,
+		UserID:    userID,
+		Email:     email,
+		IPAddress: ip,
+		Details:   fmt.Sprintf("%s locked until %s after %d %s", key, throttle.LockedUntil.Format(time.RFC3339), throttle.Failures, attempts),
+	}).Error; err != nil {
+		return fmt.Errorf("failed to log security event: %v", err)
+	}
+
+	return nil
+}
+
+// countFailure adds a failure at now, starting over once the failure window
+// or an earlier lockout has passed, and reports whether it caused a lockout.
+func countFailure(throttle *models.LoginThrottle, now time.Time, maxFailures int) bool {
+	if now.Sub(throttle.LastFailedAt) > loginFailureWindow() || (throttle.LockedUntil != nil && !throttle.LockedUntil.After(now)) {
+		throttle.Failures = 0
+		throttle.LockedUntil = nil
+	}
+	throttle.Failures++
+	throttle.LastFailedAt = now
+
+	if throttle.Failures < maxFailures || throttle.LockedUntil != nil {
+		return false
+	}
+
+	lockedUntil := now.Add(loginLockoutDuration())
+	throttle.LockedUntil = &lockedUntil
+	return true
+}
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+)
+
+func useThrottleWindows(t *testing.T) {
+	t.Helper()
+	t.Setenv("LOGIN_FAILURE_WINDOW", "15m")
+	t.Setenv("LOGIN_LOCKOUT_DURATION", "10m")
+}
+
+func TestLoginDelay(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{1, 0},
+		{2, time.Second},
+		{3, 2 * time.Second},
+		{4, 4 * time.Second},
+		{6, 16 * time.Second},
+		{7, 30 * time.Second},
+		{20, 30 * time.Second},
+		{100, 30 * time.Second},
+	}
+
+	for _, tt := range tests {
+		if got := loginDelay(tt.failures); got != tt.want {
+			t.Errorf("loginDelay(%d) = %v, want %v", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestThrottleWait(t *testing.T) {
+	useThrottleWindows(t)
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	at := func(offset time.Duration) *time.Time {
+		t := now.Add(offset)
+		return &t
+	}
+
+	tests := []struct {
+		name      string
+		throttles []models.LoginThrottle
+		want      time.Duration
+	}{
+		{"no throttles", nil, 0},
+		{"first failure", []models.LoginThrottle{
+			{Key: "email:x", Failures: 1, LastFailedAt: now},
+		}, 0},
+		{"inside the delay", []models.LoginThrottle{
+			{Key: "email:x", Failures: 4, LastFailedAt: now.Add(-time.Second)},
+		}, 3 * time.Second},
+		{"delay passed", []models.LoginThrottle{
+			{Key: "email:x", Failures: 4, LastFailedAt: now.Add(-5 * time.Second)},
+		}, 0},
+		{"failure window passed", []models.LoginThrottle{
+			{Key: "email:x", Failures: 7, LastFailedAt: now.Add(-16 * time.Minute)},
+		}, 0},
+		{"IP failures have no delay", []models.LoginThrottle{
+			{Key: "ip:10.0.0.1", Failures: 10, LastFailedAt: now},
+		}, 0},
+		{"locked", []models.LoginThrottle{
+			{Key: "ip:10.0.0.1", Failures: 20, LastFailedAt: now, LockedUntil: at(7 * time.Minute)},
+		}, 7 * time.Minute},
+		{"lockout expired", []models.LoginThrottle{
+			{Key: "ip:10.0.0.1", Failures: 20, LastFailedAt: now.Add(-11 * time.Minute), LockedUntil: at(-time.Minute)},
+		}, 0},
+		{"longest wait wins", []models.LoginThrottle{
+			{Key: "email:x", Failures: 3, LastFailedAt: now},
+			{Key: "ip:10.0.0.1", Failures: 20, LastFailedAt: now, LockedUntil: at(time.Minute)},
+		}, time.Minute},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := throttleWait(tt.throttles, now); got != tt.want {
+				t.Errorf("throttleWait = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountFailureLocksAtMaximum(t *testing.T) {
+	useThrottleWindows(t)
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	throttle := &models.LoginThrottle{Key: "email:x", LastFailedAt: now}
+
+	for i := 1; i <= 4; i++ {
+		if countFailure(throttle, now.Add(time.Duration(i)*time.Second), 5) {
+			t.Fatalf("failure %d locked the key", i)
+		}
+	}
+	if !countFailure(throttle, now.Add(5*time.Second), 5) {
+		t.Fatal("failure 5 did not lock the key")
+	}
+	if throttle.Failures != 5 {
+		t.Errorf("failures = %d, want 5", throttle.Failures)
+	}
+	if want := now.Add(5*time.Second + 10*time.Minute); throttle.LockedUntil == nil || !throttle.LockedUntil.Equal(want) {
+		t.Errorf("locked until %v, want %v", throttle.LockedUntil, want)
+	}
+
+	// Failures while locked are counted but do not extend the lockout or
+	// log another lockout event.
+	lockedUntil := *throttle.LockedUntil
+	if countFailure(throttle, now.Add(time.Minute), 5) {
+		t.Error("a failure while locked locked the key again")
+	}
+	if throttle.Failures != 6 || !throttle.LockedUntil.Equal(lockedUntil) {
+		t.Errorf("failures = %d, locked until %v, want 6 and %v", throttle.Failures, throttle.LockedUntil, lockedUntil)
+	}
+}
+
+func TestCountFailureStartsOver(t *testing.T) {
+	useThrottleWindows(t)
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Second)
+
+	tests := []struct {
+		name     string
+		throttle models.LoginThrottle
+		want     int
+	}{
+		{"inside the window", models.LoginThrottle{Failures: 3, LastFailedAt: now.Add(-14 * time.Minute)}, 4},
+		{"window passed", models.LoginThrottle{Failures: 3, LastFailedAt: now.Add(-16 * time.Minute)}, 1},
+		{"lockout expired", models.LoginThrottle{Failures: 5, LastFailedAt: now.Add(-time.Minute), LockedUntil: &expired}, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			throttle := tt.throttle
+			countFailure(&throttle, now, 5)
+			if throttle.Failures != tt.want {
+				t.Errorf("failures = %d, want %d", throttle.Failures, tt.want)
+			}
+			if throttle.LockedUntil != nil {
+				t.Errorf("locked until %v, want unlocked", throttle.LockedUntil)
+			}
+		})
+	}
+}
+
+func TestThrottleKeys(t *testing.T) {
+	key := emailThrottleKey("Jane.Doe@Example.com ")
+	if key != emailThrottleKey("jane.doe@example.com") {
+		t.Error("email throttle key depends on case or spacing")
+	}
+	if len(key) <= len("email:") || key[len("email:"):] == "jane.doe@example.com" {
+		t.Errorf("email throttle key %q does not use the blind index", key)
+	}
+
+	userID := uuid.New()
+	pin := stepUpThrottleKey(userID, models.StepUpMethodPIN)
+	totp := stepUpThrottleKey(userID, models.StepUpMethodTOTP)
+	if pin == totp {
+		t.Error("PIN and TOTP share a throttle key")
+	}
+}
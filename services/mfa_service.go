@@ -0,0 +1,389 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const recoveryCodeCount = 10
+
+var (
+	ErrMFAAlreadyEnabled   = errors.New("MFA is already enabled")
+	ErrMFANotEnabled       = errors.New("MFA is not enabled")
+	ErrMFAEnrollmentNeeded = errors.New("no MFA enrollment in progress")
+	ErrMFARequired         = errors.New("MFA is required for staff accounts and cannot be disabled")
+	ErrInvalidMFACode      = errors.New("invalid MFA code")
+	ErrInvalidPassword     = errors.New("invalid password")
+	ErrInvalidMFAChallenge = errors.New("invalid or expired MFA challenge")
+)
+
+type MFAService struct {
+	db            *gorm.DB
+	loginThrottle *LoginThrottleService
+}
+
+func NewMFAService(db *gorm.DB) *MFAService {
+	return &MFAService{
+		db:            db,
+		loginThrottle: NewLoginThrottleService(db),
+	}
+}
+
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+	QRCode          string `json:"qr_code"`
+}
+
+type MFAChallengeToken struct {
+	Token     string    `json:"mfa_token"`
+	ExpiresIn int64     `json:"expires_in"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// BeginEnrollment generates a new secret that only becomes active once
+// ConfirmEnrollment receives a valid code for it.
+func (s *MFAService) BeginEnrollment(userID string) (*MFAEnrollment, error) {
+	user, err := s.findUser(s.db, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate MFA secret: %v", err)
+	}
+
+	uri := utils.TOTPProvisioningURI(utils.MFAIssuer(), user.Email, secret)
+	qrCode, err := utils.QRCodePNG(uri, 6)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate QR code: %v", err)
+	}
+
+	if err := s.db.Model(user).Update("mfa_pending_secret", secret).Error; err != nil {
+		return nil, fmt.Errorf("failed to start MFA enrollment: %v", err)
+	}
+
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: uri,
+		QRCode:          "data:image/png;base64," + base64.StdEncoding.EncodeToString(qrCode),
+	}, nil
+}
+
+func (s *MFAService) ConfirmEnrollment(userID, code string, actor AuditActor) ([]string, error) {
+	var recoveryCodes []string
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.findUser(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+
+		if user.MFAEnabled {
+			return ErrMFAAlreadyEnabled
+		}
+		if user.MFAPendingSecret == "" {
+			return ErrMFAEnrollmentNeeded
+		}
+
+		step, ok := utils.ValidateTOTP(user.MFAPendingSecret, code, time.Now())
+		if !ok {
+			return ErrInvalidMFACode
+		}
+
+		now := time.Now()
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"mfa_enabled":        true,
+			"mfa_secret":         user.MFAPendingSecret,
+			"mfa_pending_secret": "",
+			"mfa_last_used_step": step,
+			"mfa_enabled_at":     now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to enable MFA: %v", err)
+		}
+
+		recoveryCodes, err = s.replaceRecoveryCodes(tx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditActionMFAEnable, models.AuditTargetUser, user.ID.String(),
+			map[string]interface{}{"mfa_enabled": false}, map[string]interface{}{"mfa_enabled": true})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// Disable turns MFA off after the user re-authenticates with both their
+// password and a current code or an unused recovery code.
+func (s *MFAService) Disable(userID, password, code string, actor AuditActor) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.findUser(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+
+		if !user.MFAEnabled {
+			return ErrMFANotEnabled
+		}
+		if user.Role.RequiresMFA() {
+			return ErrMFARequired
+		}
+		if !utils.CheckPasswordHash(password, user.Password) {
+			return ErrInvalidPassword
+		}
+
+		if err := s.verifyCode(tx, user, code); err != nil {
+			return err
+		}
+
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"mfa_enabled":        false,
+			"mfa_secret":         "",
+			"mfa_pending_secret": "",
+			"mfa_last_used_step": 0,
+			"mfa_enabled_at":     nil,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to disable MFA: %v", err)
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %v", err)
+		}
+
+		return recordAudit(tx, actor, models.AuditActionMFADisable, models.AuditTargetUser, user.ID.String(),
+			map[string]interface{}{"mfa_enabled": true}, map[string]interface{}{"mfa_enabled": false})
+	})
+}
+
+func (s *MFAService) RegenerateRecoveryCodes(userID, code string, actor AuditActor) ([]string, error) {
+	var recoveryCodes []string
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.findUser(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+
+		if !user.MFAEnabled {
+			return ErrMFANotEnabled
+		}
+
+		if err := s.verifyTOTP(tx, user, code); err != nil {
+			return err
+		}
+
+		recoveryCodes, err = s.replaceRecoveryCodes(tx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditActionMFARecoveryCodes, models.AuditTargetUser, user.ID.String(), nil, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+func (s *MFAService) CreateChallenge(user *models.User, client ClientInfo) (*MFAChallengeToken, error) {
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate MFA challenge: %v", err)
+	}
+
+	now := time.Now()
+	challenge := &models.MFAChallenge{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(token),
+		IPAddress: client.IPAddress,
+		ExpiresAt: now.Add(utils.MFAChallengeTTL()),
+	}
+	if err := s.db.Create(challenge).Error; err != nil {
+		return nil, fmt.Errorf("failed to store MFA challenge: %v", err)
+	}
+
+	return &MFAChallengeToken{
+		Token:     token,
+		ExpiresIn: int64(challenge.ExpiresAt.Sub(now).Seconds()),
+		ExpiresAt: challenge.ExpiresAt,
+	}, nil
+}
+
+// VerifyChallenge completes a login started with a password. A challenge can
+// be answered once and is discarded after too many wrong codes. Wrong codes
+// also count against the login throttle for the user's email and the client
+// IP, so starting new challenges does not give an attacker more guesses.
+func (s *MFAService) VerifyChallenge(challengeToken, code string, client ClientInfo) (*models.User, error) {
+	var user *models.User
+	failed := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var challenge models.MFAChallenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(challengeToken)).
+			First(&challenge).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidMFAChallenge
+			}
+			return fmt.Errorf("failed to find MFA challenge: %v", err)
+		}
+
+		now := time.Now()
+		if challenge.UsedAt != nil || now.After(challenge.ExpiresAt) || challenge.Attempts >= models.MFAChallengeMaxAttempts {
+			return ErrInvalidMFAChallenge
+		}
+
+		var err error
+		user, err = s.findUser(tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("is_active = ?", true), challenge.UserID.String())
+		if err != nil {
+			return ErrInvalidMFAChallenge
+		}
+		if !user.MFAEnabled {
+			return ErrInvalidMFAChallenge
+		}
+
+		if err := s.loginThrottle.Check(user.Email, client.IPAddress); err != nil {
+			return err
+		}
+
+		if err := s.verifyCode(tx, user, code); err != nil {
+			if !errors.Is(err, ErrInvalidMFACode) {
+				return err
+			}
+			if err := tx.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+				return fmt.Errorf("failed to update MFA challenge: %v", err)
+			}
+			failed = true
+			return nil
+		}
+
+		if err := tx.Model(&challenge).Update("used_at", now).Error; err != nil {
+			return fmt.Errorf("failed to complete MFA challenge: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if failed {
+		if err := s.loginThrottle.RecordFailure(user.Email, client.IPAddress, &user.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidMFACode
+	}
+
+	if err := s.loginThrottle.RecordSuccess(user.Email); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *MFAService) PurgeExpiredChallenges(now time.Time) (int64, error) {
+	result := s.db.Where("expires_at < ?", now).Delete(&models.MFAChallenge{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge MFA challenges: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// verifyCode accepts either a TOTP code or an unused recovery code.
+func (s *MFAService) verifyCode(tx *gorm.DB, user *models.User, code string) error {
+	code = strings.TrimSpace(code)
+	if len(code) == utils.TOTPDigits {
+		return s.verifyTOTP(tx, user, code)
+	}
+
+	result := tx.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", user.ID, hashRecoveryCode(code)).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to use recovery code: %v", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+// verifyTOTP rejects codes from a time step that was already used so that an
+// intercepted code cannot be replayed within its validity window.
+func (s *MFAService) verifyTOTP(tx *gorm.DB, user *models.User, code string) error {
+	step, ok := utils.ValidateTOTP(user.MFASecret, code, time.Now())
+	if !ok || step <= user.MFALastUsedStep {
+		return ErrInvalidMFACode
+	}
+
+	if err := tx.Model(user).Update("mfa_last_used_step", step).Error; err != nil {
+		return fmt.Errorf("failed to record MFA code: %v", err)
+	}
+
+	return nil
+}
+
+func (s *MFAService) replaceRecoveryCodes(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %v", err)
+	}
+
+	codes := make([]string, 0, recoveryCodeCount)
+	records := make([]models.MFARecoveryCode, 0, recoveryCodeCount)
+	for i := 0; i < recoveryCodeCount; i++ {
+		code, err := utils.GenerateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %v", err)
+		}
+		codes = append(codes, code)
+		records = append(records, models.MFARecoveryCode{UserID: userID, CodeHash: hashRecoveryCode(code)})
+	}
+
+	if err := tx.Create(&records).Error; err != nil {
+		return nil, fmt.Errorf("failed to store recovery codes: %v", err)
+	}
+
+	return codes, nil
+}
+
+func (s *MFAService) findUser(tx *gorm.DB, userID string) (*models.User, error) {
+	var user models.User
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if err := tx.Where("id = ?", id).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to find user: %v", err)
+	}
+
+	return &user, nil
+}
+
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	return utils.HashToken(code)
+}
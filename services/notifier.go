@@ -0,0 +1,109 @@
+package services
+
+import (
+	"fmt"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type EmailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers outbound email. NewNotifier picks the implementation from
+// MAIL_DRIVER so that local environments never need a real mail server.
+type Notifier interface {
+	Send(message EmailMessage) error
+}
+
+func NewNotifier() Notifier {
+	from := os.Getenv("MAIL_FROM")
+	if from == "" {
+		from = "no-reply@corebanking.local"
+	}
+
+	switch os.Getenv("MAIL_DRIVER") {
+	case "smtp":
+		addr := os.Getenv("SMTP_ADDR")
+		if addr == "" {
+			addr = "localhost:1025"
+		}
+		return &SMTPNotifier{
+			Addr:     addr,
+			From:     from,
+			Username: os.Getenv("SMTP_USERNAME"),
+			Password: os.Getenv("SMTP_PASSWORD"),
+		}
+	default:
+		dir := os.Getenv("MAIL_DIR")
+		if dir == "" {
+			dir = filepath.Join(os.TempDir(), "core-banking-mail")
+		}
+		return &FileNotifier{Dir: dir, From: from}
+	}
+}
+
+// FileNotifier writes every message as an .eml file instead of sending it.
+type FileNotifier struct {
+	Dir  string
+	From string
+}
+
+func (n *FileNotifier) Send(message EmailMessage) error {
+	if err := os.MkdirAll(n.Dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory: %v", err)
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.New().String()[:8])
+	if err := os.WriteFile(filepath.Join(n.Dir, name), formatEmail(n.From, message), 0o600); err != nil {
+		return fmt.Errorf("failed to write email: %v", err)
+	}
+
+	return nil
+}
+
+// SMTPNotifier sends through an SMTP server such as a local MailHog sink.
+// Authentication is only used when a username is configured.
+type SMTPNotifier struct {
+	Addr     string
+	From     string
+	Username string
+	Password string
+}
+
+func (n *SMTPNotifier) Send(message EmailMessage) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		host := n.Addr
+		if i := strings.LastIndex(host, ":"); i >= 0 {
+			host = host[:i]
+		}
+		auth = smtp.PlainAuth("", n.Username, n.Password, host)
+	}
+
+	if err := smtp.SendMail(n.Addr, auth, n.From, []string{message.To}, formatEmail(n.From, message)); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+
+	return nil
+}
+
+func formatEmail(from string, message EmailMessage) []byte {
+	var b strings.Builder
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + message.To + "\r\n")
+	b.WriteString("Subject: " + message.Subject + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+	return []byte(b.String())
+}
@@ -0,0 +1,289 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	pain001Namespace   = "urn:iso:std:iso:20022:tech:xsd:pain.001.001.09"
+	pain001MessageName = "pain.001.001.09"
+)
+
+var (
+	isoDateTimePattern = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})?$`)
+	isoDatePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+	decimalPattern     = regexp.MustCompile(`^\d{1,18}(\.\d{1,17})?$`)
+	max15NumericText   = regexp.MustCompile(`^[0-9]{1,15}$`)
+	currencyPattern    = regexp.MustCompile(`^[A-Z]{3}$`)
+	ibanSchemaPattern  = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[a-zA-Z0-9]{1,30}$`)
+)
+
+type pain001Document struct {
+	XMLName    xml.Name           `xml:"Document"`
+	Initiation *pain001Initiation `xml:"CstmrCdtTrfInitn"`
+}
+
+type pain001Initiation struct {
+	GroupHeader  *pain001GroupHeader  `xml:"GrpHdr"`
+	PaymentInfos []pain001PaymentInfo `xml:"PmtInf"`
+}
+
+type pain001GroupHeader struct {
+	MessageID            string `xml:"MsgId"`
+	CreationDateTime     string `xml:"CreDtTm"`
+	NumberOfTransactions string `xml:"NbOfTxs"`
+	ControlSum           string `xml:"CtrlSum"`
+	InitiatingPartyName  string `xml:"InitgPty>Nm"`
+}
+
+type pain001PaymentInfo struct {
+	PaymentInfoID              string               `xml:"PmtInfId"`
+	PaymentMethod              string               `xml:"PmtMtd"`
+	NumberOfTransactions       string               `xml:"NbOfTxs"`
+	ControlSum                 string               `xml:"CtrlSum"`
+	RequestedExecutionDate     string               `xml:"ReqdExctnDt>Dt"`
+	RequestedExecutionDateTime string               `xml:"ReqdExctnDt>DtTm"`
+	DebtorName                 string               `xml:"Dbtr>Nm"`
+	DebtorAccount              *pain001Account      `xml:"DbtrAcct"`
+	Transactions               []pain001Transaction `xml:"CdtTrfTxInf"`
+}
+
+type pain001Account struct {
+	IBAN     string `xml:"Id>IBAN"`
+	Other    string `xml:"Id>Othr>Id"`
+	Currency string `xml:"Ccy"`
+}
+
+type pain001Transaction struct {
+	InstructionID   string          `xml:"PmtId>InstrId"`
+	EndToEndID      string          `xml:"PmtId>EndToEndId"`
+	Amount          *pain001Amount  `xml:"Amt>InstdAmt"`
+	CreditorName    string          `xml:"Cdtr>Nm"`
+	CreditorAccount *pain001Account `xml:"CdtrAcct"`
+	Remittance      []string        `xml:"RmtInf>Ustrd"`
+}
+
+type pain001Amount struct {
+	Currency string `xml:"Ccy,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type pain002Document struct {
+	XMLName xml.Name      `xml:"urn:iso:std:iso:20022:tech:xsd:pain.002.001.10 Document"`
+	Report  pain002Report `xml:"CstmrPmtStsRpt"`
+}
+
+type pain002Report struct {
+	GroupHeader      pain002GroupHeader       `xml:"GrpHdr"`
+	OriginalGroup    pain002OriginalGroup     `xml:"OrgnlGrpInfAndSts"`
+	OriginalPayments []pain002OriginalPayment `xml:"OrgnlPmtInfAndSts,omitempty"`
+}
+
+type pain002GroupHeader struct {
+	MessageID        string `xml:"MsgId"`
+	CreationDateTime string `xml:"CreDtTm"`
+}
+
+type pain002OriginalGroup struct {
+	OriginalMessageID            string                `xml:"OrgnlMsgId"`
+	OriginalMessageNameID        string                `xml:"OrgnlMsgNmId"`
+	OriginalNumberOfTransactions string                `xml:"OrgnlNbOfTxs,omitempty"`
+	OriginalControlSum           string                `xml:"OrgnlCtrlSum,omitempty"`
+	GroupStatus                  string                `xml:"GrpSts"`
+	StatusReasons                []pain002StatusReason `xml:"StsRsnInf,omitempty"`
+}
+
+type pain002OriginalPayment struct {
+	OriginalPaymentInfoID string                `xml:"OrgnlPmtInfId"`
+	Status                string                `xml:"PmtInfSts,omitempty"`
+	StatusReasons         []pain002StatusReason `xml:"StsRsnInf,omitempty"`
+	Transactions          []pain002Transaction  `xml:"TxInfAndSts"`
+}
+
+type pain002Transaction struct {
+	StatusID              string                `xml:"StsId"`
+	OriginalInstructionID string                `xml:"OrgnlInstrId,omitempty"`
+	OriginalEndToEndID    string                `xml:"OrgnlEndToEndId"`
+	Status                string                `xml:"TxSts"`
+	StatusReasons         []pain002StatusReason `xml:"StsRsnInf,omitempty"`
+}
+
+type pain002StatusReason struct {
+	Code           string   `xml:"Rsn>Cd"`
+	AdditionalInfo []string `xml:"AddtlInf,omitempty"`
+}
+
+func newStatusReason(code, info string) []pain002StatusReason {
+	reason := pain002StatusReason{Code: code}
+	if info != "" {
+		reason.AdditionalInfo = []string{truncate(info, 105)}
+	}
+	return []pain002StatusReason{reason}
+}
+
+type pain001Validator struct {
+	errors []string
+}
+
+func (v *pain001Validator) fail(format string, args ...interface{}) {
+	v.errors = append(v.errors, fmt.Sprintf(format, args...))
+}
+
+func (v *pain001Validator) text(path, value string, maxLength int, required bool) {
+	if value == "" {
+		if required {
+			v.fail("%s is required", path)
+		}
+		return
+	}
+	if len([]rune(value)) > maxLength {
+		v.fail("%s exceeds %d characters", path, maxLength)
+	}
+}
+
+func (v *pain001Validator) pattern(path, value string, pattern *regexp.Regexp, required bool) {
+	if value == "" {
+		if required {
+			v.fail("%s is required", path)
+		}
+		return
+	}
+	if !pattern.MatchString(value) {
+		v.fail("%s has an invalid format", path)
+	}
+}
+
+func (v *pain001Validator) account(path string, account *pain001Account) {
+	if account == nil {
+		v.fail("%s is required", path)
+		return
+	}
+
+	switch {
+	case account.IBAN != "" && account.Other != "":
+		v.fail("%s/Id must contain either IBAN or Othr", path)
+	case account.IBAN != "":
+		v.pattern(path+"/Id/IBAN", account.IBAN, ibanSchemaPattern, true)
+	case account.Other != "":
+		v.text(path+"/Id/Othr/Id", account.Other, 34, true)
+	default:
+		v.fail("%s/Id is required", path)
+	}
+
+	v.pattern(path+"/Ccy", account.Currency, currencyPattern, false)
+}
+
+func (v *pain001Validator) count(path, value string, actual int) {
+	v.pattern(path, value, max15NumericText, true)
+	if n, err := strconv.Atoi(value); err == nil && n != actual {
+		v.fail("%s is %d but the file contains %d transactions", path, n, actual)
+	}
+}
+
+func (v *pain001Validator) controlSum(path, value string, actual float64) {
+	if value == "" {
+		return
+	}
+	v.pattern(path, value, decimalPattern, true)
+	if sum, err := strconv.ParseFloat(value, 64); err == nil && math.Abs(sum-actual) > 0.005 {
+		v.fail("%s is %s but the instructed amounts total %s", path, value, formatAmount(actual))
+	}
+}
+
+func validatePain001(doc *pain001Document) []string {
+	v := &pain001Validator{}
+
+	if doc.XMLName.Space != pain001Namespace {
+		v.fail("Document namespace must be %s", pain001Namespace)
+	}
+
+	if doc.Initiation == nil {
+		v.fail("CstmrCdtTrfInitn is required")
+		return v.errors
+	}
+
+	header := doc.Initiation.GroupHeader
+	if header == nil {
+		v.fail("GrpHdr is required")
+		return v.errors
+	}
+
+	v.text("GrpHdr/MsgId", header.MessageID, 35, true)
+	v.pattern("GrpHdr/CreDtTm", header.CreationDateTime, isoDateTimePattern, true)
+	v.text("GrpHdr/InitgPty/Nm", header.InitiatingPartyName, 140, false)
+
+	if len(doc.Initiation.PaymentInfos) == 0 {
+		v.fail("at least one PmtInf is required")
+	}
+
+	totalCount := 0
+	totalSum := 0.0
+	for i, payment := range doc.Initiation.PaymentInfos {
+		path := fmt.Sprintf("PmtInf[%d]", i+1)
+		v.text(path+"/PmtInfId", payment.PaymentInfoID, 35, true)
+
+		switch payment.PaymentMethod {
+		case "TRF", "CHK", "TRA":
+		case "":
+			v.fail("%s/PmtMtd is required", path)
+		default:
+			v.fail("%s/PmtMtd must be one of TRF, CHK or TRA", path)
+		}
+
+		switch {
+		case payment.RequestedExecutionDate != "":
+			v.pattern(path+"/ReqdExctnDt/Dt", payment.RequestedExecutionDate, isoDatePattern, true)
+		case payment.RequestedExecutionDateTime != "":
+			v.pattern(path+"/ReqdExctnDt/DtTm", payment.RequestedExecutionDateTime, isoDateTimePattern, true)
+		default:
+			v.fail("%s/ReqdExctnDt is required", path)
+		}
+
+		v.text(path+"/Dbtr/Nm", payment.DebtorName, 140, false)
+		v.account(path+"/DbtrAcct", payment.DebtorAccount)
+
+		if len(payment.Transactions) == 0 {
+			v.fail("%s must contain at least one CdtTrfTxInf", path)
+		}
+
+		paymentSum := 0.0
+		for j, transaction := range payment.Transactions {
+			txPath := fmt.Sprintf("%s/CdtTrfTxInf[%d]", path, j+1)
+			v.text(txPath+"/PmtId/InstrId", transaction.InstructionID, 35, false)
+			v.text(txPath+"/PmtId/EndToEndId", transaction.EndToEndID, 35, true)
+			v.text(txPath+"/Cdtr/Nm", transaction.CreditorName, 140, false)
+			v.account(txPath+"/CdtrAcct", transaction.CreditorAccount)
+
+			if transaction.Amount == nil {
+				v.fail("%s/Amt/InstdAmt is required", txPath)
+			} else {
+				v.pattern(txPath+"/Amt/InstdAmt/@Ccy", transaction.Amount.Currency, currencyPattern, true)
+				value := strings.TrimSpace(transaction.Amount.Value)
+				v.pattern(txPath+"/Amt/InstdAmt", value, decimalPattern, true)
+				if amount, err := strconv.ParseFloat(value, 64); err == nil {
+					paymentSum += amount
+				}
+			}
+
+			for k, line := range transaction.Remittance {
+				v.text(fmt.Sprintf("%s/RmtInf/Ustrd[%d]", txPath, k+1), line, 140, false)
+			}
+		}
+
+		v.count(path+"/NbOfTxs", payment.NumberOfTransactions, len(payment.Transactions))
+		v.controlSum(path+"/CtrlSum", payment.ControlSum, paymentSum)
+
+		totalCount += len(payment.Transactions)
+		totalSum += paymentSum
+	}
+
+	v.count("GrpHdr/NbOfTxs", header.NumberOfTransactions, totalCount)
+	v.controlSum("GrpHdr/CtrlSum", header.ControlSum, totalSum)
+
+	return v.errors
+}
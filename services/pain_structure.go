@@ -0,0 +1,285 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const xmlSchemaInstanceNamespace = "http://www.w3.org/2001/XMLSchema-instance"
+
+// painElement describes an element of the pain.001.001.09 subset we accept.
+// Children are listed in schema order. A max of 0 means unbounded, and a
+// choice element must contain exactly one of its children.
+type painElement struct {
+	name     string
+	min, max int
+	choice   bool
+	attrs    []string
+	children []*painElement
+}
+
+func painLeaf(name string, min, max int) *painElement {
+	return &painElement{name: name, min: min, max: max}
+}
+
+func painGroup(name string, min, max int, children ...*painElement) *painElement {
+	return &painElement{name: name, min: min, max: max, children: children}
+}
+
+func painChoice(name string, min, max int, children ...*painElement) *painElement {
+	return &painElement{name: name, min: min, max: max, choice: true, children: children}
+}
+
+func painAccountElement(name string) *painElement {
+	return painGroup(name, 1, 1,
+		painChoice("Id", 1, 1,
+			painLeaf("IBAN", 0, 1),
+			painGroup("Othr", 0, 1, painLeaf("Id", 1, 1)),
+		),
+		painLeaf("Ccy", 0, 1),
+	)
+}
+
+// painAgentElement accepts a financial institution by BIC or by another
+// identifier. Agents play no part in executing the file, but most files
+// carry them.
+func painAgentElement(name string) *painElement {
+	return painGroup(name, 0, 1,
+		painGroup("FinInstnId", 1, 1,
+			painLeaf("BICFI", 0, 1),
+			painGroup("Othr", 0, 1, painLeaf("Id", 1, 1)),
+		),
+	)
+}
+
+// pain001Schema follows the element order and cardinality of the
+// pain.001.001.09 XSD, restricted to the elements a payment file is executed
+// from. Anything else would be silently ignored, so it is rejected instead.
+var pain001Schema = painGroup("Document", 1, 1,
+	painGroup("CstmrCdtTrfInitn", 1, 1,
+		painGroup("GrpHdr", 1, 1,
+			painLeaf("MsgId", 1, 1),
+			painLeaf("CreDtTm", 1, 1),
+			painLeaf("NbOfTxs", 1, 1),
+			painLeaf("CtrlSum", 0, 1),
+			painGroup("InitgPty", 1, 1, painLeaf("Nm", 0, 1)),
+		),
+		painGroup("PmtInf", 1, 0,
+			painLeaf("PmtInfId", 1, 1),
+			painLeaf("PmtMtd", 1, 1),
+			painLeaf("NbOfTxs", 1, 1),
+			painLeaf("CtrlSum", 0, 1),
+			painChoice("ReqdExctnDt", 1, 1,
+				painLeaf("Dt", 0, 1),
+				painLeaf("DtTm", 0, 1),
+			),
+			painGroup("Dbtr", 1, 1, painLeaf("Nm", 0, 1)),
+			painAccountElement("DbtrAcct"),
+			painAgentElement("DbtrAgt"),
+			painGroup("CdtTrfTxInf", 1, 0,
+				painGroup("PmtId", 1, 1,
+					painLeaf("InstrId", 0, 1),
+					painLeaf("EndToEndId", 1, 1),
+				),
+				painGroup("Amt", 1, 1,
+					&painElement{name: "InstdAmt", min: 1, max: 1, attrs: []string{"Ccy"}},
+				),
+				painAgentElement("CdtrAgt"),
+				painGroup("Cdtr", 0, 1, painLeaf("Nm", 0, 1)),
+				painAccountElement("CdtrAcct"),
+				painGroup("RmtInf", 0, 1, painLeaf("Ustrd", 0, 0)),
+			),
+		),
+	),
+)
+
+type painFrame struct {
+	element *painElement
+	path    string
+	base    string
+	counts  []int
+	last    int
+	hasText bool
+}
+
+func (f *painFrame) childPath(child *painElement, occurrence int) string {
+	name := child.name
+	if child.max != 1 {
+		name = fmt.Sprintf("%s[%d]", name, occurrence)
+	}
+	if f.base == "" {
+		return name
+	}
+	return f.base + "/" + name
+}
+
+func (f *painFrame) close(v *pain001Validator) {
+	if f.element.choice {
+		total := 0
+		names := make([]string, len(f.element.children))
+		for i, child := range f.element.children {
+			total += f.counts[i]
+			names[i] = child.name
+		}
+		if total != 1 {
+			v.fail("%s must contain either %s", f.path, strings.Join(names, " or "))
+		}
+		return
+	}
+
+	for i, child := range f.element.children {
+		if f.counts[i] < child.min {
+			v.fail("%s is required", f.childPath(child, f.counts[i]+1))
+		}
+	}
+}
+
+// checkPain001Structure walks the raw document against pain001Schema and
+// reports unknown elements and attributes, elements out of order or over
+// their cardinality, missing elements, text inside complex elements and any
+// content outside the Document element.
+func checkPain001Structure(data []byte) []string {
+	v := &pain001Validator{}
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+
+	var namespace string
+	var stack []*painFrame
+	done := false
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			v.fail("invalid XML: %v", err)
+			return v.errors
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if done {
+				v.fail("content outside Document is not allowed")
+				return v.errors
+			}
+
+			if len(stack) == 0 {
+				if t.Name.Local != pain001Schema.name {
+					v.fail("Document is required")
+					return v.errors
+				}
+				namespace = t.Name.Space
+				checkPainAttributes(v, pain001Schema, "Document", t.Attr)
+				stack = append(stack, &painFrame{element: pain001Schema, path: "Document", counts: make([]int, len(pain001Schema.children)), last: -1})
+				continue
+			}
+
+			parent := stack[len(stack)-1]
+			index := -1
+			if t.Name.Space == namespace {
+				for i, child := range parent.element.children {
+					if child.name == t.Name.Local {
+						index = i
+						break
+					}
+				}
+			}
+			if index < 0 {
+				v.fail("%s is not allowed", parent.childPath(&painElement{name: t.Name.Local, max: 1}, 1))
+				if err := decoder.Skip(); err != nil {
+					v.fail("invalid XML: %v", err)
+					return v.errors
+				}
+				continue
+			}
+
+			child := parent.element.children[index]
+			parent.counts[index]++
+			path := parent.childPath(child, parent.counts[index])
+			switch {
+			case child.max == 1 && parent.counts[index] > 1:
+				v.fail("%s occurs more than once", path)
+			case child.max != 0 && parent.counts[index] > child.max:
+				v.fail("%s occurs more than %d times", path, child.max)
+			case !parent.element.choice && index < parent.last:
+				v.fail("%s is out of order", path)
+			}
+			if index > parent.last {
+				parent.last = index
+			}
+
+			// Paths start below CstmrCdtTrfInitn, as they do in validatePain001.
+			base := path
+			if len(stack) == 1 {
+				base = ""
+			}
+
+			checkPainAttributes(v, child, path, t.Attr)
+			stack = append(stack, &painFrame{element: child, path: path, base: base, counts: make([]int, len(child.children)), last: -1})
+
+		case xml.EndElement:
+			frame := stack[len(stack)-1]
+			frame.close(v)
+			stack = stack[:len(stack)-1]
+			if len(stack) == 0 {
+				done = true
+			}
+
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+			if done || len(stack) == 0 {
+				v.fail("content outside Document is not allowed")
+				return v.errors
+			}
+			frame := stack[len(stack)-1]
+			if len(frame.element.children) > 0 && !frame.hasText {
+				frame.hasText = true
+				v.fail("%s must not contain text", frame.path)
+			}
+
+		case xml.Directive:
+			v.fail("XML directives are not allowed")
+			return v.errors
+		}
+	}
+
+	if !done {
+		v.fail("Document is required")
+	}
+
+	return v.errors
+}
+
+func checkPainAttributes(v *pain001Validator, element *painElement, path string, attrs []xml.Attr) {
+	seen := make(map[string]bool)
+	for _, attr := range attrs {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" || attr.Name.Space == xmlSchemaInstanceNamespace {
+			continue
+		}
+
+		allowed := false
+		if attr.Name.Space == "" {
+			for _, name := range element.attrs {
+				if name == attr.Name.Local {
+					allowed = true
+				}
+			}
+		}
+		if !allowed {
+			v.fail("%s/@%s is not allowed", path, attr.Name.Local)
+			continue
+		}
+		seen[attr.Name.Local] = true
+	}
+
+	for _, name := range element.attrs {
+		if !seen[name] {
+			v.fail("%s/@%s is required", path, name)
+		}
+	}
+}
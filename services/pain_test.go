@@ -0,0 +1,310 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const validPain001 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09">
+  <CstmrCdtTrfInitn>
+    <GrpHdr>
+      <MsgId>MSG-0001</MsgId>
+      <CreDtTm>2026-10-18T09:30:00+07:00</CreDtTm>
+      <NbOfTxs>2</NbOfTxs>
+      <CtrlSum>150.25</CtrlSum>
+      <InitgPty><Nm>Acme Payroll</Nm></InitgPty>
+    </GrpHdr>
+    <PmtInf>
+      <PmtInfId>PMT-1</PmtInfId>
+      <PmtMtd>TRF</PmtMtd>
+      <NbOfTxs>2</NbOfTxs>
+      <CtrlSum>150.25</CtrlSum>
+      <ReqdExctnDt><Dt>2026-10-19</Dt></ReqdExctnDt>
+      <Dbtr><Nm>Acme</Nm></Dbtr>
+      <DbtrAcct><Id><Othr><Id>1234567890</Id></Othr></Id><Ccy>IDR</Ccy></DbtrAcct>
+      <CdtTrfTxInf>
+        <PmtId><InstrId>I-1</InstrId><EndToEndId>E2E-1</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="IDR">100.00</InstdAmt></Amt>
+        <Cdtr><Nm>Jane</Nm></Cdtr>
+        <CdtrAcct><Id><IBAN>ID12BANK0000000001</IBAN></Id></CdtrAcct>
+        <RmtInf><Ustrd>October salary</Ustrd></RmtInf>
+      </CdtTrfTxInf>
+      <CdtTrfTxInf>
+        <PmtId><EndToEndId>E2E-2</EndToEndId></PmtId>
+        <Amt><InstdAmt Ccy="IDR">50.25</InstdAmt></Amt>
+        <CdtrAcct><Id><Othr><Id>0987654321</Id></Othr></Id></CdtrAcct>
+      </CdtTrfTxInf>
+    </PmtInf>
+  </CstmrCdtTrfInitn>
+</Document>`
+
+func parsePain001(t *testing.T, data string) *pain001Document {
+	t.Helper()
+	var doc pain001Document
+	if err := xml.Unmarshal([]byte(data), &doc); err != nil {
+		t.Fatal(err)
+	}
+	return &doc
+}
+
+func TestValidatePain001Valid(t *testing.T) {
+	doc := parsePain001(t, validPain001)
+
+	if reasons := validatePain001(doc); len(reasons) > 0 {
+		t.Fatalf("validatePain001 = %q, want no reasons", reasons)
+	}
+	if total := instructedTotal(doc); total != 150.25 {
+		t.Errorf("instructedTotal = %v, want 150.25", total)
+	}
+	if id := messageIDOf(doc); id != "MSG-0001" {
+		t.Errorf("messageIDOf = %q, want MSG-0001", id)
+	}
+}
+
+func TestValidatePain001Reasons(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{"wrong namespace", "pain.001.001.09", "pain.001.001.03", "Document namespace must be"},
+		{"missing message ID", "<MsgId>MSG-0001</MsgId>", "", "GrpHdr/MsgId is required"},
+		{"long message ID", "MSG-0001", strings.Repeat("M", 36), "GrpHdr/MsgId exceeds 35 characters"},
+		{"bad creation time", "2026-10-18T09:30:00+07:00", "18/10/2026", "GrpHdr/CreDtTm has an invalid format"},
+		{"group count mismatch", "<NbOfTxs>2</NbOfTxs>\n      <CtrlSum>150.25</CtrlSum>\n      <InitgPty>", "<NbOfTxs>3</NbOfTxs>\n      <CtrlSum>150.25</CtrlSum>\n      <InitgPty>", "GrpHdr/NbOfTxs is 3 but the file contains 2 transactions"},
+		{"group control sum mismatch", "<CtrlSum>150.25</CtrlSum>\n      <InitgPty>", "<CtrlSum>150.26</CtrlSum>\n      <InitgPty>", "GrpHdr/CtrlSum is 150.26 but the instructed amounts total 150.25"},
+		{"unknown payment method", "<PmtMtd>TRF</PmtMtd>", "<PmtMtd>DD</PmtMtd>", "PmtInf[1]/PmtMtd must be one of TRF, CHK or TRA"},
+		{"missing execution date", "<ReqdExctnDt><Dt>2026-10-19</Dt></ReqdExctnDt>", "", "PmtInf[1]/ReqdExctnDt is required"},
+		{"bad execution date", "<Dt>2026-10-19</Dt>", "<Dt>19-10-2026</Dt>", "PmtInf[1]/ReqdExctnDt/Dt has an invalid format"},
+		{"debtor with IBAN and other", "<DbtrAcct><Id><Othr>", "<DbtrAcct><Id><IBAN>ID12BANK0000000001</IBAN><Othr>", "PmtInf[1]/DbtrAcct/Id must contain either IBAN or Othr"},
+		{"bad IBAN", "ID12BANK0000000001", "id12", "PmtInf[1]/CdtTrfTxInf[1]/CdtrAcct/Id/IBAN has an invalid format"},
+		{"missing end-to-end ID", "<EndToEndId>E2E-2</EndToEndId>", "", "PmtInf[1]/CdtTrfTxInf[2]/PmtId/EndToEndId is required"},
+		{"missing amount", `<Amt><InstdAmt Ccy="IDR">50.25</InstdAmt></Amt>`, "", "PmtInf[1]/CdtTrfTxInf[2]/Amt/InstdAmt is required"},
+		{"bad currency", `Ccy="IDR">50.25`, `Ccy="idr">50.25`, "PmtInf[1]/CdtTrfTxInf[2]/Amt/InstdAmt/@Ccy has an invalid format"},
+		{"negative amount", ">50.25<", ">-50.25<", "PmtInf[1]/CdtTrfTxInf[2]/Amt/InstdAmt has an invalid format"},
+		{"long remittance", "October salary", strings.Repeat("x", 141), "PmtInf[1]/CdtTrfTxInf[1]/RmtInf/Ustrd[1] exceeds 140 characters"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(validPain001, tt.old, tt.new, 1)
+			if data == validPain001 {
+				t.Fatalf("%q is not in the document", tt.old)
+			}
+
+			reasons := validatePain001(parsePain001(t, data))
+			found := false
+			for _, reason := range reasons {
+				if reason == tt.want || strings.HasPrefix(reason, tt.want) {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("validatePain001 = %q, want %q", reasons, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidatePain001Structure(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  *pain001Document
+		want string
+	}{
+		{"no initiation", &pain001Document{XMLName: xml.Name{Space: pain001Namespace}}, "CstmrCdtTrfInitn is required"},
+		{"no group header", &pain001Document{XMLName: xml.Name{Space: pain001Namespace}, Initiation: &pain001Initiation{}}, "GrpHdr is required"},
+		{"no payments", &pain001Document{XMLName: xml.Name{Space: pain001Namespace}, Initiation: &pain001Initiation{GroupHeader: &pain001GroupHeader{
+			MessageID: "MSG", CreationDateTime: "2026-10-18T09:30:00Z", NumberOfTransactions: "0",
+		}}}, "at least one PmtInf is required"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reasons := validatePain001(tt.doc)
+			if len(reasons) == 0 || reasons[len(reasons)-1] != tt.want {
+				t.Errorf("validatePain001 = %q, want %q", reasons, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckPain001StructureValid(t *testing.T) {
+	if reasons := checkPain001Structure([]byte(validPain001)); len(reasons) > 0 {
+		t.Fatalf("checkPain001Structure = %q, want no reasons", reasons)
+	}
+
+	withSchemaLocation := strings.Replace(validPain001, `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09">`,
+		`<Document xmlns="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xsi:schemaLocation="urn:iso:std:iso:20022:tech:xsd:pain.001.001.09 pain.001.001.09.xsd">`, 1)
+	if reasons := checkPain001Structure([]byte(withSchemaLocation + "\n<!-- end -->\n")); len(reasons) > 0 {
+		t.Errorf("checkPain001Structure = %q, want no reasons", reasons)
+	}
+
+	withAgents := strings.Replace(validPain001, "</Othr></Id><Ccy>IDR</Ccy></DbtrAcct>",
+		"</Othr></Id><Ccy>IDR</Ccy></DbtrAcct>\n      <DbtrAgt><FinInstnId><BICFI>BANKIDJA</BICFI></FinInstnId></DbtrAgt>", 1)
+	withAgents = strings.Replace(withAgents, "<Cdtr><Nm>Jane</Nm></Cdtr>",
+		"<CdtrAgt><FinInstnId><Othr><Id>014</Id></Othr></FinInstnId></CdtrAgt>\n        <Cdtr><Nm>Jane</Nm></Cdtr>", 1)
+	if reasons := checkPain001Structure([]byte(withAgents)); len(reasons) > 0 {
+		t.Errorf("checkPain001Structure = %q, want no reasons", reasons)
+	}
+}
+
+func TestCheckPain001StructureReasons(t *testing.T) {
+	tests := []struct {
+		name string
+		old  string
+		new  string
+		want string
+	}{
+		{"unknown group header element", "<MsgId>MSG-0001</MsgId>", "<MsgId>MSG-0001</MsgId><Authstn><Cd>AUTH</Cd></Authstn>", "GrpHdr/Authstn is not allowed"},
+		{"agent after creditor", "<Cdtr><Nm>Jane</Nm></Cdtr>", "<Cdtr><Nm>Jane</Nm></Cdtr><CdtrAgt><FinInstnId><BICFI>BANKIDJA</BICFI></FinInstnId></CdtrAgt>", "PmtInf[1]/CdtTrfTxInf[1]/CdtrAgt is out of order"},
+		{"unknown transaction element", "<Cdtr><Nm>Jane</Nm></Cdtr>", "<Purp><Cd>SALA</Cd></Purp><Cdtr><Nm>Jane</Nm></Cdtr>", "PmtInf[1]/CdtTrfTxInf[1]/Purp is not allowed"},
+		{"element inside a leaf", "<PmtMtd>TRF</PmtMtd>", "<PmtMtd><Cd>TRF</Cd></PmtMtd>", "PmtInf[1]/PmtMtd/Cd is not allowed"},
+		{"foreign namespace", "<Dbtr><Nm>Acme</Nm></Dbtr>", `<Dbtr><Nm>Acme</Nm><x:Nm xmlns:x="urn:example">Other</x:Nm></Dbtr>`, "PmtInf[1]/Dbtr/Nm is not allowed"},
+		{"header out of order", "<MsgId>MSG-0001</MsgId>\n      <CreDtTm>2026-10-18T09:30:00+07:00</CreDtTm>", "<CreDtTm>2026-10-18T09:30:00+07:00</CreDtTm>\n      <MsgId>MSG-0001</MsgId>", "GrpHdr/MsgId is out of order"},
+		{"amount before payment ID", "<PmtId><EndToEndId>E2E-2</EndToEndId></PmtId>\n        <Amt><InstdAmt Ccy=\"IDR\">50.25</InstdAmt></Amt>", "<Amt><InstdAmt Ccy=\"IDR\">50.25</InstdAmt></Amt>\n        <PmtId><EndToEndId>E2E-2</EndToEndId></PmtId>", "PmtInf[1]/CdtTrfTxInf[2]/PmtId is out of order"},
+		{"group header after payment", "</PmtInf>", "</PmtInf><GrpHdr><MsgId>M</MsgId></GrpHdr>", "GrpHdr occurs more than once"},
+		{"repeated message ID", "<MsgId>MSG-0001</MsgId>", "<MsgId>MSG-0001</MsgId><MsgId>MSG-0002</MsgId>", "GrpHdr/MsgId occurs more than once"},
+		{"missing initiating party", "<InitgPty><Nm>Acme Payroll</Nm></InitgPty>", "", "GrpHdr/InitgPty is required"},
+		{"missing payment count", "<PmtMtd>TRF</PmtMtd>\n      <NbOfTxs>2</NbOfTxs>", "<PmtMtd>TRF</PmtMtd>", "PmtInf[1]/NbOfTxs is required"},
+		{"missing debtor", "<Dbtr><Nm>Acme</Nm></Dbtr>", "", "PmtInf[1]/Dbtr is required"},
+		{"missing creditor account", "<CdtrAcct><Id><Othr><Id>0987654321</Id></Othr></Id></CdtrAcct>", "", "PmtInf[1]/CdtTrfTxInf[2]/CdtrAcct is required"},
+		{"execution date and time", "<Dt>2026-10-19</Dt>", "<Dt>2026-10-19</Dt><DtTm>2026-10-19T09:00:00</DtTm>", "PmtInf[1]/ReqdExctnDt must contain either Dt or DtTm"},
+		{"empty account ID", "<CdtrAcct><Id><IBAN>ID12BANK0000000001</IBAN></Id>", "<CdtrAcct><Id></Id>", "PmtInf[1]/CdtTrfTxInf[1]/CdtrAcct/Id must contain either IBAN or Othr"},
+		{"text in a group", "<Cdtr><Nm>Jane</Nm></Cdtr>", "<Cdtr>Jane<Nm>Jane</Nm></Cdtr>", "PmtInf[1]/CdtTrfTxInf[1]/Cdtr must not contain text"},
+		{"unknown attribute", `<InstdAmt Ccy="IDR">100.00</InstdAmt>`, `<InstdAmt Ccy="IDR" Rate="1">100.00</InstdAmt>`, "PmtInf[1]/CdtTrfTxInf[1]/Amt/InstdAmt/@Rate is not allowed"},
+		{"missing currency attribute", `<InstdAmt Ccy="IDR">100.00</InstdAmt>`, `<InstdAmt>100.00</InstdAmt>`, "PmtInf[1]/CdtTrfTxInf[1]/Amt/InstdAmt/@Ccy is required"},
+		{"trailing element", "</Document>", "</Document>\n<Document/>", "content outside Document is not allowed"},
+		{"trailing text", "</Document>", "</Document>\ntrailer", "content outside Document is not allowed"},
+		{"doctype", "<Document ", "<!DOCTYPE Document>\n<Document ", "XML directives are not allowed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := strings.Replace(validPain001, tt.old, tt.new, 1)
+			if data == validPain001 {
+				t.Fatalf("%q is not in the document", tt.old)
+			}
+
+			reasons := checkPain001Structure([]byte(data))
+			found := false
+			for _, reason := range reasons {
+				if reason == tt.want {
+					found = true
+				}
+			}
+			if !found {
+				t.Errorf("checkPain001Structure = %q, want %q", reasons, tt.want)
+			}
+		})
+	}
+}
+
+func TestInstructedAmount(t *testing.T) {
+	tests := []struct {
+		currency string
+		value    string
+		amount   float64
+		code     string
+	}{
+		{"IDR", "100", 100, ""},
+		{"IDR", " 100.5 ", 100.5, ""},
+		{"IDR", "0.01", 0.01, ""},
+		{"USD", "100", 0, "AM03"},
+		{"IDR", "0", 0, "AM12"},
+		{"IDR", "0.00", 0, "AM12"},
+		{"IDR", "10.001", 0, "AM12"},
+		{"IDR", "ten", 0, "AM12"},
+	}
+
+	for _, tt := range tests {
+		amount, code, info := instructedAmount(&pain001Amount{Currency: tt.currency, Value: tt.value}, "IDR")
+		if amount != tt.amount || code != tt.code {
+			t.Errorf("instructedAmount(%s %q) = %v, %q, want %v, %q", tt.currency, tt.value, amount, code, tt.amount, tt.code)
+		}
+		if (code == "") != (info == "") {
+			t.Errorf("instructedAmount(%s %q): code %q with info %q", tt.currency, tt.value, code, info)
+		}
+	}
+}
+
+func TestTransferReasonCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{ErrInsufficientBalance, "AM04"},
+		{ErrSameAccountTransfer, "AG01"},
+		{ErrAccountFrozen, "AC06"},
+		{fmt.Errorf("savepoint: %w", ErrAccountFrozen), "AC06"},
+		{errors.New("account not found"), "MS03"},
+	}
+
+	for _, tt := range tests {
+		if got := transferReasonCode(tt.err); got != tt.want {
+			t.Errorf("transferReasonCode(%v) = %s, want %s", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestAggregateStatus(t *testing.T) {
+	tests := []struct {
+		accepted, total int
+		want            string
+	}{
+		{2, 2, paymentStatusAccepted},
+		{1, 2, paymentStatusPartial},
+		{0, 2, paymentStatusRejected},
+		{0, 0, paymentStatusRejected},
+	}
+
+	for _, tt := range tests {
+		if got := aggregateStatus(tt.accepted, tt.total); got != tt.want {
+			t.Errorf("aggregateStatus(%d, %d) = %s, want %s", tt.accepted, tt.total, got, tt.want)
+		}
+	}
+}
+
+func TestRejectedReport(t *testing.T) {
+	doc := parsePain001(t, validPain001)
+	reasons := []string{"first", strings.Repeat("x", 200)}
+
+	var report pain002Document
+	if err := xml.Unmarshal(rejectedReport("MSG-0001", doc, "FF01", reasons), &report); err != nil {
+		t.Fatal(err)
+	}
+
+	group := report.Report.OriginalGroup
+	if group.OriginalMessageID != "MSG-0001" || group.OriginalMessageNameID != pain001MessageName {
+		t.Errorf("original message = %s %s", group.OriginalMessageID, group.OriginalMessageNameID)
+	}
+	if group.GroupStatus != paymentStatusRejected {
+		t.Errorf("GrpSts = %s, want %s", group.GroupStatus, paymentStatusRejected)
+	}
+	if group.OriginalNumberOfTransactions != "2" || group.OriginalControlSum != "150.25" {
+		t.Errorf("original totals = %s, %s", group.OriginalNumberOfTransactions, group.OriginalControlSum)
+	}
+	if len(group.StatusReasons) != len(reasons) {
+		t.Fatalf("%d status reasons, want %d", len(group.StatusReasons), len(reasons))
+	}
+	for _, reason := range group.StatusReasons {
+		if reason.Code != "FF01" {
+			t.Errorf("reason code = %s, want FF01", reason.Code)
+		}
+		if len(reason.AdditionalInfo) != 1 || len(reason.AdditionalInfo[0]) > 105 {
+			t.Errorf("AddtlInf = %q, want one line of at most 105 characters", reason.AdditionalInfo)
+		}
+	}
+
+	if err := xml.Unmarshal(rejectedReport("", nil, "FF01", nil), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Report.OriginalGroup.OriginalMessageID != "UNKNOWN" {
+		t.Errorf("OrgnlMsgId = %s, want UNKNOWN", report.Report.OriginalGroup.OriginalMessageID)
+	}
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+const defaultBreachedPasswordList = "data/breached-passwords.txt"
+
+// bcrypt ignores everything after 72 bytes, so longer passwords would give a
+// false sense of strength.
+const passwordMaxLength = 72
+
+// PasswordPolicy is read from the environment once per service. The breached
+// list is loaded lazily and shared by every policy using the same file.
+type PasswordPolicy struct {
+	MinLength        int
+	RequireUpper     bool
+	RequireLower     bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	HistorySize      int
+	BreachedListPath string
+}
+
+// PasswordPolicyError lists every rule a password broke so that the client
+// can show them all at once.
+type PasswordPolicyError struct {
+	Problems []string
+}
+
+func (e *PasswordPolicyError) Error() string {
+	return "password does not meet the policy: " + strings.Join(e.Problems, "; ")
+}
+
+var (
+	breachedPasswords   = map[string]map[string]bool{}
+	breachedPasswordsMu sync.Mutex
+)
+
+func PasswordPolicyFromEnv() PasswordPolicy {
+	policy := PasswordPolicy{
+		MinLength:        envInt("PASSWORD_MIN_LENGTH", 10),
+		RequireUpper:     envBool("PASSWORD_REQUIRE_UPPER", true),
+		RequireLower:     envBool("PASSWORD_REQUIRE_LOWER", true),
+		RequireDigit:     envBool("PASSWORD_REQUIRE_DIGIT", true),
+		RequireSymbol:    envBool("PASSWORD_REQUIRE_SYMBOL", false),
+		HistorySize:      envInt("PASSWORD_HISTORY_SIZE", 5),
+		BreachedListPath: defaultBreachedPasswordList,
+	}
+
+	// An explicitly empty PASSWORD_BREACHED_LIST turns the check off.
+	if path, ok := os.LookupEnv("PASSWORD_BREACHED_LIST"); ok {
+		policy.BreachedListPath = path
+	}
+
+	return policy
+}
+
+// Validate returns a *PasswordPolicyError when the password breaks a rule,
+// or another error if the breached password list cannot be read.
+func (p PasswordPolicy) Validate(password string) error {
+	var problems []string
+
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters long", p.MinLength))
+	}
+	if len(password) > passwordMaxLength {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes long", passwordMaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			hasSymbol = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		problems = append(problems, "must contain an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		problems = append(problems, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		problems = append(problems, "must contain a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		problems = append(problems, "must contain a symbol")
+	}
+
+	if p.BreachedListPath != "" {
+		breached, err := loadBreachedPasswords(p.BreachedListPath)
+		if err != nil {
+			return err
+		}
+		if breached[strings.ToLower(password)] {
+			problems = append(problems, "appears in a list of breached passwords")
+		}
+	}
+
+	if len(problems) > 0 {
+		return &PasswordPolicyError{Problems: problems}
+	}
+
+	return nil
+}
+
+// Rules describes the policy for clients that want to validate as the user
+// types.
+func (p PasswordPolicy) Rules() map[string]interface{} {
+	return map[string]interface{}{
+		"min_length":     p.MinLength,
+		"max_length":     passwordMaxLength,
+		"require_upper":  p.RequireUpper,
+		"require_lower":  p.RequireLower,
+		"require_digit":  p.RequireDigit,
+		"require_symbol": p.RequireSymbol,
+		"history_size":   p.HistorySize,
+		"breached_check": p.BreachedListPath != "",
+	}
+}
+
+// loadBreachedPasswords reads a list with one password per line, ignoring
+// blank lines and lines starting with "#". A failed read is not cached so
+// that fixing the file does not require a restart.
+func loadBreachedPasswords(path string) (map[string]bool, error) {
+	breachedPasswordsMu.Lock()
+	defer breachedPasswordsMu.Unlock()
+
+	if list, ok := breachedPasswords[path]; ok {
+		return list, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %v", err)
+	}
+	defer file.Close()
+
+	list := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		list[strings.ToLower(line)] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %v", err)
+	}
+
+	breachedPasswords[path] = list
+	return list, nil
+}
+
+func envBool(name string, fallback bool) bool {
+	switch strings.ToLower(os.Getenv(name)) {
+	case "true", "1", "yes":
+		return true
+	case "false", "0", "no":
+		return false
+	}
+	return fallback
+}
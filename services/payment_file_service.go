@@ -0,0 +1,838 @@
+package services
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	paymentStatusAccepted = "ACSC"
+	paymentStatusPartial  = "PART"
+	paymentStatusRejected = "RJCT"
+)
+
+// A payment file whose progress has not moved for this long is assumed to have
+// been interrupted and may be resumed.
+const paymentFileStaleAfter = 5 * time.Minute
+
+var (
+	ErrDuplicatePaymentFile  = errors.New("payment file has already been submitted")
+	ErrPaymentFileInProgress = errors.New("payment file is still being processed")
+)
+
+type PaymentFileRejectedError struct {
+	Reasons []string
+}
+
+func (e *PaymentFileRejectedError) Error() string {
+	return "payment file rejected: " + strings.Join(e.Reasons, "; ")
+}
+
+type PaymentFileService struct {
+	db                 *gorm.DB
+	transactionService *TransactionService
+	accountService     *AccountService
+	stepUpService      *StepUpService
+}
+
+func NewPaymentFileService(db *gorm.DB) *PaymentFileService {
+	return &PaymentFileService{
+		db:                 db,
+		transactionService: NewTransactionService(db),
+		accountService:     NewAccountService(db),
+		stepUpService:      NewStepUpService(db),
+	}
+}
+
+// ImportPain001 validates and stores a pain.001 file. Files that the step-up
+// policy flags are held with a single challenge for the whole file, which must
+// be answered through VerifyPaymentFile; others are executed straight away.
+func (s *PaymentFileService) ImportPain001(accountID, userID string, data []byte, method models.StepUpMethod, actor AuditActor) (*models.PaymentFile, []byte, *models.TransferChallenge, error) {
+	account, err := s.accountService.GetAccountByID(accountID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	user, err := s.stepUpService.mfaService.findUser(s.db, userID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var doc pain001Document
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&doc); err != nil {
+		reasons := []string{fmt.Sprintf("invalid XML: %v", err)}
+		return nil, rejectedReport("", nil, "FF01", reasons), nil, &PaymentFileRejectedError{Reasons: reasons}
+	}
+
+	if reasons := checkPain001Structure(data); len(reasons) > 0 {
+		return nil, rejectedReport(messageIDOf(&doc), &doc, "FF01", reasons), nil, &PaymentFileRejectedError{Reasons: reasons}
+	}
+
+	if reasons := validatePain001(&doc); len(reasons) > 0 {
+		return nil, rejectedReport(messageIDOf(&doc), &doc, "FF01", reasons), nil, &PaymentFileRejectedError{Reasons: reasons}
+	}
+
+	header := doc.Initiation.GroupHeader
+	for i, payment := range doc.Initiation.PaymentInfos {
+		if !s.isDebtorAccount(payment.DebtorAccount, account) {
+			reasons := []string{fmt.Sprintf("PmtInf[%d]/DbtrAcct does not match account %s", i+1, account.AccountNumber)}
+			return nil, rejectedReport(header.MessageID, &doc, "AC01", reasons), nil, &PaymentFileRejectedError{Reasons: reasons}
+		}
+		if payment.DebtorAccount.Currency != "" && payment.DebtorAccount.Currency != account.Currency {
+			reasons := []string{fmt.Sprintf("PmtInf[%d]/DbtrAcct/Ccy does not match account currency %s", i+1, account.Currency)}
+			return nil, rejectedReport(header.MessageID, &doc, "AM03", reasons), nil, &PaymentFileRejectedError{Reasons: reasons}
+		}
+	}
+
+	stepUpReasons, err := s.stepUpReasons(user.ID, account, &doc)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var challenge *models.TransferChallenge
+	var otp string
+	if len(stepUpReasons) > 0 {
+		challenge, otp, err = s.stepUpService.newChallenge(user, method, stepUpReasons, actor)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+
+	numberOfTransactions, _ := strconv.Atoi(header.NumberOfTransactions)
+	paymentFile := &models.PaymentFile{
+		AccountID:            account.ID,
+		UserID:               user.ID,
+		MessageID:            header.MessageID,
+		Status:               models.PaymentFileStatusProcessing,
+		NumberOfTransactions: numberOfTransactions,
+		ControlSum:           instructedTotal(&doc),
+		Document:             string(data),
+	}
+	if challenge != nil {
+		paymentFile.Status = models.PaymentFileStatusAwaitingConfirmation
+	}
+
+	var items []models.PaymentFileItem
+	for _, payment := range doc.Initiation.PaymentInfos {
+		for _, transaction := range payment.Transactions {
+			items = append(items, models.PaymentFileItem{
+				Position:   len(items),
+				EndToEndID: transaction.EndToEndID,
+				Status:     models.PaymentFileItemStatusPending,
+			})
+		}
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(paymentFile).Error; err != nil {
+			return err
+		}
+		for i := range items {
+			items[i].PaymentFileID = paymentFile.ID
+		}
+		if err := tx.Create(&items).Error; err != nil {
+			return err
+		}
+		if err := recordAudit(tx, actor, models.AuditActionPaymentFileCreate, models.AuditTargetPaymentFile, paymentFile.ID.String(), nil, auditPaymentFile(paymentFile)); err != nil {
+			return err
+		}
+
+		if challenge == nil {
+			return nil
+		}
+		challenge.PaymentFileID = &paymentFile.ID
+		if err := tx.Create(challenge).Error; err != nil {
+			return fmt.Errorf("failed to create transfer challenge: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		var existing models.PaymentFile
+		if findErr := s.db.Unscoped().Where("account_id = ? AND message_id = ?", account.ID, header.MessageID).First(&existing).Error; findErr == nil {
+			paymentFile, reportXML, err := s.resubmitted(&existing, &doc, account, actor)
+			return paymentFile, reportXML, nil, err
+		}
+		return nil, nil, nil, fmt.Errorf("failed to create payment file: %v", err)
+	}
+
+	if challenge != nil {
+		// As for single transfers, the code is mailed only once the challenge
+		// exists, and a file whose code could not be delivered is cancelled.
+		if otp != "" {
+			purpose := fmt.Sprintf("payment file %s with %d transfers totalling %.2f %s", paymentFile.MessageID, len(items), paymentFile.ControlSum, account.Currency)
+			if err := s.stepUpService.sendOTP(user, purpose, otp); err != nil {
+				if cancelErr := s.db.Transaction(func(tx *gorm.DB) error {
+					return cancelPaymentFile(tx, paymentFile, actor)
+				}); cancelErr != nil {
+					return nil, nil, nil, cancelErr
+				}
+				return nil, nil, nil, fmt.Errorf("failed to send verification code: %v", err)
+			}
+		}
+		return paymentFile, nil, challenge, nil
+	}
+
+	reportXML, err := s.process(paymentFile, &doc, account, actor)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return paymentFile, reportXML, nil, nil
+}
+
+// VerifyPaymentFile checks the second factor for a held payment file and then
+// executes it. Wrong codes are counted and the file is cancelled once the
+// attempts run out or the challenge expires.
+func (s *PaymentFileService) VerifyPaymentFile(userID, accountID, paymentFileID, code string, actor AuditActor) (*models.PaymentFile, []byte, error) {
+	var paymentFile models.PaymentFile
+	var outcome error
+
+	id, err := uuid.Parse(paymentFileID)
+	if err != nil {
+		return nil, nil, ErrTransferChallengeNotFound
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.stepUpService.mfaService.findUser(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND account_id = ?", id, accountID).
+			First(&paymentFile).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransferChallengeNotFound
+			}
+			return fmt.Errorf("failed to find payment file: %v", err)
+		}
+
+		var challenge models.TransferChallenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("payment_file_id = ? AND user_id = ?", paymentFile.ID, user.ID).
+			First(&challenge).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransferChallengeNotFound
+			}
+			return fmt.Errorf("failed to find transfer challenge: %v", err)
+		}
+
+		switch {
+		case challenge.VerifiedAt != nil:
+			return ErrTransferAlreadyConfirmed
+		case paymentFile.Status != models.PaymentFileStatusAwaitingConfirmation:
+			return ErrTransferChallengeExpired
+		}
+
+		now := time.Now()
+		if now.After(challenge.ExpiresAt) || challenge.Attempts >= models.TransferChallengeMaxAttempts {
+			outcome = ErrTransferChallengeExpired
+			return cancelPaymentFile(tx, &paymentFile, actor)
+		}
+
+		valid, err := s.stepUpService.checkCode(tx, user, &challenge, code)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			if err := tx.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+				return fmt.Errorf("failed to update transfer challenge: %v", err)
+			}
+			outcome = ErrInvalidStepUpCode
+			if challenge.Attempts+1 >= models.TransferChallengeMaxAttempts {
+				outcome = ErrTransferChallengeLocked
+				return cancelPaymentFile(tx, &paymentFile, actor)
+			}
+			return nil
+		}
+
+		if err := tx.Model(&challenge).Update("verified_at", now).Error; err != nil {
+			return fmt.Errorf("failed to complete transfer challenge: %v", err)
+		}
+
+		paymentFile.Status = models.PaymentFileStatusProcessing
+		if err := tx.Model(&paymentFile).Update("status", paymentFile.Status).Error; err != nil {
+			return fmt.Errorf("failed to update payment file: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if outcome != nil {
+		return nil, nil, outcome
+	}
+
+	account, err := s.accountService.GetAccountByID(accountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reportXML, err := s.resume(&paymentFile, account, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &paymentFile, reportXML, nil
+}
+
+// CancelExpiredPaymentFiles cancels held payment files whose challenge expired
+// without being answered.
+func (s *PaymentFileService) CancelExpiredPaymentFiles(now time.Time) (int64, error) {
+	var cancelled int64
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var paymentFiles []models.PaymentFile
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND id IN (?)", models.PaymentFileStatusAwaitingConfirmation,
+				tx.Model(&models.TransferChallenge{}).Select("payment_file_id").Where("verified_at IS NULL AND expires_at < ?", now)).
+			Find(&paymentFiles).Error; err != nil {
+			return fmt.Errorf("failed to find expired payment files: %v", err)
+		}
+
+		for i := range paymentFiles {
+			if err := cancelPaymentFile(tx, &paymentFiles[i], SystemAuditActor("payment_file_expiry")); err != nil {
+				return err
+			}
+			cancelled++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return cancelled, nil
+}
+
+// stepUpReasons runs every transfer in the file through the step-up policy.
+// The file as a whole is also checked, so that splitting a large payment
+// into many small ones does not avoid the amount and velocity rules.
+func (s *PaymentFileService) stepUpReasons(userID uuid.UUID, account *models.Account, doc *pain001Document) ([]models.StepUpReason, error) {
+	reasons := []models.StepUpReason{}
+	seen := map[models.StepUpReason]bool{}
+	add := func(reason models.StepUpReason) {
+		if !seen[reason] {
+			seen[reason] = true
+			reasons = append(reasons, reason)
+		}
+	}
+
+	now := time.Now()
+	count := 0
+	for _, payment := range doc.Initiation.PaymentInfos {
+		for _, transaction := range payment.Transactions {
+			count++
+
+			amount, err := strconv.ParseFloat(strings.TrimSpace(transaction.Amount.Value), 64)
+			if err != nil || amount <= 0 {
+				continue
+			}
+			creditor, err := s.resolveAccount(transaction.CreditorAccount)
+			if err != nil {
+				continue
+			}
+
+			transferReasons, err := s.stepUpService.Evaluate(userID, account, creditor, amount, now)
+			if err != nil {
+				return nil, err
+			}
+			for _, reason := range transferReasons {
+				add(reason)
+			}
+		}
+	}
+
+	policy := s.stepUpService.policy
+	if policy.AmountThreshold > 0 && instructedTotal(doc) >= policy.AmountThreshold {
+		add(models.StepUpReasonAmountThreshold)
+	}
+	if policy.VelocityCount > 0 && count >= policy.VelocityCount {
+		add(models.StepUpReasonVelocity)
+	}
+
+	return reasons, nil
+}
+
+func cancelPaymentFile(tx *gorm.DB, paymentFile *models.PaymentFile, actor AuditActor) error {
+	before := auditPaymentFile(paymentFile)
+	paymentFile.Status = models.PaymentFileStatusCancelled
+	paymentFile.RejectedCount = paymentFile.NumberOfTransactions
+	paymentFile.StatusReport = string(rejectedReport(paymentFile.MessageID, nil, "NARR", []string{"payment file was not confirmed"}))
+
+	if err := tx.Model(paymentFile).Updates(map[string]interface{}{
+		"status":         paymentFile.Status,
+		"rejected_count": paymentFile.RejectedCount,
+		"status_report":  paymentFile.StatusReport,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to cancel payment file: %v", err)
+	}
+
+	return recordAudit(tx, actor, models.AuditActionPaymentFileCancel, models.AuditTargetPaymentFile, paymentFile.ID.String(), before, auditPaymentFile(paymentFile))
+}
+
+// resubmitted handles a file whose MsgId was already used. A file left in
+// processing by a crash is resumed from its stored document rather than
+// rejected, which would otherwise leave it unfinished forever.
+func (s *PaymentFileService) resubmitted(existing *models.PaymentFile, doc *pain001Document, account *models.Account, actor AuditActor) (*models.PaymentFile, []byte, error) {
+	header := doc.Initiation.GroupHeader
+	if existing.Status != models.PaymentFileStatusProcessing || existing.Document == "" {
+		return nil, rejectedReport(header.MessageID, doc, "DU01", []string{"duplicate MsgId"}), ErrDuplicatePaymentFile
+	}
+
+	claimed, err := s.claim(existing)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !claimed {
+		return nil, nil, ErrPaymentFileInProgress
+	}
+
+	reportXML, err := s.resume(existing, account, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return existing, reportXML, nil
+}
+
+// ResumeStalledFiles finishes payment files whose processing was interrupted,
+// for example by a restart, and returns how many were completed.
+func (s *PaymentFileService) ResumeStalledFiles(now time.Time) (int, error) {
+	var paymentFiles []models.PaymentFile
+	if err := s.db.Where("status = ? AND updated_at < ? AND document <> ''", models.PaymentFileStatusProcessing, now.Add(-paymentFileStaleAfter)).
+		Find(&paymentFiles).Error; err != nil {
+		return 0, fmt.Errorf("failed to find stalled payment files: %v", err)
+	}
+
+	resumed := 0
+	for i := range paymentFiles {
+		paymentFile := &paymentFiles[i]
+
+		claimed, err := s.claim(paymentFile)
+		if err != nil {
+			return resumed, err
+		}
+		if !claimed {
+			continue
+		}
+
+		account, err := s.accountService.GetAccountByID(paymentFile.AccountID.String())
+		if err != nil {
+			return resumed, err
+		}
+
+		if _, err := s.resume(paymentFile, account, SystemAuditActor("payment_files")); err != nil {
+			return resumed, err
+		}
+		resumed++
+	}
+
+	return resumed, nil
+}
+
+// claim takes over a stalled file by moving its progress timestamp, so that
+// only one caller resumes it.
+func (s *PaymentFileService) claim(paymentFile *models.PaymentFile) (bool, error) {
+	now := time.Now()
+	result := s.db.Model(&models.PaymentFile{}).
+		Where("id = ? AND status = ? AND updated_at < ?", paymentFile.ID, models.PaymentFileStatusProcessing, now.Add(-paymentFileStaleAfter)).
+		UpdateColumn("updated_at", now)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to claim payment file: %v", result.Error)
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (s *PaymentFileService) resume(paymentFile *models.PaymentFile, account *models.Account, actor AuditActor) ([]byte, error) {
+	var doc pain001Document
+	if err := xml.Unmarshal([]byte(paymentFile.Document), &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse stored payment file: %v", err)
+	}
+
+	return s.process(paymentFile, &doc, account, actor)
+}
+
+// process executes the pending items of a payment file and stores the final
+// status report.
+func (s *PaymentFileService) process(paymentFile *models.PaymentFile, doc *pain001Document, account *models.Account, actor AuditActor) ([]byte, error) {
+	report, err := s.execute(doc, account, paymentFile, actor)
+	if err != nil {
+		return nil, err
+	}
+
+	reportXML, err := marshalPain002(report)
+	if err != nil {
+		return nil, err
+	}
+	paymentFile.StatusReport = string(reportXML)
+
+	if err := s.db.Model(paymentFile).Updates(map[string]interface{}{
+		"status":         paymentFile.Status,
+		"accepted_count": paymentFile.AcceptedCount,
+		"rejected_count": paymentFile.RejectedCount,
+		"status_report":  paymentFile.StatusReport,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update payment file: %v", err)
+	}
+
+	return reportXML, nil
+}
+
+func (s *PaymentFileService) GetPaymentFilesByAccountID(accountID string) ([]models.PaymentFile, error) {
+	var paymentFiles []models.PaymentFile
+
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	if err := s.db.Where("account_id = ?", id).Order("created_at DESC").Find(&paymentFiles).Error; err != nil {
+		return nil, fmt.Errorf("failed to find payment files: %v", err)
+	}
+
+	return paymentFiles, nil
+}
+
+func (s *PaymentFileService) GetPaymentFile(accountID, paymentFileID string) (*models.PaymentFile, error) {
+	var paymentFile models.PaymentFile
+
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	id, err := uuid.Parse(paymentFileID)
+	if err != nil {
+		return nil, errors.New("invalid payment file ID")
+	}
+
+	if err := s.db.Where("id = ? AND account_id = ?", id, accountUUID).First(&paymentFile).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("payment file not found")
+		}
+		return nil, fmt.Errorf("failed to find payment file: %v", err)
+	}
+
+	return &paymentFile, nil
+}
+
+func (s *PaymentFileService) execute(doc *pain001Document, account *models.Account, paymentFile *models.PaymentFile, actor AuditActor) (*pain002Document, error) {
+	var items []models.PaymentFileItem
+	if err := s.db.Where("payment_file_id = ?", paymentFile.ID).Order("position ASC").Find(&items).Error; err != nil {
+		return nil, fmt.Errorf("failed to find payment file items: %v", err)
+	}
+
+	report := newPain002(doc.Initiation.GroupHeader.MessageID, doc)
+	today := time.Now().In(utils.BankLocation()).Format("2006-01-02")
+	paymentFile.AcceptedCount, paymentFile.RejectedCount = 0, 0
+
+	for _, payment := range doc.Initiation.PaymentInfos {
+		original := pain002OriginalPayment{OriginalPaymentInfoID: payment.PaymentInfoID}
+
+		var paymentReason, paymentInfo string
+		switch {
+		case payment.PaymentMethod != "TRF":
+			paymentReason, paymentInfo = "AG03", "only credit transfers (TRF) are supported"
+		case executionDate(payment) > today:
+			paymentReason, paymentInfo = "DT01", "future-dated execution is not supported"
+		}
+
+		accepted := 0
+		for _, transaction := range payment.Transactions {
+			status := pain002Transaction{
+				StatusID:              strings.ReplaceAll(uuid.NewString(), "-", ""),
+				OriginalInstructionID: transaction.InstructionID,
+				OriginalEndToEndID:    transaction.EndToEndID,
+			}
+
+			position := paymentFile.AcceptedCount + paymentFile.RejectedCount
+			if position >= len(items) {
+				return nil, errors.New("payment file items do not match the document")
+			}
+			item := &items[position]
+
+			if item.Status == models.PaymentFileItemStatusPending {
+				if err := s.executeItem(paymentFile, item, account, transaction, paymentReason, paymentInfo, actor); err != nil {
+					return nil, err
+				}
+			}
+
+			if item.Status == models.PaymentFileItemStatusAccepted {
+				status.Status = paymentStatusAccepted
+				accepted++
+				paymentFile.AcceptedCount++
+			} else {
+				status.Status = paymentStatusRejected
+				status.StatusReasons = newStatusReason(item.ReasonCode, item.ReasonInfo)
+				paymentFile.RejectedCount++
+			}
+			original.Transactions = append(original.Transactions, status)
+		}
+
+		original.Status = aggregateStatus(accepted, len(payment.Transactions))
+		if paymentReason != "" {
+			original.StatusReasons = newStatusReason(paymentReason, paymentInfo)
+		}
+		report.Report.OriginalPayments = append(report.Report.OriginalPayments, original)
+	}
+
+	report.Report.OriginalGroup.GroupStatus = aggregateStatus(paymentFile.AcceptedCount, paymentFile.AcceptedCount+paymentFile.RejectedCount)
+	switch report.Report.OriginalGroup.GroupStatus {
+	case paymentStatusAccepted:
+		paymentFile.Status = models.PaymentFileStatusAccepted
+	case paymentStatusPartial:
+		paymentFile.Status = models.PaymentFileStatusPartiallyAccepted
+	default:
+		paymentFile.Status = models.PaymentFileStatusRejected
+	}
+
+	return report, nil
+}
+
+// executeItem runs one pending transfer and records its outcome in the same
+// database transaction, so an item can never be left pending after its
+// transfer committed. The item row is locked first, so a resumed file whose
+// item was already executed by another worker reuses that outcome.
+func (s *PaymentFileService) executeItem(paymentFile *models.PaymentFile, item *models.PaymentFileItem, account *models.Account, transaction pain001Transaction, code, info string, actor AuditActor) error {
+	var executed *models.Transaction
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var current models.PaymentFileItem
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", item.ID).First(&current).Error; err != nil {
+			return fmt.Errorf("failed to lock payment file item: %v", err)
+		}
+		if current.Status != models.PaymentFileItemStatusPending {
+			*item = current
+			return nil
+		}
+
+		if code == "" {
+			executed, code, info = s.transfer(tx, account, item, transaction, actor)
+			if executed != nil {
+				item.TransactionID = &executed.ID
+			}
+		}
+
+		item.Status = models.PaymentFileItemStatusAccepted
+		if code != "" {
+			item.Status = models.PaymentFileItemStatusRejected
+			item.ReasonCode, item.ReasonInfo = code, truncate(info, 255)
+		}
+
+		if err := tx.Model(item).Updates(map[string]interface{}{
+			"status":         item.Status,
+			"reason_code":    item.ReasonCode,
+			"reason_info":    item.ReasonInfo,
+			"transaction_id": item.TransactionID,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update payment file item: %v", err)
+		}
+		if err := tx.Model(&models.PaymentFile{}).Where("id = ?", paymentFile.ID).UpdateColumn("updated_at", time.Now()).Error; err != nil {
+			return fmt.Errorf("failed to update payment file: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if executed != nil {
+		categorizeCommitted(s.db, executed)
+	}
+
+	return nil
+}
+
+// transfer executes the item within tx. The transfer runs in a savepoint, so
+// a rejected transfer leaves tx usable for recording the rejection.
+func (s *PaymentFileService) transfer(tx *gorm.DB, account *models.Account, item *models.PaymentFileItem, transaction pain001Transaction, actor AuditActor) (*models.Transaction, string, string) {
+	amount, code, info := instructedAmount(transaction.Amount, account.Currency)
+	if code != "" {
+		return nil, code, info
+	}
+
+	creditor, err := s.resolveAccount(transaction.CreditorAccount)
+	if err != nil {
+		return nil, "AC01", err.Error()
+	}
+
+	if creditor.Currency != account.Currency {
+		return nil, "AM03", "creditor account currency does not match"
+	}
+
+	description := strings.Join(transaction.Remittance, " ")
+	if description == "" {
+		description = "Bulk payment " + transaction.EndToEndID
+	}
+
+	metadata := models.Metadata{
+		"end_to_end_id":     transaction.EndToEndID,
+		"payment_file_item": item.ID.String(),
+	}
+
+	var executed *models.Transaction
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		var err error
+		executed, err = s.transactionService.transfer(tx, account.ID, creditor.ID, amount, truncate(description, 255), metadata, nil, actor)
+		return err
+	})
+	if err != nil {
+		return nil, transferReasonCode(err), err.Error()
+	}
+
+	return executed, "", ""
+}
+
+// instructedAmount parses an item's amount, returning the ISO 20022 reason
+// code and text when it cannot be booked to an account in currency.
+func instructedAmount(instructed *pain001Amount, currency string) (float64, string, string) {
+	if instructed.Currency != currency {
+		return 0, "AM03", fmt.Sprintf("currency %s does not match account currency %s", instructed.Currency, currency)
+	}
+
+	value := strings.TrimSpace(instructed.Value)
+	amount, err := strconv.ParseFloat(value, 64)
+	if err != nil || amount <= 0 || (strings.Contains(value, ".") && len(value)-strings.Index(value, ".") > 3) {
+		return 0, "AM12", "amount must be positive with at most two decimals"
+	}
+
+	return amount, "", ""
+}
+
+// transferReasonCode maps a failed transfer to its ISO 20022 reason code.
+func transferReasonCode(err error) string {
+	switch {
+	case errors.Is(err, ErrInsufficientBalance):
+		return "AM04"
+	case errors.Is(err, ErrSameAccountTransfer):
+		return "AG01"
+	case errors.Is(err, ErrAccountFrozen):
+		return "AC06"
+	default:
+		return "MS03"
+	}
+}
+
+func (s *PaymentFileService) resolveAccount(account *pain001Account) (*models.Account, error) {
+	accountNumber := account.Other
+	if account.IBAN != "" {
+		number, err := utils.AccountNumberFromIBAN(account.IBAN)
+		if err != nil {
+			return nil, err
+		}
+		accountNumber = number
+	}
+
+	return s.accountService.GetAccountByNumber(accountNumber)
+}
+
+func (s *PaymentFileService) isDebtorAccount(debtor *pain001Account, account *models.Account) bool {
+	if debtor.IBAN != "" {
+		return utils.NormalizeIBAN(debtor.IBAN) == utils.GenerateIBAN(account.AccountNumber)
+	}
+	return debtor.Other == account.AccountNumber
+}
+
+func newPain002(originalMessageID string, doc *pain001Document) *pain002Document {
+	report := &pain002Document{
+		Report: pain002Report{
+			GroupHeader: pain002GroupHeader{
+				MessageID:        strings.ReplaceAll(uuid.NewString(), "-", ""),
+				CreationDateTime: time.Now().UTC().Format("2006-01-02T15:04:05Z"),
+			},
+			OriginalGroup: pain002OriginalGroup{
+				OriginalMessageID:     originalMessageID,
+				OriginalMessageNameID: pain001MessageName,
+			},
+		},
+	}
+
+	if doc != nil && doc.Initiation != nil && doc.Initiation.GroupHeader != nil {
+		header := doc.Initiation.GroupHeader
+		if max15NumericText.MatchString(header.NumberOfTransactions) {
+			report.Report.OriginalGroup.OriginalNumberOfTransactions = header.NumberOfTransactions
+		}
+		if decimalPattern.MatchString(header.ControlSum) {
+			report.Report.OriginalGroup.OriginalControlSum = header.ControlSum
+		}
+	}
+
+	return report
+}
+
+func rejectedReport(originalMessageID string, doc *pain001Document, code string, reasons []string) []byte {
+	if originalMessageID == "" {
+		originalMessageID = "UNKNOWN"
+	}
+
+	report := newPain002(originalMessageID, doc)
+	report.Report.OriginalGroup.GroupStatus = paymentStatusRejected
+	for _, reason := range reasons {
+		report.Report.OriginalGroup.StatusReasons = append(report.Report.OriginalGroup.StatusReasons, newStatusReason(code, reason)...)
+	}
+
+	reportXML, err := marshalPain002(report)
+	if err != nil {
+		return nil
+	}
+	return reportXML
+}
+
+func marshalPain002(report *pain002Document) ([]byte, error) {
+	body, err := xml.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate status report: %v", err)
+	}
+	return append([]byte(xml.Header), body...), nil
+}
+
+func aggregateStatus(accepted, total int) string {
+	switch {
+	case total > 0 && accepted == total:
+		return paymentStatusAccepted
+	case accepted > 0:
+		return paymentStatusPartial
+	default:
+		return paymentStatusRejected
+	}
+}
+
+func executionDate(payment pain001PaymentInfo) string {
+	if payment.RequestedExecutionDate != "" {
+		return payment.RequestedExecutionDate
+	}
+	if t, err := time.Parse(time.RFC3339, payment.RequestedExecutionDateTime); err == nil {
+		return t.In(utils.BankLocation()).Format("2006-01-02")
+	}
+	return payment.RequestedExecutionDateTime[:10]
+}
+
+func messageIDOf(doc *pain001Document) string {
+	if doc.Initiation == nil || doc.Initiation.GroupHeader == nil {
+		return ""
+	}
+	return truncate(doc.Initiation.GroupHeader.MessageID, 35)
+}
+
+func instructedTotal(doc *pain001Document) float64 {
+	total := 0.0
+	for _, payment := range doc.Initiation.PaymentInfos {
+		for _, transaction := range payment.Transactions {
+			amount, _ := strconv.ParseFloat(strings.TrimSpace(transaction.Amount.Value), 64)
+			total += amount
+		}
+	}
+	return total
+}
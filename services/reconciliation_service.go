@@ -0,0 +1,236 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	reconciliationTolerance  = 0.005
+	reconciliationStaleAfter = time.Hour
+)
+
+var ErrReconciliationRunning = errors.New("a reconciliation run is already in progress")
+
+type ReconciliationService struct {
+	db             *gorm.DB
+	accountService *AccountService
+}
+
+func NewReconciliationService(db *gorm.DB) *ReconciliationService {
+	return &ReconciliationService{
+		db:             db,
+		accountService: NewAccountService(db),
+	}
+}
+
+func (s *ReconciliationService) Run(triggeredBy string, freezeMismatched bool) (*models.ReconciliationRun, error) {
+	var running int64
+	if err := s.db.Model(&models.ReconciliationRun{}).
+		Where("status = ? AND started_at > ?", models.ReconciliationRunStatusRunning, time.Now().Add(-reconciliationStaleAfter)).
+		Count(&running).Error; err != nil {
+		return nil, fmt.Errorf("failed to check reconciliation runs: %v", err)
+	}
+	if running > 0 {
+		return nil, ErrReconciliationRunning
+	}
+
+	run := &models.ReconciliationRun{
+		Status:           models.ReconciliationRunStatusRunning,
+		TriggeredBy:      triggeredBy,
+		FreezeMismatched: freezeMismatched,
+		StartedAt:        time.Now(),
+	}
+	if err := s.db.Create(run).Error; err != nil {
+		return nil, fmt.Errorf("failed to create reconciliation run: %v", err)
+	}
+
+	var errs []error
+	var accounts []models.Account
+	result := s.db.Order("id").FindInBatches(&accounts, 100, func(tx *gorm.DB, batch int) error {
+		for i := range accounts {
+			if err := s.reconcile(run, &accounts[i]); err != nil {
+				errs = append(errs, fmt.Errorf("account %s: %v", accounts[i].ID, err))
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		errs = append(errs, fmt.Errorf("failed to load accounts: %v", result.Error))
+	}
+
+	completedAt := time.Now()
+	run.CompletedAt = &completedAt
+	run.Status = models.ReconciliationRunStatusCompleted
+	if err := errors.Join(errs...); err != nil {
+		run.Status = models.ReconciliationRunStatusFailed
+		run.Error = err.Error()
+	}
+
+	if err := s.db.Model(run).Updates(map[string]interface{}{
+		"status":              run.Status,
+		"accounts_checked":    run.AccountsChecked,
+		"accounts_mismatched": run.AccountsMismatched,
+		"accounts_frozen":     run.AccountsFrozen,
+		"discrepancy_count":   run.DiscrepancyCount,
+		"error":               run.Error,
+		"completed_at":        run.CompletedAt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update reconciliation run: %v", err)
+	}
+
+	return run, nil
+}
+
+func (s *ReconciliationService) GetRuns(limit int) ([]models.ReconciliationRun, error) {
+	var runs []models.ReconciliationRun
+
+	if err := s.db.Order("started_at DESC").Limit(limit).Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to find reconciliation runs: %v", err)
+	}
+
+	return runs, nil
+}
+
+func (s *ReconciliationService) GetRun(runID string) (*models.ReconciliationRun, error) {
+	var run models.ReconciliationRun
+
+	id, err := uuid.Parse(runID)
+	if err != nil {
+		return nil, errors.New("invalid reconciliation run ID")
+	}
+
+	if err := s.db.Preload("Discrepancies", func(db *gorm.DB) *gorm.DB {
+		return db.Order("account_id ASC, created_at ASC")
+	}).Where("id = ?", id).First(&run).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("reconciliation run not found")
+		}
+		return nil, fmt.Errorf("failed to find reconciliation run: %v", err)
+	}
+
+	return &run, nil
+}
+
+func (s *ReconciliationService) GetAccountDiscrepancies(accountID string, limit int) ([]models.ReconciliationDiscrepancy, error) {
+	var discrepancies []models.ReconciliationDiscrepancy
+
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	if err := s.db.Where("account_id = ?", id).Order("created_at DESC").Limit(limit).Find(&discrepancies).Error; err != nil {
+		return nil, fmt.Errorf("failed to find discrepancies: %v", err)
+	}
+
+	return discrepancies, nil
+}
+
+func (s *ReconciliationService) reconcile(run *models.ReconciliationRun, account *models.Account) error {
+	discrepancies, err := s.checkAccount(account.ID)
+	if err != nil {
+		return err
+	}
+
+	run.AccountsChecked++
+	if len(discrepancies) == 0 {
+		return nil
+	}
+
+	for i := range discrepancies {
+		discrepancies[i].RunID = run.ID
+	}
+	if err := s.db.Create(&discrepancies).Error; err != nil {
+		return fmt.Errorf("failed to record discrepancies: %v", err)
+	}
+
+	run.AccountsMismatched++
+	run.DiscrepancyCount += len(discrepancies)
+
+	if run.FreezeMismatched && !account.IsFrozen && account.IsActive {
+		if _, err := s.accountService.FreezeAccount(account.ID.String(), fmt.Sprintf("reconciliation run %s", run.ID), SystemAuditActor("reconciliation")); err != nil {
+			return err
+		}
+		run.AccountsFrozen++
+	}
+
+	return nil
+}
+
+func (s *ReconciliationService) checkAccount(accountID uuid.UUID) ([]models.ReconciliationDiscrepancy, error) {
+	var discrepancies []models.ReconciliationDiscrepancy
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var account models.Account
+		if err := tx.Where("id = ?", accountID).First(&account).Error; err != nil {
+			return fmt.Errorf("failed to find account: %v", err)
+		}
+
+		var transactions []models.Transaction
+		if err := completedAccountTransactions(tx, accountID).
+			Order("created_at ASC, id ASC").
+			Find(&transactions).Error; err != nil {
+			return fmt.Errorf("failed to find transactions: %v", err)
+		}
+
+		discrepancies = ledgerDiscrepancies(accountID, account.Balance, transactions)
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+
+	return discrepancies, err
+}
+
+// ledgerDiscrepancies walks an account's completed transactions in booking
+// order and reports every place where the recorded balances stop adding up.
+func ledgerDiscrepancies(accountID uuid.UUID, balance float64, transactions []models.Transaction) []models.ReconciliationDiscrepancy {
+	var discrepancies []models.ReconciliationDiscrepancy
+
+	computed := 0.0
+	chained := 0.0
+	for _, transaction := range transactions {
+		amount := transaction.SignedAmountFor(accountID)
+		computed += amount
+
+		if transaction.AccountID != accountID {
+			chained += amount
+			continue
+		}
+
+		transactionID := transaction.ID
+		if !amountsEqual(transaction.BalanceBefore, chained) {
+			discrepancies = append(discrepancies, newDiscrepancy(accountID, models.DiscrepancyTypeChainBreak, &transactionID, chained, transaction.BalanceBefore))
+		}
+		if !amountsEqual(transaction.BalanceAfter, transaction.BalanceBefore+amount) {
+			discrepancies = append(discrepancies, newDiscrepancy(accountID, models.DiscrepancyTypeAmountMismatch, &transactionID, transaction.BalanceBefore+amount, transaction.BalanceAfter))
+		}
+		chained = transaction.BalanceAfter
+	}
+
+	if !amountsEqual(balance, computed) {
+		discrepancies = append(discrepancies, newDiscrepancy(accountID, models.DiscrepancyTypeBalanceMismatch, nil, computed, balance))
+	}
+	return discrepancies
+}
+
+func newDiscrepancy(accountID uuid.UUID, discrepancyType models.DiscrepancyType, transactionID *uuid.UUID, expected, actual float64) models.ReconciliationDiscrepancy {
+	return models.ReconciliationDiscrepancy{
+		AccountID:     accountID,
+		Type:          discrepancyType,
+		TransactionID: transactionID,
+		Expected:      expected,
+		Actual:        actual,
+		Difference:    actual - expected,
+	}
+}
+
+func amountsEqual(a, b float64) bool {
+	return math.Abs(a-b) < reconciliationTolerance
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+)
+
+const statementDateFormat = "2006-01-02"
+
+func WriteStatementCSV(w io.Writer, statement *models.Statement) error {
+	writer := csv.NewWriter(w)
+
+	records := [][]string{
+		{"date", "reference", "type", "description", "amount", "running_balance"},
+		{statement.PeriodStart.Format(statementDateFormat), "", "opening_balance", "Opening balance", "", formatAmount(statement.OpeningBalance)},
+	}
+
+	for _, line := range statement.Lines {
+		records = append(records, []string{
+			line.PostedAt.In(utils.BankLocation()).Format(statementDateFormat),
+			line.Reference,
+			string(line.Type),
+			csvText(line.Description),
+			formatAmount(line.Amount),
+			formatAmount(line.RunningBalance),
+		})
+	}
+
+	records = append(records, []string{statement.PeriodEnd.Format(statementDateFormat), "", "closing_balance", "Closing balance", "", formatAmount(statement.ClosingBalance)})
+
+	if err := writer.WriteAll(records); err != nil {
+		return fmt.Errorf("failed to write statement CSV: %v", err)
+	}
+
+	return nil
+}
+
+func RenderStatementPDF(statement *models.Statement) []byte {
+	lines := []string{
+		"ACCOUNT STATEMENT",
+		"",
+		fmt.Sprintf("Account:  %s (%s)", statement.Account.AccountNumber, statement.Account.Type),
+		fmt.Sprintf("Period:   %s to %s", statement.PeriodStart.Format(statementDateFormat), statement.PeriodEnd.Format(statementDateFormat)),
+		fmt.Sprintf("Currency: %s", statement.Currency),
+		"",
+		fmt.Sprintf("%-10s  %-32s  %-12s  %14s  %14s", "Date", "Description", "Type", "Amount", "Balance"),
+		fmt.Sprintf("%-10s  %-32s  %-12s  %14s  %14s", statement.PeriodStart.Format(statementDateFormat), "Opening balance", "", "", formatAmount(statement.OpeningBalance)),
+	}
+
+	for _, line := range statement.Lines {
+		lines = append(lines, fmt.Sprintf("%-10s  %-32s  %-12s  %14s  %14s",
+			line.PostedAt.In(utils.BankLocation()).Format(statementDateFormat),
+			truncate(line.Description, 32),
+			truncate(string(line.Type), 12),
+			formatAmount(line.Amount),
+			formatAmount(line.RunningBalance),
+		))
+	}
+
+	lines = append(lines,
+		fmt.Sprintf("%-10s  %-32s  %-12s  %14s  %14s", statement.PeriodEnd.Format(statementDateFormat), "Closing balance", "", "", formatAmount(statement.ClosingBalance)),
+		"",
+		"SUMMARY",
+		fmt.Sprintf("  Opening balance  %14s", formatAmount(statement.OpeningBalance)),
+		fmt.Sprintf("  Total credits    %14s", formatAmount(statement.TotalCredits)),
+		fmt.Sprintf("  Total debits     %14s", formatAmount(statement.TotalDebits)),
+		fmt.Sprintf("  Interest         %14s", formatAmount(statement.TotalInterest)),
+		fmt.Sprintf("  Fees             %14s", formatAmount(statement.TotalFees)),
+		fmt.Sprintf("  Closing balance  %14s", formatAmount(statement.ClosingBalance)),
+		"",
+		"TOTALS BY TYPE",
+	)
+
+	for _, total := range statement.TypeTotals {
+		lines = append(lines, fmt.Sprintf("  %-14s  %5d  credits %14s  debits %14s", total.Type, total.Count, formatAmount(total.Credits), formatAmount(total.Debits)))
+	}
+
+	return utils.RenderTextPDF(lines)
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+	return value[:length]
+}
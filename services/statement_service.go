@@ -0,0 +1,258 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type StatementService struct {
+	db *gorm.DB
+}
+
+func NewStatementService(db *gorm.DB) *StatementService {
+	return &StatementService{db: db}
+}
+
+func (s *StatementService) GenerateStatement(accountID string, period time.Time) (*models.Statement, error) {
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	start := utils.StartOfMonth(period.In(utils.BankLocation()))
+	end := start.AddDate(0, 1, 0)
+	if end.After(time.Now()) {
+		return nil, errors.New("statement period has not ended yet")
+	}
+
+	periodStart := statementDate(start)
+	if existing, err := s.findStatement(id, periodStart); err == nil {
+		return existing, nil
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("failed to find statement: %v", err)
+	}
+
+	// The account balance, the movement since the period and the period's
+	// entries are read from one snapshot; a posting committed in between would
+	// otherwise leave a statement that never adds up and cannot be corrected.
+	var account models.Account
+	var openingBalance float64
+	var transactions []models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", id).First(&account).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("account not found")
+			}
+			return fmt.Errorf("failed to find account: %v", err)
+		}
+
+		var err error
+		if openingBalance, err = openingStatementBalance(tx, &account, start); err != nil {
+			return err
+		}
+
+		if err := completedAccountTransactions(tx, account.ID).
+			Where("created_at >= ? AND created_at < ?", start, end).
+			Order("created_at ASC, id ASC").
+			Find(&transactions).Error; err != nil {
+			return fmt.Errorf("failed to find transactions: %v", err)
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &models.Statement{
+		ID:             uuid.New(),
+		AccountID:      account.ID,
+		PeriodStart:    periodStart,
+		PeriodEnd:      statementDate(end.AddDate(0, 0, -1)),
+		Currency:       account.Currency,
+		OpeningBalance: openingBalance,
+		LineCount:      len(transactions),
+	}
+
+	typeTotals := make(map[models.TransactionType]*models.StatementTypeTotal)
+	running := openingBalance
+	lines := make([]models.StatementLine, 0, len(transactions))
+
+	for i, transaction := range transactions {
+		amount := transaction.SignedAmountFor(account.ID)
+		running += amount
+
+		lines = append(lines, models.StatementLine{
+			StatementID:    statement.ID,
+			Position:       i + 1,
+			TransactionID:  transaction.ID,
+			Reference:      transaction.TransactionID,
+			PostedAt:       transaction.CreatedAt,
+			Type:           transaction.Type,
+			Description:    transaction.Description,
+			Amount:         amount,
+			RunningBalance: running,
+		})
+
+		total, ok := typeTotals[transaction.Type]
+		if !ok {
+			total = &models.StatementTypeTotal{StatementID: statement.ID, Type: transaction.Type}
+			typeTotals[transaction.Type] = total
+		}
+		total.Count++
+
+		if amount >= 0 {
+			total.Credits += amount
+			statement.TotalCredits += amount
+		} else {
+			total.Debits -= amount
+			statement.TotalDebits -= amount
+		}
+
+		switch transaction.Type {
+		case models.TransactionTypeInterest:
+			statement.TotalInterest += amount
+		case models.TransactionTypeFee:
+			statement.TotalFees -= amount
+		}
+	}
+	statement.ClosingBalance = running
+
+	totals := make([]models.StatementTypeTotal, 0, len(typeTotals))
+	for _, total := range typeTotals {
+		totals = append(totals, *total)
+	}
+	sort.Slice(totals, func(i, j int) bool { return totals[i].Type < totals[j].Type })
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Lines", "TypeTotals", "Account").Create(statement).Error; err != nil {
+			return err
+		}
+		if len(lines) > 0 {
+			if err := tx.CreateInBatches(lines, 500).Error; err != nil {
+				return err
+			}
+		}
+		if len(totals) > 0 {
+			if err := tx.Create(&totals).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if existing, findErr := s.findStatement(id, periodStart); findErr == nil {
+			return existing, nil
+		}
+		return nil, fmt.Errorf("failed to create statement: %v", err)
+	}
+
+	return s.findStatement(id, periodStart)
+}
+
+func (s *StatementService) GetStatementsByAccountID(accountID string) ([]models.Statement, error) {
+	var statements []models.Statement
+
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	if err := s.db.Where("account_id = ?", id).Order("period_start DESC").Find(&statements).Error; err != nil {
+		return nil, fmt.Errorf("failed to find statements: %v", err)
+	}
+
+	return statements, nil
+}
+
+func (s *StatementService) GetStatement(accountID, statementID string) (*models.Statement, error) {
+	var statement models.Statement
+
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	id, err := uuid.Parse(statementID)
+	if err != nil {
+		return nil, errors.New("invalid statement ID")
+	}
+
+	if err := s.statementQuery().Where("id = ? AND account_id = ?", id, accountUUID).First(&statement).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("statement not found")
+		}
+		return nil, fmt.Errorf("failed to find statement: %v", err)
+	}
+
+	return &statement, nil
+}
+
+func (s *StatementService) GenerateMonthEndStatements(now time.Time) (int, error) {
+	period := utils.StartOfMonth(now.In(utils.BankLocation())).AddDate(0, -1, 0)
+	periodEnd := period.AddDate(0, 1, 0)
+
+	var accountIDs []uuid.UUID
+	if err := s.db.Model(&models.Account{}).
+		Where("is_active = ? AND created_at < ?", true, periodEnd).
+		Where("id NOT IN (?)", s.db.Model(&models.Statement{}).Select("account_id").Where("period_start = ?", statementDate(period))).
+		Pluck("id", &accountIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to find accounts without statements: %v", err)
+	}
+
+	generated := 0
+	var errs []error
+	for _, accountID := range accountIDs {
+		if _, err := s.GenerateStatement(accountID.String(), period); err != nil {
+			errs = append(errs, fmt.Errorf("account %s: %v", accountID, err))
+			continue
+		}
+		generated++
+	}
+
+	return generated, errors.Join(errs...)
+}
+
+func openingStatementBalance(tx *gorm.DB, account *models.Account, start time.Time) (float64, error) {
+	var previous models.Statement
+	err := tx.Where("account_id = ? AND period_start = ?", account.ID, statementDate(start.AddDate(0, -1, 0))).First(&previous).Error
+	if err == nil {
+		return previous.ClosingBalance, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, fmt.Errorf("failed to find previous statement: %v", err)
+	}
+
+	movementSince, err := netMovement(completedAccountTransactions(tx, account.ID).Where("created_at >= ?", start), account.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	return account.Balance - movementSince, nil
+}
+
+func (s *StatementService) findStatement(accountID uuid.UUID, periodStart time.Time) (*models.Statement, error) {
+	var statement models.Statement
+	if err := s.statementQuery().Where("account_id = ? AND period_start = ?", accountID, periodStart).First(&statement).Error; err != nil {
+		return nil, err
+	}
+	return &statement, nil
+}
+
+func (s *StatementService) statementQuery() *gorm.DB {
+	return s.db.Preload("Account").
+		Preload("Lines", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC") }).
+		Preload("TypeTotals", func(db *gorm.DB) *gorm.DB { return db.Order("type ASC") })
+}
+
+func statementDate(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
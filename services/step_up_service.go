@@ -0,0 +1,537 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	stepUpOTPDigits   = 6
+	stepUpHistorySize = 20
+	stepUpMinHistory  = 3
+)
+
+var (
+	ErrTransferChallengeNotFound = errors.New("transfer challenge not found")
+	ErrTransferChallengeExpired  = errors.New("transfer challenge has expired and the transfer was cancelled")
+	ErrTransferChallengeLocked   = errors.New("too many invalid codes, the transfer was cancelled")
+	ErrTransferAlreadyConfirmed  = errors.New("transfer has already been confirmed")
+	ErrInvalidStepUpCode         = errors.New("invalid verification code")
+	ErrInvalidStepUpMethod       = errors.New("verification method must be one of pin, totp or otp")
+	ErrStepUpMethodUnavailable   = errors.New("verification method is not set up for this user")
+	ErrInvalidTransactionPIN     = errors.New("transaction PIN must be 4 to 6 digits")
+	ErrStepUpMethodLocked        = errors.New("too many invalid codes, this verification method is temporarily locked")
+)
+
+var transactionPINPattern = regexp.MustCompile(`^[0-9]{4,6}$`)
+
+// StepUpPolicy decides when a transfer needs a second factor. A zero
+// AmountThreshold, UnusualAmountFactor or VelocityCount disables that rule.
+type StepUpPolicy struct {
+	AmountThreshold         float64
+	NewBeneficiaryMinAmount float64
+	UnusualAmountFactor     float64
+	VelocityCount           int
+	VelocityWindow          time.Duration
+	ChallengeTTL            time.Duration
+}
+
+func StepUpPolicyFromEnv() StepUpPolicy {
+	return StepUpPolicy{
+		AmountThreshold:         envFloat("STEP_UP_AMOUNT_THRESHOLD", 10000),
+		NewBeneficiaryMinAmount: envFloat("STEP_UP_NEW_BENEFICIARY_MIN_AMOUNT", 0),
+		UnusualAmountFactor:     envFloat("STEP_UP_UNUSUAL_AMOUNT_FACTOR", 5),
+		VelocityCount:           int(envFloat("STEP_UP_VELOCITY_COUNT", 5)),
+		VelocityWindow:          envDuration("STEP_UP_VELOCITY_WINDOW", time.Hour),
+		ChallengeTTL:            envDuration("STEP_UP_CHALLENGE_TTL", 5*time.Minute),
+	}
+}
+
+type StepUpService struct {
+	db                 *gorm.DB
+	policy             StepUpPolicy
+	transactionService *TransactionService
+	mfaService         *MFAService
+	loginThrottle      *LoginThrottleService
+	notifier           Notifier
+}
+
+func NewStepUpService(db *gorm.DB) *StepUpService {
+	return &StepUpService{
+		db:                 db,
+		policy:             StepUpPolicyFromEnv(),
+		transactionService: NewTransactionService(db),
+		mfaService:         NewMFAService(db),
+		loginThrottle:      NewLoginThrottleService(db),
+		notifier:           NewNotifier(),
+	}
+}
+
+type TransferInput struct {
+	FromAccountID string
+	ToAccountID   string
+	Amount        float64
+	Description   string
+	Metadata      models.Metadata
+	Tags          models.Tags
+	Method        models.StepUpMethod
+}
+
+// InitiateTransfer executes the transfer straight away when the policy allows
+// it. Otherwise the transfer is stored as pending and the returned challenge
+// must be answered through VerifyTransfer before it expires.
+func (s *StepUpService) InitiateTransfer(userID string, input TransferInput, actor AuditActor) (*models.Transaction, *models.TransferChallenge, error) {
+	user, err := s.mfaService.findUser(s.db, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fromAccount, toAccount, err := s.transactionService.ValidateTransfer(input.FromAccountID, input.ToAccountID, input.Amount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	reasons, err := s.Evaluate(user.ID, fromAccount, toAccount, input.Amount, time.Now())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(reasons) == 0 {
+		transaction, err := s.transactionService.ProcessTransfer(input.FromAccountID, input.ToAccountID, input.Amount, input.Description, input.Metadata, input.Tags, actor)
+		return transaction, nil, err
+	}
+
+	challenge, otp, err := s.newChallenge(user, input.Method, reasons, actor)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transaction := &models.Transaction{
+		TransactionID: utils.GenerateTransactionID(),
+		Type:          models.TransactionTypeTransfer,
+		Amount:        input.Amount,
+		Currency:      fromAccount.Currency,
+		Status:        models.TransactionStatusPending,
+		Description:   input.Description,
+		Metadata:      input.Metadata,
+		Tags:          input.Tags,
+		AccountID:     fromAccount.ID,
+		ToAccountID:   &toAccount.ID,
+		BalanceBefore: fromAccount.Balance,
+		BalanceAfter:  fromAccount.Balance - input.Amount,
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(transaction).Error; err != nil {
+			return fmt.Errorf("failed to create transaction: %v", err)
+		}
+
+		challenge.TransactionID = &transaction.ID
+		if err := tx.Create(challenge).Error; err != nil {
+			return fmt.Errorf("failed to create transfer challenge: %v", err)
+		}
+
+		return recordAudit(tx, actor, models.AuditActionTransferPending, models.AuditTargetTransaction, transaction.ID.String(), nil, auditTransaction(transaction))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The code is mailed only after commit, so that a slow mail server holds
+	// neither the business day lock nor the audit chain. A transfer whose
+	// code could not be delivered can never be confirmed and is cancelled.
+	if otp != "" {
+		if err := s.sendOTP(user, fmt.Sprintf("the transfer of %.2f %s", transaction.Amount, transaction.Currency), otp); err != nil {
+			if cancelErr := s.db.Transaction(func(tx *gorm.DB) error {
+				return s.cancel(tx, transaction, actor)
+			}); cancelErr != nil {
+				return nil, nil, cancelErr
+			}
+			return nil, nil, fmt.Errorf("failed to send verification code: %v", err)
+		}
+	}
+
+	categorizeCommitted(s.db, transaction)
+
+	return transaction, challenge, nil
+}
+
+// Evaluate returns the reasons a transfer needs a second factor, or none when
+// it can be executed directly.
+func (s *StepUpService) Evaluate(userID uuid.UUID, fromAccount, toAccount *models.Account, amount float64, now time.Time) ([]models.StepUpReason, error) {
+	var history stepUpHistory
+
+	if s.policy.newBeneficiaryApplies(userID, toAccount, amount) {
+		if err := s.db.Model(&models.Transaction{}).
+			Joins("JOIN accounts ON accounts.id = transactions.account_id").
+			Where("accounts.user_id = ? AND transactions.to_account_id = ? AND transactions.type = ? AND transactions.status = ?",
+				userID, toAccount.ID, models.TransactionTypeTransfer, models.TransactionStatusCompleted).
+			Count(&history.BeneficiaryTransfers).Error; err != nil {
+			return nil, fmt.Errorf("failed to check beneficiary history: %v", err)
+		}
+	}
+
+	if s.policy.UnusualAmountFactor > 0 {
+		if err := s.db.Model(&models.Transaction{}).
+			Where("account_id = ? AND type = ? AND status = ?", fromAccount.ID, models.TransactionTypeTransfer, models.TransactionStatusCompleted).
+			Order("created_at DESC").
+			Limit(stepUpHistorySize).
+			Pluck("amount", &history.RecentAmounts).Error; err != nil {
+			return nil, fmt.Errorf("failed to load transfer history: %v", err)
+		}
+	}
+
+	if s.policy.VelocityCount > 0 {
+		if err := s.db.Model(&models.Transaction{}).
+			Where("account_id = ? AND type = ? AND status IN ? AND created_at > ?",
+				fromAccount.ID, models.TransactionTypeTransfer,
+				[]models.TransactionStatus{models.TransactionStatusCompleted, models.TransactionStatusPending},
+				now.Add(-s.policy.VelocityWindow)).
+			Count(&history.WindowTransfers).Error; err != nil {
+			return nil, fmt.Errorf("failed to check transfer velocity: %v", err)
+		}
+	}
+
+	return s.policy.reasons(userID, toAccount, amount, history), nil
+}
+
+// stepUpHistory is what Evaluate loads about the sender's earlier transfers.
+type stepUpHistory struct {
+	// BeneficiaryTransfers counts completed transfers from any of the user's
+	// accounts to the recipient.
+	BeneficiaryTransfers int64
+	// RecentAmounts are the latest completed transfer amounts from the
+	// source account, newest first.
+	RecentAmounts []float64
+	// WindowTransfers counts completed and pending transfers from the source
+	// account within the velocity window.
+	WindowTransfers int64
+}
+
+func (p StepUpPolicy) newBeneficiaryApplies(userID uuid.UUID, toAccount *models.Account, amount float64) bool {
+	return toAccount.UserID != userID && amount > p.NewBeneficiaryMinAmount
+}
+
+func (p StepUpPolicy) reasons(userID uuid.UUID, toAccount *models.Account, amount float64, history stepUpHistory) []models.StepUpReason {
+	reasons := []models.StepUpReason{}
+
+	if p.AmountThreshold > 0 && amount >= p.AmountThreshold {
+		reasons = append(reasons, models.StepUpReasonAmountThreshold)
+	}
+
+	if p.newBeneficiaryApplies(userID, toAccount, amount) && history.BeneficiaryTransfers == 0 {
+		reasons = append(reasons, models.StepUpReasonNewBeneficiary)
+	}
+
+	if p.UnusualAmountFactor > 0 && len(history.RecentAmounts) >= stepUpMinHistory {
+		total := 0.0
+		for _, previous := range history.RecentAmounts {
+			total += previous
+		}
+		if amount > p.UnusualAmountFactor*total/float64(len(history.RecentAmounts)) {
+			reasons = append(reasons, models.StepUpReasonUnusualAmount)
+		}
+	}
+
+	if p.VelocityCount > 0 && history.WindowTransfers >= int64(p.VelocityCount) {
+		reasons = append(reasons, models.StepUpReasonVelocity)
+	}
+
+	return reasons
+}
+
+// VerifyTransfer checks the second factor for a pending transfer and executes
+// it. Wrong codes are counted and the transfer is cancelled once the attempts
+// run out or the challenge expires.
+func (s *StepUpService) VerifyTransfer(userID, accountID, challengeID, code string, actor AuditActor) (*models.Transaction, error) {
+	var transaction models.Transaction
+	var outcome error
+
+	challengeUUID, err := uuid.Parse(challengeID)
+	if err != nil {
+		return nil, ErrTransferChallengeNotFound
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.mfaService.findUser(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+
+		var challenge models.TransferChallenge
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND user_id = ?", challengeUUID, user.ID).
+			First(&challenge).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransferChallengeNotFound
+			}
+			return fmt.Errorf("failed to find transfer challenge: %v", err)
+		}
+		if challenge.TransactionID == nil {
+			return ErrTransferChallengeNotFound
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND account_id = ?", *challenge.TransactionID, accountID).
+			First(&transaction).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransferChallengeNotFound
+			}
+			return fmt.Errorf("failed to find transaction: %v", err)
+		}
+
+		switch {
+		case challenge.VerifiedAt != nil || transaction.Status == models.TransactionStatusCompleted:
+			return ErrTransferAlreadyConfirmed
+		case transaction.Status != models.TransactionStatusPending:
+			return ErrTransferChallengeExpired
+		}
+
+		now := time.Now()
+		if now.After(challenge.ExpiresAt) || challenge.Attempts >= models.TransferChallengeMaxAttempts {
+			outcome = ErrTransferChallengeExpired
+			return s.cancel(tx, &transaction, actor)
+		}
+
+		valid, err := s.checkCode(tx, user, &challenge, code)
+		if err != nil {
+			return err
+		}
+		if !valid {
+			if err := tx.Model(&challenge).Update("attempts", gorm.Expr("attempts + 1")).Error; err != nil {
+				return fmt.Errorf("failed to update transfer challenge: %v", err)
+			}
+			outcome = ErrInvalidStepUpCode
+			if challenge.Attempts+1 >= models.TransferChallengeMaxAttempts {
+				outcome = ErrTransferChallengeLocked
+				return s.cancel(tx, &transaction, actor)
+			}
+			return nil
+		}
+
+		if err := tx.Model(&challenge).Update("verified_at", now).Error; err != nil {
+			return fmt.Errorf("failed to complete transfer challenge: %v", err)
+		}
+
+		before := auditTransaction(&transaction)
+		action := models.AuditActionTransfer
+		if err := s.transactionService.executePendingTransfer(tx, &transaction); err != nil {
+			if !errors.Is(err, ErrInsufficientBalance) && !errors.Is(err, ErrAccountFrozen) {
+				return err
+			}
+			// The code was right but the funds are no longer available, so the
+			// transfer fails for good rather than staying open for retries.
+			outcome = err
+			action = models.AuditActionTransferFailed
+			transaction.Status = models.TransactionStatusFailed
+			if err := tx.Model(&transaction).Update("status", transaction.Status).Error; err != nil {
+				return fmt.Errorf("failed to update transaction status: %v", err)
+			}
+		}
+
+		return recordAudit(tx, actor, action, models.AuditTargetTransaction, transaction.ID.String(), before, auditTransaction(&transaction))
+	})
+	if err != nil {
+		return nil, err
+	}
+	if outcome != nil {
+		return nil, outcome
+	}
+
+	return &transaction, nil
+}
+
+// SetTransactionPIN sets or replaces the PIN used to confirm transfers. The
+// account password is required so that a stolen session cannot set one.
+func (s *StepUpService) SetTransactionPIN(userID, password, pin string, actor AuditActor) error {
+	if !transactionPINPattern.MatchString(pin) {
+		return ErrInvalidTransactionPIN
+	}
+
+	user, err := s.mfaService.findUser(s.db, userID)
+	if err != nil {
+		return err
+	}
+
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return ErrInvalidPassword
+	}
+
+	hash, err := utils.HashPassword(pin)
+	if err != nil {
+		return fmt.Errorf("failed to hash transaction PIN: %v", err)
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"transaction_pin_hash":   hash,
+			"transaction_pin_set_at": time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to set transaction PIN: %v", err)
+		}
+
+		return recordAudit(tx, actor, models.AuditActionTransactionPIN, models.AuditTargetUser, user.ID.String(), nil, nil)
+	})
+}
+
+// CancelExpiredTransfers cancels pending transfers whose challenge expired
+// without being answered.
+func (s *StepUpService) CancelExpiredTransfers(now time.Time) (int64, error) {
+	var cancelled int64
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var transactions []models.Transaction
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND id IN (?)", models.TransactionStatusPending,
+				tx.Model(&models.TransferChallenge{}).Select("transaction_id").Where("verified_at IS NULL AND expires_at < ?", now)).
+			Find(&transactions).Error; err != nil {
+			return fmt.Errorf("failed to find expired transfers: %v", err)
+		}
+
+		for i := range transactions {
+			if err := s.cancel(tx, &transactions[i], SystemAuditActor("transfer_challenge_expiry")); err != nil {
+				return err
+			}
+			cancelled++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return cancelled, nil
+}
+
+// checkCode verifies the answer to a challenge. PIN and TOTP failures also
+// count against a per-user throttle that locks the method across challenges.
+func (s *StepUpService) checkCode(tx *gorm.DB, user *models.User, challenge *models.TransferChallenge, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if challenge.Method == models.StepUpMethodOTP {
+		return subtle.ConstantTimeCompare([]byte(utils.HashToken(code)), []byte(challenge.CodeHash)) == 1, nil
+	}
+
+	locked, err := s.loginThrottle.StepUpLocked(tx, user.ID, challenge.Method)
+	if err != nil {
+		return false, err
+	}
+	if locked {
+		return false, ErrStepUpMethodLocked
+	}
+
+	valid := false
+	switch challenge.Method {
+	case models.StepUpMethodPIN:
+		valid = user.TransactionPINHash != "" && utils.CheckPasswordHash(code, user.TransactionPINHash)
+	case models.StepUpMethodTOTP:
+		if user.MFAEnabled {
+			if err := s.mfaService.verifyTOTP(tx, user, code); err != nil {
+				if !errors.Is(err, ErrInvalidMFACode) {
+					return false, err
+				}
+			} else {
+				valid = true
+			}
+		}
+	}
+
+	if !valid {
+		return false, s.loginThrottle.RecordStepUpFailure(tx, user, challenge.Method, challenge.IPAddress)
+	}
+	return true, s.loginThrottle.RecordStepUpSuccess(tx, user.ID, challenge.Method)
+}
+
+func (s *StepUpService) cancel(tx *gorm.DB, transaction *models.Transaction, actor AuditActor) error {
+	before := auditTransaction(transaction)
+	transaction.Status = models.TransactionStatusCancelled
+	if err := tx.Model(transaction).Update("status", transaction.Status).Error; err != nil {
+		return fmt.Errorf("failed to cancel transfer: %v", err)
+	}
+	return recordAudit(tx, actor, models.AuditActionTransferCanceled, models.AuditTargetTransaction, transaction.ID.String(), before, auditTransaction(transaction))
+}
+
+// newChallenge prepares a challenge for the factor the user asked for, or the
+// strongest one they have. For emailed codes it also returns the code, which
+// the caller sends once the challenge is stored.
+func (s *StepUpService) newChallenge(user *models.User, requested models.StepUpMethod, reasons []models.StepUpReason, actor AuditActor) (*models.TransferChallenge, string, error) {
+	method, err := resolveStepUpMethod(user, requested)
+	if err != nil {
+		return nil, "", err
+	}
+
+	challenge := &models.TransferChallenge{
+		UserID:    user.ID,
+		Method:    method,
+		Reasons:   models.StringList{},
+		IPAddress: actor.IPAddress,
+		ExpiresAt: time.Now().Add(s.policy.ChallengeTTL),
+	}
+	for _, reason := range reasons {
+		challenge.Reasons = append(challenge.Reasons, string(reason))
+	}
+
+	var otp string
+	if method == models.StepUpMethodOTP {
+		otp, err = utils.GenerateNumericCode(stepUpOTPDigits)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to generate verification code: %v", err)
+		}
+		challenge.CodeHash = utils.HashToken(otp)
+	}
+
+	return challenge, otp, nil
+}
+
+func (s *StepUpService) sendOTP(user *models.User, purpose, otp string) error {
+	return s.notifier.Send(EmailMessage{
+		To:      user.Email,
+		Subject: "Confirm your transfer",
+		Body: fmt.Sprintf("Hi %s,\n\nYour verification code for %s is %s.\n\nThe code expires in %s. If you did not request this transfer, do not share the code and change your password.\n",
+			user.FirstName, purpose, otp, s.policy.ChallengeTTL),
+	})
+}
+
+// resolveStepUpMethod picks the strongest factor the user has set up unless
+// the client asked for a specific one.
+func resolveStepUpMethod(user *models.User, requested models.StepUpMethod) (models.StepUpMethod, error) {
+	if requested == "" {
+		switch {
+		case user.MFAEnabled:
+			return models.StepUpMethodTOTP, nil
+		case user.TransactionPINHash != "":
+			return models.StepUpMethodPIN, nil
+		default:
+			return models.StepUpMethodOTP, nil
+		}
+	}
+
+	if !requested.IsValid() {
+		return "", ErrInvalidStepUpMethod
+	}
+	if (requested == models.StepUpMethodTOTP && !user.MFAEnabled) || (requested == models.StepUpMethodPIN && user.TransactionPINHash == "") {
+		return "", ErrStepUpMethodUnavailable
+	}
+
+	return requested, nil
+}
+
+func envFloat(name string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil || value < 0 {
+		return fallback
+	}
+	return value
+}
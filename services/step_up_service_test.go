@@ -0,0 +1,179 @@
+package services
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+)
+
+func TestStepUpPolicyReasons(t *testing.T) {
+	policy := StepUpPolicy{
+		AmountThreshold:         10000,
+		NewBeneficiaryMinAmount: 100,
+		UnusualAmountFactor:     5,
+		VelocityCount:           5,
+		VelocityWindow:          time.Hour,
+	}
+	userID := uuid.New()
+	own := &models.Account{UserID: userID}
+	other := &models.Account{UserID: uuid.New()}
+	known := stepUpHistory{BeneficiaryTransfers: 1}
+	usual := []float64{100, 200, 300}
+
+	tests := []struct {
+		name    string
+		policy  StepUpPolicy
+		to      *models.Account
+		amount  float64
+		history stepUpHistory
+		want    []models.StepUpReason
+	}{
+		{"nothing unusual", policy, other, 500, stepUpHistory{BeneficiaryTransfers: 3, RecentAmounts: usual, WindowTransfers: 4}, []models.StepUpReason{}},
+		{"at the amount threshold", policy, own, 10000, stepUpHistory{}, []models.StepUpReason{models.StepUpReasonAmountThreshold}},
+		{"below the amount threshold", policy, own, 9999.99, stepUpHistory{}, []models.StepUpReason{}},
+		{"new beneficiary", policy, other, 500, stepUpHistory{}, []models.StepUpReason{models.StepUpReasonNewBeneficiary}},
+		{"new beneficiary below the minimum", policy, other, 100, stepUpHistory{}, []models.StepUpReason{}},
+		{"own account is never new", policy, own, 500, stepUpHistory{}, []models.StepUpReason{}},
+		{"five times the average", policy, other, 1000, stepUpHistory{BeneficiaryTransfers: 1, RecentAmounts: usual}, []models.StepUpReason{}},
+		{"over five times the average", policy, other, 1000.01, stepUpHistory{BeneficiaryTransfers: 1, RecentAmounts: usual}, []models.StepUpReason{models.StepUpReasonUnusualAmount}},
+		{"too little history", policy, other, 5000, stepUpHistory{BeneficiaryTransfers: 1, RecentAmounts: usual[:2]}, []models.StepUpReason{}},
+		{"velocity reached", policy, other, 50, stepUpHistory{BeneficiaryTransfers: 1, WindowTransfers: 5}, []models.StepUpReason{models.StepUpReasonVelocity}},
+		{"velocity not reached", policy, other, 50, stepUpHistory{BeneficiaryTransfers: 1, WindowTransfers: 4}, []models.StepUpReason{}},
+		{"every rule", policy, other, 20000, stepUpHistory{RecentAmounts: usual, WindowTransfers: 9}, []models.StepUpReason{
+			models.StepUpReasonAmountThreshold,
+			models.StepUpReasonNewBeneficiary,
+			models.StepUpReasonUnusualAmount,
+			models.StepUpReasonVelocity,
+		}},
+		{"rules disabled", StepUpPolicy{}, own, 1e9, stepUpHistory{RecentAmounts: usual, WindowTransfers: 100}, []models.StepUpReason{}},
+		{"known beneficiary", policy, other, 500, known, []models.StepUpReason{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.reasons(userID, tt.to, tt.amount, tt.history); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("reasons = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveStepUpMethod(t *testing.T) {
+	none := &models.User{}
+	pin := &models.User{TransactionPINHash: "hash"}
+	totp := &models.User{TransactionPINHash: "hash", MFAEnabled: true}
+
+	tests := []struct {
+		name      string
+		user      *models.User
+		requested models.StepUpMethod
+		want      models.StepUpMethod
+		err       error
+	}{
+		{"default without factors", none, "", models.StepUpMethodOTP, nil},
+		{"default with PIN", pin, "", models.StepUpMethodPIN, nil},
+		{"default with TOTP", totp, "", models.StepUpMethodTOTP, nil},
+		{"OTP is always available", totp, models.StepUpMethodOTP, models.StepUpMethodOTP, nil},
+		{"PIN requested", totp, models.StepUpMethodPIN, models.StepUpMethodPIN, nil},
+		{"PIN not set", none, models.StepUpMethodPIN, "", ErrStepUpMethodUnavailable},
+		{"TOTP not enabled", pin, models.StepUpMethodTOTP, "", ErrStepUpMethodUnavailable},
+		{"unknown method", totp, "sms", "", ErrInvalidStepUpMethod},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveStepUpMethod(tt.user, tt.requested)
+			if got != tt.want || !errors.Is(err, tt.err) {
+				t.Errorf("resolveStepUpMethod = %q, %v, want %q, %v", got, err, tt.want, tt.err)
+			}
+		})
+	}
+}
+
+func TestStepUpFailureLockout(t *testing.T) {
+	useThrottleWindows(t)
+	now := time.Date(2026, 10, 18, 9, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		env         string
+		maxFailures int
+	}{
+		{"", 5},
+		{"3", 3},
+		{"0", 5},
+		{"many", 5},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("STEP_UP_MAX_FAILURES", tt.env)
+		if got := stepUpMaxFailures(); got != tt.maxFailures {
+			t.Fatalf("STEP_UP_MAX_FAILURES=%q: stepUpMaxFailures = %d, want %d", tt.env, got, tt.maxFailures)
+		}
+
+		// Failures count across challenges because the key only depends on
+		// the user and the method.
+		throttle := &models.LoginThrottle{Key: stepUpThrottleKey(uuid.New(), models.StepUpMethodPIN)}
+		for i := 1; i < tt.maxFailures; i++ {
+			if countFailure(throttle, now, tt.maxFailures) {
+				t.Fatalf("STEP_UP_MAX_FAILURES=%q: failure %d locked the method", tt.env, i)
+			}
+			if wait := throttleWait([]models.LoginThrottle{*throttle}, now); wait != 0 {
+				t.Fatalf("STEP_UP_MAX_FAILURES=%q: wait after failure %d = %v, want none", tt.env, i, wait)
+			}
+		}
+		if !countFailure(throttle, now, tt.maxFailures) {
+			t.Fatalf("STEP_UP_MAX_FAILURES=%q: failure %d did not lock the method", tt.env, tt.maxFailures)
+		}
+		if wait := throttleWait([]models.LoginThrottle{*throttle}, now); wait != 10*time.Minute {
+			t.Errorf("STEP_UP_MAX_FAILURES=%q: wait = %v, want 10m", tt.env, wait)
+		}
+
+		after := now.Add(10 * time.Minute)
+		if wait := throttleWait([]models.LoginThrottle{*throttle}, after); wait != 0 {
+			t.Errorf("STEP_UP_MAX_FAILURES=%q: wait after the lockout = %v, want none", tt.env, wait)
+		}
+		if countFailure(throttle, after, tt.maxFailures) || throttle.Failures != 1 {
+			t.Errorf("STEP_UP_MAX_FAILURES=%q: failure after the lockout left %d failures, want 1", tt.env, throttle.Failures)
+		}
+	}
+}
+
+func TestVerifiedTransferKeepsLedgerChain(t *testing.T) {
+	initiated := time.Date(2026, 10, 16, 23, 50, 0, 0, time.UTC)
+	deposited := initiated.Add(5 * time.Minute)
+	verified := initiated.Add(20 * time.Minute)
+	nextDay := time.Date(2026, 10, 17, 0, 0, 0, 0, time.UTC)
+
+	from, to := uuid.New(), uuid.New()
+	opening := models.Transaction{ID: uuid.New(), Type: models.TransactionTypeDeposit, Amount: 1000, AccountID: from, BalanceBefore: 0, BalanceAfter: 1000, CreatedAt: initiated.Add(-time.Hour)}
+
+	// Initiation stores the transfer as pending with the balances of the
+	// moment, then a deposit is posted while the challenge is open.
+	transfer := models.Transaction{ID: uuid.New(), Type: models.TransactionTypeTransfer, Amount: 400, AccountID: from, ToAccountID: &to,
+		Status: models.TransactionStatusPending, BalanceBefore: 1000, BalanceAfter: 600, CreatedAt: initiated, ValueDate: time.Date(2026, 10, 16, 0, 0, 0, 0, time.UTC)}
+	deposit := models.Transaction{ID: uuid.New(), Type: models.TransactionTypeDeposit, Amount: 250, AccountID: from, BalanceBefore: 1000, BalanceAfter: 1250, CreatedAt: deposited}
+
+	updates := completePendingTransfer(&transfer, deposit.BalanceAfter, verified, nextDay)
+	if updates["created_at"] != verified || updates["value_date"] != nextDay {
+		t.Errorf("updates = %v, want created_at %v and value_date %v", updates, verified, nextDay)
+	}
+	if transfer.Status != models.TransactionStatusCompleted || transfer.BalanceBefore != 1250 || transfer.BalanceAfter != 850 {
+		t.Errorf("transfer = %s %.2f -> %.2f, want completed 1250.00 -> 850.00", transfer.Status, transfer.BalanceBefore, transfer.BalanceAfter)
+	}
+
+	// Reconciliation reads the ledger in created_at order.
+	ledger := []models.Transaction{opening, transfer, deposit}
+	sort.Slice(ledger, func(i, j int) bool { return ledger[i].CreatedAt.Before(ledger[j].CreatedAt) })
+
+	if got := ledgerDiscrepancies(from, 850, ledger); len(got) != 0 {
+		t.Errorf("source account discrepancies = %+v, want none", got)
+	}
+	if got := ledgerDiscrepancies(to, 400, []models.Transaction{transfer}); len(got) != 0 {
+		t.Errorf("destination account discrepancies = %+v, want none", got)
+	}
+}
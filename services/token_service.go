@@ -0,0 +1,465 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, all sessions in this family have been revoked")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+const sessionTouchInterval = time.Minute
+
+type TokenService struct {
+	db       *gorm.DB
+	notifier Notifier
+}
+
+func NewTokenService(db *gorm.DB) *TokenService {
+	return &TokenService{
+		db:       db,
+		notifier: NewNotifier(),
+	}
+}
+
+type TokenPair struct {
+	AccessToken  string    `json:"access_token"`
+	RefreshToken string    `json:"refresh_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresIn    int64     `json:"expires_in"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	SessionID    uuid.UUID `json:"session_id"`
+}
+
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+	DeviceID  string
+}
+
+// deviceFingerprint identifies the device by the X-Device-ID header when the
+// client sends one and by its user agent otherwise.
+func (c ClientInfo) deviceFingerprint() string {
+	if c.DeviceID != "" {
+		return utils.HashToken("device:" + c.DeviceID)
+	}
+	return utils.HashToken("ua:" + c.UserAgent)
+}
+
+// IssueTokens starts a new session. When the user already has sessions but
+// none from this device, a new device notification is emailed.
+func (s *TokenService) IssueTokens(user *models.User, client ClientInfo, mfaVerified bool) (*TokenPair, error) {
+	var pair *TokenPair
+	newDevice := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		deviceID := client.deviceFingerprint()
+
+		var sessions, sameDevice int64
+		if err := tx.Model(&models.Session{}).Where("user_id = ?", user.ID).Count(&sessions).Error; err != nil {
+			return fmt.Errorf("failed to count sessions: %v", err)
+		}
+		if err := tx.Model(&models.Session{}).Where("user_id = ? AND device_id = ?", user.ID, deviceID).Count(&sameDevice).Error; err != nil {
+			return fmt.Errorf("failed to count sessions: %v", err)
+		}
+		newDevice = sessions > 0 && sameDevice == 0
+
+		now := time.Now()
+		session := &models.Session{
+			ID:         uuid.New(),
+			UserID:     user.ID,
+			DeviceID:   deviceID,
+			DeviceName: utils.DeviceName(client.UserAgent),
+			UserAgent:  truncate(client.UserAgent, 255),
+			IPAddress:  client.IPAddress,
+			LastSeenAt: now,
+			ExpiresAt:  now.Add(utils.RefreshTokenTTL()),
+		}
+		if err := tx.Create(session).Error; err != nil {
+			return fmt.Errorf("failed to create session: %v", err)
+		}
+
+		var err error
+		pair, err = s.issue(tx, user, session.ID, nil, client, mfaVerified)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if newDevice {
+		// Best effort: a mail outage must not prevent the user from logging in.
+		_ = s.notifyNewDevice(user, client)
+	}
+
+	return pair, nil
+}
+
+func (s *TokenService) GetSessions(userID string) ([]models.Session, error) {
+	var sessions []models.Session
+
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if err := s.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userUUID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to find sessions: %v", err)
+	}
+
+	return sessions, nil
+}
+
+func (s *TokenService) RevokeSession(userID, sessionID string, actor AuditActor) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	sessionUUID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var session models.Session
+		if err := tx.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionUUID, userUUID).First(&session).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrSessionNotFound
+			}
+			return fmt.Errorf("failed to find session: %v", err)
+		}
+
+		if err := s.revokeFamily(tx, session.ID, models.TokenRevocationSessionRevoked); err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditActionSessionRevoke, models.AuditTargetSession, session.ID.String(), nil, map[string]interface{}{
+			"user_id":    session.UserID,
+			"ip_address": session.IPAddress,
+			"user_agent": session.UserAgent,
+		})
+	})
+}
+
+// TouchSession records activity on a session at most once per minute.
+func (s *TokenService) TouchSession(sessionID, ipAddress string) error {
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return nil
+	}
+
+	now := time.Now()
+	if err := s.db.Model(&models.Session{}).
+		Where("id = ? AND last_seen_at < ?", id, now.Add(-sessionTouchInterval)).
+		Updates(map[string]interface{}{"last_seen_at": now, "ip_address": ipAddress}).Error; err != nil {
+		return fmt.Errorf("failed to update session: %v", err)
+	}
+
+	return nil
+}
+
+// Refresh rotates a refresh token. Every token can be exchanged exactly once;
+// presenting one that was already rotated or revoked means it has leaked, so
+// the whole family descending from the original login is revoked.
+func (s *TokenService) Refresh(refreshToken string, client ClientInfo) (*TokenPair, error) {
+	var pair *TokenPair
+	reused := false
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var token models.RefreshToken
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", utils.HashToken(refreshToken)).
+			First(&token).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return fmt.Errorf("failed to find refresh token: %v", err)
+		}
+
+		if token.UsedAt != nil || token.RevokedAt != nil {
+			if err := s.revokeFamily(tx, token.FamilyID, models.TokenRevocationReuseDetected); err != nil {
+				return err
+			}
+			reused = true
+			return nil
+		}
+
+		now := time.Now()
+		if now.After(token.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		var user models.User
+		if err := tx.Where("id = ? AND is_active = ?", token.UserID, true).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidRefreshToken
+			}
+			return fmt.Errorf("failed to find user: %v", err)
+		}
+
+		if err := tx.Model(&token).Update("used_at", now).Error; err != nil {
+			return fmt.Errorf("failed to rotate refresh token: %v", err)
+		}
+
+		var err error
+		pair, err = s.issue(tx, &user, token.FamilyID, &token.ID, client, token.MFAVerified)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, ErrRefreshTokenReused
+	}
+
+	return pair, nil
+}
+
+func (s *TokenService) Logout(userID, jti, sessionID string, accessExpiresAt time.Time) error {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user ID")
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := denyAccessToken(tx, jti, userUUID, accessExpiresAt, models.TokenRevocationLogout); err != nil {
+			return err
+		}
+
+		familyID, err := uuid.Parse(sessionID)
+		if err != nil {
+			return nil
+		}
+
+		var count int64
+		if err := tx.Model(&models.RefreshToken{}).Where("family_id = ? AND user_id = ?", familyID, userUUID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to find session: %v", err)
+		}
+		if count == 0 {
+			return nil
+		}
+
+		return s.revokeFamily(tx, familyID, models.TokenRevocationLogout)
+	})
+}
+
+func (s *TokenService) LogoutAll(userID string) (int, error) {
+	return s.revokeSessions(userID, "", models.TokenRevocationLogoutAll)
+}
+
+// LogoutOthers revokes every session of the user except the current one.
+func (s *TokenService) LogoutOthers(userID, currentSessionID string, reason models.TokenRevocationReason) (int, error) {
+	return s.revokeSessions(userID, currentSessionID, reason)
+}
+
+func (s *TokenService) revokeSessions(userID, keepSessionID string, reason models.TokenRevocationReason) (int, error) {
+	userUUID, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, errors.New("invalid user ID")
+	}
+
+	var familyIDs []uuid.UUID
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&models.RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userUUID, time.Now())
+		if keepID, err := uuid.Parse(keepSessionID); err == nil {
+			query = query.Where("family_id <> ?", keepID)
+		}
+		if err := query.Distinct().Pluck("family_id", &familyIDs).Error; err != nil {
+			return fmt.Errorf("failed to find sessions: %v", err)
+		}
+
+		for _, familyID := range familyIDs {
+			if err := s.revokeFamily(tx, familyID, reason); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return len(familyIDs), nil
+}
+
+// IsRevoked reports whether the access token was denylisted or the session
+// it belongs to has been revoked.
+func (s *TokenService) IsRevoked(jti, sessionID string) (bool, error) {
+	var count int64
+
+	if err := s.db.Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %v", err)
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	id, err := uuid.Parse(sessionID)
+	if err != nil {
+		return false, nil
+	}
+
+	if err := s.db.Model(&models.Session{}).Where("id = ? AND revoked_at IS NOT NULL", id).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check session revocation: %v", err)
+	}
+
+	return count > 0, nil
+}
+
+func (s *TokenService) PurgeExpired(now time.Time) (int64, error) {
+	revoked := s.db.Where("expires_at < ?", now).Delete(&models.RevokedToken{})
+	if revoked.Error != nil {
+		return 0, fmt.Errorf("failed to purge revoked tokens: %v", revoked.Error)
+	}
+
+	refresh := s.db.Where("expires_at < ?", now).Delete(&models.RefreshToken{})
+	if refresh.Error != nil {
+		return 0, fmt.Errorf("failed to purge refresh tokens: %v", refresh.Error)
+	}
+
+	sessions := s.db.Where("expires_at < ?", now).Delete(&models.Session{})
+	if sessions.Error != nil {
+		return 0, fmt.Errorf("failed to purge sessions: %v", sessions.Error)
+	}
+
+	return revoked.RowsAffected + refresh.RowsAffected + sessions.RowsAffected, nil
+}
+
+// issue signs an access token and stores its refresh token. Roles that
+// require MFA only carry their permissions when the session verified it.
+func (s *TokenService) issue(tx *gorm.DB, user *models.User, familyID uuid.UUID, parentID *uuid.UUID, client ClientInfo, mfaVerified bool) (*TokenPair, error) {
+	now := time.Now()
+	accessExpiresAt := now.Add(utils.AccessTokenTTL())
+	jti := uuid.New().String()
+
+	permissions := []string{}
+	if mfaVerified || !user.Role.RequiresMFA() {
+		for _, permission := range user.Role.Permissions() {
+			permissions = append(permissions, string(permission))
+		}
+	}
+
+	accessToken, err := utils.GenerateJWTToken(utils.TokenClaims{
+		UserID:        user.ID.String(),
+		Email:         user.Email,
+		Role:          string(user.Role),
+		Permissions:   permissions,
+		JTI:           jti,
+		SessionID:     familyID.String(),
+		MFA:           mfaVerified,
+		EmailVerified: user.IsEmailVerified(),
+		ExpiresAt:     accessExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %v", err)
+	}
+
+	refreshToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate refresh token: %v", err)
+	}
+
+	record := &models.RefreshToken{
+		UserID:          user.ID,
+		FamilyID:        familyID,
+		ParentID:        parentID,
+		TokenHash:       utils.HashToken(refreshToken),
+		AccessTokenJTI:  jti,
+		AccessExpiresAt: accessExpiresAt,
+		UserAgent:       truncate(client.UserAgent, 255),
+		IPAddress:       client.IPAddress,
+		MFAVerified:     mfaVerified,
+		ExpiresAt:       now.Add(utils.RefreshTokenTTL()),
+	}
+	if err := tx.Create(record).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %v", err)
+	}
+
+	if err := tx.Model(&models.Session{}).Where("id = ?", familyID).Updates(map[string]interface{}{
+		"last_seen_at": now,
+		"ip_address":   client.IPAddress,
+		"expires_at":   record.ExpiresAt,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update session: %v", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(accessExpiresAt.Sub(now).Seconds()),
+		ExpiresAt:    accessExpiresAt,
+		SessionID:    familyID,
+	}, nil
+}
+
+// revokeFamily revokes every refresh token of a login session and denylists
+// the access tokens issued with them that may still be unexpired.
+func (s *TokenService) revokeFamily(tx *gorm.DB, familyID uuid.UUID, reason models.TokenRevocationReason) error {
+	now := time.Now()
+
+	var tokens []models.RefreshToken
+	if err := tx.Where("family_id = ? AND access_expires_at > ?", familyID, now).Find(&tokens).Error; err != nil {
+		return fmt.Errorf("failed to find session tokens: %v", err)
+	}
+	for _, token := range tokens {
+		if err := denyAccessToken(tx, token.AccessTokenJTI, token.UserID, token.AccessExpiresAt, reason); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Updates(map[string]interface{}{"revoked_at": now, "revoked_reason": reason}).Error; err != nil {
+		return fmt.Errorf("failed to revoke session: %v", err)
+	}
+
+	if err := tx.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", familyID).
+		Updates(map[string]interface{}{"revoked_at": now, "revoked_reason": reason}).Error; err != nil {
+		return fmt.Errorf("failed to revoke session: %v", err)
+	}
+
+	return nil
+}
+
+func denyAccessToken(tx *gorm.DB, jti string, userID uuid.UUID, expiresAt time.Time, reason models.TokenRevocationReason) error {
+	if jti == "" {
+		return nil
+	}
+
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&models.RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		Reason:    reason,
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to revoke access token: %v", err)
+	}
+
+	return nil
+}
+
+func (s *TokenService) notifyNewDevice(user *models.User, client ClientInfo) error {
+	return s.notifier.Send(EmailMessage{
+		To:      user.Email,
+		Subject: "New sign-in to your account",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account was just signed in to from a new device.\n\nDevice: %s\nIP address: %s\nTime: %s\n\nIf this was not you, revoke the session from your session list and reset your password.\n",
+			user.FirstName, utils.DeviceName(client.UserAgent), client.IPAddress, time.Now().Format(time.RFC1123)),
+	})
+}
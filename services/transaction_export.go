@@ -0,0 +1,531 @@
+package services
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type ExportFormat string
+
+const (
+	ExportFormatCSV     ExportFormat = "csv"
+	ExportFormatOFX     ExportFormat = "ofx"
+	ExportFormatQIF     ExportFormat = "qif"
+	ExportFormatCAMT053 ExportFormat = "camt053"
+	ExportFormatMT940   ExportFormat = "mt940"
+)
+
+type TransactionExport struct {
+	db             *gorm.DB
+	format         ExportFormat
+	account        models.Account
+	from           time.Time
+	to             time.Time
+	openingBalance float64
+	closingBalance float64
+	totalCredits   float64
+	totalDebits    float64
+	creditCount    int64
+	debitCount     int64
+	generatedAt    time.Time
+}
+
+type exportLine struct {
+	transaction    models.Transaction
+	amount         float64
+	runningBalance float64
+}
+
+type exportWriter interface {
+	begin() error
+	write(line exportLine) error
+	end() error
+}
+
+func (s *TransactionService) NewExport(accountID string, format ExportFormat, from, to time.Time) (*TransactionExport, error) {
+	switch format {
+	case ExportFormatCSV, ExportFormatOFX, ExportFormatQIF, ExportFormatCAMT053, ExportFormatMT940:
+	default:
+		return nil, errors.New("unsupported export format")
+	}
+
+	if !from.Before(to) {
+		return nil, errors.New("export start date must be before end date")
+	}
+
+	id, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	export := &TransactionExport{
+		db:          s.db,
+		format:      format,
+		from:        from,
+		to:          to,
+		generatedAt: time.Now(),
+	}
+
+	if err := s.db.Where("id = ?", id).First(&export.account).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("account not found")
+		}
+		return nil, fmt.Errorf("failed to find account: %v", err)
+	}
+
+	return export, nil
+}
+
+func (e *TransactionExport) ContentType() string {
+	switch e.format {
+	case ExportFormatCSV:
+		return "text/csv"
+	case ExportFormatOFX:
+		return "application/x-ofx"
+	case ExportFormatCAMT053:
+		return "application/xml"
+	default:
+		return "text/plain"
+	}
+}
+
+func (e *TransactionExport) Filename() string {
+	extension := map[ExportFormat]string{
+		ExportFormatCSV:     "csv",
+		ExportFormatOFX:     "ofx",
+		ExportFormatQIF:     "qif",
+		ExportFormatCAMT053: "xml",
+		ExportFormatMT940:   "sta",
+	}[e.format]
+
+	return fmt.Sprintf("transactions-%s-%s-%s.%s", e.account.AccountNumber,
+		e.from.Format("20060102"), e.to.AddDate(0, 0, -1).Format("20060102"), extension)
+}
+
+// Stream writes the export. The balances, the totals and the entries are all
+// read from one snapshot, so that a posting committed meanwhile cannot make
+// the closing balance disagree with the entries listed.
+func (e *TransactionExport) Stream(w io.Writer) error {
+	return e.db.Transaction(func(tx *gorm.DB) error {
+		if err := e.summarize(tx); err != nil {
+			return err
+		}
+		return e.stream(tx, w)
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
+
+// summarize reads the opening balance and the period totals the header and
+// trailer of every format are built from.
+func (e *TransactionExport) summarize(tx *gorm.DB) error {
+	if err := tx.Where("id = ?", e.account.ID).First(&e.account).Error; err != nil {
+		return fmt.Errorf("failed to find account: %v", err)
+	}
+
+	movementSince, err := netMovement(completedAccountTransactions(tx, e.account.ID).Where("created_at >= ?", e.from), e.account.ID)
+	if err != nil {
+		return err
+	}
+	e.openingBalance = e.account.Balance - movementSince
+
+	var summary struct {
+		Credits     float64
+		Debits      float64
+		CreditCount int64
+		DebitCount  int64
+	}
+	creditCase := "(to_account_id = @account OR type IN @credit)"
+	if err := completedAccountTransactions(tx, e.account.ID).
+		Where("created_at >= ? AND created_at < ?", e.from, e.to).
+		Select(
+			"COALESCE(SUM(CASE WHEN "+creditCase+" THEN amount ELSE 0 END), 0) AS credits, "+
+				"COALESCE(SUM(CASE WHEN "+creditCase+" THEN 0 ELSE amount END), 0) AS debits, "+
+				"COUNT(CASE WHEN "+creditCase+" THEN 1 END) AS credit_count, "+
+				"COUNT(CASE WHEN "+creditCase+" THEN NULL ELSE 1 END) AS debit_count",
+			map[string]interface{}{"account": e.account.ID, "credit": models.CreditTransactionTypes},
+		).
+		Scan(&summary).Error; err != nil {
+		return fmt.Errorf("failed to summarize transactions: %v", err)
+	}
+
+	e.totalCredits = summary.Credits
+	e.totalDebits = summary.Debits
+	e.creditCount = summary.CreditCount
+	e.debitCount = summary.DebitCount
+	e.closingBalance = e.openingBalance + summary.Credits - summary.Debits
+
+	return nil
+}
+
+func (e *TransactionExport) stream(tx *gorm.DB, w io.Writer) error {
+	buffered := bufio.NewWriter(w)
+
+	var writer exportWriter
+	switch e.format {
+	case ExportFormatCSV:
+		writer = &csvExportWriter{export: e, w: csv.NewWriter(buffered)}
+	case ExportFormatOFX:
+		writer = &ofxExportWriter{export: e, w: buffered}
+	case ExportFormatQIF:
+		writer = &qifExportWriter{export: e, w: buffered}
+	case ExportFormatCAMT053:
+		writer = &camtExportWriter{export: e, w: buffered}
+	case ExportFormatMT940:
+		writer = &mt940ExportWriter{export: e, w: buffered}
+	}
+
+	if err := writer.begin(); err != nil {
+		return err
+	}
+
+	rows, err := completedAccountTransactions(tx, e.account.ID).
+		Where("created_at >= ? AND created_at < ?", e.from, e.to).
+		Order("created_at ASC, id ASC").
+		Rows()
+	if err != nil {
+		return fmt.Errorf("failed to query transactions: %v", err)
+	}
+	defer rows.Close()
+
+	running := e.openingBalance
+	for rows.Next() {
+		var transaction models.Transaction
+		if err := tx.ScanRows(rows, &transaction); err != nil {
+			return fmt.Errorf("failed to read transaction: %v", err)
+		}
+
+		amount := transaction.SignedAmountFor(e.account.ID)
+		running += amount
+
+		if err := writer.write(exportLine{transaction: transaction, amount: amount, runningBalance: running}); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read transactions: %v", err)
+	}
+
+	if err := writer.end(); err != nil {
+		return err
+	}
+
+	return buffered.Flush()
+}
+
+type csvExportWriter struct {
+	export *TransactionExport
+	w      *csv.Writer
+}
+
+func (c *csvExportWriter) begin() error {
+	return c.w.Write([]string{"transaction_id", "date", "type", "direction", "amount", "currency", "description", "balance"})
+}
+
+func (c *csvExportWriter) write(line exportLine) error {
+	return c.w.Write([]string{
+		line.transaction.TransactionID,
+		line.transaction.CreatedAt.In(utils.BankLocation()).Format(time.RFC3339),
+		string(line.transaction.Type),
+		creditDebitIndicator(line.amount),
+		formatAmount(line.amount),
+		line.transaction.Currency,
+		csvText(line.transaction.Description),
+		formatAmount(line.runningBalance),
+	})
+}
+
+func (c *csvExportWriter) end() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ofxExportWriter struct {
+	export *TransactionExport
+	w      *bufio.Writer
+}
+
+func (o *ofxExportWriter) begin() error {
+	e := o.export
+	accountType := "CHECKING"
+	if e.account.Type == models.AccountTypeSaving {
+		accountType = "SAVINGS"
+	}
+
+	_, err := fmt.Fprintf(o.w, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>%s</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>
+<STMTRS><CURDEF>%s</CURDEF>
+<BANKACCTFROM><BANKID>%s</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>%s</ACCTTYPE></BANKACCTFROM>
+<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>
+`, ofxDate(e.generatedAt), uuid.New(), xmlText(e.account.Currency), xmlText(utils.BankCode()), xmlText(e.account.AccountNumber), accountType,
+		ofxDate(e.from), ofxDate(e.to))
+	return err
+}
+
+func (o *ofxExportWriter) write(line exportLine) error {
+	transaction := line.transaction
+	_, err := fmt.Fprintf(o.w, "<STMTTRN><TRNTYPE>%s</TRNTYPE><DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT><FITID>%s</FITID><NAME>%s</NAME><MEMO>%s</MEMO></STMTTRN>\n",
+		ofxTransactionType(transaction.Type, line.amount), ofxDate(transaction.CreatedAt), formatAmount(line.amount),
+		xmlText(transaction.TransactionID), xmlText(truncate(ofxName(transaction), 32)), xmlText(truncate(transaction.Description, 255)))
+	return err
+}
+
+func (o *ofxExportWriter) end() error {
+	e := o.export
+	_, err := fmt.Fprintf(o.w, `</BANKTRANLIST>
+<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`, formatAmount(e.closingBalance), ofxDate(e.to))
+	return err
+}
+
+type qifExportWriter struct {
+	export *TransactionExport
+	w      *bufio.Writer
+}
+
+func (q *qifExportWriter) begin() error {
+	_, err := q.w.WriteString("!Type:Bank\n")
+	return err
+}
+
+func (q *qifExportWriter) write(line exportLine) error {
+	transaction := line.transaction
+	_, err := fmt.Fprintf(q.w, "D%s\nT%s\nN%s\nP%s\nM%s\n^\n",
+		transaction.CreatedAt.In(utils.BankLocation()).Format("01/02/2006"),
+		formatAmount(line.amount),
+		transaction.TransactionID,
+		singleLine(ofxName(transaction)),
+		singleLine(transaction.Description))
+	return err
+}
+
+func (q *qifExportWriter) end() error {
+	return nil
+}
+
+type camtExportWriter struct {
+	export *TransactionExport
+	w      *bufio.Writer
+}
+
+func (c *camtExportWriter) begin() error {
+	e := c.export
+	messageID := strings.ReplaceAll(uuid.New().String(), "-", "")
+	lastDay := e.to.AddDate(0, 0, -1)
+
+	_, err := fmt.Fprintf(c.w, `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+<BkToCstmrStmt>
+<GrpHdr><MsgId>%s</MsgId><CreDtTm>%s</CreDtTm></GrpHdr>
+<Stmt>
+<Id>%s</Id>
+<CreDtTm>%s</CreDtTm>
+<FrToDt><FrDtTm>%s</FrDtTm><ToDtTm>%s</ToDtTm></FrToDt>
+<Acct><Id><Othr><Id>%s</Id></Othr></Id><Ccy>%s</Ccy><Svcr><FinInstnId><Othr><Id>%s</Id></Othr></FinInstnId></Svcr></Acct>
+%s%s<TxsSummry><TtlNtries><NbOfNtries>%d</NbOfNtries></TtlNtries><TtlCdtNtries><NbOfNtries>%d</NbOfNtries><Sum>%s</Sum></TtlCdtNtries><TtlDbtNtries><NbOfNtries>%d</NbOfNtries><Sum>%s</Sum></TtlDbtNtries></TxsSummry>
+`, messageID, isoDateTime(e.generatedAt), messageID[:16], isoDateTime(e.generatedAt),
+		isoDateTime(e.from), isoDateTime(e.to.Add(-time.Second)),
+		xmlText(e.account.AccountNumber), xmlText(e.account.Currency), xmlText(utils.BankCode()),
+		camtBalance("OPBD", e.openingBalance, e.account.Currency, e.from),
+		camtBalance("CLBD", e.closingBalance, e.account.Currency, lastDay),
+		e.creditCount+e.debitCount, e.creditCount, formatAmount(e.totalCredits), e.debitCount, formatAmount(e.totalDebits))
+	return err
+}
+
+func (c *camtExportWriter) write(line exportLine) error {
+	transaction := line.transaction
+	endToEndID := transaction.TransactionID
+	if id := transaction.Metadata["end_to_end_id"]; id != "" {
+		endToEndID = id
+	}
+
+	_, err := fmt.Fprintf(c.w, `<Ntry><NtryRef>%s</NtryRef><Amt Ccy="%s">%s</Amt><CdtDbtInd>%s</CdtDbtInd><Sts><Cd>BOOK</Cd></Sts><BookgDt><DtTm>%s</DtTm></BookgDt><ValDt><Dt>%s</Dt></ValDt><AcctSvcrRef>%s</AcctSvcrRef><BkTxCd><Prtry><Cd>%s</Cd></Prtry></BkTxCd><NtryDtls><TxDtls><Refs><AcctSvcrRef>%s</AcctSvcrRef><EndToEndId>%s</EndToEndId></Refs><Amt Ccy="%s">%s</Amt><CdtDbtInd>%s</CdtDbtInd><RmtInf><Ustrd>%s</Ustrd></RmtInf></TxDtls></NtryDtls></Ntry>
+`,
+		xmlText(truncate(transaction.TransactionID, 35)), xmlText(transaction.Currency), formatAmount(math.Abs(line.amount)), creditDebitIndicator(line.amount),
+		isoDateTime(transaction.CreatedAt), transaction.ValueDate.Format(statementDateFormat), xmlText(truncate(transaction.TransactionID, 35)),
+		xmlText(strings.ToUpper(string(transaction.Type))), xmlText(truncate(transaction.TransactionID, 35)), xmlText(truncate(endToEndID, 35)),
+		xmlText(transaction.Currency), formatAmount(math.Abs(line.amount)), creditDebitIndicator(line.amount),
+		xmlText(truncate(transaction.Description, 140)))
+	return err
+}
+
+func (c *camtExportWriter) end() error {
+	_, err := c.w.WriteString("</Stmt>\n</BkToCstmrStmt>\n</Document>\n")
+	return err
+}
+
+type mt940ExportWriter struct {
+	export *TransactionExport
+	w      *bufio.Writer
+}
+
+func (m *mt940ExportWriter) begin() error {
+	e := m.export
+	_, err := fmt.Fprintf(m.w, ":20:%s\r\n:25:%s\r\n:28C:%s/1\r\n:60F:%s\r\n",
+		truncate(strings.ToUpper(e.account.AccountNumber)+e.from.Format("0601"), 16),
+		swiftText(utils.BankCode()+"/"+e.account.AccountNumber, 35),
+		e.from.Format("0601"),
+		mt940Balance(e.openingBalance, e.from, e.account.Currency))
+	return err
+}
+
+func (m *mt940ExportWriter) write(line exportLine) error {
+	transaction := line.transaction
+	date := transaction.CreatedAt.In(utils.BankLocation())
+	indicator := "C"
+	if line.amount < 0 {
+		indicator = "D"
+	}
+
+	if _, err := fmt.Fprintf(m.w, ":61:%s%s%s%sN%s%s//%s\r\n%s\r\n",
+		date.Format("060102"), date.Format("0102"), indicator, mt940Amount(math.Abs(line.amount)),
+		mt940TransactionCode(transaction.Type), truncate(transaction.TransactionID, 16),
+		truncate(transaction.TransactionID[min(16, len(transaction.TransactionID)):], 16),
+		truncate(transaction.TransactionID, 34)); err != nil {
+		return err
+	}
+
+	details := swiftText(transaction.Description, 390)
+	if details == "" {
+		details = strings.ToUpper(string(transaction.Type))
+	}
+	for i := 0; i < len(details); i += 65 {
+		prefix := ""
+		if i == 0 {
+			prefix = ":86:"
+		}
+		if _, err := fmt.Fprintf(m.w, "%s%s\r\n", prefix, details[i:min(i+65, len(details))]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *mt940ExportWriter) end() error {
+	e := m.export
+	_, err := fmt.Fprintf(m.w, ":62F:%s\r\n-\r\n", mt940Balance(e.closingBalance, e.to.AddDate(0, 0, -1), e.account.Currency))
+	return err
+}
+
+func creditDebitIndicator(amount float64) string {
+	if amount < 0 {
+		return "DBIT"
+	}
+	return "CRDT"
+}
+
+func ofxTransactionType(transactionType models.TransactionType, amount float64) string {
+	switch transactionType {
+	case models.TransactionTypeDeposit:
+		return "DEP"
+	case models.TransactionTypeTransfer:
+		return "XFER"
+	case models.TransactionTypeCardPayment:
+		return "POS"
+	case models.TransactionTypeFee:
+		return "FEE"
+	case models.TransactionTypeInterest:
+		return "INT"
+	}
+	if amount < 0 {
+		return "DEBIT"
+	}
+	return "CREDIT"
+}
+
+func ofxName(transaction models.Transaction) string {
+	if transaction.Description != "" {
+		return transaction.Description
+	}
+	return strings.ReplaceAll(string(transaction.Type), "_", " ")
+}
+
+func mt940TransactionCode(transactionType models.TransactionType) string {
+	switch transactionType {
+	case models.TransactionTypeTransfer:
+		return "TRF"
+	case models.TransactionTypeFee:
+		return "CHG"
+	case models.TransactionTypeInterest:
+		return "INT"
+	}
+	return "MSC"
+}
+
+func mt940Balance(balance float64, date time.Time, currency string) string {
+	indicator := "C"
+	if balance < 0 {
+		indicator = "D"
+	}
+	return indicator + date.In(utils.BankLocation()).Format("060102") + currency + mt940Amount(math.Abs(balance))
+}
+
+func mt940Amount(amount float64) string {
+	return strings.Replace(formatAmount(amount), ".", ",", 1)
+}
+
+func camtBalance(code string, balance float64, currency string, date time.Time) string {
+	return fmt.Sprintf("<Bal><Tp><CdOrPrtry><Cd>%s</Cd></CdOrPrtry></Tp><Amt Ccy=\"%s\">%s</Amt><CdtDbtInd>%s</CdtDbtInd><Dt><Dt>%s</Dt></Dt></Bal>\n",
+		code, xmlText(currency), formatAmount(math.Abs(balance)), creditDebitIndicator(balance), isoDate(date))
+}
+
+func ofxDate(t time.Time) string {
+	return t.UTC().Format("20060102150405") + "[0:GMT]"
+}
+
+func isoDateTime(t time.Time) string {
+	return t.In(utils.BankLocation()).Format("2006-01-02T15:04:05-07:00")
+}
+
+func isoDate(t time.Time) string {
+	return t.In(utils.BankLocation()).Format("2006-01-02")
+}
+
+func xmlText(value string) string {
+	var sb strings.Builder
+	xml.EscapeText(&sb, []byte(value))
+	return sb.String()
+}
+
+// csvText keeps customer supplied text from being read as a formula when the
+// file is opened in a spreadsheet.
+func csvText(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func singleLine(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+func swiftText(value string, length int) string {
+	var sb strings.Builder
+	for _, r := range strings.ToUpper(value) {
+		switch {
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9', strings.ContainsRune("/-?:().,'+ ", r):
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(' ')
+		}
+	}
+	return truncate(strings.TrimSpace(sb.String()), length)
+}
@@ -0,0 +1,80 @@
+package services
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+)
+
+func TestCAMTEntryReferences(t *testing.T) {
+	// Booked just before midnight but valued the next business day, as a
+	// posting that arrives during the end-of-day close is.
+	createdAt := time.Date(2026, 10, 16, 23, 55, 0, 0, time.UTC)
+	valueDate := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		metadata   models.Metadata
+		endToEndID string
+	}{
+		{"bulk payment", models.Metadata{"end_to_end_id": "E2E-INV-2041"}, "E2E-INV-2041"},
+		{"no end-to-end ID", models.Metadata{}, "TXN20261016235500ABC"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sb strings.Builder
+			w := bufio.NewWriter(&sb)
+			writer := &camtExportWriter{export: &TransactionExport{}, w: w}
+
+			transaction := models.Transaction{
+				TransactionID: "TXN20261016235500ABC",
+				Type:          models.TransactionTypeTransfer,
+				Amount:        25,
+				Currency:      "USD",
+				Metadata:      tt.metadata,
+				CreatedAt:     createdAt,
+				ValueDate:     valueDate,
+			}
+			if err := writer.write(exportLine{transaction: transaction, amount: -25}); err != nil {
+				t.Fatal(err)
+			}
+			w.Flush()
+
+			for _, want := range []string{
+				"<ValDt><Dt>2026-10-19</Dt></ValDt>",
+				"<EndToEndId>" + tt.endToEndID + "</EndToEndId>",
+				"<AcctSvcrRef>TXN20261016235500ABC</AcctSvcrRef>",
+			} {
+				if !strings.Contains(sb.String(), want) {
+					t.Errorf("entry does not contain %s:\n%s", want, sb.String())
+				}
+			}
+		})
+	}
+}
+
+func TestCSVText(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"Coffee", "Coffee"},
+		{"", ""},
+		{"=HYPERLINK(\"http://evil\")", "'=HYPERLINK(\"http://evil\")"},
+		{"+1 555 0100", "'+1 555 0100"},
+		{"-2+3", "'-2+3"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1", "'\t=1"},
+		{"Rent = 1200", "Rent = 1200"},
+	}
+
+	for _, tt := range tests {
+		if got := csvText(tt.value); got != tt.want {
+			t.Errorf("csvText(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	MaxMetadataKeys        = 20
+	MaxMetadataKeyLength   = 40
+	MaxMetadataValueLength = 500
+	MaxMetadataSize        = 4096
+	MaxTransactionTags     = 10
+	MaxTagLength           = 32
+)
+
+var (
+	ErrTransactionNotEditable = errors.New("only the originating account can update this transaction")
+	ErrInvalidAnnotations     = errors.New("invalid transaction annotations")
+
+	metadataKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	tagPattern         = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+	// systemMetadataKeys are written by the bank itself, for example when a
+	// payment file is executed, and cannot be set or removed by clients.
+	systemMetadataKeys = map[string]bool{
+		"end_to_end_id":     true,
+		"payment_file_item": true,
+	}
+)
+
+type TransactionAnnotations struct {
+	Metadata map[string]*string
+	Tags     *[]string
+}
+
+func ValidateMetadata(metadata models.Metadata) error {
+	if len(metadata) > MaxMetadataKeys {
+		return fmt.Errorf("metadata cannot have more than %d keys", MaxMetadataKeys)
+	}
+
+	for key, value := range metadata {
+		if len(key) > MaxMetadataKeyLength || !metadataKeyPattern.MatchString(key) {
+			return fmt.Errorf("invalid metadata key %q: use up to %d letters, digits, '_', '-' or '.'", key, MaxMetadataKeyLength)
+		}
+		if len(value) > MaxMetadataValueLength {
+			return fmt.Errorf("metadata value for %q exceeds %d characters", key, MaxMetadataValueLength)
+		}
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("invalid metadata: %v", err)
+	}
+	if len(data) > MaxMetadataSize {
+		return fmt.Errorf("metadata exceeds %d bytes", MaxMetadataSize)
+	}
+
+	return nil
+}
+
+// ValidateClientMetadata validates metadata supplied by a client, which may
+// not use any of the system keys.
+func ValidateClientMetadata(metadata models.Metadata) error {
+	for key := range metadata {
+		if err := checkClientMetadataKey(key); err != nil {
+			return err
+		}
+	}
+	return ValidateMetadata(metadata)
+}
+
+func checkClientMetadataKey(key string) error {
+	if systemMetadataKeys[key] {
+		return fmt.Errorf("metadata key %q is reserved", key)
+	}
+	return nil
+}
+
+func NormalizeTags(tags []string) (models.Tags, error) {
+	normalized := models.Tags{}
+	seen := make(map[string]bool, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > MaxTagLength || !tagPattern.MatchString(tag) {
+			return nil, fmt.Errorf("invalid tag %q: use up to %d lowercase letters, digits, '_' or '-'", tag, MaxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > MaxTransactionTags {
+		return nil, fmt.Errorf("a transaction cannot have more than %d tags", MaxTransactionTags)
+	}
+
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+// UpdateAnnotations merges metadata into the transaction, removing keys that
+// are set to null, and replaces its tags when they are provided. System keys
+// cannot be changed and are always kept.
+func (s *TransactionService) UpdateAnnotations(accountID, transactionID string, annotations TransactionAnnotations, actor AuditActor) (*models.Transaction, error) {
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	transactionUUID, err := uuid.Parse(transactionID)
+	if err != nil {
+		return nil, errors.New("invalid transaction ID")
+	}
+
+	var transaction models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND (account_id = ? OR to_account_id = ?)", transactionUUID, accountUUID, accountUUID).
+			First(&transaction).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrTransactionNotFound
+			}
+			return fmt.Errorf("failed to find transaction: %v", err)
+		}
+
+		if transaction.AccountID != accountUUID {
+			return ErrTransactionNotEditable
+		}
+
+		before := map[string]interface{}{"metadata": transaction.Metadata, "tags": transaction.Tags}
+		updates := map[string]interface{}{}
+
+		if annotations.Metadata != nil {
+			metadata := models.Metadata{}
+			for key, value := range transaction.Metadata {
+				metadata[key] = value
+			}
+			for key, value := range annotations.Metadata {
+				if err := checkClientMetadataKey(key); err != nil {
+					return fmt.Errorf("%w: %v", ErrInvalidAnnotations, err)
+				}
+				if value == nil {
+					delete(metadata, key)
+					continue
+				}
+				metadata[key] = *value
+			}
+			if err := ValidateMetadata(metadata); err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidAnnotations, err)
+			}
+			transaction.Metadata = metadata
+			updates["metadata"] = metadata
+		}
+
+		if annotations.Tags != nil {
+			tags, err := NormalizeTags(*annotations.Tags)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidAnnotations, err)
+			}
+			transaction.Tags = tags
+			updates["tags"] = tags
+		}
+
+		if len(updates) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&transaction).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update transaction: %v", err)
+		}
+
+		after := map[string]interface{}{"metadata": transaction.Metadata, "tags": transaction.Tags}
+		return recordAudit(tx, actor, models.AuditActionTransactionEdit, models.AuditTargetTransaction, transaction.ID.String(), before, after)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &transaction, nil
+}
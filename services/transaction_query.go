@@ -0,0 +1,197 @@
+package services
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultTransactionPageSize = 50
+	MaxTransactionPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+type TransactionDirection string
+
+const (
+	TransactionDirectionCredit TransactionDirection = "credit"
+	TransactionDirectionDebit  TransactionDirection = "debit"
+)
+
+type TransactionFilter struct {
+	From         *time.Time
+	To           *time.Time
+	Types        []models.TransactionType
+	Statuses     []models.TransactionStatus
+	MinAmount    *float64
+	MaxAmount    *float64
+	Metadata     models.Metadata
+	Tags         models.Tags
+	Direction    TransactionDirection
+	Search       string
+	Cursor       string
+	Limit        int
+	IncludeTotal bool
+}
+
+type TransactionPage struct {
+	Transactions []models.Transaction
+	Limit        int
+	NextCursor   string
+	Total        *int64
+}
+
+type transactionCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+func (s *TransactionService) ListTransactions(accountID string, filter TransactionFilter) (*TransactionPage, error) {
+	accountUUID, err := uuid.Parse(accountID)
+	if err != nil {
+		return nil, errors.New("invalid account ID")
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = DefaultTransactionPageSize
+	}
+	if limit > MaxTransactionPageSize {
+		limit = MaxTransactionPageSize
+	}
+
+	var cursor *transactionCursor
+	if filter.Cursor != "" {
+		if cursor, err = decodeTransactionCursor(filter.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// Each side of the account is paged separately so both can walk their
+	// (account, created_at, id) index instead of sorting the whole history.
+	branch := func(column string) *gorm.DB {
+		query := s.filteredTransactions(accountUUID, column, filter)
+		if cursor != nil {
+			query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+		}
+		return query.Order("created_at DESC, id DESC").Limit(limit + 1)
+	}
+
+	var transactions []models.Transaction
+	if err := s.db.Table("((?) UNION ALL (?)) AS transactions", branch("account_id"), branch("to_account_id")).
+		Order("created_at DESC, id DESC").
+		Limit(limit + 1).
+		Find(&transactions).Error; err != nil {
+		return nil, fmt.Errorf("failed to find transactions: %v", err)
+	}
+
+	page := &TransactionPage{Limit: limit}
+	if len(transactions) > limit {
+		transactions = transactions[:limit]
+		last := transactions[len(transactions)-1]
+		page.NextCursor = encodeTransactionCursor(last.CreatedAt, last.ID)
+	}
+	page.Transactions = transactions
+
+	if filter.IncludeTotal {
+		var total int64
+		for _, column := range []string{"account_id", "to_account_id"} {
+			var count int64
+			if err := s.filteredTransactions(accountUUID, column, filter).Count(&count).Error; err != nil {
+				return nil, fmt.Errorf("failed to count transactions: %v", err)
+			}
+			total += count
+		}
+		page.Total = &total
+	}
+
+	return page, nil
+}
+
+func (s *TransactionService) filteredTransactions(accountID uuid.UUID, column string, filter TransactionFilter) *gorm.DB {
+	query := s.db.Model(&models.Transaction{}).Where(column+" = ?", accountID)
+
+	incoming := column == "to_account_id"
+	switch filter.Direction {
+	case TransactionDirectionCredit:
+		if !incoming {
+			query = query.Where("type IN ?", models.CreditTransactionTypes)
+		}
+	case TransactionDirectionDebit:
+		if incoming {
+			query = query.Where("1 = 0")
+		} else {
+			query = query.Where("type NOT IN ?", models.CreditTransactionTypes)
+		}
+	}
+
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if len(filter.Statuses) > 0 {
+		query = query.Where("status IN ?", filter.Statuses)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("amount >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("amount <= ?", *filter.MaxAmount)
+	}
+	if len(filter.Metadata) > 0 {
+		query = query.Where("metadata @> ?::jsonb", filter.Metadata)
+	}
+	if len(filter.Tags) > 0 {
+		query = query.Where("tags @> ?::jsonb", filter.Tags)
+	}
+	if filter.Search != "" {
+		query = query.Where("description ILIKE ? ESCAPE '\\'", "%"+escapeLike(filter.Search)+"%")
+	}
+
+	return query
+}
+
+func encodeTransactionCursor(createdAt time.Time, id uuid.UUID) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(createdAt.UTC().Format(time.RFC3339Nano) + "|" + id.String()))
+}
+
+func decodeTransactionCursor(value string) (*transactionCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return nil, ErrInvalidCursor
+	}
+
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	return &transactionCursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
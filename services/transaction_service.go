@@ -3,19 +3,32 @@ package services
 import (
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/azainwork/core-banking-api/models"
 	"github.com/azainwork/core-banking-api/utils"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAccountFrozen       = errors.New("account is frozen")
+	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrSameAccountTransfer = errors.New("cannot transfer to the same account")
 )
 
 type TransactionService struct {
-	db *gorm.DB
+	db          *gorm.DB
+	holdService *HoldService
 }
 
 func NewTransactionService(db *gorm.DB) *TransactionService {
-	return &TransactionService{db: db}
+	return &TransactionService{
+		db:          db,
+		holdService: NewHoldService(db),
+	}
 }
 
 func (s *TransactionService) CreateTransaction(transaction *models.Transaction) error {
@@ -29,10 +42,12 @@ func (s *TransactionService) CreateTransaction(transaction *models.Transaction)
 		return fmt.Errorf("failed to create transaction: %v", err)
 	}
 
+	categorizeCommitted(s.db, transaction)
+
 	return nil
 }
 
-func (s *TransactionService) ProcessDeposit(accountID string, amount float64, description string) (*models.Transaction, error) {
+func (s *TransactionService) ProcessDeposit(accountID string, amount float64, description string, metadata models.Metadata, tags models.Tags, actor AuditActor) (*models.Transaction, error) {
 	if amount <= 0 {
 		return nil, errors.New("amount must be greater than zero")
 	}
@@ -49,12 +64,20 @@ func (s *TransactionService) ProcessDeposit(accountID string, amount float64, de
 		}
 	}()
 
+	account, err = lockAccount(tx, account.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	transaction := &models.Transaction{
 		Type:          models.TransactionTypeDeposit,
 		Amount:        amount,
 		Currency:      account.Currency,
 		Status:        models.TransactionStatusPending,
 		Description:   description,
+		Metadata:      metadata,
+		Tags:          tags,
 		AccountID:     account.ID,
 		BalanceBefore: account.Balance,
 		BalanceAfter:  account.Balance + amount,
@@ -75,15 +98,23 @@ func (s *TransactionService) ProcessDeposit(accountID string, amount float64, de
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to update transaction status: %v", err)
 	}
+	transaction.Status = models.TransactionStatusCompleted
+
+	if err := recordAudit(tx, actor, models.AuditActionDeposit, models.AuditTargetTransaction, transaction.ID.String(), nil, auditTransaction(transaction)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
+	categorizeCommitted(s.db, transaction)
+
 	return transaction, nil
 }
 
-func (s *TransactionService) ProcessWithdrawal(accountID string, amount float64, description string) (*models.Transaction, error) {
+func (s *TransactionService) ProcessWithdrawal(accountID string, amount float64, description string, metadata models.Metadata, tags models.Tags, actor AuditActor) (*models.Transaction, error) {
 	if amount <= 0 {
 		return nil, errors.New("amount must be greater than zero")
 	}
@@ -93,10 +124,6 @@ func (s *TransactionService) ProcessWithdrawal(accountID string, amount float64,
 		return nil, err
 	}
 
-	if account.Balance < amount {
-		return nil, errors.New("insufficient balance")
-	}
-
 	tx := s.db.Begin()
 	defer func() {
 		if r := recover(); r != nil {
@@ -104,12 +131,38 @@ func (s *TransactionService) ProcessWithdrawal(accountID string, amount float64,
 		}
 	}()
 
+	// Captures, transfers and withdrawals all lock the account row, so the
+	// available balance checked here cannot change before the write.
+	account, err = lockAccount(tx, account.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if account.IsFrozen {
+		tx.Rollback()
+		return nil, ErrAccountFrozen
+	}
+
+	held, err := heldAmount(tx, account.ID)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if account.Balance-held < amount {
+		tx.Rollback()
+		return nil, ErrInsufficientBalance
+	}
+
 	transaction := &models.Transaction{
 		Type:          models.TransactionTypeWithdraw,
 		Amount:        amount,
 		Currency:      account.Currency,
 		Status:        models.TransactionStatusPending,
 		Description:   description,
+		Metadata:      metadata,
+		Tags:          tags,
 		AccountID:     account.ID,
 		BalanceBefore: account.Balance,
 		BalanceAfter:  account.Balance - amount,
@@ -130,43 +183,70 @@ func (s *TransactionService) ProcessWithdrawal(accountID string, amount float64,
 		tx.Rollback()
 		return nil, fmt.Errorf("failed to update transaction status: %v", err)
 	}
+	transaction.Status = models.TransactionStatusCompleted
+
+	if err := recordAudit(tx, actor, models.AuditActionWithdrawal, models.AuditTargetTransaction, transaction.ID.String(), nil, auditTransaction(transaction)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
 
 	if err := tx.Commit().Error; err != nil {
 		return nil, fmt.Errorf("failed to commit transaction: %v", err)
 	}
 
+	categorizeCommitted(s.db, transaction)
+
 	return transaction, nil
 }
 
-func (s *TransactionService) ProcessTransfer(fromAccountID, toAccountID string, amount float64, description string) (*models.Transaction, error) {
-	if amount <= 0 {
-		return nil, errors.New("amount must be greater than zero")
+func (s *TransactionService) ProcessTransfer(fromAccountID, toAccountID string, amount float64, description string, metadata models.Metadata, tags models.Tags, actor AuditActor) (*models.Transaction, error) {
+	fromAccount, toAccount, err := s.ValidateTransfer(fromAccountID, toAccountID, amount)
+	if err != nil {
+		return nil, err
 	}
 
-	fromAccount, err := s.GetAccountByID(fromAccountID)
+	var transaction *models.Transaction
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		transaction, err = s.transfer(tx, fromAccount.ID, toAccount.ID, amount, description, metadata, tags, actor)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	toAccount, err := s.GetAccountByID(toAccountID)
+	categorizeCommitted(s.db, transaction)
+
+	return transaction, nil
+}
+
+// transfer moves funds between two accounts within tx, so that callers can
+// commit other records together with the transfer. Both accounts are locked
+// and the available balance is checked again under the lock.
+func (s *TransactionService) transfer(tx *gorm.DB, fromAccountID, toAccountID uuid.UUID, amount float64, description string, metadata models.Metadata, tags models.Tags, actor AuditActor) (*models.Transaction, error) {
+	if amount <= 0 {
+		return nil, errors.New("amount must be greater than zero")
+	}
+	if fromAccountID == toAccountID {
+		return nil, ErrSameAccountTransfer
+	}
+
+	fromAccount, toAccount, err := lockTransferAccounts(tx, fromAccountID, toAccountID)
 	if err != nil {
 		return nil, err
 	}
 
-	if fromAccount.ID == toAccount.ID {
-		return nil, errors.New("cannot transfer to the same account")
+	if fromAccount.IsFrozen {
+		return nil, ErrAccountFrozen
 	}
 
-	if fromAccount.Balance < amount {
-		return nil, errors.New("insufficient balance")
+	held, err := heldAmount(tx, fromAccount.ID)
+	if err != nil {
+		return nil, err
+	}
+	if fromAccount.Balance-held < amount {
+		return nil, ErrInsufficientBalance
 	}
-
-	tx := s.db.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
 
 	transaction := &models.Transaction{
 		Type:          models.TransactionTypeTransfer,
@@ -174,6 +254,8 @@ func (s *TransactionService) ProcessTransfer(fromAccountID, toAccountID string,
 		Currency:      fromAccount.Currency,
 		Status:        models.TransactionStatusPending,
 		Description:   description,
+		Metadata:      metadata,
+		Tags:          tags,
 		AccountID:     fromAccount.ID,
 		ToAccountID:   &toAccount.ID,
 		BalanceBefore: fromAccount.Balance,
@@ -181,73 +263,189 @@ func (s *TransactionService) ProcessTransfer(fromAccountID, toAccountID string,
 	}
 
 	if err := tx.Create(transaction).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to create transaction: %v", err)
 	}
 
 	newFromBalance := fromAccount.Balance - amount
 	if err := tx.Model(&models.Account{}).Where("id = ?", fromAccount.ID).Update("balance", newFromBalance).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to update source account balance: %v", err)
 	}
 
 	newToBalance := toAccount.Balance + amount
 	if err := tx.Model(&models.Account{}).Where("id = ?", toAccount.ID).Update("balance", newToBalance).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to update destination account balance: %v", err)
 	}
 
 	if err := tx.Model(transaction).Update("status", models.TransactionStatusCompleted).Error; err != nil {
-		tx.Rollback()
 		return nil, fmt.Errorf("failed to update transaction status: %v", err)
 	}
+	transaction.Status = models.TransactionStatusCompleted
 
-	if err := tx.Commit().Error; err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %v", err)
+	if err := recordAudit(tx, actor, models.AuditActionTransfer, models.AuditTargetTransaction, transaction.ID.String(), nil, auditTransaction(transaction)); err != nil {
+		return nil, err
 	}
 
 	return transaction, nil
 }
 
-func (s *TransactionService) GetTransactionByID(transactionID string) (*models.Transaction, error) {
-	var transaction models.Transaction
-	
-	id, err := uuid.Parse(transactionID)
+// ValidateTransfer checks that a transfer could be executed right now and
+// returns both accounts.
+func (s *TransactionService) ValidateTransfer(fromAccountID, toAccountID string, amount float64) (*models.Account, *models.Account, error) {
+	if amount <= 0 {
+		return nil, nil, errors.New("amount must be greater than zero")
+	}
+
+	fromAccount, err := s.GetAccountByID(fromAccountID)
 	if err != nil {
-		return nil, errors.New("invalid transaction ID")
+		return nil, nil, err
 	}
 
-	if err := s.db.Preload("Account").Preload("ToAccount").Where("id = ?", id).First(&transaction).Error; err != nil {
+	toAccount, err := s.GetAccountByID(toAccountID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if fromAccount.ID == toAccount.ID {
+		return nil, nil, ErrSameAccountTransfer
+	}
+
+	if fromAccount.IsFrozen {
+		return nil, nil, ErrAccountFrozen
+	}
+
+	availableBalance, err := s.holdService.GetAvailableBalance(fromAccount)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if availableBalance < amount {
+		return nil, nil, ErrInsufficientBalance
+	}
+
+	return fromAccount, toAccount, nil
+}
+
+// executePendingTransfer moves the funds for a transfer that was held back
+// for confirmation. Balances are re-read under lock because they may have
+// changed since the transfer was requested.
+func (s *TransactionService) executePendingTransfer(tx *gorm.DB, transaction *models.Transaction) error {
+	if transaction.ToAccountID == nil {
+		return errors.New("transfer has no destination account")
+	}
+
+	fromAccount, toAccount, err := lockTransferAccounts(tx, transaction.AccountID, *transaction.ToAccountID)
+	if err != nil {
+		return err
+	}
+
+	if fromAccount.IsFrozen {
+		return ErrAccountFrozen
+	}
+
+	held, err := heldAmount(tx, fromAccount.ID)
+	if err != nil {
+		return err
+	}
+	if fromAccount.Balance-held < transaction.Amount {
+		return ErrInsufficientBalance
+	}
+
+	if err := tx.Model(&models.Account{}).Where("id = ?", fromAccount.ID).Update("balance", fromAccount.Balance-transaction.Amount).Error; err != nil {
+		return fmt.Errorf("failed to update source account balance: %v", err)
+	}
+
+	if err := tx.Model(&models.Account{}).Where("id = ?", toAccount.ID).Update("balance", toAccount.Balance+transaction.Amount).Error; err != nil {
+		return fmt.Errorf("failed to update destination account balance: %v", err)
+	}
+
+	valueDate, err := models.CurrentValueDate(tx)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(transaction).Updates(completePendingTransfer(transaction, fromAccount.Balance, time.Now(), valueDate)).Error; err != nil {
+		return fmt.Errorf("failed to update transaction status: %v", err)
+	}
+
+	return nil
+}
+
+// completePendingTransfer books a verified transfer at the time it executes.
+// The row is re-dated along with its balances so that it sorts after anything
+// posted while it waited and lands in a business day that is still open.
+func completePendingTransfer(transaction *models.Transaction, fromBalance float64, now, valueDate time.Time) map[string]interface{} {
+	transaction.BalanceBefore = fromBalance
+	transaction.BalanceAfter = fromBalance - transaction.Amount
+	transaction.Status = models.TransactionStatusCompleted
+	transaction.CreatedAt = now
+	transaction.ValueDate = valueDate
+
+	return map[string]interface{}{
+		"balance_before": transaction.BalanceBefore,
+		"balance_after":  transaction.BalanceAfter,
+		"status":         transaction.Status,
+		"created_at":     transaction.CreatedAt,
+		"value_date":     transaction.ValueDate,
+	}
+}
+
+// lockAccount re-reads an active account under a row lock.
+func lockAccount(tx *gorm.DB, accountID uuid.UUID) (*models.Account, error) {
+	var account models.Account
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id = ? AND is_active = ?", accountID, true).
+		First(&account).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("transaction not found")
+			return nil, errors.New("account not found")
 		}
-		return nil, fmt.Errorf("failed to find transaction: %v", err)
+		return nil, fmt.Errorf("failed to lock account: %v", err)
+	}
+	return &account, nil
+}
+
+// lockTransferAccounts locks both sides of a transfer in ID order, so that
+// opposing transfers between the same accounts cannot deadlock.
+func lockTransferAccounts(tx *gorm.DB, fromAccountID, toAccountID uuid.UUID) (*models.Account, *models.Account, error) {
+	var accounts []models.Account
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ? AND is_active = ?", []uuid.UUID{fromAccountID, toAccountID}, true).
+		Order("id").
+		Find(&accounts).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to lock accounts: %v", err)
+	}
+
+	var fromAccount, toAccount *models.Account
+	for i := range accounts {
+		switch accounts[i].ID {
+		case fromAccountID:
+			fromAccount = &accounts[i]
+		case toAccountID:
+			toAccount = &accounts[i]
+		}
+	}
+	if fromAccount == nil || toAccount == nil {
+		return nil, nil, errors.New("account not found")
 	}
 
-	return &transaction, nil
+	return fromAccount, toAccount, nil
 }
 
-func (s *TransactionService) GetTransactionsByAccountID(accountID string, limit, offset int) ([]models.Transaction, error) {
-	var transactions []models.Transaction
+func (s *TransactionService) GetTransactionByID(transactionID string) (*models.Transaction, error) {
+	var transaction models.Transaction
 	
-	accountUUID, err := uuid.Parse(accountID)
+	id, err := uuid.Parse(transactionID)
 	if err != nil {
-		return nil, errors.New("invalid account ID")
-	}
-
-	if limit <= 0 {
-		limit = 50
+		return nil, errors.New("invalid transaction ID")
 	}
 
-	if err := s.db.Preload("Account").Preload("ToAccount").
-		Where("account_id = ? OR to_account_id = ?", accountUUID, accountUUID).
-		Order("created_at DESC").
-		Limit(limit).Offset(offset).
-		Find(&transactions).Error; err != nil {
-		return nil, fmt.Errorf("failed to find transactions: %v", err)
+	if err := s.db.Preload("Account").Preload("ToAccount").Where("id = ?", id).First(&transaction).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTransactionNotFound
+		}
+		return nil, fmt.Errorf("failed to find transaction: %v", err)
 	}
 
-	return transactions, nil
+	return &transaction, nil
 }
 
 func (s *TransactionService) GetAccountByID(accountID string) (*models.Account, error) {
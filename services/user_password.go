@@ -0,0 +1,148 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrPasswordReused = errors.New("password was used recently, choose a different one")
+
+func (s *UserService) PasswordPolicy() PasswordPolicy {
+	return s.passwordPolicy
+}
+
+// ChangePassword replaces the password after checking the current one and
+// signs the user out of every session except the one making the request.
+// It returns the number of sessions that were revoked.
+func (s *UserService) ChangePassword(userID, currentPassword, newPassword, currentSessionID string, actor AuditActor) (int, error) {
+	var user *models.User
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		user, err = s.findActiveUser(tx.Clauses(clause.Locking{Strength: "UPDATE"}), userID)
+		if err != nil {
+			return err
+		}
+
+		if !utils.CheckPasswordHash(currentPassword, user.Password) {
+			return ErrInvalidPassword
+		}
+
+		if err := s.passwordPolicy.Validate(newPassword); err != nil {
+			return err
+		}
+
+		if err := s.setPassword(tx, user, newPassword, nil); err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor, models.AuditActionPasswordChange, models.AuditTargetUser, user.ID.String(), nil, nil)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	revoked, err := s.tokenService.LogoutOthers(userID, currentSessionID, models.TokenRevocationPasswordChange)
+	if err != nil {
+		return 0, err
+	}
+
+	// Best effort: the change has already been made.
+	_ = s.notifyPasswordChanged(user)
+
+	return revoked, nil
+}
+
+// setPassword stores a new password together with any extra column updates
+// and keeps the previous hash in the history. A password matching the
+// current one or any remembered one is rejected with ErrPasswordReused.
+func (s *UserService) setPassword(tx *gorm.DB, user *models.User, password string, updates map[string]interface{}) error {
+	if utils.CheckPasswordHash(password, user.Password) {
+		return ErrPasswordReused
+	}
+
+	var history []models.PasswordHistory
+	if s.passwordPolicy.HistorySize > 1 {
+		if err := tx.Where("user_id = ?", user.ID).
+			Order("created_at DESC").
+			Limit(s.passwordPolicy.HistorySize - 1).
+			Find(&history).Error; err != nil {
+			return fmt.Errorf("failed to load password history: %v", err)
+		}
+	}
+	for _, previous := range history {
+		if utils.CheckPasswordHash(password, previous.PasswordHash) {
+			return ErrPasswordReused
+		}
+	}
+
+	hashedPassword, err := utils.HashPassword(password)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %v", err)
+	}
+
+	if err := tx.Create(&models.PasswordHistory{UserID: user.ID, PasswordHash: user.Password}).Error; err != nil {
+		return fmt.Errorf("failed to record password history: %v", err)
+	}
+
+	// Only HistorySize-1 entries are compared above, the current password
+	// being the remaining one, so older entries can go.
+	if err := tx.Where("user_id = ? AND id NOT IN (?)", user.ID,
+		tx.Model(&models.PasswordHistory{}).Select("id").Where("user_id = ?", user.ID).Order("created_at DESC").Limit(maxInt(s.passwordPolicy.HistorySize-1, 1)),
+	).Delete(&models.PasswordHistory{}).Error; err != nil {
+		return fmt.Errorf("failed to prune password history: %v", err)
+	}
+
+	if updates == nil {
+		updates = map[string]interface{}{}
+	}
+	updates["password"] = hashedPassword
+	updates["password_changed_at"] = time.Now()
+
+	if err := tx.Model(user).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+
+	return nil
+}
+
+func (s *UserService) findActiveUser(tx *gorm.DB, userID string) (*models.User, error) {
+	var user models.User
+
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user ID")
+	}
+
+	if err := tx.Where("id = ? AND is_active = ?", id, true).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, fmt.Errorf("failed to find user: %v", err)
+	}
+
+	return &user, nil
+}
+
+func (s *UserService) notifyPasswordChanged(user *models.User) error {
+	return s.notifier.Send(EmailMessage{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe password for your account was changed on %s and your other sessions were signed out.\n\nIf you did not make this change, reset your password immediately and contact support.\n",
+			user.FirstName, time.Now().Format(time.RFC1123)),
+	})
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
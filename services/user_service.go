@@ -3,25 +3,48 @@ package services
 import (
 	"errors"
 	"fmt"
+	"log"
+	"sync"
 
 	"github.com/azainwork/core-banking-api/models"
 	"github.com/azainwork/core-banking-api/utils"
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
+var (
+	dummyPasswordHash     string
+	dummyPasswordHashOnce sync.Once
 )
 
 type UserService struct {
-	db *gorm.DB
+	db             *gorm.DB
+	notifier       Notifier
+	tokenService   *TokenService
+	loginThrottle  *LoginThrottleService
+	passwordPolicy PasswordPolicy
 }
 
 func NewUserService(db *gorm.DB) *UserService {
-	return &UserService{db: db}
+	return &UserService{
+		db:             db,
+		notifier:       NewNotifier(),
+		tokenService:   NewTokenService(db),
+		loginThrottle:  NewLoginThrottleService(db),
+		passwordPolicy: PasswordPolicyFromEnv(),
+	}
 }
 
-func (s *UserService) RegisterUser(user *models.User) error {
-	var existingUser models.User
-	if err := s.db.Where("email = ?", user.Email).First(&existingUser).Error; err == nil {
-		return errors.New("user with this email already exists")
+// RegisterUser creates the user and emails a verification link. When the
+// address is already registered nothing is created and its owner is emailed
+// instead, so the caller sees the same outcome either way and cannot probe
+// which emails have accounts.
+func (s *UserService) RegisterUser(user *models.User, actor AuditActor) error {
+	if err := s.passwordPolicy.Validate(user.Password); err != nil {
+		return err
 	}
 
 	hashedPassword, err := utils.HashPassword(user.Password)
@@ -30,33 +53,94 @@ func (s *UserService) RegisterUser(user *models.User) error {
 	}
 	user.Password = hashedPassword
 
-	if err := s.db.Create(user).Error; err != nil {
-		return fmt.Errorf("failed to create user: %v", err)
+	emailIndex, err := models.EmailIndex(user.Email)
+	if err != nil {
+		return fmt.Errorf("failed to index email: %v", err)
+	}
+
+	var existingUser models.User
+	err = s.db.Where("email_index = ?", emailIndex).First(&existingUser).Error
+	switch {
+	case err == nil:
+		if err := s.sendExistingAccountNotice(&existingUser); err != nil {
+			log.Printf("failed to send existing account notice: %v", err)
+		}
+		return nil
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("failed to find user: %v", err)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return fmt.Errorf("failed to create user: %v", err)
+		}
+
+		return recordAudit(tx, actor.orUser(user.ID), models.AuditActionUserRegister, models.AuditTargetUser, user.ID.String(), nil, auditUser(user))
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.SendEmailVerification(user); err != nil {
+		log.Printf("failed to send email verification: %v", err)
 	}
 
 	return nil
 }
 
-func (s *UserService) LoginUser(email, password string) (string, *models.User, error) {
+// LoginUser verifies credentials behind the login throttle. Unknown emails
+// still run a bcrypt comparison and count as failures so that neither the
+// response nor its timing reveals whether an account exists.
+func (s *UserService) LoginUser(email, password string, client ClientInfo) (*models.User, error) {
+	if err := s.loginThrottle.Check(email, client.IPAddress); err != nil {
+		return nil, err
+	}
+
+	emailIndex, err := models.EmailIndex(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index email: %v", err)
+	}
+
 	var user models.User
-	
-	if err := s.db.Where("email = ? AND is_active = ?", email, true).First(&user).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return "", nil, errors.New("invalid email or password")
+	found := true
+	if err := s.db.Where("email_index = ? AND is_active = ?", emailIndex, true).First(&user).Error; err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("failed to find user: %v", err)
 		}
-		return "", nil, fmt.Errorf("failed to find user: %v", err)
+		found = false
+	}
+
+	if !found {
+		utils.CheckPasswordHash(password, getDummyPasswordHash())
+		if err := s.loginThrottle.RecordFailure(email, client.IPAddress, nil); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
 	}
 
 	if !utils.CheckPasswordHash(password, user.Password) {
-		return "", nil, errors.New("invalid email or password")
+		if err := s.loginThrottle.RecordFailure(email, client.IPAddress, &user.ID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
 	}
 
-	token, err := utils.GenerateJWTToken(user.ID.String(), user.Email)
-	if err != nil {
-		return "", nil, fmt.Errorf("failed to generate token: %v", err)
+	// With MFA the login is not complete yet, so the throttle is only reset
+	// once VerifyChallenge accepts the second factor.
+	if !user.MFAEnabled {
+		if err := s.loginThrottle.RecordSuccess(email); err != nil {
+			return nil, err
+		}
 	}
 
-	return token, &user, nil
+	return &user, nil
+}
+
+func getDummyPasswordHash() string {
+	dummyPasswordHashOnce.Do(func() {
+		dummyPasswordHash, _ = utils.HashPassword("dummy-password-for-timing")
+	})
+	return dummyPasswordHash
 }
 
 func (s *UserService) GetUserByID(userID string) (*models.User, error) {
@@ -79,8 +163,13 @@ func (s *UserService) GetUserByID(userID string) (*models.User, error) {
 
 func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
 	var user models.User
-	
-	if err := s.db.Where("email = ? AND is_active = ?", email, true).First(&user).Error; err != nil {
+
+	emailIndex, err := models.EmailIndex(email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to index email: %v", err)
+	}
+
+	if err := s.db.Where("email_index = ? AND is_active = ?", emailIndex, true).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("user not found")
 		}
@@ -90,14 +179,82 @@ func (s *UserService) GetUserByEmail(email string) (*models.User, error) {
 	return &user, nil
 }
 
-func (s *UserService) UpdateUser(userID string, updates map[string]interface{}) (*models.User, error) {
+// UserUpdate holds the profile fields to change; nil fields are kept.
+type UserUpdate struct {
+	Email     *string
+	FirstName *string
+	LastName  *string
+	Phone     *string
+}
+
+// UpdateUser saves profile changes through the user model, so the PII
+// columns are encrypted. A new email only takes effect once confirmed through
+// the link sent to it. When the address is already registered its owner is
+// emailed instead, so the caller sees the same outcome either way and cannot
+// probe which emails have accounts.
+func (s *UserService) UpdateUser(userID string, update UserUpdate, actor AuditActor) (*models.User, error) {
 	id, err := uuid.Parse(userID)
 	if err != nil {
 		return nil, errors.New("invalid user ID")
 	}
 
-	if err := s.db.Model(&models.User{}).Where("id = ?", id).Updates(updates).Error; err != nil {
-		return nil, fmt.Errorf("failed to update user: %v", err)
+	var user models.User
+	var owner *models.User
+	newEmail := ""
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", id).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("user not found")
+			}
+			return fmt.Errorf("failed to find user: %v", err)
+		}
+		before := auditUser(&user)
+
+		if update.Email != nil && *update.Email != user.Email {
+			emailIndex, err := models.EmailIndex(*update.Email)
+			if err != nil {
+				return fmt.Errorf("failed to index email: %v", err)
+			}
+			var existing models.User
+			err = tx.Where("email_index = ? AND id <> ?", emailIndex, id).First(&existing).Error
+			switch {
+			case err == nil:
+				owner = &existing
+			case errors.Is(err, gorm.ErrRecordNotFound):
+				newEmail = *update.Email
+			default:
+				return fmt.Errorf("failed to check email: %v", err)
+			}
+		}
+		if update.FirstName != nil {
+			user.FirstName = *update.FirstName
+		}
+		if update.LastName != nil {
+			user.LastName = *update.LastName
+		}
+		if update.Phone != nil {
+			user.Phone = *update.Phone
+		}
+
+		if err := tx.Select("first_name", "last_name", "phone").Updates(&user).Error; err != nil {
+			return fmt.Errorf("failed to update user: %v", err)
+		}
+
+		return recordAudit(tx, actor, models.AuditActionUserUpdate, models.AuditTargetUser, id.String(), before, auditUser(&user))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if owner != nil {
+		if err := s.sendEmailTakenNotice(owner); err != nil {
+			log.Printf("failed to send email taken notice: %v", err)
+		}
+	}
+	if newEmail != "" {
+		if err := s.sendEmailChangeLink(&user, newEmail); err != nil {
+			log.Printf("failed to send email change link: %v", err)
+		}
 	}
 
 	return s.GetUserByID(userID)
@@ -0,0 +1,326 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/azainwork/core-banking-api/models"
+	"github.com/azainwork/core-banking-api/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrInvalidUserToken     = errors.New("invalid or expired token")
+	ErrEmailAlreadyVerified = errors.New("email is already verified")
+)
+
+func passwordResetTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("PASSWORD_RESET_TTL"))
+	if err != nil || ttl <= 0 {
+		return time.Hour
+	}
+	return ttl
+}
+
+func emailVerificationTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("EMAIL_VERIFICATION_TTL"))
+	if err != nil || ttl <= 0 {
+		return 24 * time.Hour
+	}
+	return ttl
+}
+
+func appURL() string {
+	url := os.Getenv("APP_URL")
+	if url == "" {
+		url = "http://localhost:3000"
+	}
+	return strings.TrimRight(url, "/")
+}
+
+// RequestPasswordReset emails a reset link when the address belongs to an
+// active user. Unknown addresses are ignored so callers cannot probe which
+// emails are registered.
+func (s *UserService) RequestPasswordReset(email string) error {
+	var user models.User
+
+	emailIndex, err := models.EmailIndex(email)
+	if err != nil {
+		return fmt.Errorf("failed to index email: %v", err)
+	}
+
+	if err := s.db.Where("email_index = ? AND is_active = ?", emailIndex, true).First(&user).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		return fmt.Errorf("failed to find user: %v", err)
+	}
+
+	token, err := s.createUserToken(&user, models.UserTokenPasswordReset, user.Email, passwordResetTTL())
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Send(EmailMessage{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nUse the link below to choose a new password. It expires in %s and can only be used once.\n\n%s/reset-password?token=%s\n\nIf you did not ask to reset your password you can ignore this email.\n",
+			user.FirstName, passwordResetTTL(), appURL(), token),
+	})
+}
+
+// ResetPassword sets a new password and signs the user out everywhere. Since
+// the token arrived by email it also proves ownership of the address.
+func (s *UserService) ResetPassword(token, password string, actor AuditActor) error {
+	if err := s.passwordPolicy.Validate(password); err != nil {
+		return err
+	}
+
+	var user models.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		record, err := s.consumeUserToken(tx, token, models.UserTokenPasswordReset)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ? AND is_active = ?", record.UserID, true).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidUserToken
+			}
+			return fmt.Errorf("failed to find user: %v", err)
+		}
+
+		updates := map[string]interface{}{}
+		if user.EmailVerifiedAt == nil && strings.EqualFold(record.Email, user.Email) {
+			updates["email_verified_at"] = time.Now()
+		}
+
+		if err := s.setPassword(tx, &user, password, updates); err != nil {
+			return err
+		}
+
+		return recordAudit(tx, actor.orUser(user.ID), models.AuditActionPasswordReset, models.AuditTargetUser, user.ID.String(), nil, nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = s.tokenService.LogoutAll(user.ID.String())
+	return err
+}
+
+func (s *UserService) SendEmailVerification(user *models.User) error {
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.createUserToken(user, models.UserTokenEmailVerification, user.Email, emailVerificationTTL())
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Send(EmailMessage{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm that this is your email address. The link expires in %s.\n\n%s/verify-email?token=%s\n",
+			user.FirstName, emailVerificationTTL(), appURL(), token),
+	})
+}
+
+// sendExistingAccountNotice tells the owner of an address that someone tried
+// to register it again.
+func (s *UserService) sendExistingAccountNotice(user *models.User) error {
+	return s.notifier.Send(EmailMessage{
+		To:      user.Email,
+		Subject: "You already have an account",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone tried to create an account with this email address, but it is already registered. If that was you, sign in or reset your password at:\n\n%s/forgot-password\n\nIf it was not you, you can ignore this email; your account has not been changed.\n",
+			user.FirstName, appURL()),
+	})
+}
+
+// sendEmailChangeLink asks the new address to confirm an email change. The
+// user keeps the current address until the link is followed.
+func (s *UserService) sendEmailChangeLink(user *models.User, email string) error {
+	token, err := s.createUserToken(user, models.UserTokenEmailChange, email, emailVerificationTTL())
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Send(EmailMessage{
+		To:      email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nPlease confirm that you want to use this email address for your account. The link expires in %s.\n\n%s/confirm-email-change?token=%s\n\nIf you did not ask for this change you can ignore this email.\n",
+			user.FirstName, emailVerificationTTL(), appURL(), token),
+	})
+}
+
+// sendEmailTakenNotice tells the owner of an address that someone tried to
+// move another account to it.
+func (s *UserService) sendEmailTakenNotice(owner *models.User) error {
+	return s.notifier.Send(EmailMessage{
+		To:      owner.Email,
+		Subject: "Your email address is already in use",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone tried to change the email address of another account to this one, which is already registered to you. Nothing has been changed.\n\nIf you are worried about your account, reset your password at:\n\n%s/forgot-password\n",
+			owner.FirstName, appURL()),
+	})
+}
+
+// ConfirmEmailChange moves the user to the address an email change link was
+// sent to. Following the link proves ownership, so the address is verified.
+func (s *UserService) ConfirmEmailChange(token string, actor AuditActor) (*models.User, error) {
+	var user models.User
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		record, err := s.consumeUserToken(tx, token, models.UserTokenEmailChange)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", record.UserID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidUserToken
+			}
+			return fmt.Errorf("failed to find user: %v", err)
+		}
+
+		emailIndex, err := models.EmailIndex(record.Email)
+		if err != nil {
+			return fmt.Errorf("failed to index email: %v", err)
+		}
+
+		// The address may have been registered since the link was sent.
+		var count int64
+		if err := tx.Model(&models.User{}).Where("email_index = ? AND id <> ?", emailIndex, user.ID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check email: %v", err)
+		}
+		if count > 0 {
+			return ErrInvalidUserToken
+		}
+
+		before := auditUser(&user)
+		now := time.Now()
+		user.Email = record.Email
+		user.EmailIndex = emailIndex
+		user.EmailVerifiedAt = &now
+		if err := tx.Select("email", "email_index", "email_verified_at").Updates(&user).Error; err != nil {
+			return fmt.Errorf("failed to update email: %v", err)
+		}
+
+		return recordAudit(tx, actor.orUser(user.ID), models.AuditActionUserUpdate, models.AuditTargetUser, user.ID.String(), before, auditUser(&user))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *UserService) VerifyEmail(token string, actor AuditActor) (*models.User, error) {
+	var user models.User
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		record, err := s.consumeUserToken(tx, token, models.UserTokenEmailVerification)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("id = ?", record.UserID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrInvalidUserToken
+			}
+			return fmt.Errorf("failed to find user: %v", err)
+		}
+
+		if !strings.EqualFold(record.Email, user.Email) {
+			return ErrInvalidUserToken
+		}
+		if user.IsEmailVerified() {
+			return nil
+		}
+
+		before := auditUser(&user)
+		now := time.Now()
+		if err := tx.Model(&user).Update("email_verified_at", now).Error; err != nil {
+			return fmt.Errorf("failed to verify email: %v", err)
+		}
+		user.EmailVerifiedAt = &now
+
+		return recordAudit(tx, actor.orUser(user.ID), models.AuditActionEmailVerify, models.AuditTargetUser, user.ID.String(), before, auditUser(&user))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func (s *UserService) PurgeExpiredTokens(now time.Time) (int64, error) {
+	result := s.db.Where("expires_at < ?", now).Delete(&models.UserToken{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to purge user tokens: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// createUserToken issues a new token for the link sent to email and
+// invalidates any earlier unused token for the same purpose, so only the most
+// recent email link works.
+func (s *UserService) createUserToken(user *models.User, purpose models.UserTokenPurpose, email string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.UserToken{}).
+			Where("user_id = ? AND purpose = ? AND used_at IS NULL", user.ID, purpose).
+			Update("used_at", now).Error; err != nil {
+			return fmt.Errorf("failed to invalidate previous tokens: %v", err)
+		}
+
+		if err := tx.Create(&models.UserToken{
+			UserID:    user.ID,
+			Purpose:   purpose,
+			TokenHash: utils.HashToken(token),
+			Email:     email,
+			ExpiresAt: now.Add(ttl),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to store token: %v", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *UserService) consumeUserToken(tx *gorm.DB, token string, purpose models.UserTokenPurpose) (*models.UserToken, error) {
+	var record models.UserToken
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("token_hash = ? AND purpose = ?", utils.HashToken(token), purpose).
+		First(&record).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidUserToken
+		}
+		return nil, fmt.Errorf("failed to find token: %v", err)
+	}
+
+	now := time.Now()
+	if record.UsedAt != nil || now.After(record.ExpiresAt) {
+		return nil, ErrInvalidUserToken
+	}
+
+	if err := tx.Model(&record).Update("used_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to use token: %v", err)
+	}
+
+	return &record, nil
+}
@@ -2,7 +2,10 @@ package utils
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
+	"math/big"
 	"os"
 	"time"
 
@@ -20,22 +23,65 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
-func GenerateJWTToken(userID, email string) (string, error) {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "your-secret-key" 
+func AccessTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("ACCESS_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return 15 * time.Minute
 	}
+	return ttl
+}
+
+func RefreshTokenTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_TTL"))
+	if err != nil || ttl <= 0 {
+		return 30 * 24 * time.Hour
+	}
+	return ttl
+}
+
+func MFAChallengeTTL() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("MFA_CHALLENGE_TTL"))
+	if err != nil || ttl <= 0 {
+		return 5 * time.Minute
+	}
+	return ttl
+}
 
+type TokenClaims struct {
+	UserID        string
+	Email         string
+	Role          string
+	Permissions   []string
+	JTI           string
+	SessionID     string
+	MFA           bool
+	EmailVerified bool
+	ExpiresAt     time.Time
+}
+
+func GenerateJWTToken(tokenClaims TokenClaims) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id": userID,
-		"email":   email,
-		"exp":     time.Now().Add(time.Hour * 24 * 7).Unix(),
-		"iat":     time.Now().Unix(),
+		"user_id":     tokenClaims.UserID,
+		"email":       tokenClaims.Email,
+		"role":        tokenClaims.Role,
+		"permissions": tokenClaims.Permissions,
+		"jti":         tokenClaims.JTI,
+		"sid":         tokenClaims.SessionID,
+		"mfa":         tokenClaims.MFA,
+		"ev":          tokenClaims.EmailVerified,
+		"sub":         tokenClaims.UserID,
+		"iss":         JWTIssuer(),
+		"aud":         JWTAudience(),
+		"exp":         tokenClaims.ExpiresAt.Unix(),
+		"iat":         time.Now().Unix(),
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	keyring, err := currentJWTKeyring()
+	if err != nil {
+		return "", err
+	}
 
-	tokenString, err := token.SignedString([]byte(jwtSecret))
+	tokenString, err := keyring.sign(claims)
 	if err != nil {
 		return "", err
 	}
@@ -43,6 +89,77 @@ func GenerateJWTToken(userID, email string) (string, error) {
 	return tokenString, nil
 }
 
+func ParseJWTToken(tokenString string) (jwt.MapClaims, error) {
+	keyring, err := currentJWTKeyring()
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := jwt.Parse(tokenString, keyring.verificationKey,
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(JWTIssuer()),
+		jwt.WithAudience(JWTAudience()),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+
+	return claims, nil
+}
+
+func GenerateRefreshToken() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(bytes), nil
+}
+
+// GenerateRecoveryCode returns a code such as "k3v9q-7xm2p" that is easy to
+// read back from paper.
+func GenerateRecoveryCode() (string, error) {
+	const alphabet = "abcdefghjkmnpqrstuvwxyz023456789"
+
+	bytes := make([]byte, 10)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+
+	code := make([]byte, 0, 11)
+	for i, b := range bytes {
+		if i == 5 {
+			code = append(code, '-')
+		}
+		code = append(code, alphabet[b&31])
+	}
+	return string(code), nil
+}
+
+// GenerateNumericCode returns a uniformly random code of the given number of
+// decimal digits, keeping leading zeros.
+func GenerateNumericCode(digits int) (string, error) {
+	code := make([]byte, digits)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", err
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
 func GenerateAccountNumber() string {
 	bytes := make([]byte, 8)
 	rand.Read(bytes)
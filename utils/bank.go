@@ -0,0 +1,13 @@
+package utils
+
+import "os"
+
+const defaultBankCode = "COREBANK"
+
+func BankCode() string {
+	code := os.Getenv("BANK_CODE")
+	if code == "" {
+		return defaultBankCode
+	}
+	return code
+}
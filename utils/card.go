@@ -0,0 +1,107 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"os"
+	"strings"
+)
+
+const defaultCardBIN = "400000"
+
+// CardBIN returns the issuer BIN from CARD_BIN. It must be 6 to 8 digits so
+// that generated card numbers stay 16 digits with a valid Luhn check digit.
+func CardBIN() (string, error) {
+	bin := os.Getenv("CARD_BIN")
+	if bin == "" {
+		return defaultCardBIN, nil
+	}
+	if len(bin) < 6 || len(bin) > 8 {
+		return "", errors.New("card BIN must be 6 to 8 digits")
+	}
+	for _, r := range bin {
+		if r < '0' || r > '9' {
+			return "", errors.New("card BIN must be 6 to 8 digits")
+		}
+	}
+	return bin, nil
+}
+
+func GenerateCardNumber(bin string) string {
+	var sb strings.Builder
+	sb.WriteString(bin)
+	for sb.Len() < 15 {
+		sb.WriteString(randomDigits(1))
+	}
+
+	partial := sb.String()
+	return partial + string('0'+luhnCheckDigit(partial))
+}
+
+func IsLuhnValid(number string) bool {
+	if len(number) < 2 {
+		return false
+	}
+	for _, r := range number {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return luhnCheckDigit(number[:len(number)-1]) == number[len(number)-1]-'0'
+}
+
+func luhnCheckDigit(partial string) byte {
+	sum := 0
+	double := true
+	for i := len(partial) - 1; i >= 0; i-- {
+		digit := int(partial[i] - '0')
+		if double {
+			digit *= 2
+			if digit > 9 {
+				digit -= 9
+			}
+		}
+		sum += digit
+		double = !double
+	}
+	return byte((10 - sum%10) % 10)
+}
+
+func GenerateCVV() string {
+	return randomDigits(3)
+}
+
+func GenerateAuthorizationCode() string {
+	return randomDigits(6)
+}
+
+func GenerateCardToken() string {
+	bytes := make([]byte, 16)
+	rand.Read(bytes)
+
+	return "tok_" + hex.EncodeToString(bytes)
+}
+
+func HashCardData(value string) (string, error) {
+	secret := os.Getenv("CARD_SECRET_KEY")
+	if secret == "" {
+		return "", errors.New("card secret key is not configured")
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func randomDigits(n int) string {
+	digits := make([]byte, n)
+	for i := range digits {
+		d, _ := rand.Int(rand.Reader, big.NewInt(10))
+		digits[i] = byte('0' + d.Int64())
+	}
+	return string(digits)
+}
@@ -0,0 +1,40 @@
+package utils
+
+import "testing"
+
+func TestCardBIN(t *testing.T) {
+	tests := []struct {
+		env  string
+		want string
+		ok   bool
+	}{
+		{"", defaultCardBIN, true},
+		{"412345", "412345", true},
+		{"41234567", "41234567", true},
+		{"41234", "", false},
+		{"412345678", "", false},
+		{"41234a", "", false},
+		{" 412345", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Setenv("CARD_BIN", tt.env)
+
+		bin, err := CardBIN()
+		if tt.ok && err != nil {
+			t.Errorf("CARD_BIN=%q: %v", tt.env, err)
+			continue
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("CARD_BIN=%q: CardBIN = %q, want an error", tt.env, bin)
+			continue
+		}
+		if bin != tt.want {
+			t.Errorf("CARD_BIN=%q: CardBIN = %q, want %q", tt.env, bin, tt.want)
+			continue
+		}
+		if tt.ok && !IsLuhnValid(GenerateCardNumber(bin)) {
+			t.Errorf("CARD_BIN=%q: generated card number fails the Luhn check", tt.env)
+		}
+	}
+}
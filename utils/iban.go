@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const defaultBankCountry = "ID"
+
+var ibanPattern = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{1,30}$`)
+
+func BankCountry() string {
+	country := os.Getenv("BANK_COUNTRY")
+	if country == "" {
+		return defaultBankCountry
+	}
+	return strings.ToUpper(country)
+}
+
+func GenerateIBAN(accountNumber string) string {
+	bban := strings.ToUpper(BankCode() + accountNumber)
+	return BankCountry() + ibanCheckDigits(BankCountry(), bban) + bban
+}
+
+func NormalizeIBAN(iban string) string {
+	return strings.ToUpper(strings.ReplaceAll(iban, " ", ""))
+}
+
+func IsValidIBAN(iban string) bool {
+	iban = NormalizeIBAN(iban)
+	if !ibanPattern.MatchString(iban) {
+		return false
+	}
+	return ibanCheckDigits(iban[:2], iban[4:]) == iban[2:4]
+}
+
+func AccountNumberFromIBAN(iban string) (string, error) {
+	iban = NormalizeIBAN(iban)
+	if !IsValidIBAN(iban) {
+		return "", errors.New("invalid IBAN")
+	}
+
+	prefix := BankCountry()
+	bankCode := strings.ToUpper(BankCode())
+	if !strings.HasPrefix(iban, prefix) || !strings.HasPrefix(iban[4:], bankCode) {
+		return "", errors.New("IBAN does not belong to this bank")
+	}
+
+	return strings.ToLower(iban[4+len(bankCode):]), nil
+}
+
+func ibanCheckDigits(country, bban string) string {
+	var digits strings.Builder
+	for _, r := range bban + country + "00" {
+		if r >= 'A' && r <= 'Z' {
+			digits.WriteString(strconv.Itoa(int(r-'A') + 10))
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+
+	n, _ := new(big.Int).SetString(digits.String(), 10)
+	check := 98 - new(big.Int).Mod(n, big.NewInt(97)).Int64()
+	return fmt.Sprintf("%02d", check)
+}
@@ -0,0 +1,230 @@
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrNoJWTKeys       = errors.New("no JWT signing key configured: set JWT_KEYS_DIR and JWT_ACTIVE_KID")
+	ErrUnknownJWTKeyID = errors.New("unknown JWT key ID")
+)
+
+type jwtKey struct {
+	id         string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	publicKey  crypto.PublicKey
+}
+
+// JWTKeyring holds the key used to sign new tokens and every key that is
+// still accepted for verification, so that a retired key can keep verifying
+// tokens issued before a rotation until they expire.
+type JWTKeyring struct {
+	signing *jwtKey
+	keys    map[string]*jwtKey
+}
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+var (
+	jwtKeyring   *JWTKeyring
+	jwtKeyringMu sync.RWMutex
+)
+
+func JWTIssuer() string {
+	issuer := os.Getenv("JWT_ISSUER")
+	if issuer == "" {
+		issuer = "core-banking-api"
+	}
+	return issuer
+}
+
+func JWTAudience() string {
+	audience := os.Getenv("JWT_AUDIENCE")
+	if audience == "" {
+		audience = "core-banking-api"
+	}
+	return audience
+}
+
+// LoadJWTKeys reads every PEM file in JWT_KEYS_DIR. The file name without its
+// extension is the key ID. Private keys (RSA or Ed25519, PKCS#8 or PKCS#1)
+// can sign; public keys only verify. JWT_ACTIVE_KID selects the signing key.
+func LoadJWTKeys() (*JWTKeyring, error) {
+	dir := os.Getenv("JWT_KEYS_DIR")
+	activeKID := os.Getenv("JWT_ACTIVE_KID")
+	if dir == "" || activeKID == "" {
+		return nil, ErrNoJWTKeys
+	}
+
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list JWT keys: %v", err)
+	}
+
+	keyring := &JWTKeyring{keys: make(map[string]*jwtKey)}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read JWT key %s: %v", path, err)
+		}
+
+		key, err := parseJWTKey(strings.TrimSuffix(filepath.Base(path), ".pem"), data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse JWT key %s: %v", path, err)
+		}
+		keyring.keys[key.id] = key
+	}
+
+	signing, ok := keyring.keys[activeKID]
+	if !ok {
+		return nil, fmt.Errorf("active JWT key %q not found in %s", activeKID, dir)
+	}
+	if signing.privateKey == nil {
+		return nil, fmt.Errorf("active JWT key %q has no private key", activeKID)
+	}
+	keyring.signing = signing
+
+	return keyring, nil
+}
+
+func SetJWTKeyring(keyring *JWTKeyring) {
+	jwtKeyringMu.Lock()
+	defer jwtKeyringMu.Unlock()
+	jwtKeyring = keyring
+}
+
+func currentJWTKeyring() (*JWTKeyring, error) {
+	jwtKeyringMu.RLock()
+	defer jwtKeyringMu.RUnlock()
+	if jwtKeyring == nil {
+		return nil, ErrNoJWTKeys
+	}
+	return jwtKeyring, nil
+}
+
+func (k *JWTKeyring) sign(claims jwt.MapClaims) (string, error) {
+	token := jwt.NewWithClaims(k.signing.method, claims)
+	token.Header["kid"] = k.signing.id
+	return token.SignedString(k.signing.privateKey)
+}
+
+func (k *JWTKeyring) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, ErrUnknownJWTKeyID
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return key.publicKey, nil
+}
+
+// JWKS returns the public verification keys in JSON Web Key format, sorted by
+// key ID so that the document is stable between requests.
+func (k *JWTKeyring) JWKS() []JWK {
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	keys := make([]JWK, 0, len(ids))
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JWK{Kid: key.id, Use: "sig", Alg: key.method.Alg()}
+
+		switch publicKey := key.publicKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(publicKey)
+		}
+
+		keys = append(keys, jwk)
+	}
+
+	return keys
+}
+
+func CurrentJWKS() ([]JWK, error) {
+	keyring, err := currentJWTKeyring()
+	if err != nil {
+		return nil, err
+	}
+	return keyring.JWKS(), nil
+}
+
+func parseJWTKey(id string, data []byte) (*jwtKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var parsed interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key := &jwtKey{id: id}
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.method, key.privateKey, key.publicKey = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.method, key.publicKey = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.method, key.privateKey, key.publicKey = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.method, key.publicKey = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T, use RSA or Ed25519", parsed)
+	}
+
+	if rsaKey, ok := key.publicKey.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, errors.New("RSA keys must be at least 2048 bits")
+	}
+
+	return key, nil
+}
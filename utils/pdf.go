@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 40
+	pdfFontSize     = 8
+	pdfLineHeight   = 10
+	pdfLinesPerPage = (pdfPageHeight - 2*pdfMargin) / pdfLineHeight
+)
+
+func RenderTextPDF(lines []string) []byte {
+	var pages [][]string
+	for start := 0; start < len(lines); start += pdfLinesPerPage - 2 {
+		end := start + pdfLinesPerPage - 2
+		if end > len(lines) {
+			end = len(lines)
+		}
+		pages = append(pages, lines[start:end])
+	}
+	if len(pages) == 0 {
+		pages = append(pages, nil)
+	}
+
+	var buf bytes.Buffer
+	var offsets []int
+
+	writeObject := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n")
+
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 4+i*2)
+	}
+
+	writeObject("<< /Type /Catalog /Pages 2 0 R >>")
+	writeObject(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	writeObject("<< /Type /Font /Subtype /Type1 /BaseFont /Courier >>")
+
+	for i, pageLines := range pages {
+		var content strings.Builder
+		fmt.Fprintf(&content, "BT\n/F1 %d Tf\n%d TL\n%d %d Td\n", pdfFontSize, pdfLineHeight, pdfMargin, pdfPageHeight-pdfMargin)
+		for _, line := range pageLines {
+			fmt.Fprintf(&content, "(%s) '\n", escapePDFText(line))
+		}
+		fmt.Fprintf(&content, "() '\n(%s) '\nET", escapePDFText(fmt.Sprintf("Page %d of %d", i+1, len(pages))))
+
+		writeObject(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, 5+i*2))
+		writeObject(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()))
+	}
+
+	xrefOffset := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xrefOffset)
+
+	return buf.Bytes()
+}
+
+func escapePDFText(text string) string {
+	var sb strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case r < 32 || r > 126:
+			sb.WriteRune('?')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
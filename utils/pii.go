@@ -0,0 +1,294 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	piiPrefix        = "pii:v1:"
+	piiIndexKeyID    = "index"
+	piiDataKeyLength = 32
+)
+
+var (
+	ErrNoPIIKeys       = errors.New("no PII key provider configured: set PII_KEY_FILE")
+	ErrUnknownPIIKeyID = errors.New("unknown PII key ID")
+)
+
+// PIIKeyProvider wraps the per-value data keys used to encrypt PII. The local
+// provider keeps key encryption keys in a file; a KMS backed provider only
+// needs to implement the same wrap and unwrap calls.
+type PIIKeyProvider interface {
+	ActiveKeyID() string
+	WrapKey(keyID string, dataKey []byte) ([]byte, error)
+	UnwrapKey(keyID string, wrapped []byte) ([]byte, error)
+	BlindIndexKey() []byte
+}
+
+// LocalPIIKeyProvider holds AES-256 key encryption keys read from a file with
+// one "<key id> <base64 key>" pair per line. The reserved ID "index" holds the
+// blind index key, which must never change since indexes cannot be re-derived
+// without decrypting every row.
+type LocalPIIKeyProvider struct {
+	activeKeyID string
+	keys        map[string][]byte
+	indexKey    []byte
+}
+
+var (
+	piiKeyProvider   PIIKeyProvider
+	piiKeyProviderMu sync.RWMutex
+)
+
+// LoadPIIKeyProvider selects the provider from PII_KEY_PROVIDER. Only "local"
+// is built in. PII_ACTIVE_KID selects the key used for new ciphertexts and
+// defaults to the last key in the file.
+func LoadPIIKeyProvider() (PIIKeyProvider, error) {
+	switch provider := os.Getenv("PII_KEY_PROVIDER"); provider {
+	case "", "local":
+		path := os.Getenv("PII_KEY_FILE")
+		if path == "" {
+			return nil, ErrNoPIIKeys
+		}
+		return LoadLocalPIIKeys(path, os.Getenv("PII_ACTIVE_KID"))
+	default:
+		return nil, fmt.Errorf("unsupported PII key provider %q", provider)
+	}
+}
+
+func LoadLocalPIIKeys(path, activeKeyID string) (*LocalPIIKeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PII keys: %v", err)
+	}
+
+	provider := &LocalPIIKeyProvider{keys: make(map[string][]byte)}
+	var lastKeyID string
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) != 2 || strings.Contains(fields[0], ":") {
+			return nil, fmt.Errorf("invalid PII key on line %d", line)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != 32 {
+			return nil, fmt.Errorf("PII key on line %d must be 32 bytes of base64", line)
+		}
+
+		if fields[0] == piiIndexKeyID {
+			provider.indexKey = key
+			continue
+		}
+		provider.keys[fields[0]] = key
+		lastKeyID = fields[0]
+	}
+
+	if provider.indexKey == nil {
+		return nil, errors.New("PII key file has no index key")
+	}
+
+	if activeKeyID == "" {
+		activeKeyID = lastKeyID
+	}
+	if _, ok := provider.keys[activeKeyID]; !ok {
+		return nil, fmt.Errorf("active PII key %q not found in %s", activeKeyID, path)
+	}
+	provider.activeKeyID = activeKeyID
+
+	return provider, nil
+}
+
+func (p *LocalPIIKeyProvider) ActiveKeyID() string {
+	return p.activeKeyID
+}
+
+func (p *LocalPIIKeyProvider) WrapKey(keyID string, dataKey []byte) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, ErrUnknownPIIKeyID
+	}
+	return sealAESGCM(key, dataKey)
+}
+
+func (p *LocalPIIKeyProvider) UnwrapKey(keyID string, wrapped []byte) ([]byte, error) {
+	key, ok := p.keys[keyID]
+	if !ok {
+		return nil, ErrUnknownPIIKeyID
+	}
+	return openAESGCM(key, wrapped)
+}
+
+func (p *LocalPIIKeyProvider) BlindIndexKey() []byte {
+	return p.indexKey
+}
+
+func SetPIIKeyProvider(provider PIIKeyProvider) {
+	piiKeyProviderMu.Lock()
+	defer piiKeyProviderMu.Unlock()
+	piiKeyProvider = provider
+}
+
+func currentPIIKeyProvider() (PIIKeyProvider, error) {
+	piiKeyProviderMu.RLock()
+	defer piiKeyProviderMu.RUnlock()
+	if piiKeyProvider == nil {
+		return nil, ErrNoPIIKeys
+	}
+	return piiKeyProvider, nil
+}
+
+func ActivePIIKeyID() (string, error) {
+	provider, err := currentPIIKeyProvider()
+	if err != nil {
+		return "", err
+	}
+	return provider.ActiveKeyID(), nil
+}
+
+// PIICiphertextPrefix is the prefix of every value encrypted under the given
+// key, which lets re-encryption find rows still using an older key.
+func PIICiphertextPrefix(keyID string) string {
+	return piiPrefix + keyID + ":"
+}
+
+// EncryptPII seals a value with a fresh data key and stores the data key
+// wrapped by the active key next to it as
+// "pii:v1:<key id>:<wrapped key>:<ciphertext>".
+func EncryptPII(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+
+	provider, err := currentPIIKeyProvider()
+	if err != nil {
+		return "", err
+	}
+
+	dataKey := make([]byte, piiDataKeyLength)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", fmt.Errorf("failed to generate data key: %v", err)
+	}
+
+	keyID := provider.ActiveKeyID()
+	wrapped, err := provider.WrapKey(keyID, dataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to wrap data key: %v", err)
+	}
+
+	sealed, err := sealAESGCM(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", fmt.Errorf("failed to encrypt value: %v", err)
+	}
+
+	return PIICiphertextPrefix(keyID) +
+		base64.RawStdEncoding.EncodeToString(wrapped) + ":" +
+		base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptPII reverses EncryptPII. Values without the prefix were written
+// before encryption was enabled and are returned unchanged so that they can
+// be read until the background job re-encrypts them.
+func DecryptPII(value string) (string, error) {
+	if !strings.HasPrefix(value, piiPrefix) {
+		return value, nil
+	}
+
+	parts := strings.Split(strings.TrimPrefix(value, piiPrefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed PII ciphertext")
+	}
+
+	provider, err := currentPIIKeyProvider()
+	if err != nil {
+		return "", err
+	}
+
+	wrapped, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("malformed PII ciphertext")
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("malformed PII ciphertext")
+	}
+
+	dataKey, err := provider.UnwrapKey(parts[0], wrapped)
+	if err != nil {
+		return "", fmt.Errorf("failed to unwrap data key: %v", err)
+	}
+
+	plaintext, err := openAESGCM(dataKey, sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt value: %v", err)
+	}
+
+	return string(plaintext), nil
+}
+
+// BlindIndex returns a keyed hash that supports exact-match lookups on an
+// encrypted column. The purpose keeps indexes of different columns apart.
+func BlindIndex(purpose, value string) (string, error) {
+	provider, err := currentPIIKeyProvider()
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, provider.BlindIndexKey())
+	mac.Write([]byte(purpose + ":" + value))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func sealAESGCM(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openAESGCM(key, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
@@ -0,0 +1,277 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func piiTestKey(t *testing.T) string {
+	t.Helper()
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+func writePIIKeyFile(t *testing.T, lines ...string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pii.keys")
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// usePIIKeys installs the keys from path for the rest of the test.
+func usePIIKeys(t *testing.T, path, activeKeyID string) *LocalPIIKeyProvider {
+	t.Helper()
+	provider, err := LoadLocalPIIKeys(path, activeKeyID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	piiKeyProviderMu.RLock()
+	previous := piiKeyProvider
+	piiKeyProviderMu.RUnlock()
+	t.Cleanup(func() { SetPIIKeyProvider(previous) })
+
+	SetPIIKeyProvider(provider)
+	return provider
+}
+
+func TestPIIRoundTrip(t *testing.T) {
+	path := writePIIKeyFile(t, "index "+piiTestKey(t), "k1 "+piiTestKey(t))
+	usePIIKeys(t, path, "")
+
+	for _, plaintext := range []string{"jane.doe@example.com", "Zoë O'Brien", "+62 812 3456 7890", strings.Repeat("x", 4096)} {
+		ciphertext, err := EncryptPII(plaintext)
+		if err != nil {
+			t.Fatalf("EncryptPII(%q): %v", plaintext, err)
+		}
+		if !strings.HasPrefix(ciphertext, PIICiphertextPrefix("k1")) {
+			t.Errorf("ciphertext %q does not start with %q", ciphertext, PIICiphertextPrefix("k1"))
+		}
+		if strings.Contains(ciphertext, plaintext) {
+			t.Errorf("ciphertext contains the plaintext %q", plaintext)
+		}
+
+		decrypted, err := DecryptPII(ciphertext)
+		if err != nil {
+			t.Fatalf("DecryptPII: %v", err)
+		}
+		if decrypted != plaintext {
+			t.Errorf("DecryptPII = %q, want %q", decrypted, plaintext)
+		}
+	}
+}
+
+func TestPIIEncryptUsesFreshDataKeys(t *testing.T) {
+	path := writePIIKeyFile(t, "index "+piiTestKey(t), "k1 "+piiTestKey(t))
+	usePIIKeys(t, path, "")
+
+	first, err := EncryptPII("jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := EncryptPII("jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first == second {
+		t.Error("encrypting the same value twice gave the same ciphertext")
+	}
+}
+
+func TestPIIEmptyAndLegacyValues(t *testing.T) {
+	path := writePIIKeyFile(t, "index "+piiTestKey(t), "k1 "+piiTestKey(t))
+	usePIIKeys(t, path, "")
+
+	if ciphertext, err := EncryptPII(""); err != nil || ciphertext != "" {
+		t.Errorf("EncryptPII(\"\") = %q, %v, want empty", ciphertext, err)
+	}
+
+	// Rows written before encryption was enabled are read as they are.
+	if plaintext, err := DecryptPII("jane.doe@example.com"); err != nil || plaintext != "jane.doe@example.com" {
+		t.Errorf("DecryptPII(legacy) = %q, %v", plaintext, err)
+	}
+}
+
+func TestPIIKeyRotation(t *testing.T) {
+	index, k1, k2 := piiTestKey(t), piiTestKey(t), piiTestKey(t)
+
+	usePIIKeys(t, writePIIKeyFile(t, "index "+index, "k1 "+k1), "")
+	old, err := EncryptPII("jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldIndex, err := BlindIndex("email", "jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// k2 is listed last, so it becomes the active key while k1 still
+	// decrypts the values written before the rotation.
+	rotated := writePIIKeyFile(t, "index "+index, "k1 "+k1, "k2 "+k2)
+	if provider := usePIIKeys(t, rotated, ""); provider.ActiveKeyID() != "k2" {
+		t.Fatalf("active key = %q, want k2", provider.ActiveKeyID())
+	}
+
+	if plaintext, err := DecryptPII(old); err != nil || plaintext != "jane.doe@example.com" {
+		t.Errorf("DecryptPII(old) = %q, %v", plaintext, err)
+	}
+
+	reencrypted, err := EncryptPII("jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(reencrypted, PIICiphertextPrefix("k2")) {
+		t.Errorf("new ciphertext %q is not under k2", reencrypted)
+	}
+	if strings.HasPrefix(old, PIICiphertextPrefix("k2")) {
+		t.Errorf("old ciphertext %q is already under k2", old)
+	}
+
+	newIndex, err := BlindIndex("email", "jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newIndex != oldIndex {
+		t.Error("blind index changed when the data key rotated")
+	}
+
+	// PII_ACTIVE_KID can pin an older key.
+	if provider := usePIIKeys(t, rotated, "k1"); provider.ActiveKeyID() != "k1" {
+		t.Errorf("active key = %q, want k1", provider.ActiveKeyID())
+	}
+
+	// Once k1 is retired its values can no longer be read.
+	usePIIKeys(t, writePIIKeyFile(t, "index "+index, "k2 "+k2), "")
+	if _, err := DecryptPII(old); err == nil || !strings.Contains(err.Error(), ErrUnknownPIIKeyID.Error()) {
+		t.Errorf("DecryptPII after retiring k1: err = %v, want %v", err, ErrUnknownPIIKeyID)
+	}
+	if plaintext, err := DecryptPII(reencrypted); err != nil || plaintext != "jane.doe@example.com" {
+		t.Errorf("DecryptPII(reencrypted) = %q, %v", plaintext, err)
+	}
+}
+
+func TestPIIDecryptRejectsTampering(t *testing.T) {
+	usePIIKeys(t, writePIIKeyFile(t, "index "+piiTestKey(t), "k1 "+piiTestKey(t)), "")
+
+	ciphertext, err := EncryptPII("jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(ciphertext, ":")
+
+	sealed, err := base64.RawStdEncoding.DecodeString(parts[len(parts)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sealed[len(sealed)-1] ^= 0x01
+	flipped := strings.Join(append(parts[:len(parts)-1:len(parts)-1], base64.RawStdEncoding.EncodeToString(sealed)), ":")
+
+	for name, value := range map[string]string{
+		"flipped bit":    flipped,
+		"missing part":   strings.Join(parts[:len(parts)-1], ":"),
+		"invalid base64": strings.Join(append(parts[:len(parts)-1:len(parts)-1], "!!!"), ":"),
+		"unknown key":    strings.Replace(ciphertext, PIICiphertextPrefix("k1"), PIICiphertextPrefix("k9"), 1),
+	} {
+		if plaintext, err := DecryptPII(value); err == nil {
+			t.Errorf("%s: DecryptPII = %q, want an error", name, plaintext)
+		}
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	usePIIKeys(t, writePIIKeyFile(t, "index "+piiTestKey(t), "k1 "+piiTestKey(t)), "")
+
+	email, err := BlindIndex("email", "jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := BlindIndex("email", "jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if email != again {
+		t.Error("blind index is not deterministic")
+	}
+
+	phone, err := BlindIndex("phone", "jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if phone == email {
+		t.Error("blind indexes of different purposes are equal")
+	}
+
+	// A different index key must give unrelated indexes.
+	usePIIKeys(t, writePIIKeyFile(t, "index "+piiTestKey(t), "k1 "+piiTestKey(t)), "")
+	other, err := BlindIndex("email", "jane.doe@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == email {
+		t.Error("blind index does not depend on the index key")
+	}
+}
+
+func TestLoadLocalPIIKeysErrors(t *testing.T) {
+	index, k1 := piiTestKey(t), piiTestKey(t)
+	short := base64.StdEncoding.EncodeToString(make([]byte, 16))
+
+	tests := []struct {
+		name   string
+		lines  []string
+		active string
+	}{
+		{"no index key", []string{"k1 " + k1}, ""},
+		{"no data key", []string{"index " + index}, ""},
+		{"short key", []string{"index " + index, "k1 " + short}, ""},
+		{"not base64", []string{"index " + index, "k1 not-base64"}, ""},
+		{"colon in key ID", []string{"index " + index, "k:1 " + k1}, ""},
+		{"extra field", []string{"index " + index, "k1 " + k1 + " extra"}, ""},
+		{"unknown active key", []string{"index " + index, "k1 " + k1}, "k2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadLocalPIIKeys(writePIIKeyFile(t, tt.lines...), tt.active); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+
+	provider, err := LoadLocalPIIKeys(writePIIKeyFile(t, "# comment", "", "index "+index, "k1 "+k1), "")
+	if err != nil {
+		t.Fatalf("comments and blank lines: %v", err)
+	}
+	if provider.ActiveKeyID() != "k1" {
+		t.Errorf("active key = %q, want k1", provider.ActiveKeyID())
+	}
+}
+
+func TestPIIWithoutProvider(t *testing.T) {
+	piiKeyProviderMu.RLock()
+	previous := piiKeyProvider
+	piiKeyProviderMu.RUnlock()
+	t.Cleanup(func() { SetPIIKeyProvider(previous) })
+	SetPIIKeyProvider(nil)
+
+	if _, err := EncryptPII("jane.doe@example.com"); !errors.Is(err, ErrNoPIIKeys) {
+		t.Errorf("EncryptPII: err = %v, want ErrNoPIIKeys", err)
+	}
+	if _, err := BlindIndex("email", "jane.doe@example.com"); !errors.Is(err, ErrNoPIIKeys) {
+		t.Errorf("BlindIndex: err = %v, want ErrNoPIIKeys", err)
+	}
+	if _, err := DecryptPII(fmt.Sprintf("%sAAAA:AAAA", PIICiphertextPrefix("k1"))); !errors.Is(err, ErrNoPIIKeys) {
+		t.Errorf("DecryptPII: err = %v, want ErrNoPIIKeys", err)
+	}
+}
@@ -0,0 +1,502 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+type qrBlockLayout struct {
+	ecCodewords  int
+	group1Blocks int
+	group1Data   int
+	group2Blocks int
+	group2Data   int
+}
+
+// Error correction level M block layouts for versions 1 to 20.
+var qrLayouts = []qrBlockLayout{
+	{10, 1, 16, 0, 0},
+	{16, 1, 28, 0, 0},
+	{26, 1, 44, 0, 0},
+	{18, 2, 32, 0, 0},
+	{24, 2, 43, 0, 0},
+	{16, 4, 27, 0, 0},
+	{18, 4, 31, 0, 0},
+	{22, 2, 38, 2, 39},
+	{22, 3, 36, 2, 37},
+	{26, 4, 43, 1, 44},
+	{30, 1, 50, 4, 51},
+	{22, 6, 36, 2, 37},
+	{22, 8, 37, 1, 38},
+	{24, 4, 40, 5, 41},
+	{24, 5, 41, 5, 42},
+	{28, 7, 45, 3, 46},
+	{28, 10, 46, 1, 47},
+	{26, 9, 43, 4, 44},
+	{26, 3, 44, 11, 45},
+	{26, 3, 41, 13, 42},
+}
+
+var ErrQRCodeTooLong = errors.New("content is too long for a QR code")
+
+type qrCode struct {
+	size     int
+	modules  [][]bool
+	function [][]bool
+}
+
+func (l qrBlockLayout) dataCodewords() int {
+	return l.group1Blocks*l.group1Data + l.group2Blocks*l.group2Data
+}
+
+// QRCodePNG encodes content as a byte-mode QR code with medium error
+// correction and renders it as a PNG with a four-module quiet zone.
+func QRCodePNG(content string, scale int) ([]byte, error) {
+	code, err := encodeQRCode([]byte(content))
+	if err != nil {
+		return nil, err
+	}
+
+	if scale <= 0 {
+		scale = 1
+	}
+	const quiet = 4
+	width := (code.size + 2*quiet) * scale
+
+	img := image.NewPaletted(image.Rect(0, 0, width, width), color.Palette{color.White, color.Black})
+	for y := 0; y < code.size; y++ {
+		for x := 0; x < code.size; x++ {
+			if !code.modules[y][x] {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetColorIndex((x+quiet)*scale+dx, (y+quiet)*scale+dy, 1)
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func encodeQRCode(data []byte) (*qrCode, error) {
+	version := 0
+	for v := 1; v <= len(qrLayouts); v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+len(data)*8 <= qrLayouts[v-1].dataCodewords()*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, ErrQRCodeTooLong
+	}
+
+	layout := qrLayouts[version-1]
+	codewords := qrInterleave(layout, qrDataCodewords(data, version, layout.dataCodewords()))
+
+	code := newQRCode(version)
+	code.drawFunctionPatterns(version)
+	code.drawCodewords(codewords)
+
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		code.applyMask(mask)
+		code.drawFormatBits(mask)
+		if penalty := code.penalty(); bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		code.applyMask(mask)
+	}
+
+	code.applyMask(bestMask)
+	code.drawFormatBits(bestMask)
+
+	return code, nil
+}
+
+func qrDataCodewords(data []byte, version, capacity int) []byte {
+	var bits []bool
+	appendBits := func(value, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>i)&1 == 1)
+		}
+	}
+
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+
+	appendBits(0x4, 4)
+	appendBits(len(data), countBits)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	capacityBits := capacity * 8
+	for i := 0; i < 4 && len(bits) < capacityBits; i++ {
+		bits = append(bits, false)
+	}
+	for len(bits)%8 != 0 {
+		bits = append(bits, false)
+	}
+
+	codewords := make([]byte, 0, capacity)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << (7 - j)
+			}
+		}
+		codewords = append(codewords, b)
+	}
+
+	for pad := byte(0xEC); len(codewords) < capacity; pad ^= 0xEC ^ 0x11 {
+		codewords = append(codewords, pad)
+	}
+
+	return codewords
+}
+
+func qrInterleave(layout qrBlockLayout, data []byte) []byte {
+	divisor := qrReedSolomonDivisor(layout.ecCodewords)
+
+	var blocks, ecBlocks [][]byte
+	offset := 0
+	for i := 0; i < layout.group1Blocks+layout.group2Blocks; i++ {
+		length := layout.group1Data
+		if i >= layout.group1Blocks {
+			length = layout.group2Data
+		}
+		block := data[offset : offset+length]
+		offset += length
+
+		blocks = append(blocks, block)
+		ecBlocks = append(ecBlocks, qrReedSolomonRemainder(block, divisor))
+	}
+
+	var result []byte
+	for i := 0; i < max(layout.group1Data, layout.group2Data); i++ {
+		for _, block := range blocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < layout.ecCodewords; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+
+	return result
+}
+
+func qrReedSolomonDivisor(degree int) []byte {
+	divisor := make([]byte, degree)
+	divisor[degree-1] = 1
+
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := range divisor {
+			divisor[j] = qrMultiply(divisor[j], root)
+			if j+1 < len(divisor) {
+				divisor[j] ^= divisor[j+1]
+			}
+		}
+		root = qrMultiply(root, 0x02)
+	}
+
+	return divisor
+}
+
+func qrReedSolomonRemainder(data, divisor []byte) []byte {
+	result := make([]byte, len(divisor))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coefficient := range divisor {
+			result[i] ^= qrMultiply(coefficient, factor)
+		}
+	}
+	return result
+}
+
+func qrMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>i)&1) * int(x)
+	}
+	return byte(z)
+}
+
+func newQRCode(version int) *qrCode {
+	size := version*4 + 17
+	code := &qrCode{size: size}
+	code.modules = make([][]bool, size)
+	code.function = make([][]bool, size)
+	for i := range code.modules {
+		code.modules[i] = make([]bool, size)
+		code.function[i] = make([]bool, size)
+	}
+	return code
+}
+
+func (q *qrCode) set(x, y int, dark bool) {
+	q.modules[y][x] = dark
+	q.function[y][x] = true
+}
+
+func (q *qrCode) drawFunctionPatterns(version int) {
+	for i := 0; i < q.size; i++ {
+		q.set(6, i, i%2 == 0)
+		q.set(i, 6, i%2 == 0)
+	}
+
+	q.drawFinder(3, 3)
+	q.drawFinder(q.size-4, 3)
+	q.drawFinder(3, q.size-4)
+
+	positions := qrAlignmentPositions(version, q.size)
+	last := len(positions) - 1
+	for i, x := range positions {
+		for j, y := range positions {
+			if i == 0 && j == 0 || i == 0 && j == last || i == last && j == 0 {
+				continue
+			}
+			q.drawAlignment(x, y)
+		}
+	}
+
+	q.drawFormatBits(0)
+	q.drawVersion(version)
+}
+
+func (q *qrCode) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= q.size || y < 0 || y >= q.size {
+				continue
+			}
+			distance := max(absInt(dx), absInt(dy))
+			q.set(x, y, distance != 2 && distance != 4)
+		}
+	}
+}
+
+func (q *qrCode) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			q.set(cx+dx, cy+dy, max(absInt(dx), absInt(dy)) != 1)
+		}
+	}
+}
+
+func (q *qrCode) drawFormatBits(mask int) {
+	// Level M is encoded as 00 in the two error correction bits.
+	data := mask
+	remainder := data
+	for i := 0; i < 10; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 9) * 0x537)
+	}
+	bits := (data<<10 | remainder) ^ 0x5412
+
+	bit := func(i int) bool { return (bits>>i)&1 == 1 }
+
+	for i := 0; i <= 5; i++ {
+		q.set(8, i, bit(i))
+	}
+	q.set(8, 7, bit(6))
+	q.set(8, 8, bit(7))
+	q.set(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		q.set(14-i, 8, bit(i))
+	}
+
+	for i := 0; i < 8; i++ {
+		q.set(q.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		q.set(8, q.size-15+i, bit(i))
+	}
+	q.set(8, q.size-8, true)
+}
+
+func (q *qrCode) drawVersion(version int) {
+	if version < 7 {
+		return
+	}
+
+	remainder := version
+	for i := 0; i < 12; i++ {
+		remainder = (remainder << 1) ^ ((remainder >> 11) * 0x1F25)
+	}
+	bits := version<<12 | remainder
+
+	for i := 0; i < 18; i++ {
+		dark := (bits>>i)&1 == 1
+		a, b := q.size-11+i%3, i/3
+		q.set(a, b, dark)
+		q.set(b, a, dark)
+	}
+}
+
+func (q *qrCode) drawCodewords(codewords []byte) {
+	i := 0
+	for right := q.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		for vert := 0; vert < q.size; vert++ {
+			for j := 0; j < 2; j++ {
+				x := right - j
+				y := vert
+				if (right+1)&2 == 0 {
+					y = q.size - 1 - vert
+				}
+				if !q.function[y][x] && i < len(codewords)*8 {
+					q.modules[y][x] = (codewords[i>>3]>>(7-i&7))&1 == 1
+					i++
+				}
+			}
+		}
+	}
+}
+
+func (q *qrCode) applyMask(mask int) {
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.function[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				q.modules[y][x] = !q.modules[y][x]
+			}
+		}
+	}
+}
+
+func (q *qrCode) penalty() int {
+	result := 0
+	at := func(x, y int, horizontal bool) bool {
+		if horizontal {
+			return q.modules[y][x]
+		}
+		return q.modules[x][y]
+	}
+
+	finderLike := [][]bool{
+		{true, false, true, true, true, false, true, false, false, false, false},
+		{false, false, false, false, true, false, true, true, true, false, true},
+	}
+
+	for _, horizontal := range []bool{true, false} {
+		for y := 0; y < q.size; y++ {
+			run := 1
+			for x := 1; x < q.size; x++ {
+				if at(x, y, horizontal) == at(x-1, y, horizontal) {
+					run++
+					if run == 5 {
+						result += 3
+					} else if run > 5 {
+						result++
+					}
+				} else {
+					run = 1
+				}
+			}
+
+			for x := 0; x+11 <= q.size; x++ {
+				for _, pattern := range finderLike {
+					matched := true
+					for k, dark := range pattern {
+						if at(x+k, y, horizontal) != dark {
+							matched = false
+							break
+						}
+					}
+					if matched {
+						result += 40
+					}
+				}
+			}
+		}
+	}
+
+	dark := 0
+	for y := 0; y < q.size; y++ {
+		for x := 0; x < q.size; x++ {
+			if q.modules[y][x] {
+				dark++
+			}
+			if x+1 < q.size && y+1 < q.size {
+				c := q.modules[y][x]
+				if c == q.modules[y][x+1] && c == q.modules[y+1][x] && c == q.modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+
+	total := q.size * q.size
+	result += (absInt(dark*20-total*10)+total-1)/total*10 - 10
+
+	return result
+}
+
+func qrAlignmentPositions(version, size int) []int {
+	if version == 1 {
+		return nil
+	}
+
+	count := version/7 + 2
+	step := (version*8 + count*3 + 5) / (count*4 - 4) * 2
+	positions := make([]int, count)
+	positions[0] = 6
+	for i, pos := count-1, size-7; i >= 1; i, pos = i-1, pos-step {
+		positions[i] = pos
+	}
+	return positions
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,444 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+// The tables below are copied from ISO/IEC 18004 rather than derived from
+// the encoder, so that the decoder in this file checks the encoder against
+// the specification instead of against itself.
+
+// qrTestFormatWords are the masked format information words for error
+// correction level M, indexed by mask pattern.
+var qrTestFormatWords = []int{0x5412, 0x5125, 0x5E7C, 0x5B4B, 0x45F9, 0x40CE, 0x4F97, 0x4AA0}
+
+// qrTestVersionWords are the version information words for versions 7 to 20.
+var qrTestVersionWords = map[int]int{
+	7: 0x07C94, 8: 0x085BC, 9: 0x09A99, 10: 0x0A4D3, 11: 0x0BBF6, 12: 0x0C762, 13: 0x0D847,
+	14: 0x0E60D, 15: 0x0F928, 16: 0x10B78, 17: 0x1145D, 18: 0x12A17, 19: 0x13532, 20: 0x149A6,
+}
+
+var qrTestAlignment = map[int][]int{
+	2: {6, 18}, 3: {6, 22}, 4: {6, 26}, 5: {6, 30}, 6: {6, 34},
+	7: {6, 22, 38}, 8: {6, 24, 42}, 9: {6, 26, 46}, 10: {6, 28, 50}, 11: {6, 30, 54},
+	12: {6, 32, 58}, 13: {6, 34, 62}, 14: {6, 26, 46, 66}, 15: {6, 26, 48, 70},
+	16: {6, 26, 50, 74}, 17: {6, 30, 54, 78}, 18: {6, 30, 56, 82}, 19: {6, 30, 58, 86},
+	20: {6, 34, 62, 90},
+}
+
+// qrTestBlocks lists, for level M, the error correction codewords per block
+// followed by (count, data codewords) for each block group.
+var qrTestBlocks = map[int][5]int{
+	1: {10, 1, 16, 0, 0}, 2: {16, 1, 28, 0, 0}, 3: {26, 1, 44, 0, 0}, 4: {18, 2, 32, 0, 0},
+	5: {24, 2, 43, 0, 0}, 6: {16, 4, 27, 0, 0}, 7: {18, 4, 31, 0, 0}, 8: {22, 2, 38, 2, 39},
+	9: {22, 3, 36, 2, 37}, 10: {26, 4, 43, 1, 44}, 11: {30, 1, 50, 4, 51}, 12: {22, 6, 36, 2, 37},
+	13: {22, 8, 37, 1, 38}, 14: {24, 4, 40, 5, 41}, 15: {24, 5, 41, 5, 42}, 16: {28, 7, 45, 3, 46},
+	17: {28, 10, 46, 1, 47}, 18: {26, 9, 43, 4, 44}, 19: {26, 3, 44, 11, 45}, 20: {26, 3, 41, 13, 42},
+}
+
+var qrTestTotalCodewords = map[int]int{
+	1: 26, 2: 44, 3: 70, 4: 100, 5: 134, 6: 172, 7: 196, 8: 242, 9: 292, 10: 346,
+	11: 404, 12: 466, 13: 532, 14: 581, 15: 655, 16: 733, 17: 815, 18: 901, 19: 991, 20: 1085,
+}
+
+func TestQRCodePNGDecodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		length  int
+		version int
+	}{
+		{"version 1 full", 14, 1},
+		{"version 2", 15, 2},
+		{"version 6 full", 106, 6},
+		{"version 7 with version information", 107, 7},
+		{"version 8 split blocks", 123, 8},
+		{"version 9 full", 180, 9},
+		{"version 10 sixteen bit count", 181, 10},
+		{"version 20 full", 666, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := qrTestContent(tt.length)
+
+			data, err := QRCodePNG(content, 3)
+			if err != nil {
+				t.Fatalf("QRCodePNG: %v", err)
+			}
+
+			decoded, version, err := qrTestDecode(data, 3)
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if version != tt.version {
+				t.Errorf("version = %d, want %d", version, tt.version)
+			}
+			if decoded != content {
+				t.Errorf("decoded %q, want %q", decoded, content)
+			}
+		})
+	}
+}
+
+func TestQRCodePNGProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Core Banking", "jane.doe+mfa@example.com", "JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP")
+
+	// The scale used by MFA enrollment.
+	data, err := QRCodePNG(uri, 6)
+	if err != nil {
+		t.Fatalf("QRCodePNG: %v", err)
+	}
+
+	decoded, _, err := qrTestDecode(data, 6)
+	if err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if decoded != uri {
+		t.Errorf("decoded %q, want %q", decoded, uri)
+	}
+}
+
+func TestQRCodePNGTooLong(t *testing.T) {
+	if _, err := QRCodePNG(qrTestContent(667), 1); !errors.Is(err, ErrQRCodeTooLong) {
+		t.Fatalf("err = %v, want ErrQRCodeTooLong", err)
+	}
+}
+
+// qrTestContent returns n bytes that include multi-byte UTF-8 so that byte
+// mode is exercised beyond ASCII.
+func qrTestContent(n int) string {
+	const alphabet = "otpauth://totp/Bank:üser@example.com?secret=JBSWY3DPEHPK3PXP&issuer=Bänk "
+	var b strings.Builder
+	for b.Len() < n {
+		b.WriteString(alphabet)
+	}
+	return b.String()[:n]
+}
+
+func qrTestDecode(data []byte, scale int) (string, int, error) {
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return "", 0, err
+	}
+
+	const quiet = 4
+	width := img.Bounds().Dx()
+	if width != img.Bounds().Dy() || width%scale != 0 {
+		return "", 0, fmt.Errorf("unexpected image size %dx%d", width, img.Bounds().Dy())
+	}
+	size := width/scale - 2*quiet
+	if size < 21 || (size-17)%4 != 0 {
+		return "", 0, fmt.Errorf("invalid symbol size %d", size)
+	}
+	version := (size - 17) / 4
+
+	pixel := func(px, py int) bool {
+		return color.GrayModel.Convert(img.At(px, py)).(color.Gray).Y < 128
+	}
+	for py := 0; py < width; py++ {
+		for px := 0; px < width; px++ {
+			inSymbol := px >= quiet*scale && px < (quiet+size)*scale && py >= quiet*scale && py < (quiet+size)*scale
+			if !inSymbol && pixel(px, py) {
+				return "", 0, fmt.Errorf("dark pixel in quiet zone at %d,%d", px, py)
+			}
+		}
+	}
+
+	dark := func(x, y int) bool {
+		return pixel((x+quiet)*scale+scale/2, (y+quiet)*scale+scale/2)
+	}
+	return qrTestDecodeModules(dark, size, version)
+}
+
+func qrTestDecodeModules(dark func(x, y int) bool, size, version int) (string, int, error) {
+	abs := func(v int) int {
+		if v < 0 {
+			return -v
+		}
+		return v
+	}
+
+	for _, c := range [][2]int{{3, 3}, {size - 4, 3}, {3, size - 4}} {
+		for dy := -4; dy <= 4; dy++ {
+			for dx := -4; dx <= 4; dx++ {
+				x, y := c[0]+dx, c[1]+dy
+				if x < 0 || y < 0 || x >= size || y >= size {
+					continue
+				}
+				d := max(abs(dx), abs(dy))
+				if want := d != 2 && d != 4; dark(x, y) != want {
+					return "", 0, fmt.Errorf("finder pattern at %d,%d is wrong at %d,%d", c[0], c[1], x, y)
+				}
+			}
+		}
+	}
+
+	for i := 8; i < size-8; i++ {
+		if dark(i, 6) != (i%2 == 0) || dark(6, i) != (i%2 == 0) {
+			return "", 0, fmt.Errorf("timing pattern is wrong at %d", i)
+		}
+	}
+
+	if !dark(8, size-8) {
+		return "", 0, errors.New("dark module is missing")
+	}
+
+	function := make([][]bool, size)
+	for y := range function {
+		function[y] = make([]bool, size)
+		for x := range function[y] {
+			function[y][x] = x == 6 || y == 6 ||
+				x < 9 && y < 9 || x >= size-8 && y < 9 || x < 9 && y >= size-8
+		}
+	}
+
+	positions := qrTestAlignment[version]
+	for _, cx := range positions {
+		for _, cy := range positions {
+			if cx < 9 && cy < 9 || cx >= size-8 && cy < 9 || cx < 9 && cy >= size-8 {
+				continue
+			}
+			for dy := -2; dy <= 2; dy++ {
+				for dx := -2; dx <= 2; dx++ {
+					if want := max(abs(dx), abs(dy)) != 1; dark(cx+dx, cy+dy) != want {
+						return "", 0, fmt.Errorf("alignment pattern at %d,%d is wrong", cx, cy)
+					}
+					function[cy+dy][cx+dx] = true
+				}
+			}
+		}
+	}
+
+	readBits := func(coords [][2]int) int {
+		value := 0
+		for _, c := range coords {
+			value <<= 1
+			if dark(c[0], c[1]) {
+				value |= 1
+			}
+		}
+		return value
+	}
+
+	if version >= 7 {
+		var topRight, bottomLeft [][2]int
+		for j := 5; j >= 0; j-- {
+			for i := size - 9; i >= size-11; i-- {
+				topRight = append(topRight, [2]int{i, j})
+				bottomLeft = append(bottomLeft, [2]int{j, i})
+				function[j][i] = true
+				function[i][j] = true
+			}
+		}
+		for _, coords := range [][][2]int{topRight, bottomLeft} {
+			if got := readBits(coords); got != qrTestVersionWords[version] {
+				return "", 0, fmt.Errorf("version information is %05X, want %05X", got, qrTestVersionWords[version])
+			}
+		}
+	}
+
+	first := [][2]int{{0, 8}, {1, 8}, {2, 8}, {3, 8}, {4, 8}, {5, 8}, {7, 8}, {8, 8}, {8, 7}, {8, 5}, {8, 4}, {8, 3}, {8, 2}, {8, 1}, {8, 0}}
+	var second [][2]int
+	for y := size - 1; y >= size-7; y-- {
+		second = append(second, [2]int{8, y})
+	}
+	for x := size - 8; x < size; x++ {
+		second = append(second, [2]int{x, 8})
+	}
+	format := readBits(first)
+	if readBits(second) != format {
+		return "", 0, errors.New("format information copies differ")
+	}
+	mask := -1
+	for m, word := range qrTestFormatWords {
+		if word == format {
+			mask = m
+		}
+	}
+	if mask < 0 {
+		return "", 0, fmt.Errorf("format information %04X is not level M", format)
+	}
+
+	dataModules := 0
+	for y := range function {
+		for x := range function[y] {
+			if !function[y][x] {
+				dataModules++
+			}
+		}
+	}
+	total := qrTestTotalCodewords[version]
+	if dataModules/8 != total {
+		return "", 0, fmt.Errorf("symbol has %d data modules, want %d codewords", dataModules, total)
+	}
+
+	masked := func(row, col int) bool {
+		switch mask {
+		case 0:
+			return (row+col)%2 == 0
+		case 1:
+			return row%2 == 0
+		case 2:
+			return col%3 == 0
+		case 3:
+			return (row+col)%3 == 0
+		case 4:
+			return (row/2+col/3)%2 == 0
+		case 5:
+			return row*col%2+row*col%3 == 0
+		case 6:
+			return (row*col%2+row*col%3)%2 == 0
+		default:
+			return ((row+col)%2+row*col%3)%2 == 0
+		}
+	}
+
+	codewords := make([]byte, 0, total)
+	var current byte
+	bits := 0
+	up := true
+	for right := size - 1; right > 0; right -= 2 {
+		if right == 6 {
+			right--
+		}
+		for count := 0; count < size; count++ {
+			row := count
+			if up {
+				row = size - 1 - count
+			}
+			for col := right; col > right-2; col-- {
+				if function[row][col] || len(codewords) == total {
+					continue
+				}
+				current <<= 1
+				if dark(col, row) != masked(row, col) {
+					current |= 1
+				}
+				if bits++; bits == 8 {
+					codewords = append(codewords, current)
+					current, bits = 0, 0
+				}
+			}
+		}
+		up = !up
+	}
+
+	layout := qrTestBlocks[version]
+	ecLength := layout[0]
+	var blocks [][]byte
+	for i := 0; i < layout[1]; i++ {
+		blocks = append(blocks, make([]byte, 0, layout[2]+ecLength))
+	}
+	for i := 0; i < layout[3]; i++ {
+		blocks = append(blocks, make([]byte, 0, layout[4]+ecLength))
+	}
+	dataLength := func(b int) int {
+		if b < layout[1] {
+			return layout[2]
+		}
+		return layout[4]
+	}
+
+	next := 0
+	for i := 0; i < max(layout[2], layout[4]); i++ {
+		for b := range blocks {
+			if i < dataLength(b) {
+				blocks[b] = append(blocks[b], codewords[next])
+				next++
+			}
+		}
+	}
+	for i := 0; i < ecLength; i++ {
+		for b := range blocks {
+			blocks[b] = append(blocks[b], codewords[next])
+			next++
+		}
+	}
+
+	// Every codeword polynomial must vanish at the generator roots a^0 to
+	// a^(n-1); a non-zero syndrome means the error correction is wrong.
+	exp, log := qrTestGaloisTables()
+	multiply := func(a, b byte) byte {
+		if a == 0 || b == 0 {
+			return 0
+		}
+		return exp[(int(log[a])+int(log[b]))%255]
+	}
+	var stream []byte
+	for b, block := range blocks {
+		for i := 0; i < ecLength; i++ {
+			var syndrome byte
+			for _, c := range block {
+				syndrome = multiply(syndrome, exp[i]) ^ c
+			}
+			if syndrome != 0 {
+				return "", 0, fmt.Errorf("block %d has a non-zero syndrome %d", b, i)
+			}
+		}
+		stream = append(stream, block[:dataLength(b)]...)
+	}
+
+	position := 0
+	read := func(n int) int {
+		value := 0
+		for i := 0; i < n; i++ {
+			value <<= 1
+			if position < len(stream)*8 && (stream[position/8]>>(7-position%8))&1 == 1 {
+				value |= 1
+			}
+			position++
+		}
+		return value
+	}
+
+	if mode := read(4); mode != 0x4 {
+		return "", 0, fmt.Errorf("mode indicator is %04b, want byte mode", mode)
+	}
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	length := read(countBits)
+	if position+length*8 > len(stream)*8 {
+		return "", 0, fmt.Errorf("character count %d exceeds capacity", length)
+	}
+	content := make([]byte, length)
+	for i := range content {
+		content[i] = byte(read(8))
+	}
+
+	if terminator := min(4, len(stream)*8-position); read(terminator) != 0 {
+		return "", 0, errors.New("terminator is not zero")
+	}
+	if position%8 != 0 && read(8-position%8) != 0 {
+		return "", 0, errors.New("bit padding is not zero")
+	}
+	for i, pad := position/8, byte(0xEC); i < len(stream); i, pad = i+1, pad^0xEC^0x11 {
+		if stream[i] != pad {
+			return "", 0, fmt.Errorf("pad codeword %d is %02X, want %02X", i, stream[i], pad)
+		}
+	}
+
+	return string(content), version, nil
+}
+
+func qrTestGaloisTables() ([256]byte, [256]byte) {
+	var exp, log [256]byte
+	value := 1
+	for i := 0; i < 255; i++ {
+		exp[i] = byte(value)
+		log[value] = byte(i)
+		value <<= 1
+		if value >= 256 {
+			value ^= 0x11D
+		}
+	}
+	exp[255] = exp[0]
+	return exp, log
+}
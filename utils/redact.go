@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const redacted = "[REDACTED]"
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+(@|%40)[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`(\+|%2B)\d[\d\s\-]{6,}\d`)
+)
+
+var piiLogFields = map[string]bool{
+	"email":      true,
+	"first_name": true,
+	"last_name":  true,
+	"phone":      true,
+}
+
+// RedactPII masks email addresses and international phone numbers, including
+// URL encoded ones, in free text such as log messages and request paths.
+func RedactPII(text string) string {
+	text = emailPattern.ReplaceAllString(text, redacted)
+	return phonePattern.ReplaceAllString(text, redacted)
+}
+
+// PIIRedactionHook scrubs log entries before they are written: fields named
+// after a PII column are replaced outright and other string fields and the
+// message are passed through RedactPII.
+type PIIRedactionHook struct{}
+
+func (PIIRedactionHook) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (PIIRedactionHook) Fire(entry *logrus.Entry) error {
+	for key, value := range entry.Data {
+		if piiLogFields[strings.ToLower(key)] {
+			entry.Data[key] = redacted
+			continue
+		}
+		if text, ok := value.(string); ok {
+			entry.Data[key] = RedactPII(text)
+		}
+	}
+	entry.Message = RedactPII(entry.Message)
+	return nil
+}
@@ -0,0 +1,31 @@
+package utils
+
+import (
+	"os"
+	"time"
+)
+
+const defaultBankTimezone = "Asia/Jakarta"
+
+func BankLocation() *time.Location {
+	name := os.Getenv("BANK_TIMEZONE")
+	if name == "" {
+		name = defaultBankTimezone
+	}
+
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		return time.UTC
+	}
+	return location
+}
+
+func StartOfMonth(t time.Time) time.Time {
+	year, month, _ := t.Date()
+	return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+}
+
+func BusinessDate(t time.Time) time.Time {
+	year, month, day := t.In(BankLocation()).Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the ASCII key "12345678901234567890" used by the SHA-1 test
+// vectors of RFC 4226 and RFC 6238, in base32.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCodeRFC4226(t *testing.T) {
+	// RFC 4226 appendix D: HOTP values for counters 0 to 9.
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+
+	for counter, expected := range want {
+		code, err := TOTPCode(rfcSecret, int64(counter))
+		if err != nil {
+			t.Fatalf("counter %d: %v", counter, err)
+		}
+		if code != expected {
+			t.Errorf("counter %d: code = %s, want %s", counter, code, expected)
+		}
+	}
+}
+
+func TestTOTPCodeRFC6238(t *testing.T) {
+	// RFC 6238 appendix B lists eight digit SHA-1 codes; six digit codes are
+	// their last six digits.
+	tests := []struct {
+		unix int64
+		step int64
+		code string
+	}{
+		{59, 0x1, "94287082"},
+		{1111111109, 0x23523EC, "07081804"},
+		{1111111111, 0x23523ED, "14050471"},
+		{1234567890, 0x273EF07, "89005924"},
+		{2000000000, 0x3F940AA, "69279037"},
+		{20000000000, 0x27BC86AA, "65353130"},
+	}
+
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0).UTC()
+		if step := TOTPStep(at); step != tt.step {
+			t.Errorf("%d: step = %X, want %X", tt.unix, step, tt.step)
+		}
+
+		code, err := TOTPCode(rfcSecret, TOTPStep(at))
+		if err != nil {
+			t.Fatalf("%d: %v", tt.unix, err)
+		}
+		if want := tt.code[len(tt.code)-TOTPDigits:]; code != want {
+			t.Errorf("%d: code = %s, want %s", tt.unix, code, want)
+		}
+	}
+}
+
+func TestTOTPCodeNormalizesSecret(t *testing.T) {
+	code, err := TOTPCode(" "+strings.ToLower(rfcSecret)+" ", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if code != "287082" {
+		t.Errorf("code = %s, want 287082", code)
+	}
+
+	if _, err := TOTPCode("not base32!", 1); err == nil {
+		t.Error("expected an error for an invalid secret")
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	at := time.Unix(1111111111, 0)
+	current := TOTPStep(at)
+
+	codeAt := func(step int64) string {
+		code, err := TOTPCode(rfcSecret, step)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name string
+		code string
+		step int64
+		ok   bool
+	}{
+		{"current step", codeAt(current), current, true},
+		{"previous step", codeAt(current - 1), current - 1, true},
+		{"next step", codeAt(current + 1), current + 1, true},
+		{"two steps old", codeAt(current - 2), 0, false},
+		{"two steps ahead", codeAt(current + 2), 0, false},
+		{"spaces", " " + codeAt(current)[:3] + " " + codeAt(current)[3:] + " ", current, true},
+		{"too short", codeAt(current)[:5], 0, false},
+		{"too long", codeAt(current) + "0", 0, false},
+		{"empty", "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(rfcSecret, tt.code, at)
+			if ok != tt.ok || step != tt.step {
+				t.Errorf("ValidateTOTP(%q) = %d, %v, want %d, %v", tt.code, step, ok, tt.step, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := totpEncoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("key length = %d, want 20", len(key))
+	}
+
+	other, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other == secret {
+		t.Error("two generated secrets are equal")
+	}
+}
@@ -0,0 +1,54 @@
+package utils
+
+import "strings"
+
+// DeviceName gives a short human readable description such as
+// "Chrome on Windows" for a User-Agent header.
+func DeviceName(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	ua := strings.ToLower(userAgent)
+
+	client := "Unknown client"
+	for _, candidate := range []struct{ token, name string }{
+		{"edg/", "Edge"},
+		{"opr/", "Opera"},
+		{"firefox/", "Firefox"},
+		{"chrome/", "Chrome"},
+		{"safari/", "Safari"},
+		{"okhttp", "Android app"},
+		{"cfnetwork", "iOS app"},
+		{"postmanruntime", "Postman"},
+		{"curl/", "curl"},
+		{"go-http-client", "Go client"},
+		{"python-requests", "Python client"},
+	} {
+		if strings.Contains(ua, candidate.token) {
+			client = candidate.name
+			break
+		}
+	}
+
+	platform := ""
+	for _, candidate := range []struct{ token, name string }{
+		{"iphone", "iPhone"},
+		{"ipad", "iPad"},
+		{"android", "Android"},
+		{"windows", "Windows"},
+		{"mac os x", "macOS"},
+		{"cros", "ChromeOS"},
+		{"linux", "Linux"},
+	} {
+		if strings.Contains(ua, candidate.token) {
+			platform = candidate.name
+			break
+		}
+	}
+
+	if platform == "" {
+		return client
+	}
+	return client + " on " + platform
+}